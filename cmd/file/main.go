@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/Sene4ka/cloud_storage/configs"
 	"github.com/Sene4ka/cloud_storage/internal/api"
+	"github.com/Sene4ka/cloud_storage/internal/encryption"
 	"github.com/Sene4ka/cloud_storage/internal/file"
 	"github.com/Sene4ka/cloud_storage/internal/metrics"
 	"github.com/Sene4ka/cloud_storage/internal/repositories"
@@ -20,7 +22,14 @@ import (
 	"google.golang.org/grpc"
 )
 
+// keyRotationBatchSize is the number of user keys rewrapped per query by
+// -rotate-keys.
+const keyRotationBatchSize = 100
+
 func main() {
+	rotateKeys := flag.Bool("rotate-keys", false, "rewrap all user keys with the current master key and exit")
+	flag.Parse()
+
 	config := configs.LoadConfig()
 
 	_, port, err := net.SplitHostPort(config.Services.FileAddr)
@@ -42,11 +51,55 @@ func main() {
 	}
 	defer dbpool.Close()
 
-	fileRepo := repositories.NewFileRepository(dbpool)
-	fileSvc, err := file.NewFileServiceWithMinio(fileRepo, config)
+	deps := file.Dependencies{
+		Files:    repositories.NewFileRepository(dbpool),
+		Quotas:   repositories.NewQuotaRepository(dbpool),
+		Versions: repositories.NewVersionRepository(dbpool),
+		Links:    repositories.NewShareLinkRepository(dbpool),
+		Blobs:    repositories.NewBlobRepository(dbpool),
+	}
+	keyRepo := repositories.NewUserKeyRepository(dbpool)
+
+	if *rotateKeys {
+		rotateUserKeys(config, keyRepo)
+		return
+	}
+
+	placement, err := file.LoadPlacementPolicy(config)
+	if err != nil {
+		log.Fatalf("Failed to load placement policy: %v", err)
+	}
+	store, presigned, err := file.NewStorage(config, placement)
+	if err != nil {
+		log.Fatalf("Failed to create storage: %v", err)
+	}
+	fileSvc, err := file.NewFileServiceWithStorage(deps, keyRepo, store, presigned, placement, config)
 	if err != nil {
 		log.Fatalf("Failed to create file service: %v", err)
 	}
+	if local, ok := store.(http.Handler); ok {
+		go func() {
+			log.Printf("Local storage server starting on port %s", config.Storage.LocalPort)
+			if err := http.ListenAndServe(fmt.Sprintf(":%s", config.Storage.LocalPort), local); err != nil {
+				log.Fatalf("Failed to serve local storage: %v", err)
+			}
+		}()
+	}
+
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+	go fileSvc.RunUploadReaper(reaperCtx, config.Uploads.GCInterval)
+	go fileSvc.RunTrashPurger(reaperCtx, config.Trash.PurgeInterval)
+	if config.Versions.MaxVersions > 0 || config.Versions.MaxAge > 0 {
+		go fileSvc.RunVersionPruner(reaperCtx, config.Versions.PruneInterval)
+	}
+	go fileSvc.RunPreviewWorker(reaperCtx, config.Previews.Interval)
+	if config.Contents.MaxSize > 0 {
+		go fileSvc.RunTextExtractor(reaperCtx, config.Contents.Interval)
+	}
+	if len(placement.Migrations) > 0 {
+		go fileSvc.RunTierMigrator(reaperCtx, config.Storage.MigrationInterval)
+	}
 
 	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(metrics.UnaryServerInterceptor()))
 	fileServer := file.NewServer(fileSvc)
@@ -70,6 +123,7 @@ func main() {
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
 		log.Println("Shutting down file service...")
+		stopReaper()
 		grpcServer.GracefulStop()
 	}()
 
@@ -78,3 +132,22 @@ func main() {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
+
+// rotateUserKeys rewraps the user keys still wrapped with an older master
+// key. Objects are not rewritten, so the old key can be removed from
+// ENCRYPTION_MASTER_KEYS once this succeeds.
+func rotateUserKeys(config *configs.Config, keyRepo encryption.KeyRepository) {
+	keyring, err := encryption.NewKeyring(config.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to load master keys: %v", err)
+	}
+	if keyring == nil {
+		log.Fatalf("No master keys configured")
+	}
+
+	rotated, err := encryption.NewKeyService(keyRepo, keyring).Rotate(context.Background(), keyRotationBatchSize)
+	if err != nil {
+		log.Fatalf("Rotated %d user keys: %v", rotated, err)
+	}
+	log.Printf("Rotated %d user keys to master key %s", rotated, keyring.CurrentID())
+}
@@ -43,7 +43,10 @@ func main() {
 	defer dbpool.Close()
 
 	fileRepo := repositories.NewFileRepository(dbpool)
-	metadataSvc := metadata.NewMetadataService(fileRepo)
+	folderRepo := repositories.NewFolderRepository(dbpool)
+	shareRepo := repositories.NewShareRepository(dbpool)
+	userRepo := repositories.NewUserRepository(dbpool)
+	metadataSvc := metadata.NewMetadataService(fileRepo, folderRepo, shareRepo, userRepo)
 
 	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(metrics.UnaryServerInterceptor()))
 	metadataServer := metadata.NewServer(metadataSvc)
@@ -7,14 +7,21 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	MinIO    MinIOConfig
-	JWT      JWTConfig
-	Services ServicesConfig
-	SMTP     SMTPConfig
-	Metrics  MetricsConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	MinIO      MinIOConfig
+	Storage    StorageConfig
+	JWT        JWTConfig
+	Services   ServicesConfig
+	SMTP       SMTPConfig
+	Metrics    MetricsConfig
+	Uploads    UploadsConfig
+	Trash      TrashConfig
+	Versions   VersionsConfig
+	Previews   PreviewsConfig
+	Contents   ContentsConfig
+	Encryption EncryptionConfig
 }
 
 type ServerConfig struct {
@@ -50,6 +57,21 @@ type MinIOConfig struct {
 	Region          string
 }
 
+// StorageConfig selects the object storage driver: "minio", or "local" to
+// keep objects under LocalRoot and serve presigned URLs signed with
+// LocalSigningKey from LocalPort, reachable by clients at LocalPublicURL.
+// PlacementFile names a JSON placement policy spreading objects over
+// several buckets; without one everything goes to MINIO_BUCKET.
+type StorageConfig struct {
+	Driver            string
+	LocalRoot         string
+	LocalPort         string
+	LocalPublicURL    string
+	LocalSigningKey   string
+	PlacementFile     string
+	MigrationInterval time.Duration
+}
+
 type JWTConfig struct {
 	Secret          string
 	AccessTokenTTL  time.Duration
@@ -75,6 +97,45 @@ type MetricsConfig struct {
 	Port string
 }
 
+type UploadsConfig struct {
+	GCInterval   time.Duration
+	MultipartTTL time.Duration
+	ProxyMaxSize int64
+}
+
+type TrashConfig struct {
+	RetentionDays int
+	PurgeInterval time.Duration
+}
+
+type PreviewsConfig struct {
+	Interval time.Duration
+}
+
+// ContentsConfig controls text extraction for content search. Objects
+// larger than MaxSize bytes are not read; 0 disables extraction.
+type ContentsConfig struct {
+	Interval time.Duration
+	MaxSize  int64
+}
+
+// EncryptionConfig controls SSE-C encryption of stored objects. MasterKeys
+// lists the master keys user keys are wrapped with as comma separated
+// id:base64 pairs; MasterKeyID picks the one new keys are wrapped with and
+// may be left empty when there is only one. SSE-C needs MinIO to be
+// reached over TLS.
+type EncryptionConfig struct {
+	Enabled     bool
+	MasterKeys  string
+	MasterKeyID string
+}
+
+type VersionsConfig struct {
+	MaxVersions   int
+	MaxAge        time.Duration
+	PruneInterval time.Duration
+}
+
 func LoadConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -106,6 +167,15 @@ func LoadConfig() *Config {
 			BucketName:      getEnv("MINIO_BUCKET", "cloud-storage"),
 			Region:          getEnv("MINIO_REGION", "us-east-1"),
 		},
+		Storage: StorageConfig{
+			Driver:            getEnv("STORAGE_DRIVER", "minio"),
+			LocalRoot:         getEnv("STORAGE_LOCAL_ROOT", "./data/storage"),
+			LocalPort:         getEnv("STORAGE_LOCAL_PORT", "9010"),
+			LocalPublicURL:    getEnv("STORAGE_LOCAL_PUBLIC_URL", "http://localhost:9010"),
+			LocalSigningKey:   getEnv("STORAGE_LOCAL_SIGNING_KEY", "your-storage-signing-key-change-in-production"),
+			PlacementFile:     getEnv("STORAGE_PLACEMENT_FILE", ""),
+			MigrationInterval: getDurationEnv("STORAGE_MIGRATION_INTERVAL", time.Hour),
+		},
 		JWT: JWTConfig{
 			Secret:          getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 			AccessTokenTTL:  getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute),
@@ -127,6 +197,32 @@ func LoadConfig() *Config {
 		Metrics: MetricsConfig{
 			Port: getEnv("METRICS_PORT", "9002"),
 		},
+		Uploads: UploadsConfig{
+			GCInterval:   getDurationEnv("UPLOAD_GC_INTERVAL", time.Minute),
+			MultipartTTL: getDurationEnv("MULTIPART_UPLOAD_TTL", 24*time.Hour),
+			ProxyMaxSize: getInt64Env("UPLOAD_PROXY_MAX_SIZE", 5<<30),
+		},
+		Trash: TrashConfig{
+			RetentionDays: getIntEnv("TRASH_RETENTION_DAYS", 30),
+			PurgeInterval: getDurationEnv("TRASH_PURGE_INTERVAL", time.Hour),
+		},
+		Versions: VersionsConfig{
+			MaxVersions:   getIntEnv("FILE_MAX_VERSIONS", 10),
+			MaxAge:        getDurationEnv("FILE_VERSION_MAX_AGE", 0),
+			PruneInterval: getDurationEnv("FILE_VERSION_PRUNE_INTERVAL", time.Hour),
+		},
+		Previews: PreviewsConfig{
+			Interval: getDurationEnv("PREVIEW_INTERVAL", 30*time.Second),
+		},
+		Contents: ContentsConfig{
+			Interval: getDurationEnv("CONTENT_INDEX_INTERVAL", 30*time.Second),
+			MaxSize:  getInt64Env("CONTENT_INDEX_MAX_SIZE", 20<<20),
+		},
+		Encryption: EncryptionConfig{
+			Enabled:     getBoolEnv("ENCRYPTION_ENABLED", false),
+			MasterKeys:  getEnv("ENCRYPTION_MASTER_KEYS", ""),
+			MasterKeyID: getEnv("ENCRYPTION_MASTER_KEY_ID", ""),
+		},
 	}
 }
 
@@ -146,6 +242,15 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getInt64Env(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
@@ -0,0 +1,111 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+)
+
+// KeySize is the size of master and data keys, which are AES-256 keys.
+const KeySize = 32
+
+// Keyring holds the master keys user data keys are wrapped with. New keys
+// are wrapped with the current one; the others stay around to unwrap keys
+// that have not been rotated yet.
+type Keyring struct {
+	keys    map[string][]byte
+	current string
+}
+
+// NewKeyring parses the master keys in config. It returns nil when none are
+// configured, which is only allowed while encryption is disabled.
+func NewKeyring(config configs.EncryptionConfig) (*Keyring, error) {
+	if config.MasterKeys == "" {
+		if config.Enabled {
+			return nil, fmt.Errorf("encryption is enabled but no master key is configured")
+		}
+		return nil, nil
+	}
+
+	keyring := &Keyring{keys: make(map[string][]byte), current: config.MasterKeyID}
+	for _, entry := range strings.Split(config.MasterKeys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid master key entry %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("master key %s must be %d base64 encoded bytes", id, KeySize)
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("master key %s is configured twice", id)
+		}
+		keyring.keys[id] = key
+	}
+
+	if keyring.current == "" {
+		if len(keyring.keys) != 1 {
+			return nil, fmt.Errorf("the current master key id is required when several master keys are configured")
+		}
+		for id := range keyring.keys {
+			keyring.current = id
+		}
+	}
+	if _, ok := keyring.keys[keyring.current]; !ok {
+		return nil, fmt.Errorf("master key %s is not configured", keyring.current)
+	}
+	return keyring, nil
+}
+
+// CurrentID returns the id of the master key new keys are wrapped with.
+func (k *Keyring) CurrentID() string {
+	return k.current
+}
+
+// Wrap encrypts a data key with the current master key. The user id is
+// authenticated along, so a wrapped key cannot be moved to another user.
+func (k *Keyring) Wrap(userID string, dataKey []byte) ([]byte, error) {
+	aead, err := newAEAD(k.keys[k.current])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(userID)), nil
+}
+
+// Unwrap decrypts a data key wrapped with the master key masterKeyID.
+func (k *Keyring) Unwrap(userID, masterKeyID string, wrapped []byte) ([]byte, error) {
+	masterKey, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s is not configured", masterKeyID)
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,108 @@
+package encryption
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"log"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+)
+
+type KeyRepository interface {
+	Get(ctx context.Context, userID string) (*models.UserKey, error)
+	Create(ctx context.Context, key *models.UserKey) (*models.UserKey, error)
+	ListStale(ctx context.Context, masterKeyID string, limit int) ([]*models.UserKey, error)
+	Rewrap(ctx context.Context, key *models.UserKey, wrappedKey []byte, masterKeyID string) (bool, error)
+}
+
+// KeyService manages the per-user data keys objects are encrypted with.
+type KeyService struct {
+	repo    KeyRepository
+	keyring *Keyring
+}
+
+func NewKeyService(repo KeyRepository, keyring *Keyring) *KeyService {
+	return &KeyService{repo: repo, keyring: keyring}
+}
+
+// DataKey returns the data key of a user, creating one on first use.
+func (s *KeyService) DataKey(ctx context.Context, userID string) ([]byte, error) {
+	key, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		dataKey := make([]byte, KeySize)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, fmt.Errorf("failed to generate data key: %w", err)
+		}
+		wrapped, err := s.keyring.Wrap(userID, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		key, err = s.repo.Create(ctx, models.NewUserKey(userID, wrapped, s.keyring.CurrentID()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.keyring.Unwrap(userID, key.MasterKeyID, key.WrappedKey)
+}
+
+// Rotate rewraps every user key that is not wrapped with the current master
+// key yet, batchSize keys at a time, and returns how many it rewrapped.
+// Objects are left alone: their keys derive from the data keys, which do
+// not change. Once no key refers to an old master key anymore, it can be
+// dropped from the configuration.
+func (s *KeyService) Rotate(ctx context.Context, batchSize int) (int, error) {
+	rotated := 0
+	failed := make(map[string]bool)
+	for {
+		keys, err := s.repo.ListStale(ctx, s.keyring.CurrentID(), batchSize)
+		if err != nil {
+			return rotated, err
+		}
+
+		batchFailed := 0
+		for _, key := range keys {
+			if err := s.rewrap(ctx, key); err != nil {
+				log.Printf("Failed to rotate key of user %s: %v", key.UserID, err)
+				failed[key.UserID] = true
+				batchFailed++
+				continue
+			}
+			rotated++
+		}
+
+		if len(keys) < batchSize || batchFailed == len(keys) {
+			if len(failed) > 0 {
+				return rotated, fmt.Errorf("failed to rotate %d keys", len(failed))
+			}
+			return rotated, nil
+		}
+	}
+}
+
+func (s *KeyService) rewrap(ctx context.Context, key *models.UserKey) error {
+	dataKey, err := s.keyring.Unwrap(key.UserID, key.MasterKeyID, key.WrappedKey)
+	if err != nil {
+		return err
+	}
+	wrapped, err := s.keyring.Wrap(key.UserID, dataKey)
+	if err != nil {
+		return err
+	}
+	// A key rewrapped concurrently is already done.
+	_, err = s.repo.Rewrap(ctx, key, wrapped, s.keyring.CurrentID())
+	return err
+}
+
+// ObjectKey derives the SSE-C key of a single object from a data key. Keys
+// handed out with presigned URLs are thereby good for that object only.
+func ObjectKey(dataKey []byte, object string) []byte {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte(object))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,183 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockKeyRepository) Get(ctx context.Context, userID string) (*models.UserKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserKey), args.Error(1)
+}
+
+func (m *MockKeyRepository) Create(ctx context.Context, key *models.UserKey) (*models.UserKey, error) {
+	args := m.Called(ctx, key)
+	if fn, ok := args.Get(0).(func(context.Context, *models.UserKey) *models.UserKey); ok {
+		return fn(ctx, key), args.Error(1)
+	}
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserKey), args.Error(1)
+}
+
+func (m *MockKeyRepository) ListStale(ctx context.Context, masterKeyID string, limit int) ([]*models.UserKey, error) {
+	args := m.Called(ctx, masterKeyID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserKey), args.Error(1)
+}
+
+func (m *MockKeyRepository) Rewrap(ctx context.Context, key *models.UserKey, wrappedKey []byte, masterKeyID string) (bool, error) {
+	args := m.Called(ctx, key, wrappedKey, masterKeyID)
+	return args.Bool(0), args.Error(1)
+}
+
+func testMasterKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, KeySize))
+}
+
+func newTestKeyring(t *testing.T, masterKeys, current string) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(configs.EncryptionConfig{Enabled: true, MasterKeys: masterKeys, MasterKeyID: current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestNewKeyring(t *testing.T) {
+	t.Parallel()
+
+	keyring, err := NewKeyring(configs.EncryptionConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, keyring)
+
+	_, err = NewKeyring(configs.EncryptionConfig{Enabled: true})
+	assert.Error(t, err)
+
+	keyring, err = NewKeyring(configs.EncryptionConfig{MasterKeys: "k1:" + testMasterKey(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", keyring.CurrentID())
+
+	_, err = NewKeyring(configs.EncryptionConfig{MasterKeys: "k1:" + testMasterKey(1) + ",k2:" + testMasterKey(2)})
+	assert.Error(t, err, "current key is ambiguous")
+
+	_, err = NewKeyring(configs.EncryptionConfig{MasterKeys: "k1:" + testMasterKey(1), MasterKeyID: "k2"})
+	assert.Error(t, err, "current key is missing")
+
+	_, err = NewKeyring(configs.EncryptionConfig{MasterKeys: "k1:c2hvcnQ="})
+	assert.Error(t, err, "key is too short")
+}
+
+func TestKeyring_WrapUnwrap(t *testing.T) {
+	t.Parallel()
+
+	keyring := newTestKeyring(t, "k1:"+testMasterKey(1), "")
+	dataKey := bytes.Repeat([]byte{7}, KeySize)
+
+	wrapped, err := keyring.Wrap("user-123", dataKey)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(wrapped), string(dataKey))
+
+	unwrapped, err := keyring.Unwrap("user-123", "k1", wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	_, err = keyring.Unwrap("user-456", "k1", wrapped)
+	assert.Error(t, err, "wrapped key is bound to its user")
+
+	_, err = keyring.Unwrap("user-123", "k0", wrapped)
+	assert.Error(t, err, "unknown master key")
+}
+
+func TestObjectKey(t *testing.T) {
+	t.Parallel()
+
+	dataKey := bytes.Repeat([]byte{7}, KeySize)
+
+	key := ObjectKey(dataKey, "user-123/2024/01/01/a.txt")
+	assert.Len(t, key, KeySize)
+	assert.Equal(t, key, ObjectKey(dataKey, "user-123/2024/01/01/a.txt"))
+	assert.NotEqual(t, key, ObjectKey(dataKey, "user-123/2024/01/01/b.txt"))
+}
+
+func TestKeyService_DataKey_CreatesKey(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockKeyRepository)
+	svc := NewKeyService(repo, newTestKeyring(t, "k1:"+testMasterKey(1), ""))
+
+	var created *models.UserKey
+	repo.On("Get", mock.Anything, "user-123").Return(nil, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(key *models.UserKey) bool {
+		created = key
+		return key.UserID == "user-123" && key.MasterKeyID == "k1"
+	})).Return(func(ctx context.Context, key *models.UserKey) *models.UserKey { return key }, nil)
+
+	dataKey, err := svc.DataKey(context.Background(), "user-123")
+
+	assert.NoError(t, err)
+	assert.Len(t, dataKey, KeySize)
+	assert.NotEqual(t, dataKey, created.WrappedKey)
+	repo.AssertExpectations(t)
+}
+
+func TestKeyService_DataKey_Existing(t *testing.T) {
+	t.Parallel()
+
+	keyring := newTestKeyring(t, "k1:"+testMasterKey(1), "")
+	dataKey := bytes.Repeat([]byte{7}, KeySize)
+	wrapped, _ := keyring.Wrap("user-123", dataKey)
+	repo := new(MockKeyRepository)
+	svc := NewKeyService(repo, keyring)
+
+	repo.On("Get", mock.Anything, "user-123").Return(models.NewUserKey("user-123", wrapped, "k1"), nil)
+
+	got, err := svc.DataKey(context.Background(), "user-123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, dataKey, got)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestKeyService_Rotate(t *testing.T) {
+	t.Parallel()
+
+	old := newTestKeyring(t, "k1:"+testMasterKey(1), "")
+	dataKey := bytes.Repeat([]byte{7}, KeySize)
+	wrapped, _ := old.Wrap("user-123", dataKey)
+	stale := models.NewUserKey("user-123", wrapped, "k1")
+	lost := models.NewUserKey("user-456", []byte("garbage"), "k0")
+
+	keyring := newTestKeyring(t, "k1:"+testMasterKey(1)+",k2:"+testMasterKey(2), "k2")
+	repo := new(MockKeyRepository)
+	svc := NewKeyService(repo, keyring)
+
+	repo.On("ListStale", mock.Anything, "k2", 10).Return([]*models.UserKey{stale, lost}, nil)
+	repo.On("Rewrap", mock.Anything, stale, mock.MatchedBy(func(rewrapped []byte) bool {
+		unwrapped, err := keyring.Unwrap("user-123", "k2", rewrapped)
+		return err == nil && bytes.Equal(unwrapped, dataKey)
+	}), "k2").Return(true, nil)
+
+	rotated, err := svc.Rotate(context.Background(), 10)
+
+	assert.Error(t, err, "the key of an unknown master key is reported")
+	assert.Equal(t, 1, rotated)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,18 @@
+package file
+
+import (
+	"context"
+	"log"
+)
+
+// recordActivity puts a file the user uploaded or downloaded on their recent
+// list. Downloads through a share link have no user to note them for. The
+// transfer is done by then, so a failure is only logged.
+func (s *fileService) recordActivity(ctx context.Context, userID, fileID, action string) {
+	if userID == "" {
+		return
+	}
+	if err := s.fileRepo.RecordActivity(ctx, userID, fileID, action); err != nil {
+		log.Printf("Failed to record activity on file %s: %v", fileID, err)
+	}
+}
@@ -0,0 +1,44 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileService_GetDownloadLink_ActivityFailureIgnored(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockPresigned := new(MockPresignedURLGenerator)
+	svc := NewFileService(Dependencies{Files: mockRepo}, new(MockBlobStorage), mockPresigned, &configs.Config{})
+	downloadURL, _ := url.Parse("https://storage.example.com/download/file-123")
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "objects/file-123", time.Hour, mock.Anything).Return(downloadURL, nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityDownload).Return(errors.New("db down"))
+
+	output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, downloadURL.String(), output.DownloadURL)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_RecordActivity_SkipsAnonymous(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewFileService(Dependencies{Files: mockRepo}, new(MockBlobStorage), new(MockPresignedURLGenerator), &configs.Config{})
+
+	svc.recordActivity(context.Background(), "", "file-123", models.ActivityDownload)
+
+	mockRepo.AssertNotCalled(t, "RecordActivity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,174 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/utils"
+	"github.com/google/uuid"
+)
+
+const maxBatchSize = 500
+
+func (s *fileService) BatchTrash(ctx context.Context, input *BatchInput) (output *BatchOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("batch_trash", status)
+	}()
+
+	return s.setTrashedBatch(ctx, input, true)
+}
+
+func (s *fileService) BatchRestore(ctx context.Context, input *BatchInput) (output *BatchOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("batch_restore", status)
+	}()
+
+	return s.setTrashedBatch(ctx, input, false)
+}
+
+// BatchDelete permanently deletes each file on its own, since every object
+// has to be removed from the storage anyway; one failure does not stop the
+// rest.
+func (s *fileService) BatchDelete(ctx context.Context, input *BatchInput) (output *BatchOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("batch_delete", status)
+	}()
+
+	ids, results, err := prepareBatch(input.UserID, input.FileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		file, err := s.fileRepo.GetByID(ctx, id)
+		if err != nil || file.UserID != input.UserID {
+			results[id].Error = "file not found or access denied"
+			continue
+		}
+		if err := s.purgeFile(ctx, file); err != nil {
+			results[id].Error = err.Error()
+			continue
+		}
+		results[id].Success = true
+	}
+
+	return batchOutput(input.FileIDs, results), nil
+}
+
+func (s *fileService) BatchMove(ctx context.Context, input *BatchMoveInput) (output *BatchOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("batch_move", status)
+	}()
+
+	policy, err := normalizeConflictPolicy(input.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	ids, results, err := prepareBatch(input.UserID, input.FileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if _, err := s.moveFile(ctx, id, input.UserID, input.Path, "", policy); err != nil {
+			results[id].Error = err.Error()
+			continue
+		}
+		results[id].Success = true
+	}
+
+	return batchOutput(input.FileIDs, results), nil
+}
+
+func (s *fileService) setTrashedBatch(ctx context.Context, input *BatchInput, isTrashed bool) (*BatchOutput, error) {
+	ids, results, err := prepareBatch(input.UserID, input.FileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		updated, err := s.fileRepo.SetTrashedBatch(ctx, ids, input.UserID, isTrashed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update files: %w", err)
+		}
+		for _, id := range updated {
+			results[id].Success = true
+		}
+		for _, id := range ids {
+			if !results[id].Success {
+				results[id].Error = "file not found or access denied"
+			}
+		}
+	}
+
+	return batchOutput(input.FileIDs, results), nil
+}
+
+// prepareBatch validates a batch request. It returns the distinct well-formed
+// ids to work on and a result per requested id; malformed ids are already
+// marked as failed.
+func prepareBatch(userID string, fileIDs []string) ([]string, map[string]*BatchItemResult, error) {
+	if userID == "" {
+		return nil, nil, fmt.Errorf("user_id is required")
+	}
+	if len(fileIDs) == 0 {
+		return nil, nil, fmt.Errorf("file_ids is required")
+	}
+	if len(fileIDs) > maxBatchSize {
+		return nil, nil, fmt.Errorf("too many files in batch (max %d)", maxBatchSize)
+	}
+
+	ids := make([]string, 0, len(fileIDs))
+	results := make(map[string]*BatchItemResult, len(fileIDs))
+	for _, id := range fileIDs {
+		if _, seen := results[id]; seen {
+			continue
+		}
+		results[id] = &BatchItemResult{FileID: id}
+		if _, err := uuid.Parse(id); err != nil {
+			results[id].Error = "invalid file id"
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, results, nil
+}
+
+func batchOutput(fileIDs []string, results map[string]*BatchItemResult) *BatchOutput {
+	output := &BatchOutput{Results: make([]BatchItemResult, 0, len(results))}
+	for _, id := range fileIDs {
+		result, ok := results[id]
+		if !ok {
+			continue
+		}
+		output.Results = append(output.Results, *result)
+		if result.Success {
+			output.Succeeded++
+		} else {
+			output.Failed++
+		}
+		delete(results, id)
+	}
+	return output
+}
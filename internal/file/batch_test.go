@@ -0,0 +1,206 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	batchFileA = "3f1c2d4e-0000-4000-8000-000000000001"
+	batchFileB = "3f1c2d4e-0000-4000-8000-000000000002"
+	batchFileC = "3f1c2d4e-0000-4000-8000-000000000003"
+)
+
+func TestFileService_BatchTrash_PartialFailure(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("SetTrashedBatch", mock.Anything, []string{batchFileA, batchFileB}, "user-123", true).Return([]string{batchFileA}, nil)
+
+	output, err := svc.BatchTrash(context.Background(), &BatchInput{
+		UserID:  "user-123",
+		FileIDs: []string{batchFileA, batchFileB, "not-a-uuid", batchFileA},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, output.Results, 3)
+	assert.Equal(t, 1, output.Succeeded)
+	assert.Equal(t, 2, output.Failed)
+	assert.True(t, output.Results[0].Success)
+	assert.Equal(t, "file not found or access denied", output.Results[1].Error)
+	assert.Equal(t, "invalid file id", output.Results[2].Error)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_BatchRestore_RepoError(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("SetTrashedBatch", mock.Anything, []string{batchFileA}, "user-123", false).Return(nil, errors.New("db error"))
+
+	output, err := svc.BatchRestore(context.Background(), &BatchInput{
+		UserID:  "user-123",
+		FileIDs: []string{batchFileA},
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+}
+
+func TestFileService_BatchDelete_ContinuesAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	fileA := &models.File{ID: batchFileA, UserID: "user-123", StoragePath: "objects/a", Bucket: "cloud-storage", Size: 10}
+	fileC := &models.File{ID: batchFileC, UserID: "user-123", StoragePath: "objects/c", Bucket: "cloud-storage", Size: 30}
+
+	mockRepo.On("GetByID", mock.Anything, batchFileA).Return(fileA, nil)
+	mockRepo.On("GetByID", mock.Anything, batchFileB).Return(&models.File{ID: batchFileB, UserID: "someone-else"}, nil)
+	mockRepo.On("GetByID", mock.Anything, batchFileC).Return(fileC, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/a").Return(errors.New("storage unavailable"))
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/c").Return(nil)
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, batchFileC, "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", batchFileC, int64(30)).Return(nil)
+
+	output, err := svc.BatchDelete(context.Background(), &BatchInput{
+		UserID:  "user-123",
+		FileIDs: []string{batchFileA, batchFileB, batchFileC},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, output.Succeeded)
+	assert.Equal(t, 2, output.Failed)
+	assert.Contains(t, output.Results[0].Error, "failed to delete from storage")
+	assert.Equal(t, "file not found or access denied", output.Results[1].Error)
+	assert.True(t, output.Results[2].Success)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_BatchMove_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	fileA := &models.File{ID: batchFileA, UserID: "user-123", OriginalName: "a.txt", Path: "/", UploadState: models.UploadStateActive}
+	fileB := &models.File{ID: batchFileB, UserID: "user-123", OriginalName: "b.txt", Path: "/", UploadState: models.UploadStateActive}
+
+	mockRepo.On("GetByID", mock.Anything, batchFileA).Return(fileA, nil)
+	mockRepo.On("GetByID", mock.Anything, batchFileB).Return(fileB, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/archive", "a.txt").Return(nil, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/archive", "b.txt").Return(nil, nil)
+	mockRepo.On("Move", mock.Anything, batchFileA, "user-123", "/archive", "a.txt").Return(nil)
+	mockRepo.On("Move", mock.Anything, batchFileB, "user-123", "/archive", "b.txt").Return(nil)
+
+	output, err := svc.BatchMove(context.Background(), &BatchMoveInput{
+		UserID:  "user-123",
+		FileIDs: []string{batchFileA, batchFileB},
+		Path:    "/archive",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, output.Succeeded)
+	assert.Equal(t, 0, output.Failed)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_BatchTrash_TooManyFiles(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	output, err := svc.BatchTrash(context.Background(), &BatchInput{
+		UserID:  "user-123",
+		FileIDs: make([]string, maxBatchSize+1),
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "too many files in batch")
+}
@@ -0,0 +1,102 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+)
+
+// dedupFile attaches the content of a completed upload to the blob of its
+// SHA-256. When the same content is stored already, the file is pointed at
+// that object and its own copy is removed. hash may be empty if it is not
+// known yet. Deduplication is best effort: when it fails, the file keeps
+// its own object.
+func (s *fileService) dedupFile(ctx context.Context, file *models.File, hash string) {
+	hash = contentHash(file.ChecksumAlgorithm, file.Checksum, hash)
+	if hash == "" {
+		metrics.RecordFileOperation("dedup", "skipped")
+		return
+	}
+
+	blob, err := s.blobRepo.AttachFile(ctx, file.ID, models.NewBlob(hash, file.Bucket, file.StoragePath, file.KeyOwnerID, file.Size))
+	if err != nil {
+		log.Printf("Failed to deduplicate file %s: %v", file.ID, err)
+		metrics.RecordFileOperation("dedup", "error")
+		return
+	}
+	s.removeDuplicate(ctx, file.Bucket, file.StoragePath, blob)
+	file.BlobHash, file.Bucket, file.StoragePath, file.KeyOwnerID = blob.Hash, blob.Bucket, blob.StoragePath, blob.KeyOwnerID
+}
+
+// dedupVersion does the same as dedupFile for a version upload, before it is
+// promoted.
+func (s *fileService) dedupVersion(ctx context.Context, version *models.FileVersion, hash string) {
+	hash = contentHash(version.ChecksumAlgorithm, version.Checksum, hash)
+	if hash == "" {
+		metrics.RecordFileOperation("dedup", "skipped")
+		return
+	}
+
+	blob, err := s.blobRepo.AttachVersion(ctx, version.ID, models.NewBlob(hash, version.Bucket, version.StoragePath, version.KeyOwnerID, version.Size))
+	if err != nil {
+		log.Printf("Failed to deduplicate version %s: %v", version.ID, err)
+		metrics.RecordFileOperation("dedup", "error")
+		return
+	}
+	s.removeDuplicate(ctx, version.Bucket, version.StoragePath, blob)
+	version.BlobHash, version.Bucket, version.StoragePath, version.KeyOwnerID = blob.Hash, blob.Bucket, blob.StoragePath, blob.KeyOwnerID
+}
+
+// contentHash returns the hex SHA-256 of a stored object: the known hash, or
+// the SHA-256 checksum the storage verified on upload. Any other object
+// yields an empty string and is not deduplicated; reading it back to hash it
+// would not fit into the request that completes the upload.
+func contentHash(checksumAlgorithm, checksum, known string) string {
+	if known != "" {
+		return known
+	}
+	if checksumAlgorithm != ChecksumSHA256 {
+		return ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(raw)
+}
+
+// removeDuplicate deletes an uploaded object that turned out to duplicate
+// blob. A leftover object only wastes space, so failures are logged.
+func (s *fileService) removeDuplicate(ctx context.Context, bucket, object string, blob *models.Blob) {
+	if blob.Bucket == bucket && blob.StoragePath == object {
+		metrics.RecordFileOperation("dedup", "success")
+		return
+	}
+	if err := s.storage.RemoveObject(ctx, bucket, object); err != nil {
+		log.Printf("Failed to remove duplicate object %s: %v", object, err)
+	}
+	metrics.RecordFileOperation("dedup", "duplicate")
+	metrics.RecordDedupSaved(blob.Size)
+}
+
+// releaseBlob drops a reference to a blob and removes its object once
+// nothing refers to it anymore. By then the blob row is gone, so a failed
+// removal can only be logged.
+func (s *fileService) releaseBlob(ctx context.Context, hash string) error {
+	blob, err := s.blobRepo.Release(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("failed to release blob: %w", err)
+	}
+	if blob == nil {
+		return nil
+	}
+	if err := s.storage.RemoveObject(ctx, blob.Bucket, blob.StoragePath); err != nil {
+		log.Printf("Failed to delete blob %s from storage: %v", blob.Hash, err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileService_CompleteUpload_CollapsesDuplicate(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:                "file-123",
+		UserID:            "user-123",
+		StoragePath:       "objects/file-123",
+		Bucket:            "cloud-storage",
+		Size:              11,
+		ChecksumAlgorithm: ChecksumSHA256,
+		Checksum:          helloWorldSHA256,
+	}
+	shared := &models.Blob{Hash: helloWorldHex, Bucket: "cloud-storage", StoragePath: "objects/file-1", Size: 11, RefCount: 2}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		Size:           11,
+		ChecksumSHA256: helloWorldSHA256,
+	}, nil)
+	mockBlobs.On("AttachFile", mock.Anything, "file-123", mock.MatchedBy(func(b *models.Blob) bool {
+		return b.Hash == helloWorldHex && b.StoragePath == "objects/file-123"
+	})).Return(shared, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateActive).Return(nil)
+	mockQuota.On("Commit", mock.Anything, "file-123", int64(11)).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "objects/file-1", output.StoragePath)
+	mockStorage.AssertNotCalled(t, "GetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockStorage.AssertExpectations(t)
+	mockBlobs.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_DedupFailureKeepsObject(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:                "file-123",
+		UserID:            "user-123",
+		StoragePath:       "objects/file-123",
+		Bucket:            "cloud-storage",
+		Size:              11,
+		ChecksumAlgorithm: ChecksumSHA256,
+		Checksum:          helloWorldSHA256,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		Size:           11,
+		ChecksumSHA256: helloWorldSHA256,
+	}, nil)
+	mockBlobs.On("AttachFile", mock.Anything, "file-123", mock.Anything).Return(nil, errors.New("db down"))
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateActive).Return(nil)
+	mockQuota.On("Commit", mock.Anything, "file-123", int64(11)).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "objects/file-123", output.StoragePath)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_CopyFile_SharesBlob(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	source := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		Size:         1024,
+		MimeType:     "application/pdf",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		BlobHash:     helloWorldHex,
+		UploadState:  models.UploadStateActive,
+	}
+	shared := &models.Blob{Hash: helloWorldHex, Bucket: "cloud-storage", StoragePath: "objects/file-123", Size: 1024, RefCount: 2}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(source, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/docs", "report.pdf").Return(nil, nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockBlobs.On("AttachExisting", mock.Anything, mock.Anything, helloWorldHex).Return(shared, nil)
+	mockRepo.On("SetUploadState", mock.Anything, mock.Anything, models.UploadStateActive).Return(nil)
+	mockQuota.On("Commit", mock.Anything, mock.Anything, int64(1024)).Return(nil)
+
+	output, err := svc.CopyFile(context.Background(), &CopyFileInput{FileID: "file-123", UserID: "user-123", Path: "/docs"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "objects/file-123", output.File.StoragePath)
+	assert.Equal(t, helloWorldHex, output.File.BlobHash)
+	mockStorage.AssertNotCalled(t, "CopyObject", mock.Anything, mock.Anything, mock.Anything)
+	mockBlobs.AssertExpectations(t)
+}
+
+func TestFileService_PurgeFile_ReleasesBlobs(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", Bucket: "cloud-storage", StoragePath: "objects/file-1", BlobHash: "aaa", Size: 10}
+	version := &models.FileVersion{ID: "version-1", Bucket: "cloud-storage", StoragePath: "objects/version-1", BlobHash: "bbb", Size: 5}
+
+	mockVersions.On("ListByFileID", mock.Anything, "file-123").Return([]*models.FileVersion{version}, nil)
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockBlobs.On("Release", mock.Anything, "bbb").Return(&models.Blob{Hash: "bbb", Bucket: "cloud-storage", StoragePath: "objects/version-1"}, nil)
+	mockBlobs.On("Release", mock.Anything, "aaa").Return(nil, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", int64(10)).Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(5)).Return(nil)
+
+	err := svc.purgeFile(context.Background(), file)
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, "cloud-storage", "objects/file-1")
+	mockStorage.AssertExpectations(t)
+	mockBlobs.AssertExpectations(t)
+}
+
+func TestFileService_DeleteVersion_ReleasesBlob(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{ID: "version-1", UserID: "user-123", Bucket: "cloud-storage", StoragePath: "objects/file-1", BlobHash: "aaa", Size: 5}
+
+	mockVersions.On("Delete", mock.Anything, "version-1").Return(nil)
+	mockBlobs.On("Release", mock.Anything, "aaa").Return(nil, nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(5)).Return(nil)
+
+	err := svc.deleteVersion(context.Background(), version)
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, mock.Anything)
+	mockBlobs.AssertExpectations(t)
+}
+
+func TestFileService_ReapUpload_ReleasesBlob(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", Bucket: "cloud-storage", StoragePath: "objects/file-1", BlobHash: "aaa", Size: 10, UploadState: models.UploadStatePending}
+
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockBlobs.On("Release", mock.Anything, "aaa").Return(&models.Blob{Hash: "aaa", Bucket: "cloud-storage", StoragePath: "objects/file-1"}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-1").Return(nil).Once()
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", int64(10)).Return(nil)
+
+	err := svc.reapUpload(context.Background(), &models.StaleUpload{File: file})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+	mockBlobs.AssertExpectations(t)
+}
+
+func TestContentHash(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, helloWorldHex, contentHash(ChecksumSHA256, helloWorldSHA256, ""))
+	assert.Equal(t, "known", contentHash("", "", "known"))
+	assert.Empty(t, contentHash(ChecksumMD5, "XrY7u+Ae7tCTyyK7j1rNww==", ""))
+	assert.Empty(t, contentHash("", "", ""))
+}
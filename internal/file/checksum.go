@@ -0,0 +1,133 @@
+package file
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"mime"
+	"strings"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+)
+
+const (
+	ChecksumSHA256 = "SHA256"
+	ChecksumMD5    = "MD5"
+	ChecksumCRC32C = "CRC32C"
+)
+
+var checksumSizes = map[string]int{
+	ChecksumSHA256: 32,
+	ChecksumMD5:    16,
+	ChecksumCRC32C: 4,
+}
+
+// normalizeChecksum validates a client supplied checksum. Values are base64
+// encoded, the same way S3 expects them in checksum headers.
+func normalizeChecksum(algorithm, checksum string) (string, error) {
+	if algorithm == "" && checksum == "" {
+		return "", nil
+	}
+	algorithm = strings.ToUpper(strings.ReplaceAll(algorithm, "-", ""))
+	size, ok := checksumSizes[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+	raw, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil {
+		return "", fmt.Errorf("checksum must be base64 encoded: %w", err)
+	}
+	if len(raw) != size {
+		return "", fmt.Errorf("invalid %s checksum length", algorithm)
+	}
+	return algorithm, nil
+}
+
+// newChecksumHash returns a hash whose base64 encoded sum is comparable to
+// a checksum of the given algorithm.
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case ChecksumMD5:
+		return md5.New()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	}
+	return sha256.New()
+}
+
+// checksumHeaders returns the headers that have to be signed into the
+// presigned PUT so the storage rejects a body with a different checksum.
+func checksumHeaders(algorithm, checksum string) map[string]string {
+	switch algorithm {
+	case ChecksumSHA256:
+		return map[string]string{"x-amz-checksum-sha256": checksum}
+	case ChecksumCRC32C:
+		return map[string]string{"x-amz-checksum-crc32c": checksum}
+	case ChecksumMD5:
+		return map[string]string{"Content-MD5": checksum}
+	}
+	return map[string]string{}
+}
+
+// verifyStoredObject compares the object reported by the storage with what
+// the client declared at InitiateUpload.
+func verifyStoredObject(file *models.File, info storage.ObjectInfo, etag string) error {
+	if file.Size > 0 && info.Size != file.Size {
+		return fmt.Errorf("size mismatch: declared %d, stored %d", file.Size, info.Size)
+	}
+	if file.MimeType != "" && info.ContentType != "" && !sameMediaType(file.MimeType, info.ContentType) {
+		return fmt.Errorf("content type mismatch: declared %s, stored %s", file.MimeType, info.ContentType)
+	}
+	if etag != "" && trimETag(etag) != trimETag(info.ETag) {
+		return fmt.Errorf("etag mismatch")
+	}
+
+	var stored string
+	switch file.ChecksumAlgorithm {
+	case "":
+		return nil
+	case ChecksumSHA256:
+		stored = info.ChecksumSHA256
+	case ChecksumCRC32C:
+		stored = info.ChecksumCRC32C
+	case ChecksumMD5:
+		// The storage checked Content-MD5 on upload already; the ETag of
+		// an encrypted object is not the MD5 of its content.
+		if file.KeyOwnerID != "" {
+			return nil
+		}
+		stored = md5FromETag(info.ETag)
+	}
+	if stored != file.Checksum {
+		return fmt.Errorf("%s checksum mismatch", file.ChecksumAlgorithm)
+	}
+	return nil
+}
+
+// md5FromETag returns the base64 MD5 of a single part object, whose ETag is
+// the hex MD5 of its content. Multipart ETags yield an empty string.
+func md5FromETag(etag string) string {
+	raw, err := hex.DecodeString(trimETag(etag))
+	if err != nil || len(raw) != checksumSizes[ChecksumMD5] {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func sameMediaType(a, b string) bool {
+	mediaA, _, errA := mime.ParseMediaType(a)
+	mediaB, _, errB := mime.ParseMediaType(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return mediaA == mediaB
+}
+
+func trimETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
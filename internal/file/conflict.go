@@ -0,0 +1,116 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	conflictPolicyFail      = "fail"
+	conflictPolicyOverwrite = "overwrite"
+	conflictPolicyRename    = "rename"
+)
+
+var copyCounterRegex = regexp.MustCompile(`^(.*) \(\d+\)$`)
+
+func normalizeConflictPolicy(policy string) (string, error) {
+	switch strings.ToLower(policy) {
+	case "", conflictPolicyFail:
+		return conflictPolicyFail, nil
+	case conflictPolicyOverwrite:
+		return conflictPolicyOverwrite, nil
+	case conflictPolicyRename:
+		return conflictPolicyRename, nil
+	default:
+		return "", fmt.Errorf("unsupported conflict policy %q", policy)
+	}
+}
+
+func validateFileName(name string) error {
+	if name == "" || strings.Contains(name, "/") || name == "." || name == ".." {
+		return fmt.Errorf("invalid file name")
+	}
+	return nil
+}
+
+// resolveConflict returns the name a file should get in dir under the given
+// policy. With overwrite, it also returns the file currently holding the
+// name, which the caller replaces once its own work succeeded; selfID is the
+// source file, which can never be overwritten by itself.
+func (s *fileService) resolveConflict(ctx context.Context, userID, dir, name, policy, selfID string) (string, *models.File, error) {
+	existing, err := s.fileRepo.FindByName(ctx, userID, dir, name)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to check destination: %w", err)
+	}
+	if existing == nil {
+		return name, nil, nil
+	}
+
+	switch policy {
+	case conflictPolicyOverwrite:
+		if existing.ID == selfID {
+			return "", nil, fmt.Errorf("source and destination are the same file")
+		}
+		return name, existing, nil
+	case conflictPolicyRename:
+		name, err := s.nextAvailableName(ctx, userID, dir, name)
+		return name, nil, err
+	default:
+		return "", nil, status.Error(codes.AlreadyExists, models.ErrNameConflict.Error())
+	}
+}
+
+// purgeDisplaced deletes a file that was replaced. Replace put it in the
+// trash already, so if this fails the trash purger removes it later.
+func (s *fileService) purgeDisplaced(ctx context.Context, displaced *models.File) {
+	if displaced == nil {
+		return
+	}
+	if err := s.purgeFile(ctx, displaced); err != nil {
+		log.Printf("Failed to purge replaced file %s: %v", displaced.ID, err)
+	}
+}
+
+// nextAvailableName picks the first free "name (n).ext" in dir.
+func (s *fileService) nextAvailableName(ctx context.Context, userID, dir, name string) (string, error) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if stem == "" {
+		stem, ext = name, ""
+	}
+	if m := copyCounterRegex.FindStringSubmatch(stem); m != nil {
+		stem = m[1]
+	}
+
+	names, err := s.fileRepo.ListNamesWithPrefix(ctx, userID, dir, stem+" (")
+	if err != nil {
+		return "", fmt.Errorf("failed to check destination: %w", err)
+	}
+	taken := make(map[string]bool, len(names))
+	for _, n := range names {
+		taken[n] = true
+	}
+
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if !taken[candidate] {
+			return candidate, nil
+		}
+	}
+}
+
+func nameConflictError(err error) error {
+	if errors.Is(err, models.ErrNameConflict) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// contentChunkSize is the size of the data messages file content is
+// streamed in.
+const contentChunkSize = 64 << 10
+
+var errRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+// DownloadContent opens a file's content for streaming through the service
+// instead of handing out a presigned URL.
+func (s *fileService) DownloadContent(ctx context.Context, input *DownloadContentInput) (output *DownloadContentOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("download_content", status)
+	}()
+
+	// A file the user cannot access is reported as missing, so that file
+	// ids cannot be probed for existence.
+	hasAccess, storagePath, bucket, err := s.fileRepo.CheckAccess(ctx, input.FileID, input.UserID)
+	if err != nil || !hasAccess {
+		return nil, status.Error(codes.NotFound, "file not found")
+	}
+	file, err := s.fileRepo.GetByID(ctx, input.FileID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "file not found")
+	}
+
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, storagePath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	info, err := s.storage.StatObject(ctx, bucket, storagePath, storage.StatObjectOptions{Encryption: sse})
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "file content not found")
+	}
+
+	output = &DownloadContentOutput{
+		File:   file,
+		ETag:   strconv.Quote(info.ETag),
+		Size:   info.Size,
+		Length: info.Size,
+	}
+	if etagMatches(input.IfNoneMatch, output.ETag) {
+		output.NotModified = true
+		output.Length = 0
+		return output, nil
+	}
+
+	offset, length, partial, err := parseByteRange(input.Range, info.Size)
+	if err != nil {
+		output.RangeNotSatisfiable = true
+		output.Length = 0
+		return output, nil
+	}
+
+	// Pinning the ETag makes the read fail instead of mixing two versions
+	// if the object is replaced between the stat and the read.
+	opts := storage.GetObjectOptions{Encryption: sse, MatchETag: info.ETag}
+	if partial {
+		opts.Offset, opts.Length = offset, length
+	}
+	body, err := s.storage.GetObject(ctx, bucket, storagePath, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read file content: %v", err)
+	}
+
+	output.Offset, output.Length, output.Partial = offset, length, partial
+	output.Body = body
+	s.recordAccess(ctx, input.FileID)
+	s.recordActivity(ctx, input.UserID, input.FileID, models.ActivityDownload)
+	return output, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches etag. Weak
+// comparison is used, as the header only guards reads.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseByteRange resolves a single "bytes=" Range header against an object
+// of the given size. Headers that are malformed or ask for several ranges
+// are ignored and the whole object is served, as HTTP allows.
+func parseByteRange(header string, size int64) (offset, length int64, partial bool, err error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || size == 0 || strings.Contains(spec, ",") {
+		return 0, size, false, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, false, nil
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return 0, size, false, nil
+		}
+		if suffix == 0 {
+			return 0, 0, false, errRangeNotSatisfiable
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, suffix, true, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, false, nil
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, size, false, nil
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, errRangeNotSatisfiable
+	}
+	return start, end - start + 1, true, nil
+}
@@ -0,0 +1,244 @@
+package file
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseByteRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header  string
+		offset  int64
+		length  int64
+		partial bool
+		err     error
+	}{
+		{header: "", offset: 0, length: 100},
+		{header: "bytes=0-9", offset: 0, length: 10, partial: true},
+		{header: "bytes=90-", offset: 90, length: 10, partial: true},
+		{header: "bytes=90-500", offset: 90, length: 10, partial: true},
+		{header: "bytes=-20", offset: 80, length: 20, partial: true},
+		{header: "bytes=-500", offset: 0, length: 100, partial: true},
+		{header: "bytes=100-", err: errRangeNotSatisfiable},
+		{header: "bytes=-0", err: errRangeNotSatisfiable},
+		{header: "bytes=9-0", offset: 0, length: 100},
+		{header: "bytes=0-1,5-9", offset: 0, length: 100},
+		{header: "items=0-9", offset: 0, length: 100},
+		{header: "bytes=abc", offset: 0, length: 100},
+	}
+
+	for _, tt := range tests {
+		offset, length, partial, err := parseByteRange(tt.header, 100)
+		assert.Equal(t, tt.err, err, tt.header)
+		if tt.err == nil {
+			assert.Equal(t, tt.offset, offset, tt.header)
+			assert.Equal(t, tt.length, length, tt.header)
+			assert.Equal(t, tt.partial, partial, tt.header)
+		}
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, etagMatches(`"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`"x", W/"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`*`, `"abc"`))
+	assert.False(t, etagMatches(`"abd"`, `"abc"`))
+	assert.False(t, etagMatches(``, `"abc"`))
+}
+
+func TestFileService_DownloadContent_Range(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		MimeType:     "application/pdf",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).
+		Return(storage.ObjectInfo{ETag: "etag-1", Size: 100}, nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityDownload).Return(nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-123", mock.MatchedBy(func(opts storage.GetObjectOptions) bool {
+		return opts.Offset == 10 && opts.Length == 10 && opts.MatchETag == "etag-1"
+	})).Return(io.NopCloser(strings.NewReader("0123456789")), nil)
+
+	output, err := svc.DownloadContent(context.Background(), &DownloadContentInput{
+		FileID: "file-123",
+		UserID: "user-123",
+		Range:  "bytes=10-19",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Partial)
+	assert.Equal(t, int64(10), output.Offset)
+	assert.Equal(t, int64(10), output.Length)
+	assert.Equal(t, int64(100), output.Size)
+	assert.Equal(t, `"etag-1"`, output.ETag)
+	body, _ := io.ReadAll(output.Body)
+	assert.Equal(t, "0123456789", string(body))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_DownloadContent_NotModified(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		MimeType:     "application/pdf",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).
+		Return(storage.ObjectInfo{ETag: "etag-1", Size: 100}, nil)
+
+	output, err := svc.DownloadContent(context.Background(), &DownloadContentInput{
+		FileID:      "file-123",
+		UserID:      "user-123",
+		IfNoneMatch: `"etag-1"`,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.NotModified)
+	assert.Nil(t, output.Body)
+	mockStorage.AssertNotCalled(t, "GetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_DownloadContent_RangeNotSatisfiable(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		MimeType:     "application/pdf",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).
+		Return(storage.ObjectInfo{ETag: "etag-1", Size: 100}, nil)
+
+	output, err := svc.DownloadContent(context.Background(), &DownloadContentInput{
+		FileID: "file-123",
+		UserID: "user-123",
+		Range:  "bytes=200-",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.RangeNotSatisfiable)
+	assert.Equal(t, int64(100), output.Size)
+	assert.Nil(t, output.Body)
+	mockStorage.AssertNotCalled(t, "GetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_DownloadContent_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-456").Return(false, "", "", nil)
+
+	output, err := svc.DownloadContent(context.Background(), &DownloadContentInput{
+		FileID: "file-123",
+		UserID: "user-456",
+	})
+
+	assert.Nil(t, output)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	mockStorage.AssertNotCalled(t, "StatObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,83 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/encryption"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+)
+
+// keyOwnerFor returns the user whose key new content of a file owned by
+// ownerID is encrypted with, or an empty string while encryption is off.
+// Content is always encrypted with the owner's key, whoever uploads it.
+func (s *fileService) keyOwnerFor(ownerID string) string {
+	if !s.config.Encryption.Enabled {
+		return ""
+	}
+	return ownerID
+}
+
+// objectEncryption returns the SSE-C key of an object encrypted with the
+// key of keyOwnerID, or nil for a plaintext object.
+func (s *fileService) objectEncryption(ctx context.Context, keyOwnerID, object string) (storage.CustomerKey, error) {
+	if keyOwnerID == "" {
+		return nil, nil
+	}
+	if s.keys == nil {
+		return nil, fmt.Errorf("object is encrypted but encryption is not configured")
+	}
+	dataKey, err := s.keys.DataKey(ctx, keyOwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	return storage.CustomerKey(encryption.ObjectKey(dataKey, object)), nil
+}
+
+// downloadEncryption returns the SSE-C key of a file's current content for
+// a download authorized through CheckAccess, which does not report it.
+// Without keys nothing could be decrypted anyway, so the lookup is skipped.
+func (s *fileService) downloadEncryption(ctx context.Context, fileID, storagePath string) (storage.CustomerKey, error) {
+	if s.keys == nil {
+		return nil, nil
+	}
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+	return s.objectEncryption(ctx, file.KeyOwnerID, storagePath)
+}
+
+// addEncryptionHeaders adds the SSE-C headers a client has to send along
+// with a presigned request for an object encrypted with sse.
+func addEncryptionHeaders(headers map[string]string, sse storage.CustomerKey) {
+	if sse == nil {
+		return
+	}
+	h := make(http.Header)
+	sse.SetHeaders(h)
+	for k := range h {
+		headers[k] = h.Get(k)
+	}
+}
+
+// presignDownload signs a GET of an object. For encrypted objects the SSE-C
+// headers are signed along and returned, as the client has to send them.
+func (s *fileService) presignDownload(ctx context.Context, bucket, object string, expires time.Duration, params url.Values, sse storage.CustomerKey) (string, map[string]string, error) {
+	headers := map[string]string{}
+	var presignedURL *url.URL
+	var err error
+	if sse == nil {
+		presignedURL, err = s.presignedClient.PresignedGetObject(ctx, bucket, object, expires, params)
+	} else {
+		addEncryptionHeaders(headers, sse)
+		presignedURL, err = s.presignedClient.PresignHeader(ctx, http.MethodGet, bucket, object, expires, params, toHTTPHeader(headers))
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate download URL: %w", err)
+	}
+	return presignedURL.String(), headers, nil
+}
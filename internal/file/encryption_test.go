@@ -0,0 +1,279 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const sseAlgorithmHeader = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+
+func TestFileService_InitiateUpload_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	mockKeys := new(MockKeyProvider)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Encryption: configs.EncryptionConfig{
+			Enabled: true,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+		Keys:     mockKeys,
+	}, mockStorage, mockPresigned, config)
+
+	mockKeys.On("DataKey", mock.Anything, "user-123").Return(bytes.Repeat([]byte{7}, 32), nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.KeyOwnerID == "user-123"
+	})).Return(nil)
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/file-123")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, presignedUploadTTL, mock.Anything, mock.MatchedBy(func(h http.Header) bool {
+		return h.Get(sseAlgorithmHeader) == "AES256"
+	})).Return(presignedURL, nil)
+
+	output, err := svc.InitiateUpload(context.Background(), &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "test.txt",
+		Path:     "/files",
+		MimeType: "text/plain",
+		Size:     1024,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "AES256", output.Headers[sseAlgorithmHeader])
+	assert.NotEmpty(t, output.Headers["X-Amz-Server-Side-Encryption-Customer-Key"])
+	mockRepo.AssertExpectations(t)
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_GetDownloadLink_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	mockKeys := new(MockKeyProvider)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+		Keys:     mockKeys,
+	}, mockStorage, mockPresigned, config)
+
+	mockKeys.On("DataKey", mock.Anything, "user-123").Return(bytes.Repeat([]byte{7}, 32), nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{ID: "file-123", KeyOwnerID: "user-123"}, nil)
+	presignedURL, _ := url.Parse("https://storage.example.com/download/file-123")
+	mockPresigned.On("PresignHeader", mock.Anything, "GET", "cloud-storage", "objects/file-123", time.Hour, mock.Anything, mock.MatchedBy(func(h http.Header) bool {
+		return h.Get(sseAlgorithmHeader) == "AES256"
+	})).Return(presignedURL, nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityDownload).Return(nil)
+
+	output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, presignedURL.String(), output.DownloadURL)
+	assert.Equal(t, "AES256", output.Headers[sseAlgorithmHeader])
+	mockPresigned.AssertNotCalled(t, "PresignedGetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_GetDownloadLink_PlaintextWithKeys(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	mockKeys := new(MockKeyProvider)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Encryption: configs.EncryptionConfig{
+			Enabled: true,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+		Keys:     mockKeys,
+	}, mockStorage, mockPresigned, config)
+
+	mockKeys.On("DataKey", mock.Anything, "user-123").Return(bytes.Repeat([]byte{7}, 32), nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{ID: "file-123"}, nil)
+	presignedURL, _ := url.Parse("https://storage.example.com/download/file-123")
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "objects/file-123", time.Hour, mock.Anything).Return(presignedURL, nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityDownload).Return(nil)
+
+	output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, output.Headers[sseAlgorithmHeader])
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_DownloadContent_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	mockKeys := new(MockKeyProvider)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+		Keys:     mockKeys,
+	}, mockStorage, mockPresigned, config)
+
+	mockKeys.On("DataKey", mock.Anything, "user-123").Return(bytes.Repeat([]byte{7}, 32), nil)
+
+	file := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		KeyOwnerID:  "user-123",
+		UploadState: models.UploadStateActive,
+	}
+	encryptedStat := mock.MatchedBy(func(opts storage.StatObjectOptions) bool {
+		return opts.Encryption != nil
+	})
+	encryptedGet := mock.MatchedBy(func(opts storage.GetObjectOptions) bool {
+		return opts.Encryption != nil
+	})
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", encryptedStat).Return(storage.ObjectInfo{ETag: "etag-1", Size: 10}, nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-123", encryptedGet).Return(io.NopCloser(strings.NewReader("0123456789")), nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityDownload).Return(nil)
+
+	output, err := svc.DownloadContent(context.Background(), &DownloadContentInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(output.Body)
+	assert.Equal(t, "0123456789", string(body))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_DownloadContent_EncryptedWithoutKeys(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewFileService(Dependencies{Files: mockRepo}, new(MockBlobStorage), new(MockPresignedURLGenerator), &configs.Config{})
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{ID: "file-123", KeyOwnerID: "user-123", UploadState: models.UploadStateActive}, nil)
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+
+	_, err := svc.DownloadContent(context.Background(), &DownloadContentInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestFileService_ObjectEncryption_PerObjectKeys(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	mockKeys := new(MockKeyProvider)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Encryption: configs.EncryptionConfig{
+			Enabled: true,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+		Keys:     mockKeys,
+	}, mockStorage, mockPresigned, config)
+
+	mockKeys.On("DataKey", mock.Anything, "user-123").Return(bytes.Repeat([]byte{7}, 32), nil)
+
+	plain, err := svc.objectEncryption(context.Background(), "", "objects/file-1")
+	assert.NoError(t, err)
+	assert.Nil(t, plain)
+
+	first, err := svc.objectEncryption(context.Background(), "user-123", "objects/file-1")
+	assert.NoError(t, err)
+	second, err := svc.objectEncryption(context.Background(), "user-123", "objects/file-2")
+	assert.NoError(t, err)
+
+	firstHeaders, secondHeaders := map[string]string{}, map[string]string{}
+	addEncryptionHeaders(firstHeaders, first)
+	addEncryptionHeaders(secondHeaders, second)
+	assert.NotEqual(t, firstHeaders["X-Amz-Server-Side-Encryption-Customer-Key"], secondHeaders["X-Amz-Server-Side-Encryption-Customer-Key"])
+}
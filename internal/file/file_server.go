@@ -2,8 +2,11 @@ package file
 
 import (
 	"context"
+	"fmt"
+	"io"
 
 	"github.com/Sene4ka/cloud_storage/internal/api"
+	"github.com/Sene4ka/cloud_storage/internal/models"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -13,6 +16,30 @@ type FileService interface {
 	GetDownloadLink(ctx context.Context, input *GetDownloadLinkInput) (*GetDownloadLinkOutput, error)
 	DeleteFile(ctx context.Context, input *DeleteFileInput) (*DeleteFileOutput, error)
 	GetFileInfo(ctx context.Context, input *GetFileInfoInput) (*GetFileInfoOutput, error)
+	InitiateMultipartUpload(ctx context.Context, input *InitiateMultipartUploadInput) (*InitiateMultipartUploadOutput, error)
+	GetPartUploadURLs(ctx context.Context, input *GetPartUploadURLsInput) (*GetPartUploadURLsOutput, error)
+	CompleteMultipartUpload(ctx context.Context, input *CompleteMultipartUploadInput) (*CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, input *AbortMultipartUploadInput) (*AbortMultipartUploadOutput, error)
+	GetUsage(ctx context.Context, input *GetUsageInput) (*GetUsageOutput, error)
+	PurgeFile(ctx context.Context, input *PurgeFileInput) (*PurgeFileOutput, error)
+	EmptyTrash(ctx context.Context, input *EmptyTrashInput) (*EmptyTrashOutput, error)
+	SetTrashRetention(ctx context.Context, input *SetTrashRetentionInput) (*SetTrashRetentionOutput, error)
+	MoveFile(ctx context.Context, input *MoveFileInput) (*MoveFileOutput, error)
+	CopyFile(ctx context.Context, input *CopyFileInput) (*CopyFileOutput, error)
+	BatchTrash(ctx context.Context, input *BatchInput) (*BatchOutput, error)
+	BatchRestore(ctx context.Context, input *BatchInput) (*BatchOutput, error)
+	BatchDelete(ctx context.Context, input *BatchInput) (*BatchOutput, error)
+	BatchMove(ctx context.Context, input *BatchMoveInput) (*BatchOutput, error)
+	ListVersions(ctx context.Context, input *ListVersionsInput) (*ListVersionsOutput, error)
+	GetVersionDownloadLink(ctx context.Context, input *GetVersionDownloadLinkInput) (*GetDownloadLinkOutput, error)
+	RestoreVersion(ctx context.Context, input *RestoreVersionInput) (*RestoreVersionOutput, error)
+	DeleteVersion(ctx context.Context, input *DeleteVersionInput) (*DeleteVersionOutput, error)
+	CreateShareLink(ctx context.Context, input *CreateShareLinkInput) (*CreateShareLinkOutput, error)
+	ListShareLinks(ctx context.Context, input *ListShareLinksInput) (*ListShareLinksOutput, error)
+	RevokeShareLink(ctx context.Context, input *RevokeShareLinkInput) (*RevokeShareLinkOutput, error)
+	DownloadContent(ctx context.Context, input *DownloadContentInput) (*DownloadContentOutput, error)
+	UploadContent(ctx context.Context, input *UploadContentInput) (*UploadContentOutput, error)
+	GetPreviewLink(ctx context.Context, input *GetPreviewLinkInput) (*GetPreviewLinkOutput, error)
 }
 
 type Server struct {
@@ -26,19 +53,23 @@ func NewServer(service FileService) *Server {
 
 func (s *Server) InitiateUpload(ctx context.Context, req *api.InitiateUploadRequest) (*api.InitiateUploadResponse, error) {
 	out, err := s.service.InitiateUpload(ctx, &InitiateUploadInput{
-		UserID:   req.UserId,
-		Filename: req.Filename,
-		Path:     req.Path,
-		MimeType: req.MimeType,
-		Size:     req.Size,
-		IsPublic: req.IsPublic,
-		Tags:     req.Tags,
+		UserID:            req.UserId,
+		FileID:            req.FileId,
+		Filename:          req.Filename,
+		Path:              req.Path,
+		MimeType:          req.MimeType,
+		Size:              req.Size,
+		IsPublic:          req.IsPublic,
+		Tags:              req.Tags,
+		ChecksumAlgorithm: req.ChecksumAlgorithm,
+		Checksum:          req.Checksum,
 	})
 	if err != nil {
 		return nil, err
 	}
 	return &api.InitiateUploadResponse{
 		FileId:       out.FileID,
+		VersionId:    out.VersionID,
 		UploadUrl:    out.UploadURL,
 		UploadMethod: out.UploadMethod,
 		Headers:      out.Headers,
@@ -49,23 +80,461 @@ func (s *Server) InitiateUpload(ctx context.Context, req *api.InitiateUploadRequ
 
 func (s *Server) CompleteUpload(ctx context.Context, req *api.CompleteUploadRequest) (*api.CompleteUploadResponse, error) {
 	out, err := s.service.CompleteUpload(ctx, &CompleteUploadInput{
+		FileID:    req.FileId,
+		VersionID: req.VersionId,
+		UserID:    req.UserId,
+		ETag:      req.Etag,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CompleteUploadResponse{
+		Success:           true,
+		StoragePath:       out.StoragePath,
+		CreatedAt:         timestamppb.New(out.CreatedAt),
+		ChecksumAlgorithm: out.ChecksumAlgorithm,
+		Checksum:          out.Checksum,
+		Version:           int32(out.Version),
+	}, nil
+}
+
+func (s *Server) GetDownloadLink(ctx context.Context, req *api.GetDownloadLinkRequest) (*api.GetDownloadLinkResponse, error) {
+	out, err := s.service.GetDownloadLink(ctx, &GetDownloadLinkInput{
+		FileID:     req.FileId,
+		UserID:     req.UserId,
+		ExpiresIn:  req.ExpiresIn,
+		ShareToken: req.ShareToken,
+		Password:   req.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetDownloadLinkResponse{
+		DownloadUrl: out.DownloadURL,
+		Method:      out.Method,
+		Headers:     out.Headers,
+		ExpiresIn:   out.ExpiresIn,
+	}, nil
+}
+
+func (s *Server) DownloadContent(req *api.DownloadContentRequest, stream api.FileService_DownloadContentServer) error {
+	out, err := s.service.DownloadContent(stream.Context(), &DownloadContentInput{
+		FileID:      req.FileId,
+		UserID:      req.UserId,
+		Range:       req.Range,
+		IfNoneMatch: req.IfNoneMatch,
+	})
+	if err != nil {
+		return err
+	}
+	if out.Body != nil {
+		defer out.Body.Close()
+	}
+
+	mimeType := out.File.MimeType
+	if mimeType == "" {
+		mimeType = defaultMimeType
+	}
+	err = stream.Send(&api.DownloadContentResponse{
+		Info: &api.ContentInfo{
+			Filename:            out.File.OriginalName,
+			MimeType:            mimeType,
+			Size:                out.Size,
+			Etag:                out.ETag,
+			Offset:              out.Offset,
+			Length:              out.Length,
+			Partial:             out.Partial,
+			NotModified:         out.NotModified,
+			UpdatedAt:           timestamppb.New(out.File.UpdatedAt),
+			RangeNotSatisfiable: out.RangeNotSatisfiable,
+		},
+	})
+	if err != nil || out.Body == nil {
+		return err
+	}
+
+	buf := make([]byte, contentChunkSize)
+	for {
+		n, err := out.Body.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&api.DownloadContentResponse{Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file content: %w", err)
+		}
+	}
+}
+
+func (s *Server) UploadContent(stream api.FileService_UploadContentServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	header := first.Header
+	if header == nil {
+		return fmt.Errorf("the first message must carry the header")
+	}
+
+	out, err := s.service.UploadContent(stream.Context(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{
+			UserID:            header.UserId,
+			FileID:            header.FileId,
+			Filename:          header.Filename,
+			Path:              header.Path,
+			MimeType:          header.MimeType,
+			Size:              header.Size,
+			IsPublic:          header.IsPublic,
+			Tags:              header.Tags,
+			ChecksumAlgorithm: header.ChecksumAlgorithm,
+			Checksum:          header.Checksum,
+		},
+		Body: &uploadStreamReader{stream: stream, buf: first.Data},
+	})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(&api.UploadContentResponse{
+		FileId:            out.FileID,
+		VersionId:         out.VersionID,
+		StoragePath:       out.StoragePath,
+		Size:              out.Size,
+		MimeType:          out.MimeType,
+		ChecksumAlgorithm: out.ChecksumAlgorithm,
+		Checksum:          out.Checksum,
+		Version:           int32(out.Version),
+		CreatedAt:         timestamppb.New(out.CreatedAt),
+	})
+}
+
+func (s *Server) GetPreviewLink(ctx context.Context, req *api.GetPreviewLinkRequest) (*api.GetPreviewLinkResponse, error) {
+	out, err := s.service.GetPreviewLink(ctx, &GetPreviewLinkInput{
+		FileID:    req.FileId,
+		UserID:    req.UserId,
+		Size:      req.Size,
+		ExpiresIn: req.ExpiresIn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetPreviewLinkResponse{
+		State:     out.State,
+		Url:       out.URL,
+		MimeType:  out.MimeType,
+		Headers:   out.Headers,
+		ExpiresIn: out.ExpiresIn,
+	}, nil
+}
+
+// uploadStreamReader reads the data messages of an UploadContent stream.
+type uploadStreamReader struct {
+	stream api.FileService_UploadContentServer
+	buf    []byte
+}
+
+func (r *uploadStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (s *Server) DeleteFile(ctx context.Context, req *api.DeleteFileRequest) (*api.DeleteFileResponse, error) {
+	out, err := s.service.DeleteFile(ctx, &DeleteFileInput{
 		FileID: req.FileId,
 		UserID: req.UserId,
-		ETag:   req.Etag,
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &api.CompleteUploadResponse{
+	return &api.DeleteFileResponse{Success: out.Success}, nil
+}
+
+func (s *Server) InitiateMultipartUpload(ctx context.Context, req *api.InitiateMultipartUploadRequest) (*api.InitiateMultipartUploadResponse, error) {
+	out, err := s.service.InitiateMultipartUpload(ctx, &InitiateMultipartUploadInput{
+		UserID:   req.UserId,
+		Filename: req.Filename,
+		Path:     req.Path,
+		MimeType: req.MimeType,
+		Size:     req.Size,
+		PartSize: req.PartSize,
+		IsPublic: req.IsPublic,
+		Tags:     req.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.InitiateMultipartUploadResponse{
+		FileId:    out.FileID,
+		UploadId:  out.UploadID,
+		PartSize:  out.PartSize,
+		PartCount: int32(out.PartCount),
+		Success:   true,
+	}, nil
+}
+
+func (s *Server) GetPartUploadURLs(ctx context.Context, req *api.GetPartUploadURLsRequest) (*api.GetPartUploadURLsResponse, error) {
+	partNumbers := make([]int, len(req.PartNumbers))
+	for i, n := range req.PartNumbers {
+		partNumbers[i] = int(n)
+	}
+
+	out, err := s.service.GetPartUploadURLs(ctx, &GetPartUploadURLsInput{
+		FileID:      req.FileId,
+		UserID:      req.UserId,
+		PartNumbers: partNumbers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]*api.PartUploadURL, len(out.Parts))
+	for i, part := range out.Parts {
+		parts[i] = &api.PartUploadURL{
+			PartNumber: int32(part.PartNumber),
+			UploadUrl:  part.UploadURL,
+			Headers:    part.Headers,
+		}
+	}
+	uploadedParts := make([]*api.UploadedPart, len(out.UploadedParts))
+	for i, part := range out.UploadedParts {
+		uploadedParts[i] = &api.UploadedPart{
+			PartNumber: int32(part.PartNumber),
+			Etag:       part.ETag,
+			Size:       part.Size,
+		}
+	}
+
+	return &api.GetPartUploadURLsResponse{
+		Parts:         parts,
+		UploadedParts: uploadedParts,
+		UploadMethod:  out.UploadMethod,
+		ExpiresIn:     out.ExpiresIn,
+	}, nil
+}
+
+func (s *Server) CompleteMultipartUpload(ctx context.Context, req *api.CompleteMultipartUploadRequest) (*api.CompleteMultipartUploadResponse, error) {
+	parts := make([]CompletedPart, len(req.Parts))
+	for i, part := range req.Parts {
+		parts[i] = CompletedPart{
+			PartNumber: int(part.PartNumber),
+			ETag:       part.Etag,
+		}
+	}
+
+	out, err := s.service.CompleteMultipartUpload(ctx, &CompleteMultipartUploadInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+		Parts:  parts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CompleteMultipartUploadResponse{
 		Success:     true,
 		StoragePath: out.StoragePath,
 		CreatedAt:   timestamppb.New(out.CreatedAt),
 	}, nil
 }
 
-func (s *Server) GetDownloadLink(ctx context.Context, req *api.GetDownloadLinkRequest) (*api.GetDownloadLinkResponse, error) {
-	out, err := s.service.GetDownloadLink(ctx, &GetDownloadLinkInput{
+func (s *Server) AbortMultipartUpload(ctx context.Context, req *api.AbortMultipartUploadRequest) (*api.AbortMultipartUploadResponse, error) {
+	out, err := s.service.AbortMultipartUpload(ctx, &AbortMultipartUploadInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.AbortMultipartUploadResponse{Success: out.Success}, nil
+}
+
+func (s *Server) GetUsage(ctx context.Context, req *api.GetUsageRequest) (*api.GetUsageResponse, error) {
+	out, err := s.service.GetUsage(ctx, &GetUsageInput{UserID: req.UserId})
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetUsageResponse{
+		Plan:          out.Usage.Plan,
+		UsedBytes:     out.Usage.UsedBytes,
+		ReservedBytes: out.Usage.ReservedBytes,
+		LimitBytes:    out.Usage.LimitBytes,
+		UsedFiles:     out.Usage.UsedFiles,
+		ReservedFiles: out.Usage.ReservedFiles,
+		LimitFiles:    out.Usage.LimitFiles,
+	}, nil
+}
+
+func (s *Server) PurgeFile(ctx context.Context, req *api.PurgeFileRequest) (*api.PurgeFileResponse, error) {
+	out, err := s.service.PurgeFile(ctx, &PurgeFileInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.PurgeFileResponse{Success: out.Success}, nil
+}
+
+func (s *Server) EmptyTrash(ctx context.Context, req *api.EmptyTrashRequest) (*api.EmptyTrashResponse, error) {
+	out, err := s.service.EmptyTrash(ctx, &EmptyTrashInput{UserID: req.UserId})
+	if err != nil {
+		return nil, err
+	}
+	return &api.EmptyTrashResponse{
+		Success: true,
+		Purged:  int32(out.Purged),
+	}, nil
+}
+
+func (s *Server) SetTrashRetention(ctx context.Context, req *api.SetTrashRetentionRequest) (*api.SetTrashRetentionResponse, error) {
+	out, err := s.service.SetTrashRetention(ctx, &SetTrashRetentionInput{
+		UserID: req.UserId,
+		Days:   int(req.RetentionDays),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.SetTrashRetentionResponse{RetentionDays: int32(out.Days)}, nil
+}
+
+func (s *Server) MoveFile(ctx context.Context, req *api.MoveFileRequest) (*api.MoveFileResponse, error) {
+	out, err := s.service.MoveFile(ctx, &MoveFileInput{
+		FileID:         req.FileId,
+		UserID:         req.UserId,
+		Path:           req.Path,
+		Name:           req.Name,
+		ConflictPolicy: req.ConflictPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.MoveFileResponse{
+		Success: true,
+		FileId:  out.File.ID,
+		Path:    out.File.Path,
+		Name:    out.File.OriginalName,
+	}, nil
+}
+
+func (s *Server) CopyFile(ctx context.Context, req *api.CopyFileRequest) (*api.CopyFileResponse, error) {
+	out, err := s.service.CopyFile(ctx, &CopyFileInput{
+		FileID:         req.FileId,
+		UserID:         req.UserId,
+		Path:           req.Path,
+		Name:           req.Name,
+		ConflictPolicy: req.ConflictPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CopyFileResponse{
+		Success: true,
+		FileId:  out.File.ID,
+		Path:    out.File.Path,
+		Name:    out.File.OriginalName,
+		Size:    out.File.Size,
+	}, nil
+}
+
+func (s *Server) BatchTrash(ctx context.Context, req *api.BatchRequest) (*api.BatchResponse, error) {
+	out, err := s.service.BatchTrash(ctx, &BatchInput{UserID: req.UserId, FileIDs: req.FileIds})
+	if err != nil {
+		return nil, err
+	}
+	return convertBatchToProto(out), nil
+}
+
+func (s *Server) BatchRestore(ctx context.Context, req *api.BatchRequest) (*api.BatchResponse, error) {
+	out, err := s.service.BatchRestore(ctx, &BatchInput{UserID: req.UserId, FileIDs: req.FileIds})
+	if err != nil {
+		return nil, err
+	}
+	return convertBatchToProto(out), nil
+}
+
+func (s *Server) BatchDelete(ctx context.Context, req *api.BatchRequest) (*api.BatchResponse, error) {
+	out, err := s.service.BatchDelete(ctx, &BatchInput{UserID: req.UserId, FileIDs: req.FileIds})
+	if err != nil {
+		return nil, err
+	}
+	return convertBatchToProto(out), nil
+}
+
+func (s *Server) BatchMove(ctx context.Context, req *api.BatchMoveRequest) (*api.BatchResponse, error) {
+	out, err := s.service.BatchMove(ctx, &BatchMoveInput{
+		UserID:         req.UserId,
+		FileIDs:        req.FileIds,
+		Path:           req.Path,
+		ConflictPolicy: req.ConflictPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return convertBatchToProto(out), nil
+}
+
+func convertBatchToProto(out *BatchOutput) *api.BatchResponse {
+	results := make([]*api.BatchItemResult, len(out.Results))
+	for i, result := range out.Results {
+		results[i] = &api.BatchItemResult{
+			FileId:  result.FileID,
+			Success: result.Success,
+			Error:   result.Error,
+		}
+	}
+	return &api.BatchResponse{
+		Results:   results,
+		Succeeded: int32(out.Succeeded),
+		Failed:    int32(out.Failed),
+	}
+}
+
+func (s *Server) ListVersions(ctx context.Context, req *api.ListVersionsRequest) (*api.ListVersionsResponse, error) {
+	out, err := s.service.ListVersions(ctx, &ListVersionsInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*api.FileVersion, len(out.Versions))
+	for i, version := range out.Versions {
+		versions[i] = &api.FileVersion{
+			Id:                version.ID,
+			Version:           int32(version.Version),
+			Size:              version.Size,
+			MimeType:          version.MimeType,
+			ChecksumAlgorithm: version.ChecksumAlgorithm,
+			Checksum:          version.Checksum,
+			IsCurrent:         version.ID == req.FileId,
+			CreatedAt:         timestamppb.New(version.CreatedAt),
+		}
+		if version.SupersededAt != nil {
+			versions[i].SupersededAt = timestamppb.New(*version.SupersededAt)
+		}
+	}
+
+	return &api.ListVersionsResponse{
+		CurrentVersion: int32(out.CurrentVersion),
+		Versions:       versions,
+	}, nil
+}
+
+func (s *Server) GetVersionDownloadLink(ctx context.Context, req *api.GetVersionDownloadLinkRequest) (*api.GetDownloadLinkResponse, error) {
+	out, err := s.service.GetVersionDownloadLink(ctx, &GetVersionDownloadLinkInput{
 		FileID:    req.FileId,
+		VersionID: req.VersionId,
 		UserID:    req.UserId,
 		ExpiresIn: req.ExpiresIn,
 	})
@@ -80,13 +549,96 @@ func (s *Server) GetDownloadLink(ctx context.Context, req *api.GetDownloadLinkRe
 	}, nil
 }
 
-func (s *Server) DeleteFile(ctx context.Context, req *api.DeleteFileRequest) (*api.DeleteFileResponse, error) {
-	out, err := s.service.DeleteFile(ctx, &DeleteFileInput{
+func (s *Server) RestoreVersion(ctx context.Context, req *api.RestoreVersionRequest) (*api.RestoreVersionResponse, error) {
+	out, err := s.service.RestoreVersion(ctx, &RestoreVersionInput{
+		FileID:    req.FileId,
+		VersionID: req.VersionId,
+		UserID:    req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.RestoreVersionResponse{
+		Success:        true,
+		CurrentVersion: int32(out.CurrentVersion),
+	}, nil
+}
+
+func (s *Server) DeleteVersion(ctx context.Context, req *api.DeleteVersionRequest) (*api.DeleteVersionResponse, error) {
+	out, err := s.service.DeleteVersion(ctx, &DeleteVersionInput{
+		FileID:    req.FileId,
+		VersionID: req.VersionId,
+		UserID:    req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.DeleteVersionResponse{Success: out.Success}, nil
+}
+
+func (s *Server) CreateShareLink(ctx context.Context, req *api.CreateShareLinkRequest) (*api.CreateShareLinkResponse, error) {
+	out, err := s.service.CreateShareLink(ctx, &CreateShareLinkInput{
+		FileID:       req.FileId,
+		UserID:       req.UserId,
+		ExpiresIn:    req.ExpiresIn,
+		MaxDownloads: int(req.MaxDownloads),
+		Password:     req.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CreateShareLinkResponse{
+		Link:  convertShareLinkToProto(out.Link),
+		Token: out.Token,
+		Url:   "/s/" + out.Token,
+	}, nil
+}
+
+func (s *Server) ListShareLinks(ctx context.Context, req *api.ListShareLinksRequest) (*api.ListShareLinksResponse, error) {
+	out, err := s.service.ListShareLinks(ctx, &ListShareLinksInput{
 		FileID: req.FileId,
 		UserID: req.UserId,
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &api.DeleteFileResponse{Success: out.Success}, nil
+
+	links := make([]*api.ShareLink, len(out.Links))
+	for i, link := range out.Links {
+		links[i] = convertShareLinkToProto(link)
+	}
+	return &api.ListShareLinksResponse{Links: links}, nil
+}
+
+func (s *Server) RevokeShareLink(ctx context.Context, req *api.RevokeShareLinkRequest) (*api.RevokeShareLinkResponse, error) {
+	out, err := s.service.RevokeShareLink(ctx, &RevokeShareLinkInput{
+		FileID: req.FileId,
+		LinkID: req.LinkId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.RevokeShareLinkResponse{Success: out.Success}, nil
+}
+
+func convertShareLinkToProto(link *models.ShareLink) *api.ShareLink {
+	protoLink := &api.ShareLink{
+		Id:            link.ID,
+		FileId:        link.FileID,
+		MaxDownloads:  int32(link.MaxDownloads),
+		DownloadCount: int32(link.DownloadCount),
+		HasPassword:   link.HasPassword(),
+		CreatedAt:     timestamppb.New(link.CreatedAt),
+	}
+	if link.ExpiresAt != nil {
+		protoLink.ExpiresAt = timestamppb.New(*link.ExpiresAt)
+	}
+	if link.LastDownloadedAt != nil {
+		protoLink.LastDownloadedAt = timestamppb.New(*link.LastDownloadedAt)
+	}
+	if link.RevokedAt != nil {
+		protoLink.RevokedAt = timestamppb.New(*link.RevokedAt)
+	}
+	return protoLink
 }
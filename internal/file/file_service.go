@@ -2,16 +2,23 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/encryption"
 	"github.com/Sene4ka/cloud_storage/internal/metrics"
 	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
 	"github.com/Sene4ka/cloud_storage/internal/utils"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FileRepository interface {
@@ -19,73 +26,262 @@ type FileRepository interface {
 	GetByID(ctx context.Context, id string) (*models.File, error)
 	Delete(ctx context.Context, id, userID string) error
 	CheckAccess(ctx context.Context, fileID, userID string) (bool, string, string, error)
+	CreateMultipartUpload(ctx context.Context, upload *models.MultipartUpload) error
+	GetMultipartUpload(ctx context.Context, fileID string) (*models.MultipartUpload, error)
+	DeleteMultipartUpload(ctx context.Context, fileID string) error
+	SetChecksum(ctx context.Context, fileID, algorithm, checksum string) error
+	SetObjectInfo(ctx context.Context, fileID string, size int64, mimeType string) error
+	SetUploadState(ctx context.Context, fileID, state string) error
+	ListStaleUploads(ctx context.Context, before, multipartBefore time.Time, limit int) ([]*models.StaleUpload, error)
+	ListExpiredTrash(ctx context.Context, defaultRetentionDays, limit int) ([]*models.File, error)
+	ListTrashed(ctx context.Context, userID string, limit int) ([]*models.File, error)
+	SetTrashRetention(ctx context.Context, userID string, days int) error
+	FindByName(ctx context.Context, userID, path, originalName string) (*models.File, error)
+	ListNamesWithPrefix(ctx context.Context, userID, path, prefix string) ([]string, error)
+	Move(ctx context.Context, fileID, userID, path, originalName string) error
+	Replace(ctx context.Context, fileID, userID, path, originalName, displacedID string) error
+	SetTrashedBatch(ctx context.Context, fileIDs []string, userID string, isTrashed bool) ([]string, error)
+	SetPreviewState(ctx context.Context, fileID, state string) error
+	ClaimPendingPreviews(ctx context.Context, staleBefore time.Time, limit int) ([]*models.File, error)
+	FinishPreview(ctx context.Context, fileID, state string) (bool, error)
+	SetContentState(ctx context.Context, fileID, state string) error
+	ClaimPendingContents(ctx context.Context, staleBefore time.Time, limit int) ([]*models.File, error)
+	FinishContent(ctx context.Context, fileID, state, content string) (bool, error)
+	TouchAccessed(ctx context.Context, fileID string) error
+	RecordActivity(ctx context.Context, userID, fileID, action string) error
+	ListMigrationCandidates(ctx context.Context, bucket string, createdBefore, idleSince time.Time, limit int) ([]*models.File, error)
+	RelocateObject(ctx context.Context, storagePath, fromBucket, toBucket string) (bool, error)
+	ObjectInUse(ctx context.Context, bucket, storagePath string) (bool, error)
+}
+
+type QuotaRepository interface {
+	Reserve(ctx context.Context, userID, fileID string, bytes int64) error
+	ReserveBytes(ctx context.Context, userID, id string, bytes int64) error
+	Commit(ctx context.Context, fileID string, bytes int64) error
+	Release(ctx context.Context, userID, fileID string, bytes int64) error
+	ReleaseBytes(ctx context.Context, userID, id string, bytes int64) error
+	GetUsage(ctx context.Context, userID string) (*models.StorageUsage, error)
+}
+
+type VersionRepository interface {
+	Create(ctx context.Context, version *models.FileVersion) error
+	GetByID(ctx context.Context, id string) (*models.FileVersion, error)
+	ListByFileID(ctx context.Context, fileID string) ([]*models.FileVersion, error)
+	GetCurrentVersion(ctx context.Context, fileID string) (int, error)
+	Promote(ctx context.Context, version *models.FileVersion) (int, error)
+	Delete(ctx context.Context, id string) error
+	ListExpired(ctx context.Context, maxVersions int, maxAge time.Duration, limit int) ([]*models.FileVersion, error)
+	ListStale(ctx context.Context, before time.Time, limit int) ([]*models.FileVersion, error)
+}
+
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *models.ShareLink) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.ShareLink, error)
+	GetByID(ctx context.Context, id string) (*models.ShareLink, error)
+	ListByFileID(ctx context.Context, fileID string) ([]*models.ShareLink, error)
+	Revoke(ctx context.Context, id string) error
+	RecordDownload(ctx context.Context, id string) (bool, error)
+}
+
+type BlobRepository interface {
+	AttachFile(ctx context.Context, fileID string, blob *models.Blob) (*models.Blob, error)
+	AttachVersion(ctx context.Context, versionID string, blob *models.Blob) (*models.Blob, error)
+	AttachExisting(ctx context.Context, fileID, hash string) (*models.Blob, error)
+	Release(ctx context.Context, hash string) (*models.Blob, error)
+}
+
+// KeyProvider hands out the data keys objects are encrypted with.
+type KeyProvider interface {
+	DataKey(ctx context.Context, userID string) ([]byte, error)
 }
 
 type BlobStorage interface {
 	BucketExists(ctx context.Context, bucketName string) (bool, error)
-	MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error
-	StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
-	RemoveObject(ctx context.Context, bucketName, objectName string, opts minio.RemoveObjectOptions) error
+	MakeBucket(ctx context.Context, bucketName string, opts storage.MakeBucketOptions) error
+	StatObject(ctx context.Context, bucketName, objectName string, opts storage.StatObjectOptions) (storage.ObjectInfo, error)
+	GetObject(ctx context.Context, bucketName, objectName string, opts storage.GetObjectOptions) (io.ReadCloser, error)
+	PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts storage.PutObjectOptions) (storage.UploadInfo, error)
+	RemoveObject(ctx context.Context, bucketName, objectName string) error
+	NewMultipartUpload(ctx context.Context, bucketName, objectName string, opts storage.PutObjectOptions) (string, error)
+	ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string, partNumberMarker, maxParts int) (storage.ListObjectPartsResult, error)
+	CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []storage.CompletePart, opts storage.PutObjectOptions) (storage.UploadInfo, error)
+	AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error
+	CopyObject(ctx context.Context, dst storage.CopyDestOptions, src storage.CopySrcOptions) (storage.UploadInfo, error)
 }
 
 type PresignedURLGenerator interface {
-	PresignedPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error)
 	PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error)
+	PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error)
 }
 
+const (
+	presignedUploadTTL = 15 * time.Minute
+	defaultMimeType    = "application/octet-stream"
+
+	defaultPartSize = 16 << 20
+	minPartSize     = 5 << 20
+	maxPartSize     = 5 << 30
+	maxPartCount    = 10000
+)
+
 type fileService struct {
 	fileRepo        FileRepository
+	quotaRepo       QuotaRepository
+	versionRepo     VersionRepository
+	linkRepo        ShareLinkRepository
+	blobRepo        BlobRepository
+	keys            KeyProvider
 	storage         BlobStorage
 	presignedClient PresignedURLGenerator
 	config          *configs.Config
+	placement       *PlacementPolicy
+	previewQueued   chan struct{}
+	textQueued      chan struct{}
+}
+
+// Dependencies are the repositories the file service keeps its state in,
+// along with the provider of per-user encryption keys.
+type Dependencies struct {
+	Files    FileRepository
+	Quotas   QuotaRepository
+	Versions VersionRepository
+	Links    ShareLinkRepository
+	Blobs    BlobRepository
+	// Keys may be nil when encryption is not configured.
+	Keys KeyProvider
 }
 
-func NewFileService(fileRepo FileRepository, storage BlobStorage, presignedClient PresignedURLGenerator, config *configs.Config) *fileService {
+// NewFileService creates the file service.
+func NewFileService(deps Dependencies, storage BlobStorage, presignedClient PresignedURLGenerator, config *configs.Config) *fileService {
 	return &fileService{
-		fileRepo:        fileRepo,
+		fileRepo:        deps.Files,
+		quotaRepo:       deps.Quotas,
+		versionRepo:     deps.Versions,
+		linkRepo:        deps.Links,
+		blobRepo:        deps.Blobs,
+		keys:            deps.Keys,
 		storage:         storage,
 		presignedClient: presignedClient,
 		config:          config,
+		placement:       defaultPlacementPolicy(config),
+		previewQueued:   make(chan struct{}, 1),
+		textQueued:      make(chan struct{}, 1),
 	}
 }
 
-func NewFileServiceWithMinio(fileRepo FileRepository, config *configs.Config) (*fileService, error) {
-	minioClient, err := minio.New(config.MinIO.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.MinIO.AccessKeyID, config.MinIO.SecretAccessKey, ""),
-		Secure: config.MinIO.UseSSL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create minio client: %w", err)
+// NewStorage returns the object storage driver selected by STORAGE_DRIVER.
+// The local driver serves its presigned URLs itself; the caller has to
+// expose it as an http.Handler at STORAGE_LOCAL_PUBLIC_URL. Tiers of the
+// placement policy with an endpoint of their own get a MinIO client each.
+func NewStorage(config *configs.Config, policy *PlacementPolicy) (BlobStorage, PresignedURLGenerator, error) {
+	switch config.Storage.Driver {
+	case "local":
+		for _, tier := range policy.Tiers {
+			if tier.Endpoint != "" {
+				return nil, nil, fmt.Errorf("tier %s: endpoints are not supported by the local storage driver", tier.Name)
+			}
+		}
+		local, err := storage.NewLocal(config.Storage.LocalRoot, config.Storage.LocalPublicURL, []byte(config.Storage.LocalSigningKey))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create local storage: %w", err)
+		}
+		return local, local, nil
+	case "minio", "":
+		minioStorage, err := storage.NewMinIO(config.MinIO)
+		if err != nil {
+			return nil, nil, err
+		}
+		buckets := make(map[string]storage.Backend)
+		for _, tier := range policy.Tiers {
+			if tier.Endpoint == "" {
+				continue
+			}
+			tierStorage, err := storage.NewMinIO(tierMinIOConfig(config.MinIO, tier))
+			if err != nil {
+				return nil, nil, fmt.Errorf("tier %s: %w", tier.Name, err)
+			}
+			buckets[tier.Bucket] = tierStorage
+		}
+		if len(buckets) == 0 {
+			return minioStorage, minioStorage, nil
+		}
+		router := storage.NewRouter(minioStorage, buckets)
+		return router, router, nil
 	}
+	return nil, nil, fmt.Errorf("unknown storage driver: %s", config.Storage.Driver)
+}
 
-	ctx := context.Background()
-	exists, err := minioClient.BucketExists(ctx, config.MinIO.BucketName)
+// tierMinIOConfig returns the connection settings of a tier, filling in
+// what the tier leaves out from the default MinIO.
+func tierMinIOConfig(base configs.MinIOConfig, tier StorageTier) configs.MinIOConfig {
+	config := base
+	config.Endpoint = tier.Endpoint
+	config.PublicEndpoint = tier.PublicEndpoint
+	config.UseSSL = tier.UseSSL
+	config.BucketName = tier.Bucket
+	if tier.AccessKeyID != "" {
+		config.AccessKeyID = tier.AccessKeyID
+	}
+	if tier.SecretAccessKey != "" {
+		config.SecretAccessKey = tier.SecretAccessKey
+	}
+	if tier.Region != "" {
+		config.Region = tier.Region
+	}
+	return config
+}
+
+// NewFileServiceWithStorage creates a file service on top of a storage
+// driver, creating the buckets of the placement policy if needed. The key
+// provider of deps is built from keyRepo when encryption is enabled.
+func NewFileServiceWithStorage(deps Dependencies, keyRepo encryption.KeyRepository, store BlobStorage, presigned PresignedURLGenerator, policy *PlacementPolicy, config *configs.Config) (*fileService, error) {
+	keyring, err := encryption.NewKeyring(config.Encryption)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+		return nil, fmt.Errorf("failed to load master keys: %w", err)
 	}
-	if !exists {
-		err = minioClient.MakeBucket(ctx, config.MinIO.BucketName, minio.MakeBucketOptions{Region: config.MinIO.Region})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create bucket: %w", err)
+	if config.Encryption.Enabled {
+		if config.Storage.Driver == "local" {
+			return nil, fmt.Errorf("encryption is not supported by the local storage driver")
+		}
+		if !config.MinIO.UseSSL {
+			return nil, fmt.Errorf("encryption requires a TLS connection to MinIO")
+		}
+		for _, tier := range policy.Tiers {
+			if tier.Endpoint != "" && !tier.UseSSL {
+				return nil, fmt.Errorf("encryption requires a TLS connection to tier %s", tier.Name)
+			}
 		}
 	}
+	if keyring != nil {
+		deps.Keys = encryption.NewKeyService(keyRepo, keyring)
+	}
 
-	presignedEndpoint := config.MinIO.PublicEndpoint
-	if presignedEndpoint == "" {
-		presignedEndpoint = config.MinIO.Endpoint
+	ctx := context.Background()
+	// Previews are always kept in MINIO_BUCKET, whatever the tiers are.
+	buckets := []StorageTier{{Bucket: config.MinIO.BucketName}}
+	for _, tier := range policy.Tiers {
+		buckets = append(buckets, tier)
 	}
-	presignedClient, err := minio.New(presignedEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.MinIO.AccessKeyID, config.MinIO.SecretAccessKey, ""),
-		Secure: config.MinIO.UseSSL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create presigned minio client: %w", err)
+	for _, tier := range buckets {
+		exists, err := store.BucketExists(ctx, tier.Bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+		}
+		if exists {
+			continue
+		}
+		region := tier.Region
+		if region == "" {
+			region = config.MinIO.Region
+		}
+		if err := store.MakeBucket(ctx, tier.Bucket, storage.MakeBucketOptions{Region: region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", tier.Bucket, err)
+		}
 	}
 
-	storage := NewMinIOAdapter(minioClient)
-	presigned := NewMinIOAdapter(presignedClient)
-
-	return NewFileService(fileRepo, storage, presigned, config), nil
+	service := NewFileService(deps, store, presigned, config)
+	service.placement = policy
+	return service, nil
 }
 
 func (s *fileService) InitiateUpload(ctx context.Context, input *InitiateUploadInput) (output *InitiateUploadOutput, err error) {
@@ -101,41 +297,100 @@ func (s *fileService) InitiateUpload(ctx context.Context, input *InitiateUploadI
 		return nil, fmt.Errorf("user_id is required")
 	}
 
+	if input.Size < 0 {
+		return nil, fmt.Errorf("size cannot be negative")
+	}
+
+	if input.FileID != "" {
+		return s.initiateVersionUpload(ctx, input)
+	}
+
+	file, err := s.createPendingFile(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	presignedURL, headers, err := s.presignUpload(ctx, file.Bucket, file.StoragePath, file.MimeType, file.Size, file.ChecksumAlgorithm, file.Checksum, file.KeyOwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InitiateUploadOutput{
+		FileID:       file.ID,
+		UploadURL:    presignedURL,
+		UploadMethod: "PUT",
+		Headers:      headers,
+		ExpiresIn:    int64(presignedUploadTTL / time.Second),
+	}, nil
+}
+
+// createPendingFile books the quota for a new upload and records its
+// metadata row in the pending state.
+func (s *fileService) createPendingFile(ctx context.Context, input *InitiateUploadInput) (*models.File, error) {
 	if err := utils.ValidatePath(input.Path); err != nil {
 		return nil, fmt.Errorf("invalid path: %w", err)
 	}
 
+	checksumAlgorithm, err := normalizeChecksum(input.ChecksumAlgorithm, input.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum: %w", err)
+	}
+
 	uniqueFilename := generateUniqueFilename(input.Filename)
-	storagePath := fmt.Sprintf("%s/%s/%s", input.UserID, time.Now().Format("2006/01/02"), uniqueFilename)
+	storagePath := buildStoragePath(input.UserID, uniqueFilename)
+	mimeType := mimeTypeOrDefault(input.MimeType)
 	file := models.NewFile(
 		input.UserID,
 		uniqueFilename,
 		input.Filename,
 		input.Path,
-		input.MimeType,
+		mimeType,
 		storagePath,
-		s.config.MinIO.BucketName,
+		s.placement.bucketFor(input.UserID, mimeType, input.Size, input.Tags),
 		input.Size,
 		input.IsPublic,
 		input.Tags,
 	)
+	file.ChecksumAlgorithm = checksumAlgorithm
+	if checksumAlgorithm != "" {
+		file.Checksum = input.Checksum
+	}
+	file.KeyOwnerID = s.keyOwnerFor(file.UserID)
+
+	if err := s.reserveQuota(ctx, file); err != nil {
+		return nil, err
+	}
 
 	if err := s.fileRepo.Create(ctx, file); err != nil {
-		return nil, fmt.Errorf("failed to create metadata: %w", err)
+		_ = s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size)
+		return nil, nameConflictError(fmt.Errorf("failed to create metadata: %w", err))
 	}
 
-	presignedURL, err := s.presignedClient.PresignedPutObject(ctx, s.config.MinIO.BucketName, storagePath, 15*time.Minute)
+	return file, nil
+}
+
+// presignUpload signs a single PUT of an object. Content-Length and
+// Content-Type are part of the signature, so the storage refuses a body that
+// does not match the metadata row. An unknown size, given as 0, is left out
+// of the signature and taken from the stored object on completion. Objects
+// encrypted with the key of keyOwnerID also get their SSE-C headers signed.
+func (s *fileService) presignUpload(ctx context.Context, bucket, storagePath, mimeType string, size int64, checksumAlgorithm, checksum, keyOwnerID string) (string, map[string]string, error) {
+	sse, err := s.objectEncryption(ctx, keyOwnerID, storagePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate upload URL: %w", err)
+		return "", nil, err
+	}
+	headers := checksumHeaders(checksumAlgorithm, checksum)
+	headers["Content-Type"] = mimeType
+	if size > 0 {
+		headers["Content-Length"] = strconv.FormatInt(size, 10)
 	}
+	addEncryptionHeaders(headers, sse)
 
-	return &InitiateUploadOutput{
-		FileID:       file.ID,
-		UploadURL:    presignedURL.String(),
-		UploadMethod: "PUT",
-		Headers:      map[string]string{},
-		ExpiresIn:    int64(15 * time.Minute / time.Second),
-	}, nil
+	presignedURL, err := s.presignedClient.PresignHeader(ctx, http.MethodPut, bucket, storagePath, presignedUploadTTL, nil, toHTTPHeader(headers))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate upload URL: %w", err)
+	}
+	return presignedURL.String(), headers, nil
 }
 
 func (s *fileService) CompleteUpload(ctx context.Context, input *CompleteUploadInput) (output *CompleteUploadOutput, err error) {
@@ -147,6 +402,10 @@ func (s *fileService) CompleteUpload(ctx context.Context, input *CompleteUploadI
 		metrics.RecordFileOperation("upload_complete", status)
 	}()
 
+	if input.VersionID != "" {
+		return s.completeVersionUpload(ctx, input)
+	}
+
 	file, err := s.fileRepo.GetByID(ctx, input.FileID)
 	if err != nil {
 		return nil, fmt.Errorf("file not found: %w", err)
@@ -154,13 +413,19 @@ func (s *fileService) CompleteUpload(ctx context.Context, input *CompleteUploadI
 	if file.UserID != input.UserID {
 		return nil, fmt.Errorf("access denied")
 	}
-	_, err = s.storage.StatObject(ctx, s.config.MinIO.BucketName, file.StoragePath, minio.StatObjectOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("file not found in storage: %w", err)
+	if err := s.finalizeUpload(ctx, file, input.ETag); err != nil {
+		return nil, err
 	}
+	s.schedulePreview(ctx, file.ID, file.MimeType)
+	s.scheduleTextExtraction(ctx, file.ID, file.MimeType)
+	s.recordActivity(ctx, file.UserID, file.ID, models.ActivityUpload)
+
 	return &CompleteUploadOutput{
-		StoragePath: file.StoragePath,
-		CreatedAt:   file.CreatedAt,
+		StoragePath:       file.StoragePath,
+		CreatedAt:         file.CreatedAt,
+		ChecksumAlgorithm: file.ChecksumAlgorithm,
+		Checksum:          file.Checksum,
+		Version:           1,
 	}, nil
 }
 
@@ -173,6 +438,10 @@ func (s *fileService) GetDownloadLink(ctx context.Context, input *GetDownloadLin
 		metrics.RecordFileOperation("download", status)
 	}()
 
+	if input.ShareToken != "" {
+		return s.shareLinkDownload(ctx, input)
+	}
+
 	hasAccess, storagePath, bucket, err := s.fileRepo.CheckAccess(ctx, input.FileID, input.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check access: %w", err)
@@ -180,18 +449,24 @@ func (s *fileService) GetDownloadLink(ctx context.Context, input *GetDownloadLin
 	if !hasAccess {
 		return nil, fmt.Errorf("access denied")
 	}
+	sse, err := s.downloadEncryption(ctx, input.FileID, storagePath)
+	if err != nil {
+		return nil, err
+	}
 	expires := time.Hour
 	if input.ExpiresIn > 0 {
 		expires = time.Duration(input.ExpiresIn) * time.Second
 	}
-	presignedURL, err := s.presignedClient.PresignedGetObject(ctx, bucket, storagePath, expires, nil)
+	downloadURL, headers, err := s.presignDownload(ctx, bucket, storagePath, expires, nil, sse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate download URL: %w", err)
+		return nil, err
 	}
+	s.recordAccess(ctx, input.FileID)
+	s.recordActivity(ctx, input.UserID, input.FileID, models.ActivityDownload)
 	return &GetDownloadLinkOutput{
-		DownloadURL: presignedURL.String(),
+		DownloadURL: downloadURL,
 		Method:      "GET",
-		Headers:     map[string]string{},
+		Headers:     headers,
 		ExpiresIn:   int64(expires / time.Second),
 	}, nil
 }
@@ -212,14 +487,349 @@ func (s *fileService) DeleteFile(ctx context.Context, input *DeleteFileInput) (o
 	if file.UserID != input.UserID {
 		return nil, fmt.Errorf("access denied")
 	}
-	err = s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath, minio.RemoveObjectOptions{})
+	if err := s.purgeFile(ctx, file); err != nil {
+		return nil, err
+	}
+	return &DeleteFileOutput{Success: true}, nil
+}
+
+func (s *fileService) PurgeFile(ctx context.Context, input *PurgeFileInput) (output *PurgeFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("purge", status)
+	}()
+
+	file, err := s.fileRepo.GetByID(ctx, input.FileID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete from storage: %w", err)
+		return nil, fmt.Errorf("file not found: %w", err)
 	}
-	if err := s.fileRepo.Delete(ctx, input.FileID, input.UserID); err != nil {
-		return nil, fmt.Errorf("failed to delete metadata: %w", err)
+	if file.UserID != input.UserID {
+		return nil, fmt.Errorf("access denied")
 	}
-	return &DeleteFileOutput{Success: true}, nil
+	if !file.IsTrashed {
+		return nil, fmt.Errorf("file is not in trash")
+	}
+	if err := s.purgeFile(ctx, file); err != nil {
+		return nil, err
+	}
+	return &PurgeFileOutput{Success: true}, nil
+}
+
+func (s *fileService) EmptyTrash(ctx context.Context, input *EmptyTrashInput) (output *EmptyTrashOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("empty_trash", status)
+	}()
+
+	if input.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	purged := 0
+	for {
+		files, err := s.fileRepo.ListTrashed(ctx, input.UserID, purgeBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list trash: %w", err)
+		}
+		for _, file := range files {
+			if err := s.purgeFile(ctx, file); err != nil {
+				return nil, fmt.Errorf("purged %d files, then failed on %s: %w", purged, file.ID, err)
+			}
+			purged++
+		}
+		if len(files) < purgeBatchSize {
+			return &EmptyTrashOutput{Purged: purged}, nil
+		}
+	}
+}
+
+// SetTrashRetention sets how many days the user's trashed files are kept
+// before the purger deletes them. 0 returns to the configured default.
+func (s *fileService) SetTrashRetention(ctx context.Context, input *SetTrashRetentionInput) (output *SetTrashRetentionOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("set_trash_retention", status)
+	}()
+
+	if input.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if input.Days < 0 {
+		return nil, fmt.Errorf("retention days cannot be negative")
+	}
+	if err := s.fileRepo.SetTrashRetention(ctx, input.UserID, input.Days); err != nil {
+		return nil, err
+	}
+	return &SetTrashRetentionOutput{Days: input.Days}, nil
+}
+
+// purgeFile permanently removes a file and all of its versions from the
+// storage and the database and gives their space back to the owner's quota.
+// Content kept in a blob is only released once the rows naming it are gone,
+// and its object is removed when no other file refers to it.
+func (s *fileService) purgeFile(ctx context.Context, file *models.File) error {
+	versions, err := s.versionRepo.ListByFileID(ctx, file.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list versions: %w", err)
+	}
+	for _, version := range versions {
+		if version.BlobHash != "" {
+			continue
+		}
+		if err := s.storage.RemoveObject(ctx, version.Bucket, version.StoragePath); err != nil {
+			return fmt.Errorf("failed to delete version from storage: %w", err)
+		}
+	}
+
+	if file.BlobHash == "" {
+		if err := s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath); err != nil {
+			return fmt.Errorf("failed to delete from storage: %w", err)
+		}
+	}
+	if previewable(file.MimeType) {
+		if err := s.removePreviews(ctx, file.ID); err != nil {
+			return err
+		}
+	}
+	if err := s.fileRepo.Delete(ctx, file.ID, file.UserID); err != nil {
+		return fmt.Errorf("failed to delete metadata: %w", err)
+	}
+	for _, version := range versions {
+		if version.BlobHash != "" {
+			if err := s.releaseBlob(ctx, version.BlobHash); err != nil {
+				return err
+			}
+		}
+	}
+	if file.BlobHash != "" {
+		if err := s.releaseBlob(ctx, file.BlobHash); err != nil {
+			return err
+		}
+	}
+	if err := s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size); err != nil {
+		return fmt.Errorf("failed to release quota: %w", err)
+	}
+	for _, version := range versions {
+		if err := s.quotaRepo.ReleaseBytes(ctx, file.UserID, version.ID, version.Size); err != nil {
+			return fmt.Errorf("failed to release quota: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *fileService) MoveFile(ctx context.Context, input *MoveFileInput) (output *MoveFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("move", status)
+	}()
+
+	policy, err := normalizeConflictPolicy(input.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	file, err := s.moveFile(ctx, input.FileID, input.UserID, input.Path, input.Name, policy)
+	if err != nil {
+		return nil, err
+	}
+	return &MoveFileOutput{File: file}, nil
+}
+
+// moveFile moves a file into dir, keeping its name unless a new one is given.
+func (s *fileService) moveFile(ctx context.Context, fileID, userID, dir, name, policy string) (*models.File, error) {
+	file, err := s.getMovableFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = file.OriginalName
+	}
+	if err := validateFileName(name); err != nil {
+		return nil, err
+	}
+	if dir == file.Path && name == file.OriginalName {
+		return file, nil
+	}
+
+	name, displaced, err := s.resolveConflict(ctx, file.UserID, dir, name, policy, file.ID)
+	if err != nil {
+		return nil, err
+	}
+	if displaced != nil {
+		err = s.fileRepo.Replace(ctx, file.ID, file.UserID, dir, name, displaced.ID)
+	} else {
+		err = s.fileRepo.Move(ctx, file.ID, file.UserID, dir, name)
+	}
+	if err != nil {
+		return nil, nameConflictError(fmt.Errorf("failed to move file: %w", err))
+	}
+	s.purgeDisplaced(ctx, displaced)
+
+	file.Path = dir
+	file.OriginalName = name
+	return file, nil
+}
+
+// CopyFile registers a copy of a file as a new file of the same owner. The
+// copy shares its source's blob, or gets a storage side copy of content that
+// has none, so the content never passes through the service.
+func (s *fileService) CopyFile(ctx context.Context, input *CopyFileInput) (output *CopyFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("copy", status)
+	}()
+
+	policy, err := normalizeConflictPolicy(input.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	source, err := s.getMovableFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := input.Name
+	if name == "" {
+		name = source.OriginalName
+	}
+	if err := validateFileName(name); err != nil {
+		return nil, err
+	}
+
+	name, displaced, err := s.resolveConflict(ctx, source.UserID, input.Path, name, policy, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	uniqueFilename := generateUniqueFilename(name)
+	// A copy replacing a file goes by its unique filename until its content
+	// is in place, so a failed copy leaves the replaced file untouched.
+	createName := name
+	if displaced != nil {
+		createName = uniqueFilename
+	}
+	file := models.NewFile(
+		source.UserID,
+		uniqueFilename,
+		createName,
+		input.Path,
+		source.MimeType,
+		buildStoragePath(source.UserID, uniqueFilename),
+		s.placement.bucketFor(source.UserID, source.MimeType, source.Size, source.Tags),
+		source.Size,
+		source.IsPublic,
+		source.Tags,
+	)
+	file.ChecksumAlgorithm = source.ChecksumAlgorithm
+	file.Checksum = source.Checksum
+	file.KeyOwnerID = s.keyOwnerFor(source.UserID)
+
+	if err := s.reserveQuota(ctx, file); err != nil {
+		return nil, err
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		_ = s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size)
+		return nil, nameConflictError(fmt.Errorf("failed to create metadata: %w", err))
+	}
+
+	if err := s.copyContent(ctx, source, file); err != nil {
+		_ = s.fileRepo.Delete(ctx, file.ID, file.UserID)
+		_ = s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size)
+		return nil, err
+	}
+
+	// From here on a failure leaves a pending row behind, which the upload
+	// reaper cleans up together with the copied object.
+	if displaced != nil {
+		if err := s.fileRepo.Replace(ctx, file.ID, file.UserID, input.Path, name, displaced.ID); err != nil {
+			return nil, nameConflictError(fmt.Errorf("failed to replace %s: %w", name, err))
+		}
+		file.OriginalName = name
+	}
+	if err := s.fileRepo.SetUploadState(ctx, file.ID, models.UploadStateActive); err != nil {
+		return nil, fmt.Errorf("failed to activate copy: %w", err)
+	}
+	file.UploadState = models.UploadStateActive
+	if err := s.quotaRepo.Commit(ctx, file.ID, file.Size); err != nil {
+		return nil, fmt.Errorf("failed to commit quota: %w", err)
+	}
+	s.purgeDisplaced(ctx, displaced)
+	s.schedulePreview(ctx, file.ID, file.MimeType)
+	s.scheduleTextExtraction(ctx, file.ID, file.MimeType)
+
+	return &CopyFileOutput{File: file}, nil
+}
+
+// copyContent gives file the content of source.
+func (s *fileService) copyContent(ctx context.Context, source, file *models.File) error {
+	if source.BlobHash != "" {
+		blob, err := s.blobRepo.AttachExisting(ctx, file.ID, source.BlobHash)
+		if err != nil {
+			return fmt.Errorf("failed to share content: %w", err)
+		}
+		file.BlobHash, file.Bucket, file.StoragePath, file.KeyOwnerID = blob.Hash, blob.Bucket, blob.StoragePath, blob.KeyOwnerID
+		metrics.RecordDedupSaved(blob.Size)
+		return nil
+	}
+
+	srcSSE, err := s.objectEncryption(ctx, source.KeyOwnerID, source.StoragePath)
+	if err != nil {
+		return err
+	}
+	dstSSE, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.CopyObject(ctx,
+		storage.CopyDestOptions{Bucket: file.Bucket, Object: file.StoragePath, Encryption: dstSSE},
+		storage.CopySrcOptions{Bucket: source.Bucket, Object: source.StoragePath, Encryption: srcSSE},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	s.dedupFile(ctx, file, "")
+	return nil
+}
+
+// getMovableFile loads a file the user owns and whose upload is complete.
+func (s *fileService) getMovableFile(ctx context.Context, fileID, userID string) (*models.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, fmt.Errorf("access denied")
+	}
+	if file.UploadState != models.UploadStateActive {
+		return nil, fmt.Errorf("file upload is not complete")
+	}
+	if file.IsTrashed {
+		return nil, fmt.Errorf("file is in trash")
+	}
+	return file, nil
 }
 
 func (s *fileService) GetFileInfo(ctx context.Context, input *GetFileInfoInput) (output *GetFileInfoOutput, err error) {
@@ -245,6 +855,429 @@ func (s *fileService) GetFileInfo(ctx context.Context, input *GetFileInfoInput)
 	return &GetFileInfoOutput{File: file}, nil
 }
 
+func (s *fileService) InitiateMultipartUpload(ctx context.Context, input *InitiateMultipartUploadInput) (output *InitiateMultipartUploadOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("multipart_initiate", status)
+	}()
+
+	if input.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	partSize, partCount, err := planParts(input.Size, input.PartSize)
+	if err != nil {
+		return nil, err
+	}
+
+	uniqueFilename := generateUniqueFilename(input.Filename)
+	storagePath := buildStoragePath(input.UserID, uniqueFilename)
+	mimeType := mimeTypeOrDefault(input.MimeType)
+	file := models.NewFile(
+		input.UserID,
+		uniqueFilename,
+		input.Filename,
+		input.Path,
+		mimeType,
+		storagePath,
+		s.placement.bucketFor(input.UserID, mimeType, input.Size, input.Tags),
+		input.Size,
+		input.IsPublic,
+		input.Tags,
+	)
+	file.KeyOwnerID = s.keyOwnerFor(file.UserID)
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reserveQuota(ctx, file); err != nil {
+		return nil, err
+	}
+
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		_ = s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size)
+		return nil, fmt.Errorf("failed to create metadata: %w", err)
+	}
+
+	uploadID, err := s.storage.NewMultipartUpload(ctx, file.Bucket, storagePath, storage.PutObjectOptions{ContentType: file.MimeType, Encryption: sse})
+	if err != nil {
+		_ = s.fileRepo.Delete(ctx, file.ID, file.UserID)
+		_ = s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size)
+		return nil, fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+
+	upload := models.NewMultipartUpload(file.ID, uploadID, partSize, partCount)
+	if err := s.fileRepo.CreateMultipartUpload(ctx, upload); err != nil {
+		_ = s.storage.AbortMultipartUpload(ctx, file.Bucket, storagePath, uploadID)
+		_ = s.fileRepo.Delete(ctx, file.ID, file.UserID)
+		_ = s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size)
+		return nil, fmt.Errorf("failed to save upload session: %w", err)
+	}
+
+	return &InitiateMultipartUploadOutput{
+		FileID:    file.ID,
+		UploadID:  uploadID,
+		PartSize:  partSize,
+		PartCount: partCount,
+	}, nil
+}
+
+func (s *fileService) GetPartUploadURLs(ctx context.Context, input *GetPartUploadURLsInput) (output *GetPartUploadURLsOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("multipart_part_urls", status)
+	}()
+
+	file, upload, err := s.getOwnedMultipartUpload(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded, err := s.listUploadedParts(ctx, file, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool, len(uploaded))
+	uploadedParts := make([]UploadedPart, len(uploaded))
+	for i, part := range uploaded {
+		done[part.PartNumber] = true
+		uploadedParts[i] = UploadedPart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+			Size:       part.Size,
+		}
+	}
+
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	partNumbers := input.PartNumbers
+	if len(partNumbers) == 0 {
+		for n := 1; n <= upload.PartCount; n++ {
+			if !done[n] {
+				partNumbers = append(partNumbers, n)
+			}
+		}
+	}
+
+	parts := make([]PartUploadURL, 0, len(partNumbers))
+	for _, n := range partNumbers {
+		if n < 1 || n > upload.PartCount {
+			return nil, fmt.Errorf("part number %d out of range 1..%d", n, upload.PartCount)
+		}
+		params := url.Values{}
+		params.Set("partNumber", strconv.Itoa(n))
+		params.Set("uploadId", upload.UploadID)
+		headers := map[string]string{
+			"Content-Length": strconv.FormatInt(partLength(file.Size, upload, n), 10),
+		}
+		addEncryptionHeaders(headers, sse)
+		presignedURL, err := s.presignedClient.PresignHeader(ctx, http.MethodPut, file.Bucket, file.StoragePath, presignedUploadTTL, params, toHTTPHeader(headers))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate upload URL for part %d: %w", n, err)
+		}
+		parts = append(parts, PartUploadURL{PartNumber: n, UploadURL: presignedURL.String(), Headers: headers})
+	}
+
+	return &GetPartUploadURLsOutput{
+		Parts:         parts,
+		UploadedParts: uploadedParts,
+		UploadMethod:  "PUT",
+		ExpiresIn:     int64(presignedUploadTTL / time.Second),
+	}, nil
+}
+
+func (s *fileService) CompleteMultipartUpload(ctx context.Context, input *CompleteMultipartUploadInput) (output *CompleteMultipartUploadOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("multipart_complete", status)
+	}()
+
+	file, upload, err := s.getOwnedMultipartUpload(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := input.Parts
+	if len(parts) == 0 {
+		uploaded, err := s.listUploadedParts(ctx, file, upload)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range uploaded {
+			parts = append(parts, CompletedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+		}
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	if len(parts) != upload.PartCount {
+		return nil, fmt.Errorf("expected %d parts, got %d", upload.PartCount, len(parts))
+	}
+	completeParts := make([]storage.CompletePart, len(parts))
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return nil, fmt.Errorf("part %d is missing", i+1)
+		}
+		completeParts[i] = storage.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+	}
+
+	_, err = s.storage.CompleteMultipartUpload(ctx, file.Bucket, file.StoragePath, upload.UploadID, completeParts, storage.PutObjectOptions{ContentType: file.MimeType})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	if err := s.fileRepo.DeleteMultipartUpload(ctx, file.ID); err != nil {
+		return nil, fmt.Errorf("failed to close upload session: %w", err)
+	}
+
+	if err := s.finalizeUpload(ctx, file, ""); err != nil {
+		return nil, err
+	}
+	s.schedulePreview(ctx, file.ID, file.MimeType)
+	s.scheduleTextExtraction(ctx, file.ID, file.MimeType)
+	s.recordActivity(ctx, file.UserID, file.ID, models.ActivityUpload)
+
+	return &CompleteMultipartUploadOutput{
+		StoragePath: file.StoragePath,
+		CreatedAt:   file.CreatedAt,
+	}, nil
+}
+
+func (s *fileService) AbortMultipartUpload(ctx context.Context, input *AbortMultipartUploadInput) (output *AbortMultipartUploadOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("multipart_abort", status)
+	}()
+
+	file, upload, err := s.getOwnedMultipartUpload(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.AbortMultipartUpload(ctx, file.Bucket, file.StoragePath, upload.UploadID); err != nil {
+		return nil, fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	if err := s.fileRepo.Delete(ctx, file.ID, file.UserID); err != nil {
+		return nil, fmt.Errorf("failed to delete metadata: %w", err)
+	}
+
+	if err := s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size); err != nil {
+		return nil, fmt.Errorf("failed to release quota: %w", err)
+	}
+
+	return &AbortMultipartUploadOutput{Success: true}, nil
+}
+
+func (s *fileService) GetUsage(ctx context.Context, input *GetUsageInput) (output *GetUsageOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("get_usage", status)
+	}()
+
+	if input.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	usage, err := s.quotaRepo.GetUsage(ctx, input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return &GetUsageOutput{Usage: usage}, nil
+}
+
+func (s *fileService) reserveQuota(ctx context.Context, file *models.File) error {
+	return quotaError(s.quotaRepo.Reserve(ctx, file.UserID, file.ID, file.Size))
+}
+
+func quotaError(err error) error {
+	if errors.Is(err, models.ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reserve quota: %w", err)
+	}
+	return nil
+}
+
+// finalizeUpload checks the stored object against the metadata row. A
+// mismatching object is removed; otherwise the row is reconciled with what
+// the storage actually holds, the quota is committed and the content is
+// deduplicated. An object that does not fit the quota is removed as well.
+func (s *fileService) finalizeUpload(ctx context.Context, file *models.File, etag string) error {
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return err
+	}
+	info, err := s.storage.StatObject(ctx, file.Bucket, file.StoragePath, storage.StatObjectOptions{Checksum: true, Encryption: sse})
+	if err != nil {
+		return fmt.Errorf("file not found in storage: %w", err)
+	}
+	if err := verifyStoredObject(file, info, etag); err != nil {
+		return s.rejectUpload(ctx, file, err)
+	}
+
+	if info.Size != file.Size || (info.ContentType != "" && info.ContentType != file.MimeType) {
+		if info.ContentType != "" {
+			file.MimeType = info.ContentType
+		}
+		file.Size = info.Size
+		if err := s.fileRepo.SetObjectInfo(ctx, file.ID, file.Size, file.MimeType); err != nil {
+			return fmt.Errorf("failed to reconcile metadata: %w", err)
+		}
+	}
+
+	// The ETag of an encrypted object is not the MD5 of its content.
+	if file.ChecksumAlgorithm == "" && file.KeyOwnerID == "" {
+		if checksum := md5FromETag(info.ETag); checksum != "" {
+			if err := s.fileRepo.SetChecksum(ctx, file.ID, ChecksumMD5, checksum); err != nil {
+				return fmt.Errorf("failed to save checksum: %w", err)
+			}
+			file.ChecksumAlgorithm = ChecksumMD5
+			file.Checksum = checksum
+		}
+	}
+
+	if file.UploadState != models.UploadStateActive {
+		if err := s.quotaRepo.Commit(ctx, file.ID, file.Size); err != nil {
+			if errors.Is(err, models.ErrQuotaExceeded) {
+				return quotaError(s.rejectUpload(ctx, file, err))
+			}
+			return fmt.Errorf("failed to commit quota: %w", err)
+		}
+		if file.BlobHash == "" {
+			s.dedupFile(ctx, file, "")
+		}
+		if err := s.fileRepo.SetUploadState(ctx, file.ID, models.UploadStateActive); err != nil {
+			return fmt.Errorf("failed to activate file: %w", err)
+		}
+		file.UploadState = models.UploadStateActive
+	}
+	return nil
+}
+
+// rejectUpload marks an upload failed and removes its object, unless the
+// object is a shared blob already.
+func (s *fileService) rejectUpload(ctx context.Context, file *models.File, err error) error {
+	if stErr := s.fileRepo.SetUploadState(ctx, file.ID, models.UploadStateFailed); stErr == nil {
+		file.UploadState = models.UploadStateFailed
+	}
+	if file.BlobHash != "" {
+		return fmt.Errorf("upload rejected: %w", err)
+	}
+	if rmErr := s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath); rmErr != nil {
+		return fmt.Errorf("upload rejected: %w (failed to remove object: %v)", err, rmErr)
+	}
+	return fmt.Errorf("upload rejected: %w", err)
+}
+
+func (s *fileService) getOwnedMultipartUpload(ctx context.Context, fileID, userID string) (*models.File, *models.MultipartUpload, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file not found: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, nil, fmt.Errorf("access denied")
+	}
+	upload, err := s.fileRepo.GetMultipartUpload(ctx, fileID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload session not found: %w", err)
+	}
+	return file, upload, nil
+}
+
+func (s *fileService) listUploadedParts(ctx context.Context, file *models.File, upload *models.MultipartUpload) ([]storage.ObjectPart, error) {
+	var parts []storage.ObjectPart
+	marker := 0
+	for {
+		result, err := s.storage.ListObjectParts(ctx, file.Bucket, file.StoragePath, upload.UploadID, marker, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list uploaded parts: %w", err)
+		}
+		parts = append(parts, result.ObjectParts...)
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// planParts picks a part size that respects the S3 limits of 5 MiB minimum
+// part size and 10000 parts per upload.
+func planParts(size, partSize int64) (int64, int, error) {
+	if size <= 0 {
+		return 0, 0, fmt.Errorf("size must be positive")
+	}
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+	if partSize < minPartSize {
+		return 0, 0, fmt.Errorf("part_size must be at least %d bytes", minPartSize)
+	}
+	if partSize > maxPartSize {
+		return 0, 0, fmt.Errorf("part_size must be at most %d bytes", maxPartSize)
+	}
+	if size > maxPartSize*maxPartCount {
+		return 0, 0, fmt.Errorf("size must be at most %d bytes", int64(maxPartSize*maxPartCount))
+	}
+	if size > partSize*maxPartCount {
+		partSize = (size + maxPartCount - 1) / maxPartCount
+	}
+	partCount := int((size + partSize - 1) / partSize)
+	return partSize, partCount, nil
+}
+
+// partLength returns the expected size of part n; only the last part may be
+// shorter than the negotiated part size.
+func partLength(size int64, upload *models.MultipartUpload, n int) int64 {
+	if n == upload.PartCount {
+		return size - int64(upload.PartCount-1)*upload.PartSize
+	}
+	return upload.PartSize
+}
+
+func mimeTypeOrDefault(mimeType string) string {
+	if mimeType == "" {
+		return defaultMimeType
+	}
+	return mimeType
+}
+
+func toHTTPHeader(headers map[string]string) http.Header {
+	h := make(http.Header, len(headers))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+func buildStoragePath(userID, uniqueFilename string) string {
+	return fmt.Sprintf("%s/%s/%s", userID, time.Now().Format("2006/01/02"), uniqueFilename)
+}
+
 func generateUniqueFilename(original string) string {
 	ext := ""
 	if idx := len(original) - 1; idx > 0 {
@@ -3,15 +3,20 @@ package file
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/Sene4ka/cloud_storage/configs"
 	"github.com/Sene4ka/cloud_storage/internal/models"
-	"github.com/minio/minio-go/v7"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type MockFileRepository struct {
@@ -41,6 +46,359 @@ func (m *MockFileRepository) CheckAccess(ctx context.Context, fileID, userID str
 	return args.Bool(0), args.String(1), args.String(2), args.Error(3)
 }
 
+func (m *MockFileRepository) CreateMultipartUpload(ctx context.Context, upload *models.MultipartUpload) error {
+	args := m.Called(ctx, upload)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) GetMultipartUpload(ctx context.Context, fileID string) (*models.MultipartUpload, error) {
+	args := m.Called(ctx, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MultipartUpload), args.Error(1)
+}
+
+func (m *MockFileRepository) DeleteMultipartUpload(ctx context.Context, fileID string) error {
+	args := m.Called(ctx, fileID)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) SetObjectInfo(ctx context.Context, fileID string, size int64, mimeType string) error {
+	args := m.Called(ctx, fileID, size, mimeType)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) SetChecksum(ctx context.Context, fileID, algorithm, checksum string) error {
+	args := m.Called(ctx, fileID, algorithm, checksum)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) SetUploadState(ctx context.Context, fileID, state string) error {
+	args := m.Called(ctx, fileID, state)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ListStaleUploads(ctx context.Context, before, multipartBefore time.Time, limit int) ([]*models.StaleUpload, error) {
+	args := m.Called(ctx, before, multipartBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.StaleUpload), args.Error(1)
+}
+
+func (m *MockFileRepository) ListExpiredTrash(ctx context.Context, defaultRetentionDays, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, defaultRetentionDays, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) ListTrashed(ctx context.Context, userID string, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) SetTrashRetention(ctx context.Context, userID string, days int) error {
+	args := m.Called(ctx, userID, days)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) SetPreviewState(ctx context.Context, fileID, state string) error {
+	args := m.Called(ctx, fileID, state)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ClaimPendingPreviews(ctx context.Context, staleBefore time.Time, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, staleBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) FinishPreview(ctx context.Context, fileID, state string) (bool, error) {
+	args := m.Called(ctx, fileID, state)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) SetContentState(ctx context.Context, fileID, state string) error {
+	args := m.Called(ctx, fileID, state)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ClaimPendingContents(ctx context.Context, staleBefore time.Time, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, staleBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) FinishContent(ctx context.Context, fileID, state, content string) (bool, error) {
+	args := m.Called(ctx, fileID, state, content)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) TouchAccessed(ctx context.Context, fileID string) error {
+	args := m.Called(ctx, fileID)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) RecordActivity(ctx context.Context, userID, fileID, action string) error {
+	args := m.Called(ctx, userID, fileID, action)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ListMigrationCandidates(ctx context.Context, bucket string, createdBefore, idleSince time.Time, limit int) ([]*models.File, error) {
+	args := m.Called(ctx, bucket, createdBefore, idleSince, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) RelocateObject(ctx context.Context, storagePath, fromBucket, toBucket string) (bool, error) {
+	args := m.Called(ctx, storagePath, fromBucket, toBucket)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) ObjectInUse(ctx context.Context, bucket, storagePath string) (bool, error) {
+	args := m.Called(ctx, bucket, storagePath)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockFileRepository) FindByName(ctx context.Context, userID, path, originalName string) (*models.File, error) {
+	args := m.Called(ctx, userID, path, originalName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) ListNamesWithPrefix(ctx context.Context, userID, path, prefix string) ([]string, error) {
+	args := m.Called(ctx, userID, path, prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockFileRepository) Move(ctx context.Context, fileID, userID, path, originalName string) error {
+	args := m.Called(ctx, fileID, userID, path, originalName)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) Replace(ctx context.Context, fileID, userID, path, originalName, displacedID string) error {
+	args := m.Called(ctx, fileID, userID, path, originalName, displacedID)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) SetTrashedBatch(ctx context.Context, fileIDs []string, userID string, isTrashed bool) ([]string, error) {
+	args := m.Called(ctx, fileIDs, userID, isTrashed)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type MockQuotaRepository struct {
+	mock.Mock
+}
+
+func (m *MockQuotaRepository) Reserve(ctx context.Context, userID, fileID string, bytes int64) error {
+	args := m.Called(ctx, userID, fileID, bytes)
+	return args.Error(0)
+}
+
+func (m *MockQuotaRepository) Commit(ctx context.Context, fileID string, bytes int64) error {
+	args := m.Called(ctx, fileID, bytes)
+	return args.Error(0)
+}
+
+func (m *MockQuotaRepository) Release(ctx context.Context, userID, fileID string, bytes int64) error {
+	args := m.Called(ctx, userID, fileID, bytes)
+	return args.Error(0)
+}
+
+func (m *MockQuotaRepository) ReserveBytes(ctx context.Context, userID, id string, bytes int64) error {
+	args := m.Called(ctx, userID, id, bytes)
+	return args.Error(0)
+}
+
+func (m *MockQuotaRepository) ReleaseBytes(ctx context.Context, userID, id string, bytes int64) error {
+	args := m.Called(ctx, userID, id, bytes)
+	return args.Error(0)
+}
+
+type MockVersionRepository struct {
+	mock.Mock
+}
+
+func (m *MockVersionRepository) Create(ctx context.Context, version *models.FileVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockVersionRepository) GetByID(ctx context.Context, id string) (*models.FileVersion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileVersion), args.Error(1)
+}
+
+func (m *MockVersionRepository) ListByFileID(ctx context.Context, fileID string) ([]*models.FileVersion, error) {
+	args := m.Called(ctx, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileVersion), args.Error(1)
+}
+
+func (m *MockVersionRepository) GetCurrentVersion(ctx context.Context, fileID string) (int, error) {
+	args := m.Called(ctx, fileID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockVersionRepository) Promote(ctx context.Context, version *models.FileVersion) (int, error) {
+	args := m.Called(ctx, version)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockVersionRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockVersionRepository) ListExpired(ctx context.Context, maxVersions int, maxAge time.Duration, limit int) ([]*models.FileVersion, error) {
+	args := m.Called(ctx, maxVersions, maxAge, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileVersion), args.Error(1)
+}
+
+func (m *MockVersionRepository) ListStale(ctx context.Context, before time.Time, limit int) ([]*models.FileVersion, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileVersion), args.Error(1)
+}
+
+type MockShareLinkRepository struct {
+	mock.Mock
+}
+
+func (m *MockShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	args := m.Called(ctx, link)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.ShareLink, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ShareLink), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) GetByID(ctx context.Context, id string) (*models.ShareLink, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ShareLink), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) ListByFileID(ctx context.Context, fileID string) ([]*models.ShareLink, error) {
+	args := m.Called(ctx, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ShareLink), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) Revoke(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) RecordDownload(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockBlobRepository struct {
+	mock.Mock
+}
+
+// AttachFile and AttachVersion return the blob passed in when the mock is
+// set up to return nil without an error, as for content stored for the
+// first time.
+func (m *MockBlobRepository) AttachFile(ctx context.Context, fileID string, blob *models.Blob) (*models.Blob, error) {
+	args := m.Called(ctx, fileID, blob)
+	if args.Get(0) == nil {
+		if args.Error(1) == nil {
+			return blob, nil
+		}
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blob), args.Error(1)
+}
+
+func (m *MockBlobRepository) AttachVersion(ctx context.Context, versionID string, blob *models.Blob) (*models.Blob, error) {
+	args := m.Called(ctx, versionID, blob)
+	if args.Get(0) == nil {
+		if args.Error(1) == nil {
+			return blob, nil
+		}
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blob), args.Error(1)
+}
+
+func (m *MockBlobRepository) AttachExisting(ctx context.Context, fileID, hash string) (*models.Blob, error) {
+	args := m.Called(ctx, fileID, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blob), args.Error(1)
+}
+
+func (m *MockBlobRepository) Release(ctx context.Context, hash string) (*models.Blob, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blob), args.Error(1)
+}
+
+type MockKeyProvider struct {
+	mock.Mock
+}
+
+func (m *MockKeyProvider) DataKey(ctx context.Context, userID string) ([]byte, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockQuotaRepository) GetUsage(ctx context.Context, userID string) (*models.StorageUsage, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StorageUsage), args.Error(1)
+}
+
 type MockBlobStorage struct {
 	mock.Mock
 }
@@ -50,443 +408,2071 @@ func (m *MockBlobStorage) BucketExists(ctx context.Context, bucketName string) (
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockBlobStorage) MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error {
+func (m *MockBlobStorage) MakeBucket(ctx context.Context, bucketName string, opts storage.MakeBucketOptions) error {
 	args := m.Called(ctx, bucketName, opts)
 	return args.Error(0)
 }
 
-func (m *MockBlobStorage) StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+func (m *MockBlobStorage) StatObject(ctx context.Context, bucketName, objectName string, opts storage.StatObjectOptions) (storage.ObjectInfo, error) {
+	args := m.Called(ctx, bucketName, objectName, opts)
+	return args.Get(0).(storage.ObjectInfo), args.Error(1)
+}
+
+func (m *MockBlobStorage) GetObject(ctx context.Context, bucketName, objectName string, opts storage.GetObjectOptions) (io.ReadCloser, error) {
 	args := m.Called(ctx, bucketName, objectName, opts)
-	return args.Get(0).(minio.ObjectInfo), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
 }
 
-func (m *MockBlobStorage) RemoveObject(ctx context.Context, bucketName, objectName string, opts minio.RemoveObjectOptions) error {
+func (m *MockBlobStorage) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts storage.PutObjectOptions) (storage.UploadInfo, error) {
+	args := m.Called(ctx, bucketName, objectName, reader, objectSize, opts)
+	return args.Get(0).(storage.UploadInfo), args.Error(1)
+}
+
+func (m *MockBlobStorage) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	args := m.Called(ctx, bucketName, objectName)
+	return args.Error(0)
+}
+
+func (m *MockBlobStorage) NewMultipartUpload(ctx context.Context, bucketName, objectName string, opts storage.PutObjectOptions) (string, error) {
 	args := m.Called(ctx, bucketName, objectName, opts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockBlobStorage) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string, partNumberMarker, maxParts int) (storage.ListObjectPartsResult, error) {
+	args := m.Called(ctx, bucketName, objectName, uploadID, partNumberMarker, maxParts)
+	return args.Get(0).(storage.ListObjectPartsResult), args.Error(1)
+}
+
+func (m *MockBlobStorage) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []storage.CompletePart, opts storage.PutObjectOptions) (storage.UploadInfo, error) {
+	args := m.Called(ctx, bucketName, objectName, uploadID, parts, opts)
+	return args.Get(0).(storage.UploadInfo), args.Error(1)
+}
+
+func (m *MockBlobStorage) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	args := m.Called(ctx, bucketName, objectName, uploadID)
 	return args.Error(0)
 }
 
+func (m *MockBlobStorage) CopyObject(ctx context.Context, dst storage.CopyDestOptions, src storage.CopySrcOptions) (storage.UploadInfo, error) {
+	args := m.Called(ctx, dst, src)
+	return args.Get(0).(storage.UploadInfo), args.Error(1)
+}
+
 type MockPresignedURLGenerator struct {
 	mock.Mock
 }
 
-func (m *MockPresignedURLGenerator) PresignedPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
-	args := m.Called(ctx, bucketName, objectName, expires)
+func (m *MockPresignedURLGenerator) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
+	args := m.Called(ctx, bucketName, objectName, expires, reqParams)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*url.URL), args.Error(1)
 }
 
-func (m *MockPresignedURLGenerator) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
-	args := m.Called(ctx, bucketName, objectName, expires, reqParams)
+func (m *MockPresignedURLGenerator) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	args := m.Called(ctx, method, bucketName, objectName, expires, reqParams, extraHeaders)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*url.URL), args.Error(1)
+	return args.Get(0).(*url.URL), args.Error(1)
+}
+
+func TestFileService_InitiateUpload_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.UserID == "user-123" && f.Filename != ""
+	})).Return(nil)
+
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/file-123")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, 15*time.Minute, mock.Anything, mock.MatchedBy(func(h http.Header) bool {
+		return h.Get("Content-Length") == "1024" && h.Get("Content-Type") == "text/plain"
+	})).Return(presignedURL, nil)
+
+	input := &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "test.txt",
+		Path:     "/files",
+		MimeType: "text/plain",
+		Size:     1024,
+		IsPublic: false,
+		Tags:     map[string]string{"type": "document"},
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.NotEmpty(t, output.FileID)
+	assert.Contains(t, output.UploadURL, "storage.example.com")
+	assert.Equal(t, "PUT", output.UploadMethod)
+	assert.Equal(t, "1024", output.Headers["Content-Length"])
+	assert.Equal(t, "text/plain", output.Headers["Content-Type"])
+	mockRepo.AssertExpectations(t)
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_InitiateUpload_UnknownSize(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:  mockRepo,
+		Quotas: mockQuota,
+	}, new(MockBlobStorage), mockPresigned, config)
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(0)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/file-123")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, 15*time.Minute, mock.Anything, mock.MatchedBy(func(h http.Header) bool {
+		_, signed := h["Content-Length"]
+		return !signed
+	})).Return(presignedURL, nil)
+
+	output, err := svc.InitiateUpload(context.Background(), &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "test.txt",
+		Path:     "/files",
+		MimeType: "text/plain",
+	})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, output.Headers, "Content-Length")
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_InitiateUpload_DefaultsMimeType(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.MimeType == "application/octet-stream"
+	})).Return(nil)
+
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/file-123")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, presignedUploadTTL, mock.Anything, mock.Anything).Return(presignedURL, nil)
+
+	input := &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "blob.bin",
+		Path:     "/files",
+		Size:     10,
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/octet-stream", output.Headers["Content-Type"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_InitiateUpload_InvalidPath(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	input := &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "test.txt",
+		Path:     "../../../etc/passwd",
+		MimeType: "text/plain",
+		Size:     1024,
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid path")
+	assert.Nil(t, output)
+}
+
+func TestFileService_InitiateUpload_RepoError(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("db error"))
+
+	input := &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "test.txt",
+		Path:     "/files",
+		MimeType: "text/plain",
+		Size:     1024,
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create metadata")
+	assert.Nil(t, output)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_InitiateUpload_QuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(1<<40)).Return(models.ErrQuotaExceeded)
+
+	input := &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "big.bin",
+		Path:     "/files",
+		Size:     1 << 40,
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Nil(t, output)
+	mockQuota.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestFileService_CompleteUpload_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		CreatedAt:   time.Now(),
+	}
+
+	mockQuota.On("Commit", mock.Anything, "file-123", mock.Anything).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateActive).Return(nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{}, nil)
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.Equal(t, "objects/file-123", output.StoragePath)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "GetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockBlobs.AssertNotCalled(t, "AttachFile", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_InitiateUpload_WithChecksum(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	checksum := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.ChecksumAlgorithm == ChecksumSHA256 && f.Checksum == checksum
+	})).Return(nil)
+
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/file-123")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, presignedUploadTTL, mock.Anything, mock.MatchedBy(func(h http.Header) bool {
+		return h.Get("x-amz-checksum-sha256") == checksum
+	})).Return(presignedURL, nil)
+
+	input := &InitiateUploadInput{
+		UserID:            "user-123",
+		Filename:          "empty.txt",
+		Path:              "/files",
+		MimeType:          "text/plain",
+		ChecksumAlgorithm: "sha256",
+		Checksum:          checksum,
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.Equal(t, checksum, output.Headers["x-amz-checksum-sha256"])
+	mockRepo.AssertExpectations(t)
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_InitiateUpload_InvalidChecksum(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	input := &InitiateUploadInput{
+		UserID:            "user-123",
+		Filename:          "test.txt",
+		Path:              "/files",
+		ChecksumAlgorithm: "SHA256",
+		Checksum:          "not-a-checksum",
+	}
+
+	output, err := svc.InitiateUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid checksum")
+	assert.Nil(t, output)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestFileService_CompleteUpload_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:                "file-123",
+		UserID:            "user-123",
+		StoragePath:       "objects/file-123",
+		Bucket:            "cloud-storage",
+		Size:              1024,
+		ChecksumAlgorithm: ChecksumSHA256,
+		Checksum:          "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateFailed).Return(nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		Size:           1024,
+		ChecksumSHA256: "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=",
+	}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SHA256 checksum mismatch")
+	assert.Nil(t, output)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_ExceedsQuota(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		UploadState: models.UploadStatePending,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{Size: 4096}, nil)
+	mockRepo.On("SetObjectInfo", mock.Anything, "file-123", int64(4096), mock.Anything).Return(nil)
+	mockQuota.On("Commit", mock.Anything, "file-123", int64(4096)).Return(models.ErrQuotaExceeded)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateFailed).Return(nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetUploadState", mock.Anything, "file-123", models.UploadStateActive)
+}
+
+func TestFileService_CompleteUpload_RecordsMD5FromETag(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		Size:        5,
+	}
+
+	mockQuota.On("Commit", mock.Anything, "file-123", mock.Anything).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateActive).Return(nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		Size: 5,
+		ETag: "5d41402abc4b2a76b9719d911017c592",
+	}, nil)
+	mockRepo.On("SetChecksum", mock.Anything, "file-123", ChecksumMD5, "XUFAKrxLKna5cZ2REBfFkg==").Return(nil)
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+		ETag:   `"5d41402abc4b2a76b9719d911017c592"`,
+	}
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.Equal(t, ChecksumMD5, output.ChecksumAlgorithm)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_ContentTypeMismatch(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		Size:        1024,
+		MimeType:    "image/png",
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateFailed).Return(nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		Size:        1024,
+		ContentType: "application/x-msdownload",
+	}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "content type mismatch")
+	assert.Nil(t, output)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_ReconcilesContentType(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:                "file-123",
+		UserID:            "user-123",
+		StoragePath:       "objects/file-123",
+		Bucket:            "cloud-storage",
+		Size:              1024,
+		MimeType:          "text/plain",
+		ChecksumAlgorithm: ChecksumCRC32C,
+		Checksum:          "yZRlqg==",
+	}
+
+	mockQuota.On("Commit", mock.Anything, "file-123", mock.Anything).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateActive).Return(nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		Size:           1024,
+		ContentType:    "text/plain; charset=utf-8",
+		ChecksumCRC32C: "yZRlqg==",
+	}, nil)
+	mockRepo.On("SetObjectInfo", mock.Anything, "file-123", int64(1024), "text/plain; charset=utf-8").Return(nil)
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	otherUserFile := &models.File{
+		ID:     "file-123",
+		UserID: "other-user",
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(otherUserFile, nil)
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Nil(t, output)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_FileNotFound(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(nil, errors.New("not found"))
+
+	input := &CompleteUploadInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.CompleteUpload(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+	assert.Nil(t, output)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_GetDownloadLink_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+
+	presignedURL, _ := url.Parse("https://storage.example.com/download/file-123")
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "objects/file-123", time.Hour, mock.Anything).Return(presignedURL, nil)
+
+	input := &GetDownloadLinkInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityDownload).Return(nil)
+
+	output, err := svc.GetDownloadLink(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.Contains(t, output.DownloadURL, "storage.example.com")
+	assert.Equal(t, "GET", output.Method)
+	mockRepo.AssertExpectations(t)
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_GetDownloadLink_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(false, "", "", nil)
+
+	input := &GetDownloadLinkInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.GetDownloadLink(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Nil(t, output)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_DeleteFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", mock.Anything).Return(nil)
+
+	input := &DeleteFileInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.DeleteFile(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.True(t, output.Success)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_DeleteFile_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	otherUserFile := &models.File{
+		ID:     "file-123",
+		UserID: "other-user",
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(otherUserFile, nil)
+
+	input := &DeleteFileInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.DeleteFile(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Nil(t, output)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_PurgeFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	trashedFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		Size:        1024,
+		IsTrashed:   true,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(trashedFile, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", int64(1024)).Return(nil)
+
+	output, err := svc.PurgeFile(context.Background(), &PurgeFileInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_PurgeFile_NotTrashed(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	existingFile := &models.File{
+		ID:     "file-123",
+		UserID: "user-123",
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+
+	output, err := svc.PurgeFile(context.Background(), &PurgeFileInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not in trash")
+	assert.Nil(t, output)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_EmptyTrash_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	trashed := []*models.File{
+		{ID: "file-1", UserID: "user-123", StoragePath: "objects/file-1", Bucket: "cloud-storage", IsTrashed: true},
+		{ID: "file-2", UserID: "user-123", StoragePath: "objects/file-2", Bucket: "cloud-storage", IsTrashed: true},
+	}
+
+	mockRepo.On("ListTrashed", mock.Anything, "user-123", purgeBatchSize).Return(trashed, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", mock.Anything).Return(nil)
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, mock.Anything, "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+
+	output, err := svc.EmptyTrash(context.Background(), &EmptyTrashInput{UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, output.Purged)
+	mockRepo.AssertNumberOfCalls(t, "Delete", 2)
+	mockStorage.AssertNumberOfCalls(t, "RemoveObject", 2)
+}
+
+func TestFileService_GetFileInfo_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:       "file-123",
+		UserID:   "user-123",
+		Filename: "test.txt",
+	}
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "", "", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	input := &GetFileInfoInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.GetFileInfo(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.Equal(t, "test.txt", output.File.Filename)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_GetFileInfo_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(false, "", "", nil)
+
+	input := &GetFileInfoInput{
+		FileID: "file-123",
+		UserID: "user-123",
+	}
+
+	output, err := svc.GetFileInfo(context.Background(), input)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Nil(t, output)
+	mockRepo.AssertExpectations(t)
 }
 
-func TestFileService_InitiateUpload_Success(t *testing.T) {
+func TestFileService_InitiateMultipartUpload_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
-
-	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
-		return f.UserID == "user-123" && f.Filename != ""
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("NewMultipartUpload", mock.Anything, "cloud-storage", mock.Anything, mock.Anything).Return("upload-123", nil)
+	mockRepo.On("CreateMultipartUpload", mock.Anything, mock.MatchedBy(func(u *models.MultipartUpload) bool {
+		return u.UploadID == "upload-123" && u.PartCount == 3 && u.PartSize == defaultPartSize
 	})).Return(nil)
 
-	presignedURL, _ := url.Parse("https://storage.example.com/upload/file-123")
-	mockPresigned.On("PresignedPutObject", mock.Anything, "cloud-storage", mock.Anything, 15*time.Minute).Return(presignedURL, nil)
-
-	input := &InitiateUploadInput{
+	input := &InitiateMultipartUploadInput{
 		UserID:   "user-123",
-		Filename: "test.txt",
-		Path:     "/files",
-		MimeType: "text/plain",
-		Size:     1024,
-		IsPublic: false,
-		Tags:     map[string]string{"type": "document"},
+		Filename: "video.mp4",
+		Path:     "/videos",
+		MimeType: "video/mp4",
+		Size:     2*defaultPartSize + 1,
 	}
 
-	output, err := svc.InitiateUpload(context.Background(), input)
+	output, err := svc.InitiateMultipartUpload(context.Background(), input)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, output)
 	assert.NotEmpty(t, output.FileID)
-	assert.Contains(t, output.UploadURL, "storage.example.com")
-	assert.Equal(t, "PUT", output.UploadMethod)
+	assert.Equal(t, "upload-123", output.UploadID)
+	assert.Equal(t, 3, output.PartCount)
 	mockRepo.AssertExpectations(t)
-	mockPresigned.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
 }
 
-func TestFileService_InitiateUpload_InvalidPath(t *testing.T) {
+func TestFileService_InitiateMultipartUpload_PartSizeTooSmall(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
 
-	input := &InitiateUploadInput{
+	input := &InitiateMultipartUploadInput{
 		UserID:   "user-123",
-		Filename: "test.txt",
-		Path:     "../../../etc/passwd",
-		MimeType: "text/plain",
-		Size:     1024,
+		Filename: "video.mp4",
+		Path:     "/videos",
+		Size:     100 << 20,
+		PartSize: 1024,
 	}
 
-	output, err := svc.InitiateUpload(context.Background(), input)
+	output, err := svc.InitiateMultipartUpload(context.Background(), input)
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "invalid path")
+	assert.Contains(t, err.Error(), "part_size must be at least")
 	assert.Nil(t, output)
 }
 
-func TestFileService_InitiateUpload_RepoError(t *testing.T) {
+func TestPlanParts_Limits(t *testing.T) {
 	t.Parallel()
 
-	mockRepo := new(MockFileRepository)
-	mockStorage := new(MockBlobStorage)
-	mockPresigned := new(MockPresignedURLGenerator)
-	config := &configs.Config{
-		MinIO: configs.MinIOConfig{
-			BucketName: "cloud-storage",
-		},
-	}
-
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
-
-	mockRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("db error"))
-
-	input := &InitiateUploadInput{
-		UserID:   "user-123",
-		Filename: "test.txt",
-		Path:     "/files",
-		MimeType: "text/plain",
-		Size:     1024,
-	}
+	_, _, err := planParts(100<<30, 6<<30)
+	assert.ErrorContains(t, err, "part_size must be at most")
 
-	output, err := svc.InitiateUpload(context.Background(), input)
+	_, _, err = planParts(maxPartSize*maxPartCount+1, 0)
+	assert.ErrorContains(t, err, "size must be at most")
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to create metadata")
-	assert.Nil(t, output)
-	mockRepo.AssertExpectations(t)
+	partSize, partCount, err := planParts(maxPartSize*maxPartCount, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(maxPartSize), partSize)
+	assert.Equal(t, maxPartCount, partCount)
 }
 
-func TestFileService_CompleteUpload_Success(t *testing.T) {
+func TestFileService_GetPartUploadURLs_SkipsUploadedParts(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
 
 	existingFile := &models.File{
 		ID:          "file-123",
 		UserID:      "user-123",
 		StoragePath: "objects/file-123",
 		Bucket:      "cloud-storage",
-		CreatedAt:   time.Now(),
+		Size:        2*minPartSize + 1,
 	}
+	upload := &models.MultipartUpload{FileID: "file-123", UploadID: "upload-123", PartSize: minPartSize, PartCount: 3}
 
 	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
-	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(minio.ObjectInfo{}, nil)
+	mockRepo.On("GetMultipartUpload", mock.Anything, "file-123").Return(upload, nil)
+	mockStorage.On("ListObjectParts", mock.Anything, "cloud-storage", "objects/file-123", "upload-123", 0, 1000).Return(storage.ListObjectPartsResult{
+		ObjectParts: []storage.ObjectPart{{PartNumber: 2, ETag: "etag-2", Size: minPartSize}},
+	}, nil)
 
-	input := &CompleteUploadInput{
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/part")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", "objects/file-123", presignedUploadTTL, mock.Anything, mock.Anything).Return(presignedURL, nil)
+
+	input := &GetPartUploadURLsInput{
 		FileID: "file-123",
 		UserID: "user-123",
 	}
 
-	output, err := svc.CompleteUpload(context.Background(), input)
+	output, err := svc.GetPartUploadURLs(context.Background(), input)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, output)
-	assert.Equal(t, "objects/file-123", output.StoragePath)
+	assert.Len(t, output.Parts, 2)
+	assert.Equal(t, 1, output.Parts[0].PartNumber)
+	assert.Equal(t, 3, output.Parts[1].PartNumber)
+	assert.Equal(t, strconv.Itoa(minPartSize), output.Parts[0].Headers["Content-Length"])
+	assert.Equal(t, "1", output.Parts[1].Headers["Content-Length"])
+	assert.Len(t, output.UploadedParts, 1)
+	assert.Equal(t, "etag-2", output.UploadedParts[0].ETag)
 	mockRepo.AssertExpectations(t)
 	mockStorage.AssertExpectations(t)
+	mockPresigned.AssertExpectations(t)
 }
 
-func TestFileService_CompleteUpload_AccessDenied(t *testing.T) {
+func TestFileService_CompleteMultipartUpload_MissingPart(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
 
-	otherUserFile := &models.File{
-		ID:     "file-123",
-		UserID: "other-user",
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
 	}
+	upload := &models.MultipartUpload{FileID: "file-123", UploadID: "upload-123", PartSize: minPartSize, PartCount: 2}
 
-	mockRepo.On("GetByID", mock.Anything, "file-123").Return(otherUserFile, nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("GetMultipartUpload", mock.Anything, "file-123").Return(upload, nil)
 
-	input := &CompleteUploadInput{
+	input := &CompleteMultipartUploadInput{
 		FileID: "file-123",
 		UserID: "user-123",
+		Parts:  []CompletedPart{{PartNumber: 1, ETag: "etag-1"}, {PartNumber: 3, ETag: "etag-3"}},
 	}
 
-	output, err := svc.CompleteUpload(context.Background(), input)
+	output, err := svc.CompleteMultipartUpload(context.Background(), input)
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "access denied")
+	assert.Contains(t, err.Error(), "part 2 is missing")
 	assert.Nil(t, output)
-	mockRepo.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "CompleteMultipartUpload", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestFileService_CompleteUpload_FileNotFound(t *testing.T) {
+func TestFileService_CompleteMultipartUpload_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
 
-	mockRepo.On("GetByID", mock.Anything, "file-123").Return(nil, errors.New("not found"))
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		CreatedAt:   time.Now(),
+	}
+	upload := &models.MultipartUpload{FileID: "file-123", UploadID: "upload-123", PartSize: minPartSize, PartCount: 2}
 
-	input := &CompleteUploadInput{
+	mockQuota.On("Commit", mock.Anything, "file-123", mock.Anything).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("SetUploadState", mock.Anything, "file-123", models.UploadStateActive).Return(nil)
+	mockRepo.On("GetMultipartUpload", mock.Anything, "file-123").Return(upload, nil)
+	mockStorage.On("CompleteMultipartUpload", mock.Anything, "cloud-storage", "objects/file-123", "upload-123", []storage.CompletePart{
+		{PartNumber: 1, ETag: "etag-1"},
+		{PartNumber: 2, ETag: "etag-2"},
+	}, mock.Anything).Return(storage.UploadInfo{}, nil)
+	mockRepo.On("DeleteMultipartUpload", mock.Anything, "file-123").Return(nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(storage.ObjectInfo{
+		ETag: "d41d8cd98f00b204e9800998ecf8427e-2",
+	}, nil)
+
+	input := &CompleteMultipartUploadInput{
 		FileID: "file-123",
 		UserID: "user-123",
+		Parts:  []CompletedPart{{PartNumber: 2, ETag: "etag-2"}, {PartNumber: 1, ETag: "etag-1"}},
 	}
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
 
-	output, err := svc.CompleteUpload(context.Background(), input)
+	output, err := svc.CompleteMultipartUpload(context.Background(), input)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "file not found")
-	assert.Nil(t, output)
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.Equal(t, "objects/file-123", output.StoragePath)
 	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
 }
 
-func TestFileService_GetDownloadLink_Success(t *testing.T) {
+func TestFileService_AbortMultipartUpload_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
 
-	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "objects/file-123", "cloud-storage", nil)
+	existingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+	}
+	upload := &models.MultipartUpload{FileID: "file-123", UploadID: "upload-123", PartSize: minPartSize, PartCount: 2}
 
-	presignedURL, _ := url.Parse("https://storage.example.com/download/file-123")
-	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "objects/file-123", time.Hour, mock.Anything).Return(presignedURL, nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
+	mockRepo.On("GetMultipartUpload", mock.Anything, "file-123").Return(upload, nil)
+	mockStorage.On("AbortMultipartUpload", mock.Anything, "cloud-storage", "objects/file-123", "upload-123").Return(nil)
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", mock.Anything).Return(nil)
 
-	input := &GetDownloadLinkInput{
+	input := &AbortMultipartUploadInput{
 		FileID: "file-123",
 		UserID: "user-123",
 	}
 
-	output, err := svc.GetDownloadLink(context.Background(), input)
+	output, err := svc.AbortMultipartUpload(context.Background(), input)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, output)
-	assert.Contains(t, output.DownloadURL, "storage.example.com")
-	assert.Equal(t, "GET", output.Method)
+	assert.True(t, output.Success)
 	mockRepo.AssertExpectations(t)
-	mockPresigned.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
 }
 
-func TestFileService_GetDownloadLink_AccessDenied(t *testing.T) {
+func TestFileService_GetUsage_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
 
-	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(false, "", "", nil)
+	mockQuota.On("GetUsage", mock.Anything, "user-123").Return(&models.StorageUsage{
+		UserID:        "user-123",
+		Plan:          "default",
+		UsedBytes:     2048,
+		ReservedBytes: 1024,
+		LimitBytes:    10 << 30,
+	}, nil)
 
-	input := &GetDownloadLinkInput{
+	output, err := svc.GetUsage(context.Background(), &GetUsageInput{UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2048), output.Usage.UsedBytes)
+	assert.Equal(t, int64(1024), output.Usage.ReservedBytes)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_MoveFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/docs", "report.pdf").Return(nil, nil)
+	mockRepo.On("Move", mock.Anything, "file-123", "user-123", "/docs", "report.pdf").Return(nil)
+
+	output, err := svc.MoveFile(context.Background(), &MoveFileInput{
 		FileID: "file-123",
 		UserID: "user-123",
-	}
+		Path:   "/docs",
+	})
 
-	output, err := svc.GetDownloadLink(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equal(t, "/docs", output.File.Path)
+	assert.Equal(t, "report.pdf", output.File.OriginalName)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_MoveFile_ConflictFails(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", OriginalName: "report.pdf", Path: "/", UploadState: models.UploadStateActive}
+	existing := &models.File{ID: "file-456", UserID: "user-123", OriginalName: "report.pdf", Path: "/docs"}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/docs", "report.pdf").Return(existing, nil)
+
+	output, err := svc.MoveFile(context.Background(), &MoveFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/docs",
+		ConflictPolicy: "fail",
+	})
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "access denied")
 	assert.Nil(t, output)
-	mockRepo.AssertExpectations(t)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	mockRepo.AssertNotCalled(t, "Move", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestFileService_DeleteFile_Success(t *testing.T) {
+func TestFileService_MoveFile_AutoRename(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
-	config := &configs.Config{
-		MinIO: configs.MinIOConfig{
-			BucketName: "cloud-storage",
-		},
-	}
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", OriginalName: "report.pdf", Path: "/", UploadState: models.UploadStateActive}
+	existing := &models.File{ID: "file-456", UserID: "user-123", OriginalName: "report.pdf", Path: "/docs"}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/docs", "report.pdf").Return(existing, nil)
+	mockRepo.On("ListNamesWithPrefix", mock.Anything, "user-123", "/docs", "report (").Return([]string{"report (1).pdf"}, nil)
+	mockRepo.On("Move", mock.Anything, "file-123", "user-123", "/docs", "report (2).pdf").Return(nil)
 
-	existingFile := &models.File{
-		ID:          "file-123",
-		UserID:      "user-123",
-		StoragePath: "objects/file-123",
-		Bucket:      "cloud-storage",
-	}
+	output, err := svc.MoveFile(context.Background(), &MoveFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/docs",
+		ConflictPolicy: "rename",
+	})
 
-	mockRepo.On("GetByID", mock.Anything, "file-123").Return(existingFile, nil)
-	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(nil)
-	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "report (2).pdf", output.File.OriginalName)
+	mockRepo.AssertExpectations(t)
+}
 
-	input := &DeleteFileInput{
-		FileID: "file-123",
-		UserID: "user-123",
+func TestFileService_MoveFile_Overwrite(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", OriginalName: "report.pdf", Path: "/", UploadState: models.UploadStateActive}
+	existing := &models.File{
+		ID:           "file-456",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/docs",
+		StoragePath:  "objects/file-456",
+		Bucket:       "cloud-storage",
+		Size:         2048,
 	}
 
-	output, err := svc.DeleteFile(context.Background(), input)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/docs", "report.pdf").Return(existing, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-456").Return(nil)
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, "file-456", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-456", int64(2048)).Return(nil)
+	mockRepo.On("Replace", mock.Anything, "file-123", "user-123", "/docs", "report.pdf", "file-456").Return(nil)
+
+	output, err := svc.MoveFile(context.Background(), &MoveFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/docs",
+		ConflictPolicy: "overwrite",
+	})
 
 	assert.NoError(t, err)
-	assert.NotNil(t, output)
-	assert.True(t, output.Success)
+	assert.Equal(t, "/docs", output.File.Path)
 	mockRepo.AssertExpectations(t)
 	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
 }
 
-func TestFileService_DeleteFile_AccessDenied(t *testing.T) {
+func TestFileService_MoveFile_InvalidPolicy(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
-	config := &configs.Config{
-		MinIO: configs.MinIOConfig{
-			BucketName: "cloud-storage",
-		},
-	}
-
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	output, err := svc.MoveFile(context.Background(), &MoveFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/docs",
+		ConflictPolicy: "merge",
+	})
 
-	otherUserFile := &models.File{
-		ID:     "file-123",
-		UserID: "other-user",
-	}
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "unsupported conflict policy")
+}
 
-	mockRepo.On("GetByID", mock.Anything, "file-123").Return(otherUserFile, nil)
+func TestFileService_MoveFile_OverwriteFailureKeepsExisting(t *testing.T) {
+	t.Parallel()
 
-	input := &DeleteFileInput{
-		FileID: "file-123",
-		UserID: "user-123",
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", OriginalName: "report.pdf", Path: "/", UploadState: models.UploadStateActive}
+	existing := &models.File{
+		ID:           "file-456",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/docs",
+		StoragePath:  "objects/file-456",
+		Bucket:       "cloud-storage",
+		Size:         2048,
 	}
 
-	output, err := svc.DeleteFile(context.Background(), input)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/docs", "report.pdf").Return(existing, nil)
+	mockRepo.On("Replace", mock.Anything, "file-123", "user-123", "/docs", "report.pdf", "file-456").Return(errors.New("database unavailable"))
+
+	output, err := svc.MoveFile(context.Background(), &MoveFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/docs",
+		ConflictPolicy: "overwrite",
+	})
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "access denied")
 	assert.Nil(t, output)
 	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, "file-456", mock.Anything)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, "objects/file-456")
+	mockQuota.AssertNotCalled(t, "Release", mock.Anything, mock.Anything, "file-456", mock.Anything)
 }
 
-func TestFileService_GetFileInfo_Success(t *testing.T) {
+func TestFileService_CopyFile_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
-
-	file := &models.File{
-		ID:       "file-123",
-		UserID:   "user-123",
-		Filename: "test.txt",
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	source := &models.File{
+		ID:                "file-123",
+		UserID:            "user-123",
+		OriginalName:      "report.pdf",
+		Path:              "/",
+		Size:              1024,
+		MimeType:          "application/pdf",
+		StoragePath:       "objects/file-123",
+		Bucket:            "cloud-storage",
+		ChecksumAlgorithm: "sha256",
+		Checksum:          "abc",
+		UploadState:       models.UploadStateActive,
 	}
 
-	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "", "", nil)
-	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
-
-	input := &GetFileInfoInput{
-		FileID: "file-123",
-		UserID: "user-123",
-	}
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(source, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/", "report.pdf").Return(source, nil)
+	mockRepo.On("ListNamesWithPrefix", mock.Anything, "user-123", "/", "report (").Return([]string{}, nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.OriginalName == "report (1).pdf" && f.Checksum == "abc" && f.UploadState == models.UploadStatePending
+	})).Return(nil)
+	mockStorage.On("CopyObject", mock.Anything, mock.Anything, storage.CopySrcOptions{Bucket: "cloud-storage", Object: "objects/file-123"}).Return(storage.UploadInfo{}, nil)
+	mockRepo.On("SetUploadState", mock.Anything, mock.Anything, models.UploadStateActive).Return(nil)
+	mockQuota.On("Commit", mock.Anything, mock.Anything, int64(1024)).Return(nil)
 
-	output, err := svc.GetFileInfo(context.Background(), input)
+	output, err := svc.CopyFile(context.Background(), &CopyFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/",
+		ConflictPolicy: "rename",
+	})
 
 	assert.NoError(t, err)
-	assert.NotNil(t, output)
-	assert.Equal(t, "test.txt", output.File.Filename)
+	assert.NotEqual(t, "file-123", output.File.ID)
+	assert.Equal(t, "report (1).pdf", output.File.OriginalName)
+	assert.Equal(t, models.UploadStateActive, output.File.UploadState)
 	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
 }
 
-func TestFileService_GetFileInfo_AccessDenied(t *testing.T) {
+func TestFileService_CopyFile_StorageErrorRollsBack(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
 	mockStorage := new(MockBlobStorage)
 	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
 	config := &configs.Config{
 		MinIO: configs.MinIOConfig{
 			BucketName: "cloud-storage",
 		},
 	}
 
-	svc := NewFileService(mockRepo, mockStorage, mockPresigned, config)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	source := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		Size:         1024,
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
 
-	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(false, "", "", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(source, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/backup", "report.pdf").Return(nil, nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("CopyObject", mock.Anything, mock.Anything, mock.Anything).Return(storage.UploadInfo{}, errors.New("storage unavailable"))
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, mock.Anything, "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
 
-	input := &GetFileInfoInput{
+	output, err := svc.CopyFile(context.Background(), &CopyFileInput{
 		FileID: "file-123",
 		UserID: "user-123",
+		Path:   "/backup",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "failed to copy object")
+	mockRepo.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+	mockQuota.AssertNotCalled(t, "Commit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_CopyFile_OverwriteFailureKeepsExisting(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
 	}
 
-	output, err := svc.GetFileInfo(context.Background(), input)
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	source := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		Size:         1024,
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+	existing := &models.File{
+		ID:           "file-456",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/backup",
+		StoragePath:  "objects/file-456",
+		Bucket:       "cloud-storage",
+		Size:         2048,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(source, nil)
+	mockRepo.On("FindByName", mock.Anything, "user-123", "/backup", "report.pdf").Return(existing, nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.OriginalName != "report.pdf"
+	})).Return(nil)
+	mockStorage.On("CopyObject", mock.Anything, mock.Anything, mock.Anything).Return(storage.UploadInfo{}, errors.New("storage unavailable"))
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, mock.Anything, "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", mock.Anything, int64(1024)).Return(nil)
+
+	output, err := svc.CopyFile(context.Background(), &CopyFileInput{
+		FileID:         "file-123",
+		UserID:         "user-123",
+		Path:           "/backup",
+		ConflictPolicy: "overwrite",
+	})
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "access denied")
 	assert.Nil(t, output)
 	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Replace", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, "file-456", mock.Anything)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, "objects/file-456")
 }
@@ -1,23 +1,30 @@
 package file
 
 import (
+	"io"
 	"time"
 
 	"github.com/Sene4ka/cloud_storage/internal/models"
 )
 
+// InitiateUploadInput with a FileID uploads a new version of that file; the
+// name, path and tags are then taken from the existing file.
 type InitiateUploadInput struct {
-	UserID   string
-	Filename string
-	Path     string
-	MimeType string
-	Size     int64
-	IsPublic bool
-	Tags     map[string]string
+	UserID            string
+	FileID            string
+	Filename          string
+	Path              string
+	MimeType          string
+	Size              int64
+	IsPublic          bool
+	Tags              map[string]string
+	ChecksumAlgorithm string
+	Checksum          string
 }
 
 type InitiateUploadOutput struct {
 	FileID       string
+	VersionID    string
 	UploadURL    string
 	UploadMethod string
 	Headers      map[string]string
@@ -25,20 +32,28 @@ type InitiateUploadOutput struct {
 }
 
 type CompleteUploadInput struct {
-	FileID string
-	UserID string
-	ETag   string
+	FileID    string
+	VersionID string
+	UserID    string
+	ETag      string
 }
 
 type CompleteUploadOutput struct {
-	StoragePath string
-	CreatedAt   time.Time
+	StoragePath       string
+	CreatedAt         time.Time
+	ChecksumAlgorithm string
+	Checksum          string
+	Version           int
 }
 
 type GetDownloadLinkInput struct {
 	FileID    string
 	UserID    string
 	ExpiresIn int64
+	// ShareToken, when set, authorizes the download through a share link
+	// instead of UserID. Password unlocks password-protected links.
+	ShareToken string
+	Password   string
 }
 
 type GetDownloadLinkOutput struct {
@@ -48,6 +63,31 @@ type GetDownloadLinkOutput struct {
 	ExpiresIn   int64
 }
 
+// DownloadContentInput carries the raw Range and If-None-Match request
+// headers; they are resolved against the stored object.
+type DownloadContentInput struct {
+	FileID      string
+	UserID      string
+	Range       string
+	IfNoneMatch string
+}
+
+// DownloadContentOutput describes the bytes Body yields: Length bytes of the
+// object starting at Offset. Body is nil when NotModified is set.
+type DownloadContentOutput struct {
+	File        *models.File
+	ETag        string
+	Size        int64
+	Offset      int64
+	Length      int64
+	Partial     bool
+	NotModified bool
+	// RangeNotSatisfiable is set instead of an error so that the caller
+	// learns the size to report with the refusal.
+	RangeNotSatisfiable bool
+	Body                io.ReadCloser
+}
+
 type DeleteFileInput struct {
 	FileID string
 	UserID string
@@ -65,3 +105,259 @@ type GetFileInfoInput struct {
 type GetFileInfoOutput struct {
 	File *models.File
 }
+
+type InitiateMultipartUploadInput struct {
+	UserID   string
+	Filename string
+	Path     string
+	MimeType string
+	Size     int64
+	PartSize int64
+	IsPublic bool
+	Tags     map[string]string
+}
+
+type InitiateMultipartUploadOutput struct {
+	FileID    string
+	UploadID  string
+	PartSize  int64
+	PartCount int
+}
+
+type GetPartUploadURLsInput struct {
+	FileID      string
+	UserID      string
+	PartNumbers []int
+}
+
+type PartUploadURL struct {
+	PartNumber int
+	UploadURL  string
+	Headers    map[string]string
+}
+
+type UploadedPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+type GetPartUploadURLsOutput struct {
+	Parts         []PartUploadURL
+	UploadedParts []UploadedPart
+	UploadMethod  string
+	ExpiresIn     int64
+}
+
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type CompleteMultipartUploadInput struct {
+	FileID string
+	UserID string
+	Parts  []CompletedPart
+}
+
+type CompleteMultipartUploadOutput struct {
+	StoragePath string
+	CreatedAt   time.Time
+}
+
+type AbortMultipartUploadInput struct {
+	FileID string
+	UserID string
+}
+
+type AbortMultipartUploadOutput struct {
+	Success bool
+}
+
+type GetUsageInput struct {
+	UserID string
+}
+
+type GetUsageOutput struct {
+	Usage *models.StorageUsage
+}
+
+type PurgeFileInput struct {
+	FileID string
+	UserID string
+}
+
+type PurgeFileOutput struct {
+	Success bool
+}
+
+type EmptyTrashInput struct {
+	UserID string
+}
+
+type EmptyTrashOutput struct {
+	Purged int
+}
+
+type SetTrashRetentionInput struct {
+	UserID string
+	Days   int
+}
+
+type SetTrashRetentionOutput struct {
+	Days int
+}
+
+type MoveFileInput struct {
+	FileID         string
+	UserID         string
+	Path           string
+	Name           string
+	ConflictPolicy string
+}
+
+type MoveFileOutput struct {
+	File *models.File
+}
+
+type CopyFileInput struct {
+	FileID         string
+	UserID         string
+	Path           string
+	Name           string
+	ConflictPolicy string
+}
+
+type CopyFileOutput struct {
+	File *models.File
+}
+
+type BatchInput struct {
+	UserID  string
+	FileIDs []string
+}
+
+type BatchMoveInput struct {
+	UserID         string
+	FileIDs        []string
+	Path           string
+	ConflictPolicy string
+}
+
+type BatchItemResult struct {
+	FileID  string
+	Success bool
+	Error   string
+}
+
+type BatchOutput struct {
+	Results   []BatchItemResult
+	Succeeded int
+	Failed    int
+}
+
+type ListVersionsInput struct {
+	FileID string
+	UserID string
+}
+
+type ListVersionsOutput struct {
+	CurrentVersion int
+	Versions       []*models.FileVersion
+}
+
+type GetVersionDownloadLinkInput struct {
+	FileID    string
+	VersionID string
+	UserID    string
+	ExpiresIn int64
+}
+
+type RestoreVersionInput struct {
+	FileID    string
+	VersionID string
+	UserID    string
+}
+
+type RestoreVersionOutput struct {
+	CurrentVersion int
+}
+
+type DeleteVersionInput struct {
+	FileID    string
+	VersionID string
+	UserID    string
+}
+
+type DeleteVersionOutput struct {
+	Success bool
+}
+
+type CreateShareLinkInput struct {
+	FileID       string
+	UserID       string
+	ExpiresIn    int64
+	MaxDownloads int
+	Password     string
+}
+
+type CreateShareLinkOutput struct {
+	Link  *models.ShareLink
+	Token string
+}
+
+type ListShareLinksInput struct {
+	FileID string
+	UserID string
+}
+
+type ListShareLinksOutput struct {
+	Links []*models.ShareLink
+}
+
+type RevokeShareLinkInput struct {
+	FileID string
+	LinkID string
+	UserID string
+}
+
+type RevokeShareLinkOutput struct {
+	Success bool
+}
+
+// UploadContentInput streams Body into the storage. Without a FileID a new
+// file is created; otherwise Body becomes the content of that file if its
+// upload is still pending, or a new version of it. Size is -1 when the
+// length is not known up front.
+type UploadContentInput struct {
+	InitiateUploadInput
+	Body io.Reader
+}
+
+type UploadContentOutput struct {
+	FileID            string
+	VersionID         string
+	StoragePath       string
+	Size              int64
+	MimeType          string
+	ChecksumAlgorithm string
+	Checksum          string
+	Version           int
+	CreatedAt         time.Time
+}
+
+type GetPreviewLinkInput struct {
+	FileID    string
+	UserID    string
+	Size      string
+	ExpiresIn int64
+}
+
+// GetPreviewLinkOutput has no URL while State is pending or processing.
+type GetPreviewLinkOutput struct {
+	State     string
+	URL       string
+	MimeType  string
+	Headers   map[string]string
+	ExpiresIn int64
+}
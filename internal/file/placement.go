@@ -0,0 +1,207 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+)
+
+// PlacementPolicy decides which storage tier new content is written to and
+// when content is moved to another tier. It is read from the JSON file named
+// by STORAGE_PLACEMENT_FILE.
+type PlacementPolicy struct {
+	Tiers       []StorageTier   `json:"tiers"`
+	DefaultTier string          `json:"default_tier"`
+	Rules       []PlacementRule `json:"rules"`
+	Migrations  []MigrationRule `json:"migrations"`
+}
+
+// StorageTier is a bucket objects can be placed in. A tier with an Endpoint
+// lives on a MinIO of its own; credentials it leaves out are taken from the
+// MINIO_* settings.
+type StorageTier struct {
+	Name            string `json:"name"`
+	Bucket          string `json:"bucket"`
+	Endpoint        string `json:"endpoint"`
+	PublicEndpoint  string `json:"public_endpoint"`
+	AccessKeyID     string `json:"access_key"`
+	SecretAccessKey string `json:"secret_key"`
+	UseSSL          bool   `json:"use_ssl"`
+	Region          string `json:"region"`
+}
+
+// PlacementRule sends content matching all of its criteria to Tier. Empty
+// criteria match anything; MimeTypes may end in "/*" to match a whole
+// type. The first matching rule wins.
+type PlacementRule struct {
+	Tier      string            `json:"tier"`
+	Users     []string          `json:"users"`
+	MimeTypes []string          `json:"mime_types"`
+	MinSize   int64             `json:"min_size"`
+	MaxSize   int64             `json:"max_size"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// MigrationRule moves files from one tier to another once they are older
+// than MinAge and have not been downloaded for IdleFor.
+type MigrationRule struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	MinAge  policyDuration `json:"min_age"`
+	IdleFor policyDuration `json:"idle_for"`
+}
+
+// policyDuration is a time.Duration written as a string such as "720h".
+type policyDuration time.Duration
+
+func (d *policyDuration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = policyDuration(duration)
+	return nil
+}
+
+// LoadPlacementPolicy reads the placement policy of config. Without a
+// policy file all content is placed in MINIO_BUCKET.
+func LoadPlacementPolicy(config *configs.Config) (*PlacementPolicy, error) {
+	if config.Storage.PlacementFile == "" {
+		return defaultPlacementPolicy(config), nil
+	}
+
+	raw, err := os.ReadFile(config.Storage.PlacementFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read placement policy: %w", err)
+	}
+	var policy PlacementPolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse placement policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid placement policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func defaultPlacementPolicy(config *configs.Config) *PlacementPolicy {
+	return &PlacementPolicy{
+		Tiers:       []StorageTier{{Name: "default", Bucket: config.MinIO.BucketName}},
+		DefaultTier: "default",
+	}
+}
+
+func (p *PlacementPolicy) validate() error {
+	if len(p.Tiers) == 0 {
+		return fmt.Errorf("no tiers configured")
+	}
+	names := make(map[string]bool, len(p.Tiers))
+	for _, tier := range p.Tiers {
+		if tier.Name == "" || tier.Bucket == "" {
+			return fmt.Errorf("every tier needs a name and a bucket")
+		}
+		if names[tier.Name] {
+			return fmt.Errorf("duplicate tier %q", tier.Name)
+		}
+		names[tier.Name] = true
+	}
+
+	if p.DefaultTier == "" {
+		p.DefaultTier = p.Tiers[0].Name
+	}
+	if !names[p.DefaultTier] {
+		return fmt.Errorf("unknown default tier %q", p.DefaultTier)
+	}
+	for _, rule := range p.Rules {
+		if !names[rule.Tier] {
+			return fmt.Errorf("rule refers to unknown tier %q", rule.Tier)
+		}
+	}
+	for _, migration := range p.Migrations {
+		if !names[migration.From] || !names[migration.To] {
+			return fmt.Errorf("migration from %q to %q refers to an unknown tier", migration.From, migration.To)
+		}
+		if p.tier(migration.From).Bucket == p.tier(migration.To).Bucket {
+			return fmt.Errorf("migration from %q to %q does not change the bucket", migration.From, migration.To)
+		}
+	}
+	return nil
+}
+
+func (p *PlacementPolicy) tier(name string) *StorageTier {
+	for i := range p.Tiers {
+		if p.Tiers[i].Name == name {
+			return &p.Tiers[i]
+		}
+	}
+	return nil
+}
+
+// bucketFor returns the bucket of the first rule matching the content, or
+// of the default tier. A negative size, an upload of unknown size, does not
+// match rules with size bounds.
+func (p *PlacementPolicy) bucketFor(userID, mimeType string, size int64, tags map[string]string) string {
+	for _, rule := range p.Rules {
+		if rule.matches(userID, mimeType, size, tags) {
+			return p.tier(rule.Tier).Bucket
+		}
+	}
+	return p.tier(p.DefaultTier).Bucket
+}
+
+// tracksAccess reports whether a migration rule depends on when files were
+// last downloaded.
+func (p *PlacementPolicy) tracksAccess() bool {
+	for _, migration := range p.Migrations {
+		if migration.IdleFor > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PlacementRule) matches(userID, mimeType string, size int64, tags map[string]string) bool {
+	if len(r.Users) > 0 && !slices.Contains(r.Users, userID) {
+		return false
+	}
+	if len(r.MimeTypes) > 0 && !matchesMimeType(r.MimeTypes, mimeType) {
+		return false
+	}
+	if (r.MinSize > 0 || r.MaxSize > 0) && size < 0 {
+		return false
+	}
+	if r.MinSize > 0 && size < r.MinSize {
+		return false
+	}
+	if r.MaxSize > 0 && size > r.MaxSize {
+		return false
+	}
+	for key, value := range r.Tags {
+		if actual, ok := tags[key]; !ok || (value != "" && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesMimeType(patterns []string, mimeType string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mimeType, prefix+"/") {
+				return true
+			}
+		} else if pattern == mimeType {
+			return true
+		}
+	}
+	return false
+}
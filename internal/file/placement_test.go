@@ -0,0 +1,133 @@
+package file
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testPlacementPolicy() *PlacementPolicy {
+	return &PlacementPolicy{
+		Tiers: []StorageTier{
+			{Name: "hot", Bucket: "hot-bucket"},
+			{Name: "cold", Bucket: "cold-bucket"},
+			{Name: "media", Bucket: "media-bucket"},
+		},
+		DefaultTier: "hot",
+		Rules: []PlacementRule{
+			{Tier: "cold", Tags: map[string]string{"archive": ""}},
+			{Tier: "media", MimeTypes: []string{"video/*", "image/png"}, MinSize: 1024},
+			{Tier: "cold", Users: []string{"user-archive"}},
+		},
+	}
+}
+
+func TestPlacementPolicy_BucketFor(t *testing.T) {
+	t.Parallel()
+
+	policy := testPlacementPolicy()
+
+	tests := []struct {
+		name     string
+		userID   string
+		mimeType string
+		size     int64
+		tags     map[string]string
+		expected string
+	}{
+		{"default tier", "user-1", "text/plain", 10, nil, "hot-bucket"},
+		{"tag present", "user-1", "text/plain", 10, map[string]string{"archive": "2024"}, "cold-bucket"},
+		{"mime wildcard and size", "user-1", "video/mp4", 4096, nil, "media-bucket"},
+		{"exact mime", "user-1", "image/png", 4096, nil, "media-bucket"},
+		{"too small", "user-1", "video/mp4", 100, nil, "hot-bucket"},
+		{"unknown size", "user-1", "video/mp4", -1, nil, "hot-bucket"},
+		{"other image", "user-1", "image/jpeg", 4096, nil, "hot-bucket"},
+		{"user", "user-archive", "text/plain", 10, nil, "cold-bucket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, policy.bucketFor(tt.userID, tt.mimeType, tt.size, tt.tags))
+		})
+	}
+}
+
+func TestLoadPlacementPolicy(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) *configs.Config {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return &configs.Config{Storage: configs.StorageConfig{PlacementFile: path}}
+	}
+
+	policy, err := LoadPlacementPolicy(write("valid.json", `{
+		"tiers": [{"name": "hot", "bucket": "hot-bucket"}, {"name": "cold", "bucket": "cold-bucket"}],
+		"migrations": [{"from": "hot", "to": "cold", "min_age": "720h", "idle_for": "168h"}]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "hot", policy.DefaultTier)
+	assert.Equal(t, policyDuration(720*time.Hour), policy.Migrations[0].MinAge)
+	assert.True(t, policy.tracksAccess())
+
+	_, err = LoadPlacementPolicy(write("unknown.json", `{
+		"tiers": [{"name": "hot", "bucket": "hot-bucket"}],
+		"rules": [{"tier": "cold"}]
+	}`))
+	assert.Error(t, err)
+
+	_, err = LoadPlacementPolicy(write("same.json", `{
+		"tiers": [{"name": "hot", "bucket": "b"}, {"name": "cold", "bucket": "b"}],
+		"migrations": [{"from": "hot", "to": "cold", "min_age": "1h"}]
+	}`))
+	assert.Error(t, err)
+
+	policy, err = LoadPlacementPolicy(&configs.Config{MinIO: configs.MinIOConfig{BucketName: "cloud-storage"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "cloud-storage", policy.bucketFor("user-1", "text/plain", 1, nil))
+	assert.False(t, policy.tracksAccess())
+}
+
+func TestFileService_InitiateUpload_PlacedByPolicy(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	config := &configs.Config{MinIO: configs.MinIOConfig{BucketName: "hot-bucket"}}
+	svc := NewFileService(Dependencies{
+		Files:  mockRepo,
+		Quotas: mockQuota,
+	}, new(MockBlobStorage), mockPresigned, config)
+	svc.placement = testPlacementPolicy()
+
+	presignedURL, _ := url.Parse("https://storage.example.com/upload")
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(4096)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.Bucket == "media-bucket"
+	})).Return(nil)
+	mockPresigned.On("PresignHeader", mock.Anything, http.MethodPut, "media-bucket", mock.Anything, presignedUploadTTL, mock.Anything, mock.Anything).Return(presignedURL, nil)
+
+	_, err := svc.InitiateUpload(context.Background(), &InitiateUploadInput{
+		UserID:   "user-123",
+		Filename: "clip.mp4",
+		Path:     "/",
+		MimeType: "video/mp4",
+		Size:     4096,
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockPresigned.AssertExpectations(t)
+}
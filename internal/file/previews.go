@@ -0,0 +1,302 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	previewPrefix      = "previews"
+	previewContentType = "image/jpeg"
+	previewQuality     = 80
+	previewBatchSize   = 20
+
+	// Sources beyond these limits are not decoded at all.
+	maxPreviewSourceSize   = 50 << 20
+	maxPreviewSourcePixels = 50_000_000
+
+	// A claimed job not finished within this time is picked up again.
+	previewClaimTimeout = 10 * time.Minute
+)
+
+// previewSizes are the thumbnails generated for every image, by the longest
+// side in pixels.
+var previewSizes = []struct {
+	Name   string
+	Pixels int
+}{
+	{Name: "small", Pixels: 128},
+	{Name: "medium", Pixels: 512},
+	{Name: "large", Pixels: 1024},
+}
+
+var previewMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var errPreviewSourceTooLarge = errors.New("image is too large to preview")
+
+func previewable(mimeType string) bool {
+	media, _, err := mime.ParseMediaType(mimeType)
+	return err == nil && previewMimeTypes[media]
+}
+
+func previewObject(fileID, size string) string {
+	return fmt.Sprintf("%s/%s/%s.jpg", previewPrefix, fileID, size)
+}
+
+// RunPreviewWorker generates pending previews every interval, or as soon as
+// an upload queues one, until ctx is done.
+func (s *fileService) RunPreviewWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.previewQueued:
+		}
+		generated, err := s.GeneratePendingPreviews(ctx)
+		if err != nil {
+			log.Printf("Preview generation failed: %v", err)
+			continue
+		}
+		if generated > 0 {
+			log.Printf("Preview generation finished %d files", generated)
+		}
+	}
+}
+
+// GeneratePendingPreviews works through the files waiting for previews.
+// A file whose image cannot be decoded is marked failed, and one that is no
+// longer an image loses its previews.
+func (s *fileService) GeneratePendingPreviews(ctx context.Context) (int, error) {
+	generated := 0
+	for {
+		files, err := s.fileRepo.ClaimPendingPreviews(ctx, time.Now().Add(-previewClaimTimeout), previewBatchSize)
+		if err != nil {
+			return generated, fmt.Errorf("failed to claim previews: %w", err)
+		}
+
+		for _, file := range files {
+			if !previewable(file.MimeType) {
+				if err := s.removePreviews(ctx, file.ID); err != nil {
+					log.Printf("Failed to remove previews of %s: %v", file.ID, err)
+					continue
+				}
+				if _, err := s.fileRepo.FinishPreview(ctx, file.ID, models.PreviewStateNone); err != nil {
+					return generated, err
+				}
+				continue
+			}
+
+			state := models.PreviewStateReady
+			if err := s.generatePreviews(ctx, file); err != nil {
+				log.Printf("Failed to generate previews for %s: %v", file.ID, err)
+				metrics.RecordFileOperation("preview_generate", "error")
+				state = models.PreviewStateFailed
+			} else {
+				metrics.RecordFileOperation("preview_generate", "success")
+				generated++
+			}
+			if _, err := s.fileRepo.FinishPreview(ctx, file.ID, state); err != nil {
+				return generated, err
+			}
+		}
+
+		if len(files) < previewBatchSize {
+			return generated, nil
+		}
+	}
+}
+
+func (s *fileService) generatePreviews(ctx context.Context, file *models.File) error {
+	if file.Size > maxPreviewSourceSize {
+		return errPreviewSourceTooLarge
+	}
+
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return err
+	}
+	obj, err := s.storage.GetObject(ctx, file.Bucket, file.StoragePath, storage.GetObjectOptions{Encryption: sse})
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(io.LimitReader(obj, maxPreviewSourceSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxPreviewSourceSize {
+		return errPreviewSourceTooLarge
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPreviewSourcePixels {
+		return errPreviewSourceTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	for _, size := range previewSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaleToFit(img, size.Pixels), &jpeg.Options{Quality: previewQuality}); err != nil {
+			return fmt.Errorf("failed to encode %s preview: %w", size.Name, err)
+		}
+		// Previews of encrypted files are encrypted with the same key owner.
+		object := previewObject(file.ID, size.Name)
+		previewSSE, err := s.objectEncryption(ctx, file.KeyOwnerID, object)
+		if err != nil {
+			return err
+		}
+		_, err = s.storage.PutObject(ctx, s.config.MinIO.BucketName, object, &buf, int64(buf.Len()),
+			storage.PutObjectOptions{ContentType: previewContentType, Encryption: previewSSE})
+		if err != nil {
+			return fmt.Errorf("failed to store %s preview: %w", size.Name, err)
+		}
+	}
+	return nil
+}
+
+// scaleToFit shrinks img so that its longest side is at most pixels, on a
+// white background since JPEG has no transparency. Smaller images keep
+// their size.
+func scaleToFit(img image.Image, pixels int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > pixels || height > pixels {
+		if width >= height {
+			width, height = pixels, max(1, height*pixels/width)
+		} else {
+			width, height = max(1, width*pixels/height), pixels
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// schedulePreview queues preview generation after a file got image content.
+// Previews are best effort, so failures are only logged. Promoting a version
+// queues files that already have previews by itself, which also takes care
+// of removing them once the content is no longer an image.
+func (s *fileService) schedulePreview(ctx context.Context, fileID, mimeType string) {
+	if !previewable(mimeType) {
+		return
+	}
+
+	if err := s.fileRepo.SetPreviewState(ctx, fileID, models.PreviewStatePending); err != nil {
+		log.Printf("Failed to queue previews for %s: %v", fileID, err)
+		return
+	}
+	select {
+	case s.previewQueued <- struct{}{}:
+	default:
+	}
+}
+
+func (s *fileService) removePreviews(ctx context.Context, fileID string) error {
+	for _, size := range previewSizes {
+		if err := s.storage.RemoveObject(ctx, s.config.MinIO.BucketName, previewObject(fileID, size.Name)); err != nil {
+			return fmt.Errorf("failed to delete %s preview: %w", size.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetPreviewLink returns a presigned URL of one of a file's previews. While
+// the previews are still being generated only the state is returned.
+func (s *fileService) GetPreviewLink(ctx context.Context, input *GetPreviewLinkInput) (output *GetPreviewLinkOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("preview_link", status)
+	}()
+
+	size := input.Size
+	if size == "" {
+		size = previewSizes[0].Name
+	}
+	known := false
+	for _, preview := range previewSizes {
+		known = known || preview.Name == size
+	}
+	if !known {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown preview size: %s", size)
+	}
+
+	hasAccess, _, _, err := s.fileRepo.CheckAccess(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "file not found")
+	}
+	if !hasAccess {
+		return nil, status.Error(codes.PermissionDenied, "access denied")
+	}
+	file, err := s.fileRepo.GetByID(ctx, input.FileID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "file not found")
+	}
+
+	switch file.PreviewState {
+	case models.PreviewStateReady:
+	case models.PreviewStatePending, models.PreviewStateProcessing:
+		return &GetPreviewLinkOutput{State: file.PreviewState}, nil
+	default:
+		return nil, status.Error(codes.NotFound, "file has no preview")
+	}
+
+	expires := time.Hour
+	if input.ExpiresIn > 0 {
+		expires = time.Duration(input.ExpiresIn) * time.Second
+	}
+	object := previewObject(file.ID, size)
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, object)
+	if err != nil {
+		return nil, err
+	}
+	previewURL, headers, err := s.presignDownload(ctx, s.config.MinIO.BucketName, object, expires, nil, sse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetPreviewLinkOutput{
+		State:     file.PreviewState,
+		URL:       previewURL,
+		MimeType:  previewContentType,
+		Headers:   headers,
+		ExpiresIn: int64(expires / time.Second),
+	}, nil
+}
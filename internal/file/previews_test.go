@@ -0,0 +1,361 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPreviewable(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, previewable("image/png"))
+	assert.True(t, previewable("image/jpeg; charset=binary"))
+	assert.True(t, previewable("image/webp"))
+	assert.False(t, previewable("image/svg+xml"))
+	assert.False(t, previewable("application/pdf"))
+	assert.False(t, previewable(""))
+}
+
+func TestScaleToFit(t *testing.T) {
+	t.Parallel()
+
+	wide := scaleToFit(image.NewRGBA(image.Rect(0, 0, 2000, 500)), 512)
+	assert.Equal(t, image.Rect(0, 0, 512, 128), wide.Bounds())
+
+	tall := scaleToFit(image.NewRGBA(image.Rect(0, 0, 300, 3000)), 128)
+	assert.Equal(t, image.Rect(0, 0, 12, 128), tall.Bounds())
+
+	small := scaleToFit(image.NewRGBA(image.Rect(0, 0, 64, 32)), 128)
+	assert.Equal(t, image.Rect(0, 0, 64, 32), small.Bounds())
+}
+
+func TestFileService_GeneratePendingPreviews_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	data := encodeTestPNG(t, 800, 600)
+	file := &models.File{ID: "file-123", MimeType: "image/png", Bucket: "cloud-storage", StoragePath: "objects/file-123", Size: int64(len(data))}
+
+	mockRepo.On("ClaimPendingPreviews", mock.Anything, mock.Anything, previewBatchSize).Return([]*models.File{file}, nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(io.NopCloser(bytes.NewReader(data)), nil)
+	for _, size := range []string{"small", "medium", "large"} {
+		mockStorage.On("PutObject", mock.Anything, "cloud-storage", "previews/file-123/"+size+".jpg", mock.Anything, mock.Anything,
+			storage.PutObjectOptions{ContentType: "image/jpeg"}).Return(storage.UploadInfo{}, nil)
+	}
+	mockRepo.On("FinishPreview", mock.Anything, "file-123", models.PreviewStateReady).Return(true, nil)
+
+	generated, err := svc.GeneratePendingPreviews(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, generated)
+	mockStorage.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_GeneratePendingPreviews_InvalidImage(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", MimeType: "image/jpeg", Bucket: "cloud-storage", StoragePath: "objects/file-123", Size: 9}
+
+	mockRepo.On("ClaimPendingPreviews", mock.Anything, mock.Anything, previewBatchSize).Return([]*models.File{file}, nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(io.NopCloser(bytes.NewReader([]byte("not a jpg"))), nil)
+	mockRepo.On("FinishPreview", mock.Anything, "file-123", models.PreviewStateFailed).Return(true, nil)
+
+	generated, err := svc.GeneratePendingPreviews(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, generated)
+	mockStorage.AssertNotCalled(t, "PutObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_GeneratePendingPreviews_NoLongerImage(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", MimeType: "application/pdf", Bucket: "cloud-storage", StoragePath: "objects/file-123"}
+
+	mockRepo.On("ClaimPendingPreviews", mock.Anything, mock.Anything, previewBatchSize).Return([]*models.File{file}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", mock.MatchedBy(func(object string) bool {
+		return object == "previews/file-123/small.jpg" || object == "previews/file-123/medium.jpg" || object == "previews/file-123/large.jpg"
+	})).Return(nil).Times(3)
+	mockRepo.On("FinishPreview", mock.Anything, "file-123", models.PreviewStateNone).Return(true, nil)
+
+	generated, err := svc.GeneratePendingPreviews(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, generated)
+	mockStorage.AssertNotCalled(t, "GetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockStorage.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_GetPreviewLink_Ready(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	presigned, _ := url.Parse("https://minio/cloud-storage/previews/file-123/medium.jpg?sig=abc")
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "owner", "", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{ID: "file-123", PreviewState: models.PreviewStateReady}, nil)
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "previews/file-123/medium.jpg", 5*time.Minute, mock.Anything).Return(presigned, nil)
+
+	output, err := svc.GetPreviewLink(context.Background(), &GetPreviewLinkInput{
+		FileID:    "file-123",
+		UserID:    "user-123",
+		Size:      "medium",
+		ExpiresIn: 300,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, presigned.String(), output.URL)
+	assert.Equal(t, "image/jpeg", output.MimeType)
+	assert.Equal(t, int64(300), output.ExpiresIn)
+	assert.Equal(t, models.PreviewStateReady, output.State)
+}
+
+func TestFileService_GetPreviewLink_Pending(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "owner", "", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{ID: "file-123", PreviewState: models.PreviewStatePending}, nil)
+
+	output, err := svc.GetPreviewLink(context.Background(), &GetPreviewLinkInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PreviewStatePending, output.State)
+	assert.Empty(t, output.URL)
+	mockPresigned.AssertNotCalled(t, "PresignedGetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_GetPreviewLink_NoPreview(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-123").Return(true, "owner", "", nil)
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{ID: "file-123", PreviewState: models.PreviewStateNone}, nil)
+
+	_, err := svc.GetPreviewLink(context.Background(), &GetPreviewLinkInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestFileService_GetPreviewLink_UnknownSize(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	_, err := svc.GetPreviewLink(context.Background(), &GetPreviewLinkInput{FileID: "file-123", UserID: "user-123", Size: "huge"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockRepo.AssertNotCalled(t, "CheckAccess", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_PurgeFile_RemovesPreviews(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", UserID: "user-123", MimeType: "image/png", Bucket: "cloud-storage", StoragePath: "objects/file-123", Size: 10}
+
+	mockVersions.On("ListByFileID", mock.Anything, "file-123").Return([]*models.FileVersion{}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+	for _, size := range []string{"small", "medium", "large"} {
+		mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "previews/file-123/"+size+".jpg").Return(nil)
+	}
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", int64(10)).Return(nil)
+
+	err := svc.purgeFile(context.Background(), file)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,181 @@
+package file
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/url"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// shareLinkDownloadTTL bounds the presigned URL a share link redirects
+	// to, so the URL itself is not worth passing around.
+	shareLinkDownloadTTL = 5 * time.Minute
+
+	shareTokenBytes = 32
+)
+
+func (s *fileService) CreateShareLink(ctx context.Context, input *CreateShareLinkInput) (output *CreateShareLinkOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("create_share_link", status)
+	}()
+
+	if input.ExpiresIn < 0 || input.MaxDownloads < 0 {
+		return nil, fmt.Errorf("expiry and download limit cannot be negative")
+	}
+
+	file, err := s.getMovableFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := models.NewShareLink(file.ID, file.UserID, hashShareToken(token))
+	link.MaxDownloads = input.MaxDownloads
+	if input.ExpiresIn > 0 {
+		expiresAt := link.CreatedAt.Add(time.Duration(input.ExpiresIn) * time.Second)
+		link.ExpiresAt = &expiresAt
+	}
+	if input.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	if err := s.linkRepo.Create(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+	return &CreateShareLinkOutput{Link: link, Token: token}, nil
+}
+
+func (s *fileService) ListShareLinks(ctx context.Context, input *ListShareLinksInput) (output *ListShareLinksOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("list_share_links", status)
+	}()
+
+	file, err := s.getOwnedFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.linkRepo.ListByFileID(ctx, file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	return &ListShareLinksOutput{Links: links}, nil
+}
+
+func (s *fileService) RevokeShareLink(ctx context.Context, input *RevokeShareLinkInput) (output *RevokeShareLinkOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("revoke_share_link", status)
+	}()
+
+	link, err := s.linkRepo.GetByID(ctx, input.LinkID)
+	if err != nil {
+		return nil, fmt.Errorf("share link not found: %w", err)
+	}
+	if link.FileID != input.FileID || link.UserID != input.UserID {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if err := s.linkRepo.Revoke(ctx, link.ID); err != nil {
+		return nil, fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	return &RevokeShareLinkOutput{Success: true}, nil
+}
+
+// shareLinkDownload resolves a share link token to a short-lived download
+// URL, counting the download against the link.
+func (s *fileService) shareLinkDownload(ctx context.Context, input *GetDownloadLinkInput) (*GetDownloadLinkOutput, error) {
+	link, err := s.linkRepo.GetByTokenHash(ctx, hashShareToken(input.ShareToken))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "share link not found")
+	}
+	if !link.Usable(time.Now()) {
+		return nil, status.Error(codes.NotFound, "share link has expired")
+	}
+	if link.HasPassword() {
+		if input.Password == "" {
+			return nil, status.Error(codes.Unauthenticated, "password required")
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(input.Password)) != nil {
+			return nil, status.Error(codes.PermissionDenied, "invalid password")
+		}
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, link.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file.IsTrashed || file.UploadState != models.UploadStateActive {
+		return nil, status.Error(codes.NotFound, "share link not found")
+	}
+
+	counted, err := s.linkRepo.RecordDownload(ctx, link.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !counted {
+		return nil, status.Error(codes.NotFound, "share link has expired")
+	}
+
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.OriginalName}))
+	downloadURL, headers, err := s.presignDownload(ctx, file.Bucket, file.StoragePath, shareLinkDownloadTTL, params, sse)
+	if err != nil {
+		return nil, err
+	}
+	s.recordAccess(ctx, file.ID)
+	return &GetDownloadLinkOutput{
+		DownloadURL: downloadURL,
+		Method:      "GET",
+		Headers:     headers,
+		ExpiresIn:   int64(shareLinkDownloadTTL / time.Second),
+	}, nil
+}
+
+func generateShareToken() (string, error) {
+	buf := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
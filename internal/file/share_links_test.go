@@ -0,0 +1,329 @@
+package file
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFileService_CreateShareLink_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockLinks.On("Create", mock.Anything, mock.MatchedBy(func(l *models.ShareLink) bool {
+		return l.FileID == "file-123" && l.MaxDownloads == 3 && l.ExpiresAt != nil && l.HasPassword() && len(l.TokenHash) == 64
+	})).Return(nil)
+
+	output, err := svc.CreateShareLink(context.Background(), &CreateShareLinkInput{
+		FileID:       "file-123",
+		UserID:       "user-123",
+		ExpiresIn:    3600,
+		MaxDownloads: 3,
+		Password:     "secret",
+	})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, output.Token)
+	assert.Equal(t, hashShareToken(output.Token), output.Link.TokenHash)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(output.Link.PasswordHash), []byte("secret")))
+	mockLinks.AssertExpectations(t)
+}
+
+func TestFileService_CreateShareLink_NotOwner(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	output, err := svc.CreateShareLink(context.Background(), &CreateShareLinkInput{FileID: "file-123", UserID: "user-456"})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockLinks.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestFileService_GetDownloadLink_ShareToken(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	link := &models.ShareLink{ID: "link-1", FileID: "file-123", MaxDownloads: 2, DownloadCount: 1}
+	downloadURL, _ := url.Parse("https://storage.example.com/objects/file-123")
+
+	mockLinks.On("GetByTokenHash", mock.Anything, hashShareToken("token-abc")).Return(link, nil)
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockLinks.On("RecordDownload", mock.Anything, "link-1").Return(true, nil)
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "objects/file-123", shareLinkDownloadTTL, mock.MatchedBy(func(v url.Values) bool {
+		return v.Get("response-content-disposition") == "attachment; filename=report.pdf"
+	})).Return(downloadURL, nil)
+
+	output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{ShareToken: "token-abc"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, downloadURL.String(), output.DownloadURL)
+	assert.Equal(t, int64(300), output.ExpiresIn)
+	mockRepo.AssertNotCalled(t, "CheckAccess", mock.Anything, mock.Anything, mock.Anything)
+	mockLinks.AssertExpectations(t)
+}
+
+func TestFileService_GetDownloadLink_ShareTokenExhausted(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	expired := time.Now().Add(-time.Minute)
+	links := map[string]*models.ShareLink{
+		"used-up": {ID: "link-1", FileID: "file-123", MaxDownloads: 2, DownloadCount: 2},
+		"expired": {ID: "link-2", FileID: "file-123", ExpiresAt: &expired},
+		"revoked": {ID: "link-3", FileID: "file-123", RevokedAt: &expired},
+	}
+	for token, link := range links {
+		mockLinks.On("GetByTokenHash", mock.Anything, hashShareToken(token)).Return(link, nil)
+	}
+
+	for token := range links {
+		output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{ShareToken: token})
+
+		assert.Nil(t, output)
+		assert.Equal(t, codes.NotFound, status.Code(err), token)
+	}
+	mockLinks.AssertNotCalled(t, "RecordDownload", mock.Anything, mock.Anything)
+}
+
+func TestFileService_GetDownloadLink_ShareTokenPassword(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	link := &models.ShareLink{ID: "link-1", FileID: "file-123", PasswordHash: string(hash)}
+	mockLinks.On("GetByTokenHash", mock.Anything, hashShareToken("token-abc")).Return(link, nil)
+
+	output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{ShareToken: "token-abc"})
+	assert.Nil(t, output)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	output, err = svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{ShareToken: "token-abc", Password: "wrong"})
+	assert.Nil(t, output)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	mockLinks.AssertNotCalled(t, "RecordDownload", mock.Anything, mock.Anything)
+}
+
+func TestFileService_GetDownloadLink_ShareTokenRace(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	link := &models.ShareLink{ID: "link-1", FileID: "file-123", MaxDownloads: 1}
+	mockLinks.On("GetByTokenHash", mock.Anything, hashShareToken("token-abc")).Return(link, nil)
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockLinks.On("RecordDownload", mock.Anything, "link-1").Return(false, nil)
+
+	output, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{ShareToken: "token-abc"})
+
+	assert.Nil(t, output)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	mockPresigned.AssertNotCalled(t, "PresignedGetObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_RevokeShareLink_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockLinks.On("GetByID", mock.Anything, "link-1").Return(&models.ShareLink{ID: "link-1", FileID: "file-123", UserID: "user-123"}, nil)
+	mockLinks.On("Revoke", mock.Anything, "link-1").Return(nil)
+
+	output, err := svc.RevokeShareLink(context.Background(), &RevokeShareLinkInput{FileID: "file-123", LinkID: "link-1", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockLinks.AssertExpectations(t)
+}
+
+func TestFileService_RevokeShareLink_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockLinks.On("GetByID", mock.Anything, "link-1").Return(&models.ShareLink{ID: "link-1", FileID: "file-123", UserID: "user-123"}, nil)
+
+	output, err := svc.RevokeShareLink(context.Background(), &RevokeShareLinkInput{FileID: "file-123", LinkID: "link-1", UserID: "user-456"})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockLinks.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything)
+}
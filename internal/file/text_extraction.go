@@ -0,0 +1,214 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	textExtractionBatchSize = 20
+
+	// maxIndexedText bounds the text stored per file, which keeps its
+	// tsvector well below the 1 MB Postgres allows.
+	maxIndexedText = 256 << 10
+
+	// Files whose extraction stopped midway, say because the service was
+	// restarted, are claimed again once this much time has passed.
+	textClaimTimeout = 10 * time.Minute
+)
+
+var errTextSourceTooLarge = errors.New("document is too large to index")
+
+// textExtractable reports whether text can be extracted from content of
+// mimeType: plain text of any kind, JSON and PDF.
+func textExtractable(mimeType string) bool {
+	media, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(media, "text/") || media == "application/json" ||
+		strings.HasSuffix(media, "+json") || media == "application/pdf"
+}
+
+// RunTextExtractor extracts the text of files waiting for it every interval,
+// or as soon as an upload queues one, until ctx is done.
+func (s *fileService) RunTextExtractor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.textQueued:
+		}
+		extracted, err := s.ExtractPendingTexts(ctx)
+		if err != nil {
+			log.Printf("Text extraction failed: %v", err)
+			continue
+		}
+		if extracted > 0 {
+			log.Printf("Text extraction finished %d files", extracted)
+		}
+	}
+}
+
+// ExtractPendingTexts works through the files waiting for text extraction
+// and stores their text for content search. A file whose text cannot be
+// read is marked failed, and one that no longer holds text loses its text.
+func (s *fileService) ExtractPendingTexts(ctx context.Context) (int, error) {
+	extracted := 0
+	for {
+		files, err := s.fileRepo.ClaimPendingContents(ctx, time.Now().Add(-textClaimTimeout), textExtractionBatchSize)
+		if err != nil {
+			return extracted, fmt.Errorf("failed to claim text extraction: %w", err)
+		}
+
+		for _, file := range files {
+			state, text := models.ContentStateNone, ""
+			if textExtractable(file.MimeType) {
+				text, err = s.extractText(ctx, file)
+				if err != nil {
+					log.Printf("Failed to extract text of %s: %v", file.ID, err)
+					metrics.RecordFileOperation("text_extract", "error")
+					state = models.ContentStateFailed
+				} else {
+					metrics.RecordFileOperation("text_extract", "success")
+					state = models.ContentStateReady
+					extracted++
+				}
+			}
+			if _, err := s.fileRepo.FinishContent(ctx, file.ID, state, text); err != nil {
+				return extracted, err
+			}
+		}
+
+		if len(files) < textExtractionBatchSize {
+			return extracted, nil
+		}
+	}
+}
+
+// extractText reads the text of a file. Text beyond the configured size
+// limit is left out; a PDF cannot be read in part, so larger ones are not
+// indexed at all.
+func (s *fileService) extractText(ctx context.Context, file *models.File) (string, error) {
+	limit := s.config.Contents.MaxSize
+	media, _, _ := mime.ParseMediaType(file.MimeType)
+	isPDF := media == "application/pdf"
+	if isPDF && file.Size > limit {
+		return "", errTextSourceTooLarge
+	}
+
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return "", err
+	}
+	obj, err := s.storage.GetObject(ctx, file.Bucket, file.StoragePath, storage.GetObjectOptions{Encryption: sse})
+	if err != nil {
+		return "", fmt.Errorf("failed to open document: %w", err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(io.LimitReader(obj, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+
+	if !isPDF {
+		return cleanText(string(data[:min(int64(len(data)), limit)])), nil
+	}
+	if int64(len(data)) > limit {
+		return "", errTextSourceTooLarge
+	}
+	text, err := pdfText(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return cleanText(text), nil
+}
+
+// pdfText returns the text layer of a PDF, page by page, stopping once
+// there is more than can be indexed. The PDF reader panics on some
+// malformed files, which is reported as an error.
+func pdfText(data []byte) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage() && buf.Len() <= maxIndexedText; i++ {
+		page := reader.Page(i)
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(pageText)
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+// cleanText makes extracted text safe to store: valid UTF-8 without control
+// characters other than whitespace, cut to maxIndexedText bytes.
+func cleanText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || (unicode.IsControl(r) && !unicode.IsSpace(r)) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(text, ""))
+	if len(text) > maxIndexedText {
+		cut := maxIndexedText
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
+
+// scheduleTextExtraction queues text extraction after a file got content
+// that holds text. Like previews, this is best effort, and promoting a
+// version queues files that already have text by itself.
+func (s *fileService) scheduleTextExtraction(ctx context.Context, fileID, mimeType string) {
+	if s.config.Contents.MaxSize <= 0 || !textExtractable(mimeType) {
+		return
+	}
+
+	if err := s.fileRepo.SetContentState(ctx, fileID, models.ContentStatePending); err != nil {
+		log.Printf("Failed to queue text extraction for %s: %v", fileID, err)
+		return
+	}
+	select {
+	case s.textQueued <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,245 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// buildTestPDF returns a single page PDF showing text in Helvetica.
+func buildTestPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestTextExtractable(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, textExtractable("text/plain; charset=utf-8"))
+	assert.True(t, textExtractable("text/markdown"))
+	assert.True(t, textExtractable("text/csv"))
+	assert.True(t, textExtractable("application/json"))
+	assert.True(t, textExtractable("application/geo+json"))
+	assert.True(t, textExtractable("application/pdf"))
+	assert.False(t, textExtractable("image/png"))
+	assert.False(t, textExtractable("application/octet-stream"))
+	assert.False(t, textExtractable(""))
+}
+
+func TestCleanText(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "line one\nline\ttwo", cleanText("line one\nline\x00\ttwo\x02"))
+	assert.Equal(t, "caf", cleanText("caf\xe9"))
+
+	long := strings.Repeat("é", maxIndexedText)
+	cleaned := cleanText(long)
+	assert.LessOrEqual(t, len(cleaned), maxIndexedText)
+	assert.True(t, strings.HasPrefix(long, cleaned))
+}
+
+func TestFileService_ExtractPendingTexts_TruncatesText(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Contents: configs.ContentsConfig{
+			MaxSize: 16,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{ID: "file-123", MimeType: "text/plain", Bucket: "cloud-storage", StoragePath: "objects/file-123", Size: 28}
+
+	mockRepo.On("ClaimPendingContents", mock.Anything, mock.Anything, textExtractionBatchSize).Return([]*models.File{file}, nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).
+		Return(io.NopCloser(strings.NewReader("invoice 2024 for the project")), nil)
+	mockRepo.On("FinishContent", mock.Anything, "file-123", models.ContentStateReady, "invoice 2024 for").Return(true, nil)
+
+	extracted, err := svc.ExtractPendingTexts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, extracted)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_ExtractPendingTexts_PDF(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Contents: configs.ContentsConfig{
+			MaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	data := buildTestPDF("Invoice 2024")
+	file := &models.File{ID: "file-123", MimeType: "application/pdf", Bucket: "cloud-storage", StoragePath: "objects/file-123", Size: int64(len(data))}
+
+	mockRepo.On("ClaimPendingContents", mock.Anything, mock.Anything, textExtractionBatchSize).Return([]*models.File{file}, nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything).Return(io.NopCloser(bytes.NewReader(data)), nil)
+	mockRepo.On("FinishContent", mock.Anything, "file-123", models.ContentStateReady, mock.MatchedBy(func(text string) bool {
+		return strings.Contains(text, "Invoice 2024")
+	})).Return(true, nil)
+
+	extracted, err := svc.ExtractPendingTexts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, extracted)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_ExtractPendingTexts_Failures(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Contents: configs.ContentsConfig{
+			MaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	broken := &models.File{ID: "file-1", MimeType: "application/pdf", Bucket: "cloud-storage", StoragePath: "objects/file-1", Size: 11}
+	tooLarge := &models.File{ID: "file-2", MimeType: "application/pdf", Bucket: "cloud-storage", StoragePath: "objects/file-2", Size: 2 << 20}
+	image := &models.File{ID: "file-3", MimeType: "image/png", Bucket: "cloud-storage", StoragePath: "objects/file-3"}
+
+	mockRepo.On("ClaimPendingContents", mock.Anything, mock.Anything, textExtractionBatchSize).Return([]*models.File{broken, tooLarge, image}, nil)
+	mockStorage.On("GetObject", mock.Anything, "cloud-storage", "objects/file-1", mock.Anything).Return(io.NopCloser(strings.NewReader("not a pdf!!")), nil)
+	mockRepo.On("FinishContent", mock.Anything, "file-1", models.ContentStateFailed, "").Return(true, nil)
+	mockRepo.On("FinishContent", mock.Anything, "file-2", models.ContentStateFailed, "").Return(true, nil)
+	mockRepo.On("FinishContent", mock.Anything, "file-3", models.ContentStateNone, "").Return(true, nil)
+
+	extracted, err := svc.ExtractPendingTexts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, extracted)
+	mockStorage.AssertNumberOfCalls(t, "GetObject", 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_ScheduleTextExtraction(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Contents: configs.ContentsConfig{
+			MaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("SetContentState", mock.Anything, "file-123", models.ContentStatePending).Return(nil)
+
+	svc.scheduleTextExtraction(context.Background(), "file-123", "text/markdown")
+	svc.scheduleTextExtraction(context.Background(), "file-456", "image/png")
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "SetContentState", 1)
+	assert.Len(t, svc.textQueued, 1)
+
+	disabledRepo := new(MockFileRepository)
+	disabled := NewFileService(Dependencies{
+		Files:    disabledRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, &configs.Config{})
+	disabled.scheduleTextExtraction(context.Background(), "file-123", "text/plain")
+	disabledRepo.AssertNotCalled(t, "SetContentState", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,145 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+)
+
+const migrationBatchSize = 100
+
+// RunTierMigrator moves files between storage tiers according to the
+// migration rules of the placement policy every interval until ctx is done.
+func (s *fileService) RunTierMigrator(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			migrated, err := s.MigrateTiers(ctx)
+			if err != nil {
+				log.Printf("Tier migration failed: %v", err)
+				continue
+			}
+			if migrated > 0 {
+				log.Printf("Tier migration moved %d objects", migrated)
+			}
+		}
+	}
+}
+
+// MigrateTiers applies every migration rule once and returns the number of
+// objects moved.
+func (s *fileService) MigrateTiers(ctx context.Context) (int, error) {
+	migrated := 0
+	for _, rule := range s.placement.Migrations {
+		moved, err := s.migrateTier(ctx, rule)
+		migrated += moved
+		if err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+func (s *fileService) migrateTier(ctx context.Context, rule MigrationRule) (int, error) {
+	from, to := s.placement.tier(rule.From).Bucket, s.placement.tier(rule.To).Bucket
+	now := time.Now()
+	createdBefore := now.Add(-time.Duration(rule.MinAge))
+	idleSince := now.Add(-time.Duration(rule.IdleFor))
+
+	migrated := 0
+	for {
+		files, err := s.fileRepo.ListMigrationCandidates(ctx, from, createdBefore, idleSince, migrationBatchSize)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to list migration candidates: %w", err)
+		}
+
+		moved := 0
+		for _, file := range files {
+			ok, err := s.migrateObject(ctx, file, to)
+			if err != nil {
+				log.Printf("Failed to migrate file %s to %s: %v", file.ID, to, err)
+				metrics.RecordFileOperation("tier_migrate", "error")
+				continue
+			}
+			if ok {
+				metrics.RecordFileOperation("tier_migrate", "success")
+				moved++
+			}
+		}
+		migrated += moved
+
+		if len(files) < migrationBatchSize || moved == 0 {
+			return migrated, nil
+		}
+	}
+}
+
+// migrateObject copies the object of file to bucket under the same name and
+// repoints everything sharing it. The SSE-C key of an object depends on its
+// name only, so the copy is encrypted with the same key. Download URLs
+// presigned for the old bucket stop working once the original is removed.
+// It reports false when the object was deleted while being moved.
+func (s *fileService) migrateObject(ctx context.Context, file *models.File, bucket string) (bool, error) {
+	sse, err := s.objectEncryption(ctx, file.KeyOwnerID, file.StoragePath)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.storage.CopyObject(ctx,
+		storage.CopyDestOptions{Bucket: bucket, Object: file.StoragePath, Encryption: sse},
+		storage.CopySrcOptions{Bucket: file.Bucket, Object: file.StoragePath, Encryption: sse},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	moved, err := s.fileRepo.RelocateObject(ctx, file.StoragePath, file.Bucket, bucket)
+	if err != nil || !moved {
+		s.discardCopy(ctx, file, bucket)
+		return false, err
+	}
+
+	if err := s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath); err != nil {
+		log.Printf("Failed to remove migrated object %s from %s: %v", file.StoragePath, file.Bucket, err)
+	}
+	// A purge that read the file before the relocation removed the original
+	// instead of the copy, which nothing refers to once the purge is done.
+	if !s.discardCopy(ctx, file, bucket) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// discardCopy removes the copy made for a migration unless something refers
+// to it, as after a concurrent run that moved the object to the same
+// bucket. It reports whether the copy is kept.
+func (s *fileService) discardCopy(ctx context.Context, file *models.File, bucket string) bool {
+	inUse, err := s.fileRepo.ObjectInUse(ctx, bucket, file.StoragePath)
+	if err != nil || inUse {
+		return true
+	}
+	if err := s.storage.RemoveObject(ctx, bucket, file.StoragePath); err != nil {
+		log.Printf("Failed to remove copy of %s from %s: %v", file.StoragePath, bucket, err)
+	}
+	return false
+}
+
+// recordAccess notes a download of a file when a migration rule depends on
+// it. A failure is logged only, as it must not fail the download.
+func (s *fileService) recordAccess(ctx context.Context, fileID string) {
+	if !s.placement.tracksAccess() {
+		return
+	}
+	if err := s.fileRepo.TouchAccessed(ctx, fileID); err != nil {
+		log.Printf("Failed to record access to file %s: %v", fileID, err)
+	}
+}
@@ -0,0 +1,231 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func copiedTo(bucket, object string) interface{} {
+	return mock.MatchedBy(func(dst storage.CopyDestOptions) bool {
+		return dst.Bucket == bucket && dst.Object == object
+	})
+}
+
+func TestFileService_MigrateTiers_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "hot-bucket",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+	svc.placement = testPlacementPolicy()
+	svc.placement.Migrations = []MigrationRule{{From: "hot", To: "cold", IdleFor: policyDuration(time.Hour)}}
+
+	candidates := []*models.File{
+		{ID: "file-1", StoragePath: "user-1/a", Bucket: "hot-bucket"},
+		{ID: "file-2", StoragePath: "user-1/b", Bucket: "hot-bucket"},
+	}
+
+	mockRepo.On("ListMigrationCandidates", mock.Anything, "hot-bucket", mock.Anything, mock.Anything, migrationBatchSize).Return(candidates, nil)
+	mockStorage.On("CopyObject", mock.Anything, copiedTo("cold-bucket", "user-1/a"), storage.CopySrcOptions{Bucket: "hot-bucket", Object: "user-1/a"}).Return(storage.UploadInfo{}, nil)
+	mockStorage.On("CopyObject", mock.Anything, copiedTo("cold-bucket", "user-1/b"), mock.Anything).Return(storage.UploadInfo{}, errors.New("storage unavailable"))
+	mockRepo.On("RelocateObject", mock.Anything, "user-1/a", "hot-bucket", "cold-bucket").Return(true, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "hot-bucket", "user-1/a").Return(nil)
+	mockRepo.On("ObjectInUse", mock.Anything, "cold-bucket", "user-1/a").Return(true, nil)
+
+	migrated, err := svc.MigrateTiers(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+	mockRepo.AssertNotCalled(t, "RelocateObject", mock.Anything, "user-1/b", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_MigrateTiers_DiscardsCopyOfDeletedFile(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "hot-bucket",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+	svc.placement = testPlacementPolicy()
+	svc.placement.Migrations = []MigrationRule{{From: "hot", To: "cold", IdleFor: policyDuration(time.Hour)}}
+
+	candidates := []*models.File{{ID: "file-1", StoragePath: "user-1/a", Bucket: "hot-bucket"}}
+
+	mockRepo.On("ListMigrationCandidates", mock.Anything, "hot-bucket", mock.Anything, mock.Anything, migrationBatchSize).Return(candidates, nil)
+	mockStorage.On("CopyObject", mock.Anything, copiedTo("cold-bucket", "user-1/a"), mock.Anything).Return(storage.UploadInfo{}, nil)
+	mockRepo.On("RelocateObject", mock.Anything, "user-1/a", "hot-bucket", "cold-bucket").Return(false, nil)
+	mockRepo.On("ObjectInUse", mock.Anything, "cold-bucket", "user-1/a").Return(false, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cold-bucket", "user-1/a").Return(nil)
+
+	migrated, err := svc.MigrateTiers(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, "hot-bucket", mock.Anything)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_MigrateTiers_KeepsCopyMovedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "hot-bucket",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+	svc.placement = testPlacementPolicy()
+	svc.placement.Migrations = []MigrationRule{{From: "hot", To: "cold", IdleFor: policyDuration(time.Hour)}}
+
+	candidates := []*models.File{{ID: "file-1", StoragePath: "user-1/a", Bucket: "hot-bucket"}}
+
+	mockRepo.On("ListMigrationCandidates", mock.Anything, "hot-bucket", mock.Anything, mock.Anything, migrationBatchSize).Return(candidates, nil)
+	mockStorage.On("CopyObject", mock.Anything, copiedTo("cold-bucket", "user-1/a"), mock.Anything).Return(storage.UploadInfo{}, nil)
+	mockRepo.On("RelocateObject", mock.Anything, "user-1/a", "hot-bucket", "cold-bucket").Return(false, nil)
+	mockRepo.On("ObjectInUse", mock.Anything, "cold-bucket", "user-1/a").Return(true, nil)
+
+	migrated, err := svc.MigrateTiers(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_MigrateTiers_DiscardsCopyOfFilePurgedWhileMoving(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "hot-bucket",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+	svc.placement = testPlacementPolicy()
+	svc.placement.Migrations = []MigrationRule{{From: "hot", To: "cold", IdleFor: policyDuration(time.Hour)}}
+
+	candidates := []*models.File{{ID: "file-1", StoragePath: "user-1/a", Bucket: "hot-bucket"}}
+
+	mockRepo.On("ListMigrationCandidates", mock.Anything, "hot-bucket", mock.Anything, mock.Anything, migrationBatchSize).Return(candidates, nil)
+	mockStorage.On("CopyObject", mock.Anything, copiedTo("cold-bucket", "user-1/a"), mock.Anything).Return(storage.UploadInfo{}, nil)
+	mockRepo.On("RelocateObject", mock.Anything, "user-1/a", "hot-bucket", "cold-bucket").Return(true, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "hot-bucket", "user-1/a").Return(nil)
+	mockRepo.On("ObjectInUse", mock.Anything, "cold-bucket", "user-1/a").Return(false, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cold-bucket", "user-1/a").Return(nil)
+
+	migrated, err := svc.MigrateTiers(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_GetDownloadLink_RecordsAccess(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "hot-bucket",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+	svc.placement = testPlacementPolicy()
+	svc.placement.Migrations = []MigrationRule{{From: "hot", To: "cold", IdleFor: policyDuration(time.Hour)}}
+
+	downloadURL, _ := url.Parse("https://storage.example.com/download")
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-1", "user-1").Return(true, "user-1/a", "cold-bucket", nil)
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cold-bucket", "user-1/a", time.Hour, mock.Anything).Return(downloadURL, nil)
+	mockRepo.On("TouchAccessed", mock.Anything, "file-1").Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-1", "file-1", models.ActivityDownload).Return(nil)
+
+	_, err := svc.GetDownloadLink(context.Background(), &GetDownloadLinkInput{FileID: "file-1", UserID: "user-1"})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,63 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+)
+
+const purgeBatchSize = 100
+
+// RunTrashPurger permanently deletes expired trash every interval until ctx
+// is done.
+func (s *fileService) RunTrashPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpiredTrash(ctx)
+			if err != nil {
+				log.Printf("Trash purge failed: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Trash purge removed %d files", purged)
+			}
+		}
+	}
+}
+
+// PurgeExpiredTrash deletes files that have been in the trash longer than
+// their owner's retention period, falling back to the configured default.
+func (s *fileService) PurgeExpiredTrash(ctx context.Context) (int, error) {
+	purged := 0
+	for {
+		files, err := s.fileRepo.ListExpiredTrash(ctx, s.config.Trash.RetentionDays, purgeBatchSize)
+		if err != nil {
+			return purged, fmt.Errorf("failed to list expired trash: %w", err)
+		}
+
+		failed := 0
+		for _, file := range files {
+			if err := s.purgeFile(ctx, file); err != nil {
+				log.Printf("Failed to purge file %s: %v", file.ID, err)
+				metrics.RecordFileOperation("trash_purge", "error")
+				failed++
+				continue
+			}
+			metrics.RecordFileOperation("trash_purge", "success")
+			purged++
+		}
+
+		if len(files) < purgeBatchSize || failed == len(files) {
+			return purged, nil
+		}
+	}
+}
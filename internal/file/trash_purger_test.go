@@ -0,0 +1,110 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileService_PurgeExpiredTrash_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Trash: configs.TrashConfig{
+			RetentionDays: 30,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	expired := []*models.File{
+		{ID: "file-1", UserID: "user-1", StoragePath: "objects/file-1", Bucket: "cloud-storage", Size: 10, IsTrashed: true},
+		{ID: "file-2", UserID: "user-2", StoragePath: "objects/file-2", Bucket: "cloud-storage", Size: 20, IsTrashed: true},
+	}
+
+	mockRepo.On("ListExpiredTrash", mock.Anything, 30, purgeBatchSize).Return(expired, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-1").Return(nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-2").Return(errors.New("storage unavailable"))
+	mockVersions.On("ListByFileID", mock.Anything, mock.Anything).Return([]*models.FileVersion{}, nil)
+	mockRepo.On("Delete", mock.Anything, "file-1", "user-1").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-1", "file-1", int64(10)).Return(nil)
+
+	purged, err := svc.PurgeExpiredTrash(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, "file-2", "user-2")
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_PurgeExpiredTrash_ListError(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		Trash: configs.TrashConfig{
+			RetentionDays: 30,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("ListExpiredTrash", mock.Anything, 30, purgeBatchSize).Return(nil, errors.New("db error"))
+
+	purged, err := svc.PurgeExpiredTrash(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, purged)
+}
+
+func TestFileService_SetTrashRetention(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewFileService(Dependencies{Files: mockRepo}, new(MockBlobStorage), new(MockPresignedURLGenerator), &configs.Config{})
+
+	mockRepo.On("SetTrashRetention", mock.Anything, "user-1", 7).Return(nil)
+
+	output, err := svc.SetTrashRetention(context.Background(), &SetTrashRetentionInput{UserID: "user-1", Days: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, output.Days)
+
+	_, err = svc.SetTrashRetention(context.Background(), &SetTrashRetentionInput{UserID: "user-1", Days: -1})
+	assert.Error(t, err)
+	mockRepo.AssertNumberOfCalls(t, "SetTrashRetention", 1)
+}
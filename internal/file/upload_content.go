@@ -0,0 +1,368 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errUploadTooLarge = errors.New("upload exceeds the allowed size")
+
+// storedContent is what putContent actually wrote to the storage. SHA256
+// is the hex digest the content is deduplicated by.
+type storedContent struct {
+	Size              int64
+	ChecksumAlgorithm string
+	Checksum          string
+	SHA256            string
+}
+
+// uploadReader hashes a proxied body on its way to the storage and fails
+// once more than limit bytes arrive. When hash is not a SHA-256, digest
+// additionally computes one for deduplication.
+type uploadReader struct {
+	body     io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+	hash     hash.Hash
+	digest   hash.Hash
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		r.exceeded = true
+		return 0, errUploadTooLarge
+	}
+	r.hash.Write(p[:n])
+	if r.digest != nil {
+		r.digest.Write(p[:n])
+	}
+	return n, err
+}
+
+// UploadContent stores a body streamed through the service and finalizes
+// the metadata in the same call, so no CompleteUpload is needed.
+func (s *fileService) UploadContent(ctx context.Context, input *UploadContentInput) (output *UploadContentOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("upload_content", status)
+	}()
+
+	if input.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if input.Size < -1 {
+		return nil, fmt.Errorf("size cannot be negative")
+	}
+	if input.Size > s.config.Uploads.ProxyMaxSize {
+		return nil, status.Errorf(codes.InvalidArgument, "upload exceeds the limit of %d bytes", s.config.Uploads.ProxyMaxSize)
+	}
+
+	if input.FileID == "" {
+		return s.uploadNewFile(ctx, input)
+	}
+
+	file, err := s.getOwnedFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UploadState == models.UploadStatePending {
+		return s.uploadPendingFile(ctx, file, input)
+	}
+	return s.uploadNewVersion(ctx, input)
+}
+
+func (s *fileService) uploadNewFile(ctx context.Context, input *UploadContentInput) (*UploadContentOutput, error) {
+	fileInput := input.InitiateUploadInput
+	limit, err := s.uploadLimit(ctx, input.UserID, input.Size)
+	if err != nil {
+		return nil, err
+	}
+	fileInput.Size = limit
+
+	file, err := s.createPendingFile(ctx, &fileInput)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.putContent(ctx, file.Bucket, file.StoragePath, file.MimeType, input.Size, limit, file.ChecksumAlgorithm, file.Checksum, file.KeyOwnerID, input.Body)
+	if err != nil {
+		return nil, s.discardFile(ctx, file, err)
+	}
+	if err := s.activateContent(ctx, file, stored); err != nil {
+		return nil, s.discardFile(ctx, file, err)
+	}
+	return fileUploadOutput(file), nil
+}
+
+// uploadPendingFile fills a file created by InitiateUpload. The body has to
+// match the declared size, if one was declared; a failed attempt leaves the
+// upload pending so it can be retried.
+func (s *fileService) uploadPendingFile(ctx context.Context, file *models.File, input *UploadContentInput) (*UploadContentOutput, error) {
+	if file.BlobHash != "" {
+		// The content is stored already; writing it again would overwrite
+		// an object other files may share.
+		return nil, status.Error(codes.AlreadyExists, "upload content is already stored")
+	}
+	if file.Size > s.config.Uploads.ProxyMaxSize {
+		return nil, status.Errorf(codes.InvalidArgument, "upload exceeds the limit of %d bytes", s.config.Uploads.ProxyMaxSize)
+	}
+	size, limit := file.Size, file.Size
+	if file.Size == 0 {
+		// Nothing was declared or reserved, so the body is taken like one
+		// for a new file and checked against the quota on commit.
+		var err error
+		size = input.Size
+		if limit, err = s.uploadLimit(ctx, file.UserID, input.Size); err != nil {
+			return nil, err
+		}
+	} else if input.Size >= 0 && input.Size != file.Size {
+		return nil, status.Errorf(codes.InvalidArgument, "size mismatch: declared %d, sent %d", file.Size, input.Size)
+	}
+
+	stored, err := s.putContent(ctx, file.Bucket, file.StoragePath, file.MimeType, size, limit, file.ChecksumAlgorithm, file.Checksum, file.KeyOwnerID, input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.activateContent(ctx, file, stored); err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			if rmErr := s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath); rmErr != nil {
+				return nil, fmt.Errorf("%w (failed to remove object: %v)", err, rmErr)
+			}
+		}
+		return nil, err
+	}
+	return fileUploadOutput(file), nil
+}
+
+func (s *fileService) uploadNewVersion(ctx context.Context, input *UploadContentInput) (*UploadContentOutput, error) {
+	versionInput := input.InitiateUploadInput
+	limit, err := s.uploadLimit(ctx, input.UserID, input.Size)
+	if err != nil {
+		return nil, err
+	}
+	versionInput.Size = limit
+
+	version, err := s.createPendingVersion(ctx, &versionInput)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.putContent(ctx, version.Bucket, version.StoragePath, version.MimeType, input.Size, limit, version.ChecksumAlgorithm, version.Checksum, version.KeyOwnerID, input.Body)
+	if err != nil {
+		return nil, s.discardVersion(ctx, version, err)
+	}
+
+	version.Size = stored.Size
+	version.ChecksumAlgorithm = stored.ChecksumAlgorithm
+	version.Checksum = stored.Checksum
+	s.dedupVersion(ctx, version, stored.SHA256)
+
+	// The quota is committed first: until Promote succeeds the version is
+	// still pending and can be removed as a whole on failure.
+	if err := s.quotaRepo.Commit(ctx, version.ID, version.Size); err != nil {
+		if errors.Is(err, models.ErrQuotaExceeded) {
+			return nil, quotaError(s.discardVersion(ctx, version, fmt.Errorf("upload rejected: %w", err)))
+		}
+		return nil, s.discardVersion(ctx, version, fmt.Errorf("failed to commit quota: %w", err))
+	}
+	current, err := s.versionRepo.Promote(ctx, version)
+	if errors.Is(err, models.ErrVersionConflict) {
+		// Someone else completed or removed the version meanwhile.
+		return nil, versionConflictError(fmt.Errorf("failed to activate version: %w", err))
+	}
+	if err != nil {
+		return nil, s.discardVersion(ctx, version, fmt.Errorf("failed to activate version: %w", err))
+	}
+	s.schedulePreview(ctx, version.FileID, version.MimeType)
+	s.scheduleTextExtraction(ctx, version.FileID, version.MimeType)
+	s.recordActivity(ctx, version.UserID, version.FileID, models.ActivityUpload)
+
+	return &UploadContentOutput{
+		FileID:            version.FileID,
+		VersionID:         version.ID,
+		StoragePath:       version.StoragePath,
+		Size:              version.Size,
+		MimeType:          version.MimeType,
+		ChecksumAlgorithm: version.ChecksumAlgorithm,
+		Checksum:          version.Checksum,
+		Version:           current,
+		CreatedAt:         version.CreatedAt,
+	}, nil
+}
+
+// discardFile removes a file whose upload failed with err, together with its
+// object and reservation, and returns err, noting a failed removal. Unlike
+// uploads left to the reaper, nobody can retry it, as its id was never
+// returned.
+func (s *fileService) discardFile(ctx context.Context, file *models.File, err error) error {
+	if file.BlobHash == "" {
+		if rmErr := s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath); rmErr != nil {
+			return fmt.Errorf("%w (failed to remove object: %v)", err, rmErr)
+		}
+	}
+	if rmErr := s.fileRepo.Delete(ctx, file.ID, file.UserID); rmErr != nil {
+		return fmt.Errorf("%w (failed to delete metadata: %v)", err, rmErr)
+	}
+	if file.BlobHash != "" {
+		if rmErr := s.releaseBlob(ctx, file.BlobHash); rmErr != nil {
+			return fmt.Errorf("%w (%v)", err, rmErr)
+		}
+	}
+	if rmErr := s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size); rmErr != nil {
+		return fmt.Errorf("%w (failed to release quota: %v)", err, rmErr)
+	}
+	return err
+}
+
+// discardVersion removes a version whose upload failed with err and returns
+// err, noting a failed removal.
+func (s *fileService) discardVersion(ctx context.Context, version *models.FileVersion, err error) error {
+	if rmErr := s.deleteVersion(ctx, version); rmErr != nil {
+		return fmt.Errorf("%w (failed to remove version: %v)", err, rmErr)
+	}
+	return err
+}
+
+// uploadLimit returns how many bytes an upload may send, which is also what
+// gets reserved for it. Without a declared size that is whatever is left of
+// the user's quota, capped at the proxy limit.
+func (s *fileService) uploadLimit(ctx context.Context, userID string, size int64) (int64, error) {
+	if size >= 0 {
+		return size, nil
+	}
+	usage, err := s.quotaRepo.GetUsage(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get usage: %w", err)
+	}
+	remaining := usage.LimitBytes - usage.UsedBytes - usage.ReservedBytes
+	if remaining <= 0 {
+		return 0, status.Error(codes.ResourceExhausted, models.ErrQuotaExceeded.Error())
+	}
+	return min(remaining, s.config.Uploads.ProxyMaxSize), nil
+}
+
+// putContent writes body to the storage while hashing it. With a known size
+// the body has to be exactly that long; otherwise at most limit bytes are
+// accepted. A body that does not match its declared checksum is removed.
+// Content is encrypted with the key of keyOwnerID, if set.
+func (s *fileService) putContent(ctx context.Context, bucket, object, mimeType string, size, limit int64, checksumAlgorithm, checksum, keyOwnerID string, body io.Reader) (*storedContent, error) {
+	sse, err := s.objectEncryption(ctx, keyOwnerID, object)
+	if err != nil {
+		return nil, err
+	}
+	reader := &uploadReader{body: body, limit: limit, hash: newChecksumHash(checksumAlgorithm)}
+	digest := reader.hash
+	if checksumAlgorithm != "" && checksumAlgorithm != ChecksumSHA256 {
+		digest = sha256.New()
+		reader.digest = digest
+	}
+	opts := storage.PutObjectOptions{ContentType: mimeType, Encryption: sse}
+	if size < 0 {
+		opts.PartSize = defaultPartSize
+	}
+
+	info, err := s.storage.PutObject(ctx, bucket, object, reader, size, opts)
+	if reader.exceeded {
+		switch {
+		case size >= 0:
+			return nil, status.Errorf(codes.InvalidArgument, "body is longer than the declared %d bytes", size)
+		case limit < s.config.Uploads.ProxyMaxSize:
+			return nil, status.Error(codes.ResourceExhausted, models.ErrQuotaExceeded.Error())
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "upload exceeds the limit of %d bytes", limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store content: %w", err)
+	}
+
+	stored := &storedContent{
+		Size:              info.Size,
+		ChecksumAlgorithm: checksumAlgorithm,
+		Checksum:          base64.StdEncoding.EncodeToString(reader.hash.Sum(nil)),
+		SHA256:            hex.EncodeToString(digest.Sum(nil)),
+	}
+	if stored.ChecksumAlgorithm == "" {
+		stored.ChecksumAlgorithm = ChecksumSHA256
+	}
+
+	var rejected error
+	if size >= 0 {
+		if _, err := io.ReadFull(body, make([]byte, 1)); err != io.EOF {
+			rejected = fmt.Errorf("body is longer than the declared %d bytes", size)
+		}
+	}
+	if rejected == nil && checksumAlgorithm != "" && stored.Checksum != checksum {
+		rejected = fmt.Errorf("%s checksum mismatch", checksumAlgorithm)
+	}
+	if rejected != nil {
+		if rmErr := s.storage.RemoveObject(ctx, bucket, object); rmErr != nil {
+			return nil, fmt.Errorf("upload rejected: %w (failed to remove object: %v)", rejected, rmErr)
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "upload rejected: %v", rejected)
+	}
+	return stored, nil
+}
+
+// activateContent records what putContent stored and makes the file
+// available.
+func (s *fileService) activateContent(ctx context.Context, file *models.File, stored *storedContent) error {
+	file.Size = stored.Size
+	if err := s.fileRepo.SetObjectInfo(ctx, file.ID, file.Size, file.MimeType); err != nil {
+		return fmt.Errorf("failed to reconcile metadata: %w", err)
+	}
+	if err := s.fileRepo.SetChecksum(ctx, file.ID, stored.ChecksumAlgorithm, stored.Checksum); err != nil {
+		return fmt.Errorf("failed to save checksum: %w", err)
+	}
+	file.ChecksumAlgorithm = stored.ChecksumAlgorithm
+	file.Checksum = stored.Checksum
+
+	// Committing before activation keeps a file that does not fit the quota
+	// pending, so the caller can still remove it.
+	if err := s.quotaRepo.Commit(ctx, file.ID, file.Size); err != nil {
+		if errors.Is(err, models.ErrQuotaExceeded) {
+			return status.Errorf(codes.ResourceExhausted, "upload rejected: %v", err)
+		}
+		return fmt.Errorf("failed to commit quota: %w", err)
+	}
+	s.dedupFile(ctx, file, stored.SHA256)
+
+	if err := s.fileRepo.SetUploadState(ctx, file.ID, models.UploadStateActive); err != nil {
+		return fmt.Errorf("failed to activate file: %w", err)
+	}
+	file.UploadState = models.UploadStateActive
+	s.schedulePreview(ctx, file.ID, file.MimeType)
+	s.scheduleTextExtraction(ctx, file.ID, file.MimeType)
+	s.recordActivity(ctx, file.UserID, file.ID, models.ActivityUpload)
+	return nil
+}
+
+func fileUploadOutput(file *models.File) *UploadContentOutput {
+	return &UploadContentOutput{
+		FileID:            file.ID,
+		StoragePath:       file.StoragePath,
+		Size:              file.Size,
+		MimeType:          file.MimeType,
+		ChecksumAlgorithm: file.ChecksumAlgorithm,
+		Checksum:          file.Checksum,
+		Version:           1,
+		CreatedAt:         file.CreatedAt,
+	}
+}
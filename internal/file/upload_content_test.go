@@ -0,0 +1,570 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sha256 of "hello world", base64 and hex encoded
+const (
+	helloWorldSHA256 = "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="
+	helloWorldHex    = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+)
+
+// drainBody makes a PutObject mock read the whole body, as the storage
+// client does.
+func drainBody(args mock.Arguments) {
+	_, _ = io.Copy(io.Discard, args.Get(3).(io.Reader))
+}
+
+func TestFileService_UploadContent_NewFile(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("GetUsage", mock.Anything, "user-123").Return(&models.StorageUsage{LimitBytes: 100, UsedBytes: 10}, nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(90)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.OriginalName == "hello.txt" && f.Path == "/docs" && f.UploadState == models.UploadStatePending
+	})).Return(nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", mock.Anything, mock.Anything, int64(-1), storage.PutObjectOptions{ContentType: "text/plain", PartSize: defaultPartSize}).
+		Run(drainBody).Return(storage.UploadInfo{Size: 11}, nil)
+	mockRepo.On("SetObjectInfo", mock.Anything, mock.Anything, int64(11), "text/plain").Return(nil)
+	mockRepo.On("SetChecksum", mock.Anything, mock.Anything, ChecksumSHA256, helloWorldSHA256).Return(nil)
+	mockBlobs.On("AttachFile", mock.Anything, mock.Anything, mock.MatchedBy(func(b *models.Blob) bool {
+		return b.Hash == helloWorldHex && b.Size == 11
+	})).Return(nil, nil)
+	mockRepo.On("SetUploadState", mock.Anything, mock.Anything, models.UploadStateActive).Return(nil)
+	mockQuota.On("Commit", mock.Anything, mock.Anything, int64(11)).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", mock.Anything, models.ActivityUpload).Return(nil)
+
+	output, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{
+			UserID:   "user-123",
+			Filename: "hello.txt",
+			Path:     "/docs",
+			MimeType: "text/plain",
+			Size:     -1,
+		},
+		Body: strings.NewReader("hello world"),
+	})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, output.FileID)
+	assert.Equal(t, int64(11), output.Size)
+	assert.Equal(t, ChecksumSHA256, output.ChecksumAlgorithm)
+	assert.Equal(t, helloWorldSHA256, output.Checksum)
+	assert.Equal(t, 1, output.Version)
+	mockRepo.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_UploadContent_ExceedsQuota(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockQuota.On("GetUsage", mock.Anything, "user-123").Return(&models.StorageUsage{LimitBytes: 15, UsedBytes: 10}, nil)
+	mockQuota.On("Reserve", mock.Anything, "user-123", mock.Anything, int64(5)).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", mock.Anything, mock.Anything, int64(-1), mock.Anything).
+		Run(drainBody).Return(storage.UploadInfo{}, errUploadTooLarge)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", mock.Anything).Return(nil)
+	mockRepo.On("Delete", mock.Anything, mock.Anything, "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", mock.Anything, int64(5)).Return(nil)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", Filename: "hello.txt", Path: "/", Size: -1},
+		Body:                strings.NewReader("hello world"),
+	})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	mockRepo.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertNotCalled(t, "Commit", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SetUploadState", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadContent_SizeLimit(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", Filename: "big.bin", Path: "/", Size: 2 << 20},
+		Body:                strings.NewReader(""),
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockQuota.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadContent_PendingFileChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:                "file-123",
+		UserID:            "user-123",
+		Size:              11,
+		MimeType:          "text/plain",
+		StoragePath:       "objects/file-123",
+		Bucket:            "cloud-storage",
+		ChecksumAlgorithm: ChecksumSHA256,
+		Checksum:          "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		UploadState:       models.UploadStatePending,
+	}
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything, int64(11), storage.PutObjectOptions{ContentType: "text/plain"}).
+		Run(drainBody).Return(storage.UploadInfo{Size: 11}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", FileID: "file-123", Size: 11},
+		Body:                strings.NewReader("hello world"),
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	mockStorage.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetUploadState", mock.Anything, mock.Anything, mock.Anything)
+	mockQuota.AssertNotCalled(t, "Commit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadContent_PendingFileSizeMismatch(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		Size:        5,
+		UploadState: models.UploadStatePending,
+	}, nil)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", FileID: "file-123", Size: 11},
+		Body:                strings.NewReader("hello world"),
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockStorage.AssertNotCalled(t, "PutObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadContent_PendingFileSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		Size:        1 << 40,
+		UploadState: models.UploadStatePending,
+	}, nil)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", FileID: "file-123", Size: -1},
+		Body:                strings.NewReader("hello world"),
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockStorage.AssertNotCalled(t, "PutObject", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadContent_PendingFileUnknownSize(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		MimeType:    "text/plain",
+		StoragePath: "objects/file-123",
+		Bucket:      "cloud-storage",
+		UploadState: models.UploadStatePending,
+	}, nil)
+	mockQuota.On("GetUsage", mock.Anything, "user-123").Return(&models.StorageUsage{LimitBytes: 100, UsedBytes: 10}, nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", "objects/file-123", mock.Anything, int64(-1), storage.PutObjectOptions{ContentType: "text/plain", PartSize: defaultPartSize}).
+		Run(drainBody).Return(storage.UploadInfo{Size: 11}, nil)
+	mockRepo.On("SetObjectInfo", mock.Anything, "file-123", int64(11), "text/plain").Return(nil)
+	mockRepo.On("SetChecksum", mock.Anything, "file-123", ChecksumSHA256, helloWorldSHA256).Return(nil)
+	mockQuota.On("Commit", mock.Anything, "file-123", int64(11)).Return(models.ErrQuotaExceeded)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", FileID: "file-123", Size: -1},
+		Body:                strings.NewReader("hello world"),
+	})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SetUploadState", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadContent_NewVersion(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "hello.txt",
+		MimeType:     "text/plain",
+		UploadState:  models.UploadStateActive,
+	}, nil)
+	mockQuota.On("ReserveBytes", mock.Anything, "user-123", mock.Anything, int64(11)).Return(nil)
+	mockVersions.On("Create", mock.Anything, mock.MatchedBy(func(v *models.FileVersion) bool {
+		return v.FileID == "file-123" && v.MimeType == "text/plain"
+	})).Return(nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", mock.Anything, mock.Anything, int64(11), storage.PutObjectOptions{ContentType: "text/plain"}).
+		Run(drainBody).Return(storage.UploadInfo{Size: 11}, nil)
+	mockBlobs.On("AttachVersion", mock.Anything, mock.Anything, mock.MatchedBy(func(b *models.Blob) bool {
+		return b.Hash == helloWorldHex
+	})).Return(nil, nil)
+	mockVersions.On("Promote", mock.Anything, mock.MatchedBy(func(v *models.FileVersion) bool {
+		return v.Size == 11 && v.ChecksumAlgorithm == ChecksumSHA256 && v.Checksum == helloWorldSHA256
+	})).Return(2, nil)
+	mockQuota.On("Commit", mock.Anything, mock.Anything, int64(11)).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{
+			UserID:            "user-123",
+			FileID:            "file-123",
+			Size:              11,
+			ChecksumAlgorithm: "sha-256",
+			Checksum:          helloWorldSHA256,
+		},
+		Body: strings.NewReader("hello world"),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "file-123", output.FileID)
+	assert.NotEmpty(t, output.VersionID)
+	assert.Equal(t, 2, output.Version)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_UploadContent_NewVersionPromoteFailureRemovesVersion(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "hello.txt",
+		MimeType:     "text/plain",
+		UploadState:  models.UploadStateActive,
+	}, nil)
+	mockQuota.On("ReserveBytes", mock.Anything, "user-123", mock.Anything, int64(11)).Return(nil)
+	mockVersions.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", mock.Anything, mock.Anything, int64(11), mock.Anything).
+		Run(drainBody).Return(storage.UploadInfo{Size: 11}, nil)
+	mockBlobs.On("AttachVersion", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("database unavailable"))
+	mockQuota.On("Commit", mock.Anything, mock.Anything, int64(11)).Return(nil)
+	mockVersions.On("Promote", mock.Anything, mock.Anything).Return(0, errors.New("database unavailable"))
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", mock.Anything).Return(nil)
+	mockVersions.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", mock.Anything, int64(11)).Return(nil)
+
+	output, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{
+			UserID: "user-123",
+			FileID: "file-123",
+			Size:   11,
+		},
+		Body: strings.NewReader("hello world"),
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestFileService_UploadContent_NewVersionTooLong(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			ProxyMaxSize: 1 << 20,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(&models.File{
+		ID:          "file-123",
+		UserID:      "user-123",
+		UploadState: models.UploadStateActive,
+	}, nil)
+	mockQuota.On("ReserveBytes", mock.Anything, "user-123", mock.Anything, int64(5)).Return(nil)
+	mockVersions.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("PutObject", mock.Anything, "cloud-storage", mock.Anything, mock.Anything, int64(5), mock.Anything).
+		Run(drainBody).Return(storage.UploadInfo{}, errUploadTooLarge)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", mock.Anything).Return(nil)
+	mockVersions.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", mock.Anything, int64(5)).Return(nil)
+
+	_, err := svc.UploadContent(context.Background(), &UploadContentInput{
+		InitiateUploadInput: InitiateUploadInput{UserID: "user-123", FileID: "file-123", Size: 5},
+		Body:                strings.NewReader("hello world"),
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "longer than the declared 5 bytes")
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+	mockVersions.AssertNotCalled(t, "Promote", mock.Anything, mock.Anything)
+}
+
+func TestNewChecksumHash(t *testing.T) {
+	t.Parallel()
+
+	sum := func(algorithm string) string {
+		reader := &uploadReader{body: strings.NewReader("hello world"), limit: 100, hash: newChecksumHash(algorithm)}
+		_, _ = io.Copy(io.Discard, reader)
+		return base64.StdEncoding.EncodeToString(reader.hash.Sum(nil))
+	}
+
+	assert.Equal(t, helloWorldSHA256, sum(ChecksumSHA256))
+	assert.Equal(t, "XrY7u+Ae7tCTyyK7j1rNww==", sum(ChecksumMD5))
+	assert.Equal(t, "yZRlqg==", sum(ChecksumCRC32C))
+}
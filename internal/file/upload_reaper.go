@@ -0,0 +1,151 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+)
+
+const (
+	// Single PUT uploads get this much time on top of the presigned URL TTL
+	// before they are considered abandoned.
+	pendingUploadGracePeriod = 15 * time.Minute
+
+	reapBatchSize = 100
+)
+
+// RunUploadReaper removes abandoned uploads every interval until ctx is done.
+func (s *fileService) RunUploadReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := s.ReapAbandonedUploads(ctx)
+			if err != nil {
+				log.Printf("Upload reaper failed: %v", err)
+				continue
+			}
+			if reaped > 0 {
+				log.Printf("Upload reaper removed %d abandoned uploads", reaped)
+			}
+		}
+	}
+}
+
+// ReapAbandonedUploads deletes pending and failed rows whose upload window
+// has passed, together with any partial object and the quota they reserved.
+// Abandoned uploads of new file versions are removed the same way.
+func (s *fileService) ReapAbandonedUploads(ctx context.Context) (int, error) {
+	now := time.Now()
+	before := now.Add(-presignedUploadTTL - pendingUploadGracePeriod)
+	multipartBefore := now.Add(-s.config.Uploads.MultipartTTL)
+
+	reaped, err := s.reapStaleFiles(ctx, before, multipartBefore)
+	if err != nil {
+		return reaped, err
+	}
+	versions, err := s.reapStaleVersions(ctx, before)
+	return reaped + versions, err
+}
+
+func (s *fileService) reapStaleFiles(ctx context.Context, before, multipartBefore time.Time) (int, error) {
+	reaped := 0
+	for {
+		uploads, err := s.fileRepo.ListStaleUploads(ctx, before, multipartBefore, reapBatchSize)
+		if err != nil {
+			return reaped, fmt.Errorf("failed to list stale uploads: %w", err)
+		}
+
+		failed := 0
+		for _, upload := range uploads {
+			if err := s.reapUpload(ctx, upload); err != nil {
+				log.Printf("Failed to reap upload %s: %v", upload.File.ID, err)
+				metrics.RecordUploadGCError()
+				failed++
+				continue
+			}
+			reaped++
+		}
+
+		// Stop on a short batch, or when nothing in a full batch could be
+		// removed, to avoid spinning on the same rows.
+		if len(uploads) < reapBatchSize || failed == len(uploads) {
+			return reaped, nil
+		}
+	}
+}
+
+func (s *fileService) reapStaleVersions(ctx context.Context, before time.Time) (int, error) {
+	reaped := 0
+	for {
+		versions, err := s.versionRepo.ListStale(ctx, before, reapBatchSize)
+		if err != nil {
+			return reaped, fmt.Errorf("failed to list stale versions: %w", err)
+		}
+
+		failed := 0
+		for _, version := range versions {
+			if err := s.deleteVersion(ctx, version); err != nil {
+				log.Printf("Failed to reap version upload %s: %v", version.ID, err)
+				metrics.RecordUploadGCError()
+				failed++
+				continue
+			}
+			metrics.RecordUploadGCCleaned("version")
+			reaped++
+		}
+
+		if len(versions) < reapBatchSize || failed == len(versions) {
+			return reaped, nil
+		}
+	}
+}
+
+func (s *fileService) reapUpload(ctx context.Context, upload *models.StaleUpload) error {
+	file := upload.File
+
+	if upload.UploadID != "" {
+		if err := s.storage.AbortMultipartUpload(ctx, file.Bucket, file.StoragePath, upload.UploadID); err != nil {
+			if !errors.Is(err, storage.ErrUploadNotFound) {
+				return fmt.Errorf("failed to abort multipart upload: %w", err)
+			}
+		}
+		metrics.RecordUploadGCCleaned("multipart")
+	}
+
+	// A single PUT may have landed without CompleteUpload being called. An
+	// upload that already got attached to a blob must not take the shared
+	// object with it.
+	if file.BlobHash == "" {
+		if err := s.storage.RemoveObject(ctx, file.Bucket, file.StoragePath); err != nil {
+			return fmt.Errorf("failed to remove object: %w", err)
+		}
+		metrics.RecordUploadGCCleaned("object")
+	}
+
+	if err := s.fileRepo.Delete(ctx, file.ID, file.UserID); err != nil {
+		return fmt.Errorf("failed to delete metadata: %w", err)
+	}
+	metrics.RecordUploadGCCleaned(file.UploadState)
+
+	if file.BlobHash != "" {
+		if err := s.releaseBlob(ctx, file.BlobHash); err != nil {
+			return err
+		}
+	}
+
+	if err := s.quotaRepo.Release(ctx, file.UserID, file.ID, file.Size); err != nil {
+		return fmt.Errorf("failed to release quota: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileService_ReapAbandonedUploads_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+		Uploads: configs.UploadsConfig{
+			MultipartTTL: 24 * time.Hour,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	stale := []*models.StaleUpload{
+		{
+			File: &models.File{
+				ID:          "file-1",
+				UserID:      "user-123",
+				StoragePath: "objects/file-1",
+				Bucket:      "cloud-storage",
+				Size:        1024,
+				UploadState: models.UploadStatePending,
+			},
+		},
+		{
+			File: &models.File{
+				ID:          "file-2",
+				UserID:      "user-123",
+				StoragePath: "objects/file-2",
+				Bucket:      "cloud-storage",
+				Size:        64 << 20,
+				UploadState: models.UploadStatePending,
+			},
+			UploadID: "upload-abc",
+		},
+	}
+
+	mockRepo.On("ListStaleUploads", mock.Anything, mock.Anything, mock.Anything, reapBatchSize).Return(stale, nil)
+	mockVersions.On("ListStale", mock.Anything, mock.Anything, reapBatchSize).Return([]*models.FileVersion{}, nil)
+	mockStorage.On("AbortMultipartUpload", mock.Anything, "cloud-storage", "objects/file-2", "upload-abc").Return(nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-1").Return(nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-2").Return(nil)
+	mockRepo.On("Delete", mock.Anything, "file-1", "user-123").Return(nil)
+	mockRepo.On("Delete", mock.Anything, "file-2", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-1", int64(1024)).Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-2", int64(64<<20)).Return(nil)
+
+	reaped, err := svc.ReapAbandonedUploads(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reaped)
+	mockRepo.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_ReapAbandonedUploads_KeepsRowWhenStorageFails(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	stale := []*models.StaleUpload{
+		{
+			File: &models.File{
+				ID:          "file-1",
+				UserID:      "user-123",
+				StoragePath: "objects/file-1",
+				Bucket:      "cloud-storage",
+				UploadState: models.UploadStateFailed,
+			},
+		},
+	}
+
+	mockRepo.On("ListStaleUploads", mock.Anything, mock.Anything, mock.Anything, reapBatchSize).Return(stale, nil)
+	mockVersions.On("ListStale", mock.Anything, mock.Anything, reapBatchSize).Return([]*models.FileVersion{}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-1").Return(errors.New("storage unavailable"))
+
+	reaped, err := svc.ReapAbandonedUploads(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, reaped)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	mockQuota.AssertNotCalled(t, "Release", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_ReapAbandonedUploads_ListError(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	mockRepo.On("ListStaleUploads", mock.Anything, mock.Anything, mock.Anything, reapBatchSize).Return(nil, errors.New("db error"))
+
+	reaped, err := svc.ReapAbandonedUploads(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list stale uploads")
+	assert.Equal(t, 0, reaped)
+}
@@ -0,0 +1,61 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+)
+
+// RunVersionPruner removes versions outside the retention policy every
+// interval until ctx is done.
+func (s *fileService) RunVersionPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pruned, err := s.PruneVersions(ctx)
+			if err != nil {
+				log.Printf("Version pruning failed: %v", err)
+				continue
+			}
+			if pruned > 0 {
+				log.Printf("Version pruning removed %d versions", pruned)
+			}
+		}
+	}
+}
+
+// PruneVersions deletes superseded versions beyond the configured maximum
+// count per file or older than the configured maximum age.
+func (s *fileService) PruneVersions(ctx context.Context) (int, error) {
+	pruned := 0
+	for {
+		versions, err := s.versionRepo.ListExpired(ctx, s.config.Versions.MaxVersions, s.config.Versions.MaxAge, purgeBatchSize)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to list expired versions: %w", err)
+		}
+
+		failed := 0
+		for _, version := range versions {
+			if err := s.deleteVersion(ctx, version); err != nil {
+				log.Printf("Failed to prune version %s: %v", version.ID, err)
+				metrics.RecordFileOperation("version_prune", "error")
+				failed++
+				continue
+			}
+			metrics.RecordFileOperation("version_prune", "success")
+			pruned++
+		}
+
+		if len(versions) < purgeBatchSize || failed == len(versions) {
+			return pruned, nil
+		}
+	}
+}
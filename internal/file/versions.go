@@ -0,0 +1,360 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// initiateVersionUpload starts the upload of new content for an existing
+// file. The file keeps serving its current content until the upload is
+// completed.
+func (s *fileService) initiateVersionUpload(ctx context.Context, input *InitiateUploadInput) (*InitiateUploadOutput, error) {
+	version, err := s.createPendingVersion(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	presignedURL, headers, err := s.presignUpload(ctx, version.Bucket, version.StoragePath, version.MimeType, version.Size, version.ChecksumAlgorithm, version.Checksum, version.KeyOwnerID)
+	if err != nil {
+		if rmErr := s.deleteVersion(ctx, version); rmErr != nil {
+			return nil, fmt.Errorf("%w (failed to remove version: %v)", err, rmErr)
+		}
+		return nil, err
+	}
+
+	return &InitiateUploadOutput{
+		FileID:       version.FileID,
+		VersionID:    version.ID,
+		UploadURL:    presignedURL,
+		UploadMethod: "PUT",
+		Headers:      headers,
+		ExpiresIn:    int64(presignedUploadTTL / time.Second),
+	}, nil
+}
+
+// createPendingVersion books the quota for new content of input.FileID and
+// records it as a pending version.
+func (s *fileService) createPendingVersion(ctx context.Context, input *InitiateUploadInput) (*models.FileVersion, error) {
+	checksumAlgorithm, err := normalizeChecksum(input.ChecksumAlgorithm, input.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum: %w", err)
+	}
+
+	file, err := s.getMovableFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := input.MimeType
+	if mimeType == "" {
+		mimeType = file.MimeType
+	}
+
+	uniqueFilename := generateUniqueFilename(file.OriginalName)
+	bucket := s.placement.bucketFor(file.UserID, mimeType, input.Size, file.Tags)
+	version := models.NewFileVersion(file, buildStoragePath(file.UserID, uniqueFilename), bucket, mimeType, input.Size)
+	version.ChecksumAlgorithm = checksumAlgorithm
+	if checksumAlgorithm != "" {
+		version.Checksum = input.Checksum
+	}
+	version.KeyOwnerID = s.keyOwnerFor(file.UserID)
+
+	if err := quotaError(s.quotaRepo.ReserveBytes(ctx, file.UserID, version.ID, version.Size)); err != nil {
+		return nil, err
+	}
+	if err := s.versionRepo.Create(ctx, version); err != nil {
+		_ = s.quotaRepo.ReleaseBytes(ctx, file.UserID, version.ID, version.Size)
+		return nil, fmt.Errorf("failed to create version: %w", err)
+	}
+
+	return version, nil
+}
+
+// completeVersionUpload verifies an uploaded version the same way as a new
+// file and makes it the current content. The replaced content is kept as
+// the newest entry of the file's history.
+func (s *fileService) completeVersionUpload(ctx context.Context, input *CompleteUploadInput) (*CompleteUploadOutput, error) {
+	version, err := s.versionRepo.GetByID(ctx, input.VersionID)
+	if err != nil {
+		return nil, fmt.Errorf("version not found: %w", err)
+	}
+	if version.FileID != input.FileID || version.UserID != input.UserID {
+		return nil, fmt.Errorf("access denied")
+	}
+	if version.UploadState != models.UploadStatePending {
+		return nil, fmt.Errorf("version upload is already complete")
+	}
+
+	sse, err := s.objectEncryption(ctx, version.KeyOwnerID, version.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.storage.StatObject(ctx, version.Bucket, version.StoragePath, storage.StatObjectOptions{Checksum: true, Encryption: sse})
+	if err != nil {
+		return nil, fmt.Errorf("file not found in storage: %w", err)
+	}
+	declared := &models.File{
+		Size:              version.Size,
+		MimeType:          version.MimeType,
+		ChecksumAlgorithm: version.ChecksumAlgorithm,
+		Checksum:          version.Checksum,
+		KeyOwnerID:        version.KeyOwnerID,
+	}
+	if err := verifyStoredObject(declared, info, input.ETag); err != nil {
+		if rmErr := s.deleteVersion(ctx, version); rmErr != nil {
+			return nil, fmt.Errorf("upload rejected: %w (failed to remove version: %v)", err, rmErr)
+		}
+		return nil, fmt.Errorf("upload rejected: %w", err)
+	}
+
+	version.Size = info.Size
+	if info.ContentType != "" {
+		version.MimeType = info.ContentType
+	}
+	if version.ChecksumAlgorithm == "" && version.KeyOwnerID == "" {
+		if checksum := md5FromETag(info.ETag); checksum != "" {
+			version.ChecksumAlgorithm = ChecksumMD5
+			version.Checksum = checksum
+		}
+	}
+
+	s.dedupVersion(ctx, version, "")
+
+	// As with proxied uploads the quota is committed before Promote, so a
+	// version that does not fit is removed while it is still pending.
+	if err := s.quotaRepo.Commit(ctx, version.ID, version.Size); err != nil {
+		if errors.Is(err, models.ErrQuotaExceeded) {
+			return nil, quotaError(s.discardVersion(ctx, version, fmt.Errorf("upload rejected: %w", err)))
+		}
+		return nil, s.discardVersion(ctx, version, fmt.Errorf("failed to commit quota: %w", err))
+	}
+	current, err := s.versionRepo.Promote(ctx, version)
+	if errors.Is(err, models.ErrVersionConflict) {
+		return nil, versionConflictError(fmt.Errorf("failed to activate version: %w", err))
+	}
+	if err != nil {
+		return nil, s.discardVersion(ctx, version, fmt.Errorf("failed to activate version: %w", err))
+	}
+	s.schedulePreview(ctx, version.FileID, version.MimeType)
+	s.scheduleTextExtraction(ctx, version.FileID, version.MimeType)
+	s.recordActivity(ctx, version.UserID, version.FileID, models.ActivityUpload)
+
+	return &CompleteUploadOutput{
+		StoragePath:       version.StoragePath,
+		CreatedAt:         version.CreatedAt,
+		ChecksumAlgorithm: version.ChecksumAlgorithm,
+		Checksum:          version.Checksum,
+		Version:           current,
+	}, nil
+}
+
+// ListVersions returns the current content of a file followed by its
+// retained history, newest first. The current entry carries the file id.
+func (s *fileService) ListVersions(ctx context.Context, input *ListVersionsInput) (output *ListVersionsOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("list_versions", status)
+	}()
+
+	file, err := s.getOwnedFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.versionRepo.GetCurrentVersion(ctx, file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current version: %w", err)
+	}
+	history, err := s.versionRepo.ListByFileID(ctx, file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	versions := []*models.FileVersion{{
+		ID:                file.ID,
+		FileID:            file.ID,
+		UserID:            file.UserID,
+		Version:           current,
+		Size:              file.Size,
+		MimeType:          file.MimeType,
+		StoragePath:       file.StoragePath,
+		Bucket:            file.Bucket,
+		ChecksumAlgorithm: file.ChecksumAlgorithm,
+		Checksum:          file.Checksum,
+		UploadState:       file.UploadState,
+		CreatedAt:         file.UpdatedAt,
+	}}
+	for _, version := range history {
+		if version.UploadState == models.UploadStateActive {
+			versions = append(versions, version)
+		}
+	}
+
+	return &ListVersionsOutput{CurrentVersion: current, Versions: versions}, nil
+}
+
+func (s *fileService) GetVersionDownloadLink(ctx context.Context, input *GetVersionDownloadLinkInput) (output *GetDownloadLinkOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("version_download", status)
+	}()
+
+	file, err := s.getOwnedFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, storagePath, keyOwnerID := file.Bucket, file.StoragePath, file.KeyOwnerID
+	if input.VersionID != file.ID {
+		version, err := s.getFileVersion(ctx, file, input.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		bucket, storagePath, keyOwnerID = version.Bucket, version.StoragePath, version.KeyOwnerID
+	}
+	sse, err := s.objectEncryption(ctx, keyOwnerID, storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := time.Hour
+	if input.ExpiresIn > 0 {
+		expires = time.Duration(input.ExpiresIn) * time.Second
+	}
+	downloadURL, headers, err := s.presignDownload(ctx, bucket, storagePath, expires, nil, sse)
+	if err != nil {
+		return nil, err
+	}
+	return &GetDownloadLinkOutput{
+		DownloadURL: downloadURL,
+		Method:      "GET",
+		Headers:     headers,
+		ExpiresIn:   int64(expires / time.Second),
+	}, nil
+}
+
+// RestoreVersion makes an old version current again. Its content gets a new
+// version number and the content it replaces joins the history, so no data
+// is copied and the quota does not change.
+func (s *fileService) RestoreVersion(ctx context.Context, input *RestoreVersionInput) (output *RestoreVersionOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("restore_version", status)
+	}()
+
+	file, err := s.getMovableFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if input.VersionID == file.ID {
+		return nil, fmt.Errorf("version is already current")
+	}
+	version, err := s.getFileVersion(ctx, file, input.VersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.versionRepo.Promote(ctx, version)
+	if err != nil {
+		return nil, versionConflictError(fmt.Errorf("failed to restore version: %w", err))
+	}
+	s.schedulePreview(ctx, file.ID, version.MimeType)
+	s.scheduleTextExtraction(ctx, file.ID, version.MimeType)
+	return &RestoreVersionOutput{CurrentVersion: current}, nil
+}
+
+func (s *fileService) DeleteVersion(ctx context.Context, input *DeleteVersionInput) (output *DeleteVersionOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordFileOperation("delete_version", status)
+	}()
+
+	file, err := s.getOwnedFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if input.VersionID == file.ID {
+		return nil, fmt.Errorf("cannot delete the current version")
+	}
+	version, err := s.getFileVersion(ctx, file, input.VersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.deleteVersion(ctx, version); err != nil {
+		return nil, err
+	}
+	return &DeleteVersionOutput{Success: true}, nil
+}
+
+// deleteVersion removes a version's content and row and gives its space
+// back. Content kept in a blob is released instead of removed.
+func (s *fileService) deleteVersion(ctx context.Context, version *models.FileVersion) error {
+	if version.BlobHash == "" {
+		if err := s.storage.RemoveObject(ctx, version.Bucket, version.StoragePath); err != nil {
+			return fmt.Errorf("failed to delete version from storage: %w", err)
+		}
+	}
+	if err := s.versionRepo.Delete(ctx, version.ID); err != nil {
+		return fmt.Errorf("failed to delete version: %w", err)
+	}
+	if version.BlobHash != "" {
+		if err := s.releaseBlob(ctx, version.BlobHash); err != nil {
+			return err
+		}
+	}
+	if err := s.quotaRepo.ReleaseBytes(ctx, version.UserID, version.ID, version.Size); err != nil {
+		return fmt.Errorf("failed to release quota: %w", err)
+	}
+	return nil
+}
+
+func versionConflictError(err error) error {
+	if errors.Is(err, models.ErrVersionConflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return err
+}
+
+func (s *fileService) getOwnedFile(ctx context.Context, fileID, userID string) (*models.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, fmt.Errorf("access denied")
+	}
+	return file, nil
+}
+
+// getFileVersion loads a completed version that belongs to file.
+func (s *fileService) getFileVersion(ctx context.Context, file *models.File, versionID string) (*models.FileVersion, error) {
+	version, err := s.versionRepo.GetByID(ctx, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("version not found: %w", err)
+	}
+	if version.FileID != file.ID || version.UploadState != models.UploadStateActive {
+		return nil, fmt.Errorf("version not found")
+	}
+	return version, nil
+}
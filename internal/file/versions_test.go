@@ -0,0 +1,896 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFileService_InitiateUpload_NewVersion(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockQuota.On("ReserveBytes", mock.Anything, "user-123", mock.Anything, int64(2048)).Return(nil)
+	mockVersions.On("Create", mock.Anything, mock.MatchedBy(func(v *models.FileVersion) bool {
+		return v.FileID == "file-123" && v.UploadState == models.UploadStatePending &&
+			v.MimeType == "application/pdf" && v.StoragePath != "objects/file-123"
+	})).Return(nil)
+
+	presignedURL, _ := url.Parse("https://storage.example.com/upload/version")
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, 15*time.Minute, mock.Anything, mock.MatchedBy(func(h http.Header) bool {
+		return h.Get("Content-Length") == "2048"
+	})).Return(presignedURL, nil)
+
+	output, err := svc.InitiateUpload(context.Background(), &InitiateUploadInput{
+		UserID: "user-123",
+		FileID: "file-123",
+		Size:   2048,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "file-123", output.FileID)
+	assert.NotEmpty(t, output.VersionID)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockQuota.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_InitiateUpload_NewVersionQuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockQuota.On("ReserveBytes", mock.Anything, "user-123", mock.Anything, int64(2048)).Return(models.ErrQuotaExceeded)
+
+	output, err := svc.InitiateUpload(context.Background(), &InitiateUploadInput{
+		UserID: "user-123",
+		FileID: "file-123",
+		Size:   2048,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockVersions.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestFileService_InitiateUpload_NewVersionPresignFailureRemovesVersion(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockQuota.On("ReserveBytes", mock.Anything, "user-123", mock.Anything, int64(2048)).Return(nil)
+	mockVersions.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockPresigned.On("PresignHeader", mock.Anything, "PUT", "cloud-storage", mock.Anything, 15*time.Minute, mock.Anything, mock.Anything).Return(nil, errors.New("presign failed"))
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", mock.Anything).Return(nil)
+	mockVersions.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", mock.Anything, int64(2048)).Return(nil)
+
+	output, err := svc.InitiateUpload(context.Background(), &InitiateUploadInput{
+		UserID: "user-123",
+		FileID: "file-123",
+		Size:   2048,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_PromotesVersion(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{
+		ID:          "version-1",
+		FileID:      "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/version-1",
+		Bucket:      "cloud-storage",
+		MimeType:    "application/pdf",
+		Size:        2048,
+		UploadState: models.UploadStatePending,
+	}
+
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/version-1", mock.Anything).Return(storage.ObjectInfo{Size: 2048}, nil)
+	mockVersions.On("Promote", mock.Anything, version).Return(3, nil)
+	mockQuota.On("Commit", mock.Anything, "version-1", int64(2048)).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-123", "file-123", models.ActivityUpload).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, output.Version)
+	assert.Equal(t, "objects/version-1", output.StoragePath)
+	mockRepo.AssertNotCalled(t, "SetUploadState", mock.Anything, mock.Anything, mock.Anything)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_CompleteUpload_VersionAlreadyPromoted(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{
+		ID:          "version-1",
+		FileID:      "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/version-1",
+		Bucket:      "cloud-storage",
+		MimeType:    "application/pdf",
+		Size:        2048,
+		UploadState: models.UploadStatePending,
+	}
+
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/version-1", mock.Anything).Return(storage.ObjectInfo{Size: 2048}, nil)
+	mockQuota.On("Commit", mock.Anything, "version-1", int64(2048)).Return(nil)
+	mockVersions.On("Promote", mock.Anything, version).Return(0, models.ErrVersionConflict)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, mock.Anything)
+	mockVersions.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestFileService_CompleteUpload_VersionExceedsQuota(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{
+		ID:          "version-1",
+		FileID:      "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/version-1",
+		Bucket:      "cloud-storage",
+		MimeType:    "application/pdf",
+		UploadState: models.UploadStatePending,
+	}
+
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/version-1", mock.Anything).Return(storage.ObjectInfo{Size: 4096}, nil)
+	mockQuota.On("Commit", mock.Anything, "version-1", int64(4096)).Return(models.ErrQuotaExceeded)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockVersions.On("Delete", mock.Anything, "version-1").Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(4096)).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	mockStorage.AssertExpectations(t)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+	mockVersions.AssertNotCalled(t, "Promote", mock.Anything, mock.Anything)
+}
+
+func TestFileService_CompleteUpload_VersionSizeMismatch(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		MinIO: configs.MinIOConfig{
+			BucketName: "cloud-storage",
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{
+		ID:          "version-1",
+		FileID:      "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/version-1",
+		Bucket:      "cloud-storage",
+		Size:        2048,
+		UploadState: models.UploadStatePending,
+	}
+
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockStorage.On("StatObject", mock.Anything, "cloud-storage", "objects/version-1", mock.Anything).Return(storage.ObjectInfo{Size: 10}, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockVersions.On("Delete", mock.Anything, "version-1").Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(2048)).Return(nil)
+
+	output, err := svc.CompleteUpload(context.Background(), &CompleteUploadInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "upload rejected")
+	mockVersions.AssertNotCalled(t, "Promote", mock.Anything, mock.Anything)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_ListVersions_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	history := []*models.FileVersion{
+		{ID: "version-pending", FileID: "file-123", UploadState: models.UploadStatePending},
+		{ID: "version-2", FileID: "file-123", Version: 2, UploadState: models.UploadStateActive},
+		{ID: "version-1", FileID: "file-123", Version: 1, UploadState: models.UploadStateActive},
+	}
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockVersions.On("GetCurrentVersion", mock.Anything, "file-123").Return(3, nil)
+	mockVersions.On("ListByFileID", mock.Anything, "file-123").Return(history, nil)
+
+	output, err := svc.ListVersions(context.Background(), &ListVersionsInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, output.CurrentVersion)
+	assert.Len(t, output.Versions, 3)
+	assert.Equal(t, "file-123", output.Versions[0].ID)
+	assert.Equal(t, 3, output.Versions[0].Version)
+	assert.Equal(t, "version-2", output.Versions[1].ID)
+	assert.Equal(t, "version-1", output.Versions[2].ID)
+}
+
+func TestFileService_ListVersions_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	output, err := svc.ListVersions(context.Background(), &ListVersionsInput{FileID: "file-123", UserID: "user-456"})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "access denied")
+}
+
+func TestFileService_GetVersionDownloadLink_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{
+		ID:          "version-1",
+		FileID:      "file-123",
+		StoragePath: "objects/version-1",
+		Bucket:      "cloud-storage",
+		UploadState: models.UploadStateActive,
+	}
+	downloadURL, _ := url.Parse("https://storage.example.com/objects/version-1")
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockPresigned.On("PresignedGetObject", mock.Anything, "cloud-storage", "objects/version-1", time.Hour, mock.Anything).Return(downloadURL, nil)
+
+	output, err := svc.GetVersionDownloadLink(context.Background(), &GetVersionDownloadLinkInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, downloadURL.String(), output.DownloadURL)
+	mockPresigned.AssertExpectations(t)
+}
+
+func TestFileService_RestoreVersion_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{ID: "version-1", FileID: "file-123", Version: 1, UploadState: models.UploadStateActive}
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockVersions.On("Promote", mock.Anything, version).Return(4, nil)
+
+	output, err := svc.RestoreVersion(context.Background(), &RestoreVersionInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, output.CurrentVersion)
+	mockQuota.AssertNotCalled(t, "ReserveBytes", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_RestoreVersion_OtherFile(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{ID: "version-1", FileID: "file-456", UploadState: models.UploadStateActive}
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+
+	output, err := svc.RestoreVersion(context.Background(), &RestoreVersionInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockVersions.AssertNotCalled(t, "Promote", mock.Anything, mock.Anything)
+}
+
+func TestFileService_DeleteVersion_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	version := &models.FileVersion{
+		ID:          "version-1",
+		FileID:      "file-123",
+		UserID:      "user-123",
+		StoragePath: "objects/version-1",
+		Bucket:      "cloud-storage",
+		Size:        512,
+		UploadState: models.UploadStateActive,
+	}
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockVersions.On("GetByID", mock.Anything, "version-1").Return(version, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockVersions.On("Delete", mock.Anything, "version-1").Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(512)).Return(nil)
+
+	output, err := svc.DeleteVersion(context.Background(), &DeleteVersionInput{
+		FileID:    "file-123",
+		VersionID: "version-1",
+		UserID:    "user-123",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockStorage.AssertExpectations(t)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_DeleteVersion_Current(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	output, err := svc.DeleteVersion(context.Background(), &DeleteVersionInput{
+		FileID:    "file-123",
+		VersionID: "file-123",
+		UserID:    "user-123",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockStorage.AssertNotCalled(t, "RemoveObject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_PurgeFile_RemovesVersions(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "user-123",
+		OriginalName: "report.pdf",
+		Path:         "/",
+		StoragePath:  "objects/file-123",
+		Bucket:       "cloud-storage",
+		MimeType:     "application/pdf",
+		Size:         1024,
+		UploadState:  models.UploadStateActive,
+	}
+	file.IsTrashed = true
+	versions := []*models.FileVersion{
+		{ID: "version-1", FileID: "file-123", UserID: "user-123", StoragePath: "objects/version-1", Bucket: "cloud-storage", Size: 512},
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockVersions.On("ListByFileID", mock.Anything, "file-123").Return(versions, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/file-123").Return(nil)
+	mockRepo.On("Delete", mock.Anything, "file-123", "user-123").Return(nil)
+	mockQuota.On("Release", mock.Anything, "user-123", "file-123", int64(1024)).Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(512)).Return(nil)
+
+	output, err := svc.PurgeFile(context.Background(), &PurgeFileInput{FileID: "file-123", UserID: "user-123"})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockStorage.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_PruneVersions_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		Versions: configs.VersionsConfig{
+			MaxVersions: 5,
+			MaxAge:      720 * time.Hour,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	expired := []*models.FileVersion{
+		{ID: "version-1", UserID: "user-1", StoragePath: "objects/version-1", Bucket: "cloud-storage", Size: 10},
+		{ID: "version-2", UserID: "user-2", StoragePath: "objects/version-2", Bucket: "cloud-storage", Size: 20},
+	}
+
+	mockVersions.On("ListExpired", mock.Anything, 5, 720*time.Hour, purgeBatchSize).Return(expired, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-2").Return(errors.New("storage unavailable"))
+	mockVersions.On("Delete", mock.Anything, "version-1").Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-1", "version-1", int64(10)).Return(nil)
+
+	pruned, err := svc.PruneVersions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	mockVersions.AssertNotCalled(t, "Delete", mock.Anything, "version-2")
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
+
+func TestFileService_ReapAbandonedUploads_Versions(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockStorage := new(MockBlobStorage)
+	mockPresigned := new(MockPresignedURLGenerator)
+	mockQuota := new(MockQuotaRepository)
+	mockVersions := new(MockVersionRepository)
+	mockLinks := new(MockShareLinkRepository)
+	mockBlobs := new(MockBlobRepository)
+	config := &configs.Config{
+		Uploads: configs.UploadsConfig{
+			MultipartTTL: 24 * time.Hour,
+		},
+	}
+
+	svc := NewFileService(Dependencies{
+		Files:    mockRepo,
+		Quotas:   mockQuota,
+		Versions: mockVersions,
+		Links:    mockLinks,
+		Blobs:    mockBlobs,
+	}, mockStorage, mockPresigned, config)
+
+	stale := []*models.FileVersion{
+		{ID: "version-1", UserID: "user-123", StoragePath: "objects/version-1", Bucket: "cloud-storage", Size: 2048, UploadState: models.UploadStatePending},
+	}
+
+	mockRepo.On("ListStaleUploads", mock.Anything, mock.Anything, mock.Anything, reapBatchSize).Return([]*models.StaleUpload{}, nil)
+	mockVersions.On("ListStale", mock.Anything, mock.Anything, reapBatchSize).Return(stale, nil)
+	mockStorage.On("RemoveObject", mock.Anything, "cloud-storage", "objects/version-1").Return(nil)
+	mockVersions.On("Delete", mock.Anything, "version-1").Return(nil)
+	mockQuota.On("ReleaseBytes", mock.Anything, "user-123", "version-1", int64(2048)).Return(nil)
+
+	reaped, err := svc.ReapAbandonedUploads(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reaped)
+	mockVersions.AssertExpectations(t)
+	mockQuota.AssertExpectations(t)
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Sene4ka/cloud_storage/internal/api"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -19,6 +23,25 @@ type MetadataClient interface {
 	UpdateMetadata(ctx context.Context, in *api.UpdateMetadataRequest, opts ...grpc.CallOption) (*api.UpdateMetadataResponse, error)
 	TrashFile(ctx context.Context, in *api.TrashFileRequest, opts ...grpc.CallOption) (*api.TrashFileResponse, error)
 	RestoreFile(ctx context.Context, in *api.RestoreFileRequest, opts ...grpc.CallOption) (*api.RestoreFileResponse, error)
+	CreateFolder(ctx context.Context, in *api.CreateFolderRequest, opts ...grpc.CallOption) (*api.CreateFolderResponse, error)
+	ListFolder(ctx context.Context, in *api.ListFolderRequest, opts ...grpc.CallOption) (*api.ListFolderResponse, error)
+	RenameFolder(ctx context.Context, in *api.RenameFolderRequest, opts ...grpc.CallOption) (*api.RenameFolderResponse, error)
+	MoveFolder(ctx context.Context, in *api.MoveFolderRequest, opts ...grpc.CallOption) (*api.MoveFolderResponse, error)
+	DeleteFolder(ctx context.Context, in *api.DeleteFolderRequest, opts ...grpc.CallOption) (*api.DeleteFolderResponse, error)
+	ShareFile(ctx context.Context, in *api.ShareFileRequest, opts ...grpc.CallOption) (*api.ShareFileResponse, error)
+	UnshareFile(ctx context.Context, in *api.UnshareFileRequest, opts ...grpc.CallOption) (*api.UnshareFileResponse, error)
+	ListShares(ctx context.Context, in *api.ListSharesRequest, opts ...grpc.CallOption) (*api.ListSharesResponse, error)
+	ListSharedWithMe(ctx context.Context, in *api.ListSharedWithMeRequest, opts ...grpc.CallOption) (*api.ListSharedWithMeResponse, error)
+	ShareFolder(ctx context.Context, in *api.ShareFolderRequest, opts ...grpc.CallOption) (*api.ShareFolderResponse, error)
+	UnshareFolder(ctx context.Context, in *api.UnshareFolderRequest, opts ...grpc.CallOption) (*api.UnshareFolderResponse, error)
+	ListFolderShares(ctx context.Context, in *api.ListFolderSharesRequest, opts ...grpc.CallOption) (*api.ListFolderSharesResponse, error)
+	ListFoldersSharedWithMe(ctx context.Context, in *api.ListFoldersSharedWithMeRequest, opts ...grpc.CallOption) (*api.ListFoldersSharedWithMeResponse, error)
+	ListTags(ctx context.Context, in *api.ListTagsRequest, opts ...grpc.CallOption) (*api.ListTagsResponse, error)
+	SearchFiles(ctx context.Context, in *api.SearchFilesRequest, opts ...grpc.CallOption) (*api.SearchFilesResponse, error)
+	StarFile(ctx context.Context, in *api.StarFileRequest, opts ...grpc.CallOption) (*api.StarFileResponse, error)
+	UnstarFile(ctx context.Context, in *api.UnstarFileRequest, opts ...grpc.CallOption) (*api.UnstarFileResponse, error)
+	ListRecent(ctx context.Context, in *api.ListRecentRequest, opts ...grpc.CallOption) (*api.ListRecentResponse, error)
+	ListStarred(ctx context.Context, in *api.ListStarredRequest, opts ...grpc.CallOption) (*api.ListStarredResponse, error)
 }
 
 type FileClient interface {
@@ -26,8 +49,36 @@ type FileClient interface {
 	CompleteUpload(ctx context.Context, in *api.CompleteUploadRequest, opts ...grpc.CallOption) (*api.CompleteUploadResponse, error)
 	GetDownloadLink(ctx context.Context, in *api.GetDownloadLinkRequest, opts ...grpc.CallOption) (*api.GetDownloadLinkResponse, error)
 	DeleteFile(ctx context.Context, in *api.DeleteFileRequest, opts ...grpc.CallOption) (*api.DeleteFileResponse, error)
+	InitiateMultipartUpload(ctx context.Context, in *api.InitiateMultipartUploadRequest, opts ...grpc.CallOption) (*api.InitiateMultipartUploadResponse, error)
+	GetPartUploadURLs(ctx context.Context, in *api.GetPartUploadURLsRequest, opts ...grpc.CallOption) (*api.GetPartUploadURLsResponse, error)
+	CompleteMultipartUpload(ctx context.Context, in *api.CompleteMultipartUploadRequest, opts ...grpc.CallOption) (*api.CompleteMultipartUploadResponse, error)
+	AbortMultipartUpload(ctx context.Context, in *api.AbortMultipartUploadRequest, opts ...grpc.CallOption) (*api.AbortMultipartUploadResponse, error)
+	GetUsage(ctx context.Context, in *api.GetUsageRequest, opts ...grpc.CallOption) (*api.GetUsageResponse, error)
+	PurgeFile(ctx context.Context, in *api.PurgeFileRequest, opts ...grpc.CallOption) (*api.PurgeFileResponse, error)
+	EmptyTrash(ctx context.Context, in *api.EmptyTrashRequest, opts ...grpc.CallOption) (*api.EmptyTrashResponse, error)
+	SetTrashRetention(ctx context.Context, in *api.SetTrashRetentionRequest, opts ...grpc.CallOption) (*api.SetTrashRetentionResponse, error)
+	MoveFile(ctx context.Context, in *api.MoveFileRequest, opts ...grpc.CallOption) (*api.MoveFileResponse, error)
+	CopyFile(ctx context.Context, in *api.CopyFileRequest, opts ...grpc.CallOption) (*api.CopyFileResponse, error)
+	BatchTrash(ctx context.Context, in *api.BatchRequest, opts ...grpc.CallOption) (*api.BatchResponse, error)
+	BatchRestore(ctx context.Context, in *api.BatchRequest, opts ...grpc.CallOption) (*api.BatchResponse, error)
+	BatchDelete(ctx context.Context, in *api.BatchRequest, opts ...grpc.CallOption) (*api.BatchResponse, error)
+	BatchMove(ctx context.Context, in *api.BatchMoveRequest, opts ...grpc.CallOption) (*api.BatchResponse, error)
+	ListVersions(ctx context.Context, in *api.ListVersionsRequest, opts ...grpc.CallOption) (*api.ListVersionsResponse, error)
+	GetVersionDownloadLink(ctx context.Context, in *api.GetVersionDownloadLinkRequest, opts ...grpc.CallOption) (*api.GetDownloadLinkResponse, error)
+	RestoreVersion(ctx context.Context, in *api.RestoreVersionRequest, opts ...grpc.CallOption) (*api.RestoreVersionResponse, error)
+	DeleteVersion(ctx context.Context, in *api.DeleteVersionRequest, opts ...grpc.CallOption) (*api.DeleteVersionResponse, error)
+	CreateShareLink(ctx context.Context, in *api.CreateShareLinkRequest, opts ...grpc.CallOption) (*api.CreateShareLinkResponse, error)
+	ListShareLinks(ctx context.Context, in *api.ListShareLinksRequest, opts ...grpc.CallOption) (*api.ListShareLinksResponse, error)
+	RevokeShareLink(ctx context.Context, in *api.RevokeShareLinkRequest, opts ...grpc.CallOption) (*api.RevokeShareLinkResponse, error)
+	DownloadContent(ctx context.Context, in *api.DownloadContentRequest, opts ...grpc.CallOption) (api.FileService_DownloadContentClient, error)
+	UploadContent(ctx context.Context, opts ...grpc.CallOption) (api.FileService_UploadContentClient, error)
+	GetPreviewLink(ctx context.Context, in *api.GetPreviewLinkRequest, opts ...grpc.CallOption) (*api.GetPreviewLinkResponse, error)
 }
 
+// uploadChunkSize is the size of the data messages request bodies are
+// streamed to the file service in.
+const uploadChunkSize = 64 << 10
+
 type FileHandler struct {
 	metadataClient MetadataClient
 	fileClient     FileClient
@@ -58,14 +109,29 @@ func (h *FileHandler) HandleFiles(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		includePending, _ := strconv.ParseBool(r.URL.Query().Get("include_pending"))
+
+		var includeTotal *wrapperspb.BoolValue
+		if total := r.URL.Query().Get("include_total"); total != "" {
+			if val, err := strconv.ParseBool(total); err == nil {
+				includeTotal = wrapperspb.Bool(val)
+			}
+		}
+
 		resp, err := h.metadataClient.ListMetadata(r.Context(), &api.ListMetadataRequest{
-			UserId:    userID,
-			Page:      int32(page),
-			PageSize:  int32(pageSize),
-			SortBy:    r.URL.Query().Get("sort_by"),
-			SortOrder: r.URL.Query().Get("sort_order"),
-			Search:    r.URL.Query().Get("search"),
-			IsTrashed: isTrashed,
+			UserId:         userID,
+			Page:           int32(page),
+			PageSize:       int32(pageSize),
+			SortBy:         r.URL.Query().Get("sort_by"),
+			SortOrder:      r.URL.Query().Get("sort_order"),
+			Search:         r.URL.Query().Get("search"),
+			IsTrashed:      isTrashed,
+			IncludePending: includePending,
+			OwnerId:        r.URL.Query().Get("owner_id"),
+			Path:           r.URL.Query().Get("path"),
+			Tags:           parseTagFilters(r.URL.Query()["tag"]),
+			PageToken:      r.URL.Query().Get("page_token"),
+			IncludeTotal:   includeTotal,
 		})
 
 		if err != nil {
@@ -73,19 +139,147 @@ func (h *FileHandler) HandleFiles(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		JSONResponse(w, http.StatusOK, resp)
+	case http.MethodPost:
+		h.HandleFormUpload(w, r)
 	default:
 		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
 	}
 }
 
+// parseTagFilters turns tag query parameters into filters. "tag=key" asks
+// for files carrying the tag, "tag=key=value" for a value of it; several
+// values given for the same key match any of them.
+func parseTagFilters(params []string) []*api.TagFilter {
+	var filters []*api.TagFilter
+	byKey := make(map[string]*api.TagFilter)
+	for _, param := range params {
+		key, value, hasValue := strings.Cut(param, "=")
+		filter, ok := byKey[key]
+		if !ok {
+			filter = &api.TagFilter{Key: key}
+			byKey[key] = filter
+			filters = append(filters, filter)
+		}
+		if hasValue {
+			filter.Values = append(filter.Values, value)
+		}
+	}
+	return filters
+}
+
+func (h *FileHandler) HandleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	resp, err := h.metadataClient.ListTags(r.Context(), &api.ListTagsRequest{
+		UserId: userID,
+		Key:    r.URL.Query().Get("key"),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleSearch searches the user's files. Besides the query "q" it takes a
+// folder "path", any number of "type" values (a family such as "image" or a
+// full MIME type), "min_size" and "max_size" in bytes, the date ranges
+// "created_after", "created_before", "updated_after" and "updated_before",
+// and "tag" filters as for listing.
+func (h *FileHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	req := &api.SearchFilesRequest{
+		UserId:    r.Context().Value("userID").(string),
+		Query:     query.Get("q"),
+		Path:      query.Get("path"),
+		MimeTypes: query["type"],
+		Tags:      parseTagFilters(query["tag"]),
+	}
+
+	for name, field := range map[string]*int64{"min_size": &req.MinSize, "max_size": &req.MaxSize} {
+		if value := query.Get(name); value != "" {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error": "invalid %s"}`, name), http.StatusBadRequest)
+				return
+			}
+			*field = size
+		}
+	}
+	for name, field := range map[string]**timestamppb.Timestamp{
+		"created_after":  &req.CreatedAfter,
+		"created_before": &req.CreatedBefore,
+		"updated_after":  &req.UpdatedAfter,
+		"updated_before": &req.UpdatedBefore,
+	} {
+		if value := query.Get(name); value != "" {
+			ts, err := parseSearchTime(value, strings.HasSuffix(name, "_before"))
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error": "invalid %s"}`, name), http.StatusBadRequest)
+				return
+			}
+			*field = ts
+		}
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	req.Page, req.PageSize = int32(page), int32(pageSize)
+
+	resp, err := h.metadataClient.SearchFiles(r.Context(), req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// parseSearchTime accepts RFC 3339 times and plain dates. A date given as an
+// upper bound includes the whole day.
+func parseSearchTime(value string, upperBound bool) (*timestamppb.Timestamp, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamppb.New(t), nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return timestamppb.New(t), nil
+}
+
 func (h *FileHandler) HandleFileDetail(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/content") {
+		h.HandleFileContent(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/preview") {
+		h.HandlePreviewLink(w, r)
+		return
+	}
+
 	userID := r.Context().Value("userID").(string)
 	fileID := strings.TrimPrefix(r.URL.Path, "/api/v1/files/")
 	switch r.Method {
 	case http.MethodGet:
+		includePending, _ := strconv.ParseBool(r.URL.Query().Get("include_pending"))
 		resp, err := h.metadataClient.GetMetadata(r.Context(), &api.GetMetadataRequest{
-			Id:     fileID,
-			UserId: userID,
+			Id:             fileID,
+			UserId:         userID,
+			IncludePending: includePending,
 		})
 
 		if err != nil {
@@ -141,7 +335,7 @@ func (h *FileHandler) HandleInitiateUpload(w http.ResponseWriter, r *http.Reques
 	req.UserId = userID
 	resp, err := h.fileClient.InitiateUpload(r.Context(), &req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
 		return
 	}
 
@@ -243,3 +437,991 @@ func (h *FileHandler) HandleRestoreFile(w http.ResponseWriter, r *http.Request)
 
 	JSONResponse(w, http.StatusOK, resp)
 }
+
+func (h *FileHandler) HandleInitiateMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.InitiateMultipartUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.fileClient.InitiateMultipartUpload(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+
+	JSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *FileHandler) HandleGetPartUploadURLs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.GetPartUploadURLsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.fileClient.GetPartUploadURLs(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleCompleteMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.CompleteMultipartUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.fileClient.CompleteMultipartUpload(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleAbortMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.AbortMultipartUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.fileClient.AbortMultipartUpload(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	resp, err := h.fileClient.GetUsage(r.Context(), &api.GetUsageRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandlePurgeFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	fileID := strings.TrimPrefix(r.URL.Path, "/api/v2/files/purge/")
+
+	resp, err := h.fileClient.PurgeFile(r.Context(), &api.PurgeFileRequest{
+		FileId: fileID,
+		UserId: userID,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	resp, err := h.fileClient.EmptyTrash(r.Context(), &api.EmptyTrashRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleTrashRetention sets how many days the caller's trash is kept, from
+// a body like {"retention_days": 7}; 0 returns to the server default.
+func (h *FileHandler) HandleTrashRetention(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req api.SetTrashRetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.RetentionDays < 0 {
+		http.Error(w, `{"error": "retention_days cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = r.Context().Value("userID").(string)
+	resp, err := h.fileClient.SetTrashRetention(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleMoveFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.MoveFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.fileClient.MoveFile(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleCopyFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.CopyFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.fileClient.CopyFile(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+
+	JSONResponse(w, http.StatusCreated, resp)
+}
+
+type batchRequest struct {
+	Action         string   `json:"action"`
+	FileIDs        []string `json:"file_ids"`
+	Path           string   `json:"path"`
+	ConflictPolicy string   `json:"conflict_policy"`
+}
+
+func (h *FileHandler) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	batch := &api.BatchRequest{UserId: userID, FileIds: req.FileIDs}
+	var resp *api.BatchResponse
+	var err error
+	switch req.Action {
+	case "trash":
+		resp, err = h.fileClient.BatchTrash(r.Context(), batch)
+	case "restore":
+		resp, err = h.fileClient.BatchRestore(r.Context(), batch)
+	case "delete":
+		resp, err = h.fileClient.BatchDelete(r.Context(), batch)
+	case "move":
+		resp, err = h.fileClient.BatchMove(r.Context(), &api.BatchMoveRequest{
+			UserId:         userID,
+			FileIds:        req.FileIDs,
+			Path:           req.Path,
+			ConflictPolicy: req.ConflictPolicy,
+		})
+	default:
+		http.Error(w, `{"error": "unsupported action"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleFolders(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	switch r.Method {
+	case http.MethodGet:
+		folderPath := r.URL.Query().Get("path")
+		if folderPath == "" {
+			folderPath = "/"
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+
+		pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		resp, err := h.metadataClient.ListFolder(r.Context(), &api.ListFolderRequest{
+			UserId:   userID,
+			Path:     folderPath,
+			Page:     int32(page),
+			PageSize: int32(pageSize),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case http.MethodPost:
+		var req api.CreateFolderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		req.UserId = userID
+		resp, err := h.metadataClient.CreateFolder(r.Context(), &req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusCreated, resp)
+	case http.MethodDelete:
+		recursive, _ := strconv.ParseBool(r.URL.Query().Get("recursive"))
+		resp, err := h.metadataClient.DeleteFolder(r.Context(), &api.DeleteFolderRequest{
+			UserId:    userID,
+			Path:      r.URL.Query().Get("path"),
+			Recursive: recursive,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	default:
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *FileHandler) HandleRenameFolder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.RenameFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.metadataClient.RenameFolder(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleMoveFolder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	var req api.MoveFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.UserId = userID
+	resp, err := h.metadataClient.MoveFolder(r.Context(), &req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleFileVersions serves /api/v2/files/versions/{fileID} and
+// /api/v2/files/versions/{fileID}/{versionID}[/download|/restore].
+func (h *FileHandler) HandleFileVersions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v2/files/versions/"), "/"), "/")
+	fileID := parts[0]
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		resp, err := h.fileClient.ListVersions(r.Context(), &api.ListVersionsRequest{
+			FileId: fileID,
+			UserId: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusNotFound)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		resp, err := h.fileClient.DeleteVersion(r.Context(), &api.DeleteVersionRequest{
+			FileId:    fileID,
+			VersionId: parts[1],
+			UserId:    userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case len(parts) == 3 && parts[2] == "download" && r.Method == http.MethodGet:
+		expiresIn := int64(3600)
+		if exp := r.URL.Query().Get("expires_in"); exp != "" {
+			if val, err := strconv.ParseInt(exp, 10, 64); err == nil {
+				expiresIn = val
+			}
+		}
+
+		resp, err := h.fileClient.GetVersionDownloadLink(r.Context(), &api.GetVersionDownloadLinkRequest{
+			FileId:    fileID,
+			VersionId: parts[1],
+			UserId:    userID,
+			ExpiresIn: expiresIn,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusNotFound)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case len(parts) == 3 && parts[2] == "restore" && r.Method == http.MethodPost:
+		resp, err := h.fileClient.RestoreVersion(r.Context(), &api.RestoreVersionRequest{
+			FileId:    fileID,
+			VersionId: parts[1],
+			UserId:    userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case len(parts) > 3 || fileID == "":
+		http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleFileShares serves /api/v2/files/shares/{fileID}: GET lists the
+// file's shares, POST grants a role to a user by email and DELETE revokes
+// the share of the user given by the email query parameter.
+func (h *FileHandler) HandleFileShares(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	fileID := strings.TrimPrefix(r.URL.Path, "/api/v2/files/shares/")
+	switch r.Method {
+	case http.MethodGet:
+		resp, err := h.metadataClient.ListShares(r.Context(), &api.ListSharesRequest{
+			FileId: fileID,
+			UserId: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case http.MethodPost:
+		var req api.ShareFileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		req.FileId = fileID
+		req.UserId = userID
+		resp, err := h.metadataClient.ShareFile(r.Context(), &req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusCreated, resp)
+	case http.MethodDelete:
+		resp, err := h.metadataClient.UnshareFile(r.Context(), &api.UnshareFileRequest{
+			FileId: fileID,
+			UserId: userID,
+			Email:  r.URL.Query().Get("email"),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	default:
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *FileHandler) HandleSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	resp, err := h.metadataClient.ListSharedWithMe(r.Context(), &api.ListSharedWithMeRequest{
+		UserId:   userID,
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleStar serves /api/v2/files/star/{fileID}: POST stars the file for
+// the caller and DELETE unstars it.
+func (h *FileHandler) HandleStar(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	fileID := strings.TrimPrefix(r.URL.Path, "/api/v2/files/star/")
+	switch r.Method {
+	case http.MethodPost:
+		resp, err := h.metadataClient.StarFile(r.Context(), &api.StarFileRequest{
+			FileId: fileID,
+			UserId: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case http.MethodDelete:
+		resp, err := h.metadataClient.UnstarFile(r.Context(), &api.UnstarFileRequest{
+			FileId: fileID,
+			UserId: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	default:
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleRecent lists the files the caller uploaded, downloaded or edited
+// last, up to the limit query parameter.
+func (h *FileHandler) HandleRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	resp, err := h.metadataClient.ListRecent(r.Context(), &api.ListRecentRequest{
+		UserId: userID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *FileHandler) HandleStarred(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	resp, err := h.metadataClient.ListStarred(r.Context(), &api.ListStarredRequest{
+		UserId:   userID,
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleFileLinks serves /api/v2/files/links/{fileID}: GET lists the file's
+// share links and POST creates one. DELETE /api/v2/files/links/{fileID}/{linkID}
+// revokes a link.
+func (h *FileHandler) HandleFileLinks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v2/files/links/"), "/"), "/")
+	fileID := parts[0]
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		resp, err := h.fileClient.ListShareLinks(r.Context(), &api.ListShareLinksRequest{
+			FileId: fileID,
+			UserId: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusNotFound)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var req api.CreateShareLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		req.FileId = fileID
+		req.UserId = userID
+		resp, err := h.fileClient.CreateShareLink(r.Context(), &req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusCreated, resp)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		resp, err := h.fileClient.RevokeShareLink(r.Context(), &api.RevokeShareLinkRequest{
+			FileId: fileID,
+			LinkId: parts[1],
+			UserId: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case len(parts) > 2 || fileID == "":
+		http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleShareLink serves the unauthenticated /s/{token} route by redirecting
+// to a short-lived download URL. Password-protected links take the password
+// from the X-Share-Password header or the password field of a POST form,
+// never from the query string, where it would end up in logs.
+func (h *FileHandler) HandleShareLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, "/s/")
+	if token == "" || strings.Contains(token, "/") {
+		http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+		return
+	}
+
+	password := r.Header.Get("X-Share-Password")
+	if password == "" {
+		password = r.PostFormValue("password")
+	}
+
+	resp, err := h.fileClient.GetDownloadLink(r.Context(), &api.GetDownloadLinkRequest{
+		ShareToken: token,
+		Password:   password,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	// A redirect cannot carry the SSE-C headers of an encrypted file, so
+	// the client is handed the link and headers to use itself.
+	if len(resp.Headers) > 0 {
+		JSONResponse(w, http.StatusOK, resp)
+		return
+	}
+	http.Redirect(w, r, resp.DownloadUrl, http.StatusFound)
+}
+
+// HandleFolderShares serves /api/v2/folders/shares: GET lists the shares of
+// the folder given by the path query parameter, POST grants a role on a
+// folder to a user by email and DELETE revokes a share. A grantee leaving a
+// folder passes the owner's id as owner_id.
+func (h *FileHandler) HandleFolderShares(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	switch r.Method {
+	case http.MethodGet:
+		resp, err := h.metadataClient.ListFolderShares(r.Context(), &api.ListFolderSharesRequest{
+			UserId: userID,
+			Path:   r.URL.Query().Get("path"),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	case http.MethodPost:
+		var req api.ShareFolderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		req.UserId = userID
+		resp, err := h.metadataClient.ShareFolder(r.Context(), &req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusCreated, resp)
+	case http.MethodDelete:
+		resp, err := h.metadataClient.UnshareFolder(r.Context(), &api.UnshareFolderRequest{
+			UserId:  userID,
+			OwnerId: r.URL.Query().Get("owner_id"),
+			Path:    r.URL.Query().Get("path"),
+			Email:   r.URL.Query().Get("email"),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, http.StatusOK, resp)
+	default:
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *FileHandler) HandleFoldersSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	resp, err := h.metadataClient.ListFoldersSharedWithMe(r.Context(), &api.ListFoldersSharedWithMeRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleFileContent serves GET /api/v2/files/{fileID}/content by streaming
+// the file through the gateway, for clients that cannot reach the storage
+// endpoint presigned URLs point at.
+func (h *FileHandler) HandleFileContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		h.HandleUploadContent(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	fileID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v2/files/"), "/content")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream, err := h.fileClient.DownloadContent(ctx, &api.DownloadContentRequest{
+		FileId:      fileID,
+		UserId:      userID,
+		Range:       r.Header.Get("Range"),
+		IfNoneMatch: r.Header.Get("If-None-Match"),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+
+	info := first.Info
+	header := w.Header()
+	header.Set("ETag", info.Etag)
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("Cache-Control", "private, no-cache")
+	if info.UpdatedAt != nil {
+		header.Set("Last-Modified", info.UpdatedAt.AsTime().UTC().Format(http.TimeFormat))
+	}
+	if info.NotModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if info.RangeNotSatisfiable {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+		http.Error(w, `{"error": "requested range not satisfiable"}`, http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	header.Set("Content-Type", info.MimeType)
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Filename}))
+	header.Set("Content-Length", strconv.FormatInt(info.Length, 10))
+	if info.Partial {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", info.Offset, info.Offset+info.Length-1, info.Size))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	// Large files take longer than the server's write timeout allows.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			// The status line is already out; cutting the body short is
+			// the only way left to report the failure.
+			return
+		}
+		if _, err := w.Write(msg.Data); err != nil {
+			return
+		}
+	}
+}
+
+// HandleUploadContent serves PUT /api/v2/files/{fileID}/content. The body
+// completes a pending upload of the file, or becomes its new version.
+func (h *FileHandler) HandleUploadContent(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	fileID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v2/files/"), "/content")
+
+	resp, err := h.streamUpload(w, r, &api.InitiateUploadRequest{
+		UserId:            userID,
+		FileId:            fileID,
+		Size:              r.ContentLength,
+		MimeType:          r.Header.Get("Content-Type"),
+		ChecksumAlgorithm: r.Header.Get("X-Checksum-Algorithm"),
+		Checksum:          r.Header.Get("X-Checksum"),
+	}, r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+	JSONResponse(w, http.StatusOK, resp)
+}
+
+// HandleFormUpload serves a multipart/form-data POST /api/v2/files that
+// uploads a new file in one request. The path, is_public, size,
+// checksum_algorithm and checksum fields have to precede the file part.
+func (h *FileHandler) HandleFormUpload(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error": "multipart/form-data body expected"}`, http.StatusBadRequest)
+		return
+	}
+
+	req := &api.InitiateUploadRequest{UserId: userID, Path: "/", Size: -1}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, `{"error": "file part is required"}`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() == "file" {
+			req.Filename = part.FileName()
+			req.MimeType = part.Header.Get("Content-Type")
+			resp, err := h.streamUpload(w, r, req, part)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+				return
+			}
+			JSONResponse(w, http.StatusCreated, resp)
+			return
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, 4<<10))
+		if err != nil {
+			http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		switch part.FormName() {
+		case "path":
+			req.Path = string(value)
+		case "is_public":
+			req.IsPublic, _ = strconv.ParseBool(string(value))
+		case "size":
+			if size, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				req.Size = size
+			}
+		case "checksum_algorithm":
+			req.ChecksumAlgorithm = string(value)
+		case "checksum":
+			req.Checksum = string(value)
+		}
+	}
+}
+
+// streamUpload sends header and then body over an UploadContent stream.
+func (h *FileHandler) streamUpload(w http.ResponseWriter, r *http.Request, header *api.InitiateUploadRequest, body io.Reader) (*api.UploadContentResponse, error) {
+	// Large bodies take longer than the server's timeouts allow.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream, err := h.fileClient.UploadContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send reports io.EOF once the service has given up on the upload; the
+	// reason is then returned by CloseAndRecv.
+	err = stream.Send(&api.UploadContentRequest{Header: header})
+	buf := make([]byte, uploadChunkSize)
+	for err == nil {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			err = stream.Send(&api.UploadContentRequest{Data: buf[:n]})
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", readErr)
+		}
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return stream.CloseAndRecv()
+}
+
+// HandlePreviewLink serves GET /api/v2/files/{fileID}/preview?size=. It
+// answers 202 without a URL while the previews are still being generated.
+func (h *FileHandler) HandlePreviewLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.Context().Value("userID").(string)
+	fileID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v2/files/"), "/preview")
+	expiresIn, _ := strconv.ParseInt(r.URL.Query().Get("expires_in"), 10, 64)
+
+	resp, err := h.fileClient.GetPreviewLink(r.Context(), &api.GetPreviewLinkRequest{
+		FileId:    fileID,
+		UserId:    userID,
+		Size:      r.URL.Query().Get("size"),
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), grpcHTTPStatus(err))
+		return
+	}
+	if resp.Url == "" {
+		JSONResponse(w, http.StatusAccepted, resp)
+		return
+	}
+	JSONResponse(w, http.StatusOK, resp)
+}
@@ -3,6 +3,8 @@ package handler
 import (
 	"context"
 	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +15,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -68,6 +73,158 @@ func (m *MockMetadataClient) RestoreFile(ctx context.Context, in *api.RestoreFil
 	return args.Get(0).(*api.RestoreFileResponse), args.Error(1)
 }
 
+func (m *MockMetadataClient) CreateFolder(ctx context.Context, in *api.CreateFolderRequest, opts ...grpc.CallOption) (*api.CreateFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.CreateFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListFolder(ctx context.Context, in *api.ListFolderRequest, opts ...grpc.CallOption) (*api.ListFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) RenameFolder(ctx context.Context, in *api.RenameFolderRequest, opts ...grpc.CallOption) (*api.RenameFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.RenameFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) MoveFolder(ctx context.Context, in *api.MoveFolderRequest, opts ...grpc.CallOption) (*api.MoveFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.MoveFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) DeleteFolder(ctx context.Context, in *api.DeleteFolderRequest, opts ...grpc.CallOption) (*api.DeleteFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.DeleteFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ShareFile(ctx context.Context, in *api.ShareFileRequest, opts ...grpc.CallOption) (*api.ShareFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ShareFileResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) UnshareFile(ctx context.Context, in *api.UnshareFileRequest, opts ...grpc.CallOption) (*api.UnshareFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.UnshareFileResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListShares(ctx context.Context, in *api.ListSharesRequest, opts ...grpc.CallOption) (*api.ListSharesResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListSharesResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListSharedWithMe(ctx context.Context, in *api.ListSharedWithMeRequest, opts ...grpc.CallOption) (*api.ListSharedWithMeResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListSharedWithMeResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) StarFile(ctx context.Context, in *api.StarFileRequest, opts ...grpc.CallOption) (*api.StarFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.StarFileResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) UnstarFile(ctx context.Context, in *api.UnstarFileRequest, opts ...grpc.CallOption) (*api.UnstarFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.UnstarFileResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListRecent(ctx context.Context, in *api.ListRecentRequest, opts ...grpc.CallOption) (*api.ListRecentResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListRecentResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListStarred(ctx context.Context, in *api.ListStarredRequest, opts ...grpc.CallOption) (*api.ListStarredResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListStarredResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ShareFolder(ctx context.Context, in *api.ShareFolderRequest, opts ...grpc.CallOption) (*api.ShareFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ShareFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) UnshareFolder(ctx context.Context, in *api.UnshareFolderRequest, opts ...grpc.CallOption) (*api.UnshareFolderResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.UnshareFolderResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListFolderShares(ctx context.Context, in *api.ListFolderSharesRequest, opts ...grpc.CallOption) (*api.ListFolderSharesResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListFolderSharesResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListFoldersSharedWithMe(ctx context.Context, in *api.ListFoldersSharedWithMeRequest, opts ...grpc.CallOption) (*api.ListFoldersSharedWithMeResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListFoldersSharedWithMeResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) ListTags(ctx context.Context, in *api.ListTagsRequest, opts ...grpc.CallOption) (*api.ListTagsResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListTagsResponse), args.Error(1)
+}
+
+func (m *MockMetadataClient) SearchFiles(ctx context.Context, in *api.SearchFilesRequest, opts ...grpc.CallOption) (*api.SearchFilesResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.SearchFilesResponse), args.Error(1)
+}
+
 type MockFileClient struct {
 	mock.Mock
 }
@@ -104,480 +261,2189 @@ func (m *MockFileClient) DeleteFile(ctx context.Context, in *api.DeleteFileReque
 	return args.Get(0).(*api.DeleteFileResponse), args.Error(1)
 }
 
-func TestFileHandler_HandleFiles_Success(t *testing.T) {
-	t.Parallel()
+func (m *MockFileClient) InitiateMultipartUpload(ctx context.Context, in *api.InitiateMultipartUploadRequest, opts ...grpc.CallOption) (*api.InitiateMultipartUploadResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.InitiateMultipartUploadResponse), args.Error(1)
+}
 
-	mockMetadata := new(MockMetadataClient)
-	mockFile := new(MockFileClient)
-	handler := NewFileHandler(mockMetadata, mockFile)
+func (m *MockFileClient) GetPartUploadURLs(ctx context.Context, in *api.GetPartUploadURLsRequest, opts ...grpc.CallOption) (*api.GetPartUploadURLsResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.GetPartUploadURLsResponse), args.Error(1)
+}
 
-	mockMetadata.On("ListMetadata", mock.Anything, mock.Anything).Return(&api.ListMetadataResponse{
-		Items: []*api.FileMetadata{
-			{Id: "file-1", Filename: "test1.txt", UserId: "user-123"},
-			{Id: "file-2", Filename: "test2.txt", UserId: "user-123"},
-		},
-		Total:    2,
-		Page:     1,
-		PageSize: 20,
-	}, nil)
+func (m *MockFileClient) CompleteMultipartUpload(ctx context.Context, in *api.CompleteMultipartUploadRequest, opts ...grpc.CallOption) (*api.CompleteMultipartUploadResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.CompleteMultipartUploadResponse), args.Error(1)
+}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?page=1&page_size=20", nil)
-	req = ContextWithUser(req, "user-123")
-	rr := httptest.NewRecorder()
+func (m *MockFileClient) AbortMultipartUpload(ctx context.Context, in *api.AbortMultipartUploadRequest, opts ...grpc.CallOption) (*api.AbortMultipartUploadResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.AbortMultipartUploadResponse), args.Error(1)
+}
 
-	handler.HandleFiles(rr, req)
+func (m *MockFileClient) GetUsage(ctx context.Context, in *api.GetUsageRequest, opts ...grpc.CallOption) (*api.GetUsageResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.GetUsageResponse), args.Error(1)
+}
 
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "file-1")
-	mockMetadata.AssertExpectations(t)
+func (m *MockFileClient) PurgeFile(ctx context.Context, in *api.PurgeFileRequest, opts ...grpc.CallOption) (*api.PurgeFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.PurgeFileResponse), args.Error(1)
 }
 
-func TestFileHandler_HandleFiles_MethodNotAllowed(t *testing.T) {
-	t.Parallel()
+func (m *MockFileClient) SetTrashRetention(ctx context.Context, in *api.SetTrashRetentionRequest, opts ...grpc.CallOption) (*api.SetTrashRetentionResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.SetTrashRetentionResponse), args.Error(1)
+}
 
-	mockMetadata := new(MockMetadataClient)
-	mockFile := new(MockFileClient)
-	handler := NewFileHandler(mockMetadata, mockFile)
+func (m *MockFileClient) EmptyTrash(ctx context.Context, in *api.EmptyTrashRequest, opts ...grpc.CallOption) (*api.EmptyTrashResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.EmptyTrashResponse), args.Error(1)
+}
+
+func (m *MockFileClient) MoveFile(ctx context.Context, in *api.MoveFileRequest, opts ...grpc.CallOption) (*api.MoveFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.MoveFileResponse), args.Error(1)
+}
+
+func (m *MockFileClient) CopyFile(ctx context.Context, in *api.CopyFileRequest, opts ...grpc.CallOption) (*api.CopyFileResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.CopyFileResponse), args.Error(1)
+}
+
+func (m *MockFileClient) BatchTrash(ctx context.Context, in *api.BatchRequest, opts ...grpc.CallOption) (*api.BatchResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.BatchResponse), args.Error(1)
+}
+
+func (m *MockFileClient) BatchRestore(ctx context.Context, in *api.BatchRequest, opts ...grpc.CallOption) (*api.BatchResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.BatchResponse), args.Error(1)
+}
+
+func (m *MockFileClient) BatchDelete(ctx context.Context, in *api.BatchRequest, opts ...grpc.CallOption) (*api.BatchResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.BatchResponse), args.Error(1)
+}
+
+func (m *MockFileClient) BatchMove(ctx context.Context, in *api.BatchMoveRequest, opts ...grpc.CallOption) (*api.BatchResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.BatchResponse), args.Error(1)
+}
+
+func (m *MockFileClient) ListVersions(ctx context.Context, in *api.ListVersionsRequest, opts ...grpc.CallOption) (*api.ListVersionsResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListVersionsResponse), args.Error(1)
+}
+
+func (m *MockFileClient) GetVersionDownloadLink(ctx context.Context, in *api.GetVersionDownloadLinkRequest, opts ...grpc.CallOption) (*api.GetDownloadLinkResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.GetDownloadLinkResponse), args.Error(1)
+}
+
+func (m *MockFileClient) RestoreVersion(ctx context.Context, in *api.RestoreVersionRequest, opts ...grpc.CallOption) (*api.RestoreVersionResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.RestoreVersionResponse), args.Error(1)
+}
+
+func (m *MockFileClient) DeleteVersion(ctx context.Context, in *api.DeleteVersionRequest, opts ...grpc.CallOption) (*api.DeleteVersionResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.DeleteVersionResponse), args.Error(1)
+}
+
+func (m *MockFileClient) DownloadContent(ctx context.Context, in *api.DownloadContentRequest, opts ...grpc.CallOption) (api.FileService_DownloadContentClient, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(api.FileService_DownloadContentClient), args.Error(1)
+}
+
+// fakeContentStream replays messages and then ends with err, or io.EOF.
+type fakeContentStream struct {
+	grpc.ClientStream
+	messages []*api.DownloadContentResponse
+	err      error
+}
+
+func (s *fakeContentStream) Recv() (*api.DownloadContentResponse, error) {
+	if len(s.messages) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	msg := s.messages[0]
+	s.messages = s.messages[1:]
+	return msg, nil
+}
+
+func (m *MockFileClient) UploadContent(ctx context.Context, opts ...grpc.CallOption) (api.FileService_UploadContentClient, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(api.FileService_UploadContentClient), args.Error(1)
+}
+
+func (m *MockFileClient) GetPreviewLink(ctx context.Context, in *api.GetPreviewLinkRequest, opts ...grpc.CallOption) (*api.GetPreviewLinkResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.GetPreviewLinkResponse), args.Error(1)
+}
+
+// fakeUploadStream collects what is sent and answers with resp, or err.
+type fakeUploadStream struct {
+	grpc.ClientStream
+	header *api.InitiateUploadRequest
+	data   []byte
+	resp   *api.UploadContentResponse
+	err    error
+}
+
+func (s *fakeUploadStream) Send(req *api.UploadContentRequest) error {
+	if req.Header != nil {
+		s.header = req.Header
+	}
+	s.data = append(s.data, req.Data...)
+	return nil
+}
+
+func (s *fakeUploadStream) CloseAndRecv() (*api.UploadContentResponse, error) {
+	return s.resp, s.err
+}
+
+func (m *MockFileClient) CreateShareLink(ctx context.Context, in *api.CreateShareLinkRequest, opts ...grpc.CallOption) (*api.CreateShareLinkResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.CreateShareLinkResponse), args.Error(1)
+}
+
+func (m *MockFileClient) ListShareLinks(ctx context.Context, in *api.ListShareLinksRequest, opts ...grpc.CallOption) (*api.ListShareLinksResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.ListShareLinksResponse), args.Error(1)
+}
+
+func (m *MockFileClient) RevokeShareLink(ctx context.Context, in *api.RevokeShareLinkRequest, opts ...grpc.CallOption) (*api.RevokeShareLinkResponse, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*api.RevokeShareLinkResponse), args.Error(1)
+}
+
+func TestFileHandler_HandleFiles_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListMetadata", mock.Anything, mock.Anything).Return(&api.ListMetadataResponse{
+		Items: []*api.FileMetadata{
+			{Id: "file-1", Filename: "test1.txt", UserId: "user-123"},
+			{Id: "file-2", Filename: "test2.txt", UserId: "user-123"},
+		},
+		Total:    proto.Int32(2),
+		Page:     1,
+		PageSize: 20,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?page=1&page_size=20", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFiles(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "file-1")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFiles_MethodNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFiles(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleFiles_WithFilters(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListMetadata", mock.Anything, mock.Anything).Return(&api.ListMetadataResponse{
+		Items: []*api.FileMetadata{},
+		Total: proto.Int32(0),
+		Page:  1,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?page=1&page_size=10&is_trashed=true&sort_by=created_at", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFiles(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFiles_TagFilters(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListMetadata", mock.Anything, mock.MatchedBy(func(req *api.ListMetadataRequest) bool {
+		return len(req.Tags) == 2 &&
+			req.Tags[0].Key == "project" && len(req.Tags[0].Values) == 0 &&
+			req.Tags[1].Key == "status" && assert.ObjectsAreEqual([]string{"draft", "a=b,c"}, req.Tags[1].Values)
+	})).Return(&api.ListMetadataResponse{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?tag=project&tag=status%3Ddraft&tag=status%3Da%3Db%2Cc", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFiles(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleTags(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListTags", mock.Anything, &api.ListTagsRequest{UserId: "user-123", Key: "project"}).Return(&api.ListTagsResponse{
+		Tags: []*api.TagKeyCount{{Key: "project", Count: 2, Values: []*api.TagValueCount{{Value: "apollo", Count: 2}}}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/tags?key=project", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleTags(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "apollo")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleSearch(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("SearchFiles", mock.Anything, mock.MatchedBy(func(req *api.SearchFilesRequest) bool {
+		return req.UserId == "user-123" && req.Query == "report" &&
+			len(req.MimeTypes) == 2 && req.MinSize == 1024 &&
+			req.CreatedAfter.AsTime().Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+			req.CreatedBefore.AsTime().Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) &&
+			req.UpdatedAfter == nil && len(req.Tags) == 1
+	})).Return(&api.SearchFilesResponse{
+		Items: []*api.FileMetadata{{Id: "file-1", OriginalName: "report.pdf"}},
+		Total: 1,
+		Types: []*api.FacetCount{{Value: "application", Count: 1}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/files/search?q=report&type=image&type=application/pdf&min_size=1024&created_after=2026-01-01&created_before=2026-01-31&tag=project", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleSearch(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "report.pdf")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleSearch_InvalidDate(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	handler := NewFileHandler(mockMetadata, new(MockFileClient))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/files/search?updated_after=yesterday", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleSearch(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockMetadata.AssertNotCalled(t, "SearchFiles", mock.Anything, mock.Anything)
+}
+
+func TestFileHandler_HandleFileDetail_Get_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("GetMetadata", mock.Anything, mock.Anything).Return(&api.GetMetadataResponse{
+		Metadata: &api.FileMetadata{
+			Id:           "file-123",
+			Filename:     "test.txt",
+			UserId:       "user-123",
+			OriginalName: "test.txt",
+			Size:         1024,
+			MimeType:     "text/plain",
+			IsPublic:     false,
+			IsTrashed:    false,
+			CreatedAt:    timestamppb.New(time.Now()),
+			UpdatedAt:    timestamppb.New(time.Now()),
+		},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "file-123")
+	assert.Contains(t, rr.Body.String(), "test.txt")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileDetail_Get_NotFound(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("GetMetadata", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileDetail_Update_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("UpdateMetadata", mock.Anything, mock.Anything).Return(&api.UpdateMetadataResponse{
+		Metadata: &api.FileMetadata{
+			Id:       "file-123",
+			Filename: "updated.txt",
+			UserId:   "user-123",
+			IsPublic: true,
+		},
+	}, nil)
+
+	req := NewTestRequest(http.MethodPut, "/api/v1/files/file-123", map[string]interface{}{
+		"filename":  "updated.txt",
+		"is_public": true,
+		"tags":      map[string]string{"version": "2"},
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "updated.txt")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileDetail_Update_InvalidBody(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files/file-123", strings.NewReader("invalid json"))
+	req.Header.Set("Content-Type", "application/json")
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid request body")
+}
+
+func TestFileHandler_HandleFileDetail_Delete_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("DeleteFile", mock.Anything, mock.Anything).Return(&api.DeleteFileResponse{
+		Success: true,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "true")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileDetail_Delete_Error(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("DeleteFile", mock.Anything, mock.Anything).Return(nil, errors.New("delete failed"))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileDetail_MethodNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileDetail(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleInitiateUpload_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("InitiateUpload", mock.Anything, mock.Anything).Return(&api.InitiateUploadResponse{
+		FileId:       "file-123",
+		UploadUrl:    "https://storage.example.com/upload/file-123",
+		UploadMethod: "PUT",
+		Headers:      map[string]string{},
+		ExpiresIn:    900,
+		Success:      true,
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v1/files/upload", map[string]interface{}{
+		"filename":  "test.txt",
+		"path":      "/files",
+		"mime_type": "text/plain",
+		"size":      1024,
+		"is_public": false,
+		"tags":      map[string]string{"type": "document"},
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleInitiateUpload(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "https://storage.example.com/upload/file-123", rr.Header().Get("Location"))
+	assert.Contains(t, rr.Body.String(), "file-123")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleInitiateUpload_QuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("InitiateUpload", mock.Anything, mock.Anything).Return(nil, status.Error(codes.ResourceExhausted, "storage quota exceeded"))
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/upload", map[string]interface{}{
+		"filename": "big.bin",
+		"path":     "/files",
+		"size":     1 << 40,
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleInitiateUpload(rr, req)
+
+	assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
+	assert.Contains(t, rr.Body.String(), "storage quota exceeded")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleInitiateUpload_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodGet, "/api/v1/files/upload", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleInitiateUpload(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleInitiateUpload_InvalidBody(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload", strings.NewReader("invalid json"))
+	req.Header.Set("Content-Type", "application/json")
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleInitiateUpload(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid request body")
+}
+
+func TestFileHandler_HandleCompleteUpload_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("CompleteUpload", mock.Anything, mock.Anything).Return(&api.CompleteUploadResponse{
+		Success:     true,
+		StoragePath: "objects/user-123/file-123",
+		CreatedAt:   timestamppb.New(time.Now()),
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v1/files/upload/complete", map[string]interface{}{
+		"file_id": "file-123",
+		"etag":    "abc123",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCompleteUpload(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "true")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleCompleteUpload_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodGet, "/api/v1/files/upload/complete", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCompleteUpload(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleDownloadLink_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("GetDownloadLink", mock.Anything, mock.Anything).Return(&api.GetDownloadLinkResponse{
+		DownloadUrl: "https://storage.example.com/download/file-123?token=abc",
+		Method:      "GET",
+		Headers:     map[string]string{},
+		ExpiresIn:   3600,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleDownloadLink(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "download")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleDownloadLink_WithExpires(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("GetDownloadLink", mock.Anything, mock.Anything).Return(&api.GetDownloadLinkResponse{
+		DownloadUrl: "https://storage.example.com/download/file-123",
+		Method:      "GET",
+		ExpiresIn:   7200,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download/file-123?expires_in=7200", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleDownloadLink(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleDownloadLink_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/download/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleDownloadLink(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleTrashFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("TrashFile", mock.Anything, mock.Anything).Return(&api.TrashFileResponse{
+		Success: true,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/trash/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleTrashFile(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "true")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleTrashFile_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/trash/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleTrashFile(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleRestoreFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("RestoreFile", mock.Anything, mock.Anything).Return(&api.RestoreFileResponse{
+		Success: true,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/restore/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleRestoreFile(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "true")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleRestoreFile_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/restore/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleRestoreFile(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleInitiateMultipartUpload_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("InitiateMultipartUpload", mock.Anything, mock.MatchedBy(func(r *api.InitiateMultipartUploadRequest) bool {
+		return r.UserId == "user-123" && r.Size == 50<<20
+	})).Return(&api.InitiateMultipartUploadResponse{
+		FileId:    "file-123",
+		UploadId:  "upload-123",
+		PartSize:  16 << 20,
+		PartCount: 4,
+		Success:   true,
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/upload/multipart", map[string]interface{}{
+		"filename":  "video.mp4",
+		"path":      "/videos",
+		"mime_type": "video/mp4",
+		"size":      50 << 20,
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleInitiateMultipartUpload(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), "upload-123")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleInitiateMultipartUpload_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/upload/multipart", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleInitiateMultipartUpload(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleGetPartUploadURLs_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("GetPartUploadURLs", mock.Anything, mock.MatchedBy(func(r *api.GetPartUploadURLsRequest) bool {
+		return r.FileId == "file-123" && len(r.PartNumbers) == 2
+	})).Return(&api.GetPartUploadURLsResponse{
+		Parts: []*api.PartUploadURL{
+			{PartNumber: 1, UploadUrl: "https://storage.example.com/part-1"},
+			{PartNumber: 2, UploadUrl: "https://storage.example.com/part-2"},
+		},
+		UploadMethod: "PUT",
+		ExpiresIn:    900,
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/upload/multipart/parts", map[string]interface{}{
+		"file_id":      "file-123",
+		"part_numbers": []int{1, 2},
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleGetPartUploadURLs(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "part-2")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleCompleteMultipartUpload_Error(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("CompleteMultipartUpload", mock.Anything, mock.Anything).Return(nil, errors.New("part 2 is missing"))
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/upload/multipart/complete", map[string]interface{}{
+		"file_id": "file-123",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCompleteMultipartUpload(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "part 2 is missing")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleAbortMultipartUpload_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("AbortMultipartUpload", mock.Anything, mock.Anything).Return(&api.AbortMultipartUploadResponse{Success: true}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/upload/multipart/abort", map[string]interface{}{
+		"file_id": "file-123",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAbortMultipartUpload(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleUsage_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("GetUsage", mock.Anything, mock.MatchedBy(func(req *api.GetUsageRequest) bool {
+		return req.UserId == "user-123"
+	})).Return(&api.GetUsageResponse{
+		Plan:       "default",
+		UsedBytes:  2048,
+		LimitBytes: 10 << 30,
+	}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/users/me/usage", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleUsage(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "2048")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandlePurgeFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("PurgeFile", mock.Anything, mock.MatchedBy(func(req *api.PurgeFileRequest) bool {
+		return req.FileId == "file-123" && req.UserId == "user-123"
+	})).Return(&api.PurgeFileResponse{Success: true}, nil)
+
+	req := NewTestRequest(http.MethodDelete, "/api/v2/files/purge/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandlePurgeFile(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleEmptyTrash_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("EmptyTrash", mock.Anything, mock.Anything).Return(&api.EmptyTrashResponse{Success: true, Purged: 3}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/trash/empty", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleEmptyTrash(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"purged":3`)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleEmptyTrash_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/trash/empty", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleEmptyTrash(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleTrashRetention(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("SetTrashRetention", mock.Anything, mock.MatchedBy(func(req *api.SetTrashRetentionRequest) bool {
+		return req.UserId == "user-123" && req.RetentionDays == 7
+	})).Return(&api.SetTrashRetentionResponse{RetentionDays: 7}, nil)
+
+	req := ContextWithUser(NewTestRequest(http.MethodPut, "/api/v2/users/me/trash-retention", map[string]interface{}{"retention_days": 7}), "user-123")
+	rr := httptest.NewRecorder()
+	handler.HandleTrashRetention(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"retention_days":7`)
+
+	req = ContextWithUser(NewTestRequest(http.MethodPut, "/api/v2/users/me/trash-retention", map[string]interface{}{"retention_days": -1}), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleTrashRetention(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFolders_List_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListFolder", mock.Anything, mock.MatchedBy(func(req *api.ListFolderRequest) bool {
+		return req.Path == "/docs" && req.Page == 1 && req.PageSize == 20
+	})).Return(&api.ListFolderResponse{
+		Folders: []*api.Folder{{Id: "folder-1", Name: "reports", Path: "/docs/reports"}},
+		Files:   []*api.FileMetadata{{Id: "file-1", Filename: "notes.txt"}},
+		Total:   2,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/folders?path=/docs", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFolders(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "folder-1")
+	assert.Contains(t, rr.Body.String(), "file-1")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFolders_Create_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("CreateFolder", mock.Anything, mock.MatchedBy(func(req *api.CreateFolderRequest) bool {
+		return req.UserId == "user-123" && req.ParentPath == "/" && req.Name == "docs"
+	})).Return(&api.CreateFolderResponse{
+		Folder: &api.Folder{Id: "folder-1", Name: "docs", Path: "/docs"},
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/folders", map[string]string{
+		"parent_path": "/",
+		"name":        "docs",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFolders(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFolders_Delete_Recursive(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("DeleteFolder", mock.Anything, mock.MatchedBy(func(req *api.DeleteFolderRequest) bool {
+		return req.Path == "/docs" && req.Recursive
+	})).Return(&api.DeleteFolderResponse{Success: true, TrashedFiles: 4}, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/folders?path=/docs&recursive=true", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFolders(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"trashed_files":4`)
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleRenameFolder_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("RenameFolder", mock.Anything, mock.Anything).Return(&api.RenameFolderResponse{
+		Folder: &api.Folder{Id: "folder-1", Name: "archive", Path: "/archive"},
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/folders/rename", map[string]string{
+		"path":     "/docs",
+		"new_name": "archive",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleRenameFolder(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "/archive")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleMoveFolder_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/folders/move", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleMoveFolder(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleMoveFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("MoveFile", mock.Anything, mock.MatchedBy(func(req *api.MoveFileRequest) bool {
+		return req.UserId == "user-123" && req.FileId == "file-1" && req.ConflictPolicy == "rename"
+	})).Return(&api.MoveFileResponse{Success: true, FileId: "file-1", Path: "/docs", Name: "report (1).pdf"}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/move", map[string]string{
+		"file_id":         "file-1",
+		"path":            "/docs",
+		"conflict_policy": "rename",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleMoveFile(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "report (1).pdf")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleMoveFile_Conflict(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("MoveFile", mock.Anything, mock.Anything).Return(nil, status.Error(codes.AlreadyExists, "a file with the same name already exists"))
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/move", map[string]string{
+		"file_id": "file-1",
+		"path":    "/docs",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleMoveFile(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestFileHandler_HandleCopyFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("CopyFile", mock.Anything, mock.Anything).Return(&api.CopyFileResponse{Success: true, FileId: "file-2", Path: "/", Name: "report (1).pdf"}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/copy", map[string]string{
+		"file_id":         "file-1",
+		"path":            "/",
+		"conflict_policy": "rename",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCopyFile(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), "file-2")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleCopyFile_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/copy", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCopyFile(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleBatch_Trash(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("BatchTrash", mock.Anything, mock.MatchedBy(func(req *api.BatchRequest) bool {
+		return req.UserId == "user-123" && len(req.FileIds) == 2
+	})).Return(&api.BatchResponse{
+		Results: []*api.BatchItemResult{
+			{FileId: "file-1", Success: true},
+			{FileId: "file-2", Error: "file not found or access denied"},
+		},
+		Succeeded: 1,
+		Failed:    1,
+	}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/batch", map[string]interface{}{
+		"action":   "trash",
+		"file_ids": []string{"file-1", "file-2"},
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleBatch(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "file not found or access denied")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleBatch_Move(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("BatchMove", mock.Anything, mock.MatchedBy(func(req *api.BatchMoveRequest) bool {
+		return req.Path == "/archive" && req.ConflictPolicy == "rename"
+	})).Return(&api.BatchResponse{Succeeded: 1}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/batch", map[string]interface{}{
+		"action":          "move",
+		"file_ids":        []string{"file-1"},
+		"path":            "/archive",
+		"conflict_policy": "rename",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleBatch(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleBatch_UnsupportedAction(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/batch", map[string]interface{}{
+		"action":   "archive",
+		"file_ids": []string{"file-1"},
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleBatch(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestFileHandler_HandleFileVersions_List(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("ListVersions", mock.Anything, &api.ListVersionsRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+	}).Return(&api.ListVersionsResponse{
+		CurrentVersion: 2,
+		Versions: []*api.FileVersion{
+			{Id: "file-123", Version: 2, IsCurrent: true},
+			{Id: "version-1", Version: 1},
+		},
+	}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/versions/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileVersions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "version-1")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileVersions_Download(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("GetVersionDownloadLink", mock.Anything, mock.MatchedBy(func(req *api.GetVersionDownloadLinkRequest) bool {
+		return req.FileId == "file-123" && req.VersionId == "version-1" && req.ExpiresIn == 600
+	})).Return(&api.GetDownloadLinkResponse{DownloadUrl: "https://storage.example.com/version-1"}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/versions/file-123/version-1/download?expires_in=600", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileVersions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "storage.example.com")
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileVersions_Restore(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("RestoreVersion", mock.Anything, &api.RestoreVersionRequest{
+		FileId:    "file-123",
+		VersionId: "version-1",
+		UserId:    "user-123",
+	}).Return(&api.RestoreVersionResponse{Success: true, CurrentVersion: 3}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/versions/file-123/version-1/restore", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileVersions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileVersions_Delete(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("DeleteVersion", mock.Anything, &api.DeleteVersionRequest{
+		FileId:    "file-123",
+		VersionId: "version-1",
+		UserId:    "user-123",
+	}).Return(&api.DeleteVersionResponse{Success: true}, nil)
+
+	req := NewTestRequest(http.MethodDelete, "/api/v2/files/versions/file-123/version-1", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileVersions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileVersions_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodPut, "/api/v2/files/versions/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileVersions(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleFileShares_Share(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ShareFile", mock.Anything, mock.MatchedBy(func(req *api.ShareFileRequest) bool {
+		return req.FileId == "file-123" && req.UserId == "user-123" && req.Email == "bob@example.com" && req.Role == "editor"
+	})).Return(&api.ShareFileResponse{Share: &api.FileShare{Id: "share-1", Email: "bob@example.com", Role: "editor"}}, nil)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/shares/file-123", map[string]interface{}{
+		"email": "bob@example.com",
+		"role":  "editor",
+	})
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileShares(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), "share-1")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileShares_List(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListShares", mock.Anything, &api.ListSharesRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+	}).Return(&api.ListSharesResponse{Shares: []*api.FileShare{{Email: "bob@example.com", Role: "viewer"}}}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/shares/file-123", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileShares(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "bob@example.com")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleFileShares_Unshare(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("UnshareFile", mock.Anything, &api.UnshareFileRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+		Email:  "bob@example.com",
+	}).Return(&api.UnshareFileResponse{Success: true}, nil)
+
+	req := NewTestRequest(http.MethodDelete, "/api/v2/files/shares/file-123?email=bob@example.com", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleFileShares(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleSharedWithMe_Success(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockMetadata.On("ListSharedWithMe", mock.Anything, &api.ListSharedWithMeRequest{
+		UserId:   "user-123",
+		Page:     2,
+		PageSize: 20,
+	}).Return(&api.ListSharedWithMeResponse{
+		Items: []*api.SharedFile{
+			{Metadata: &api.FileMetadata{Id: "file-9", Filename: "plan.txt"}, Role: "viewer", OwnerEmail: "alice@example.com"},
+		},
+		Total: 21,
+	}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/shared?page=2", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleSharedWithMe(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "alice@example.com")
+	mockMetadata.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleSharedWithMe_InvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/shared", nil)
+	req = ContextWithUser(req, "user-123")
+	rr := httptest.NewRecorder()
+
+	handler.HandleSharedWithMe(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestFileHandler_HandleFileLinks_Create(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	mockFile := new(MockFileClient)
+	handler := NewFileHandler(mockMetadata, mockFile)
+
+	mockFile.On("CreateShareLink", mock.Anything, mock.MatchedBy(func(req *api.CreateShareLinkRequest) bool {
+		return req.FileId == "file-123" && req.UserId == "user-123" && req.ExpiresIn == 3600 && req.MaxDownloads == 5 && req.Password == "secret"
+	})).Return(&api.CreateShareLinkResponse{
+		Link:  &api.ShareLink{Id: "link-1", FileId: "file-123", MaxDownloads: 5, HasPassword: true},
+		Token: "token-abc",
+		Url:   "/s/token-abc",
+	}, nil)
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files", nil)
+	req := NewTestRequest(http.MethodPost, "/api/v2/files/links/file-123", map[string]interface{}{
+		"expires_in":    3600,
+		"max_downloads": 5,
+		"password":      "secret",
+	})
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFiles(rr, req)
+	handler.HandleFileLinks(rr, req)
 
-	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), "/s/token-abc")
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFiles_WithFilters(t *testing.T) {
+func TestFileHandler_HandleFileLinks_List(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockMetadata.On("ListMetadata", mock.Anything, mock.Anything).Return(&api.ListMetadataResponse{
-		Items: []*api.FileMetadata{},
-		Total: 0,
-		Page:  1,
+	mockFile.On("ListShareLinks", mock.Anything, &api.ListShareLinksRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+	}).Return(&api.ListShareLinksResponse{
+		Links: []*api.ShareLink{{Id: "link-1", FileId: "file-123", DownloadCount: 2}},
 	}, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?page=1&page_size=10&is_trashed=true&sort_by=created_at", nil)
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/links/file-123", nil)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFiles(rr, req)
+	handler.HandleFileLinks(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
-	mockMetadata.AssertExpectations(t)
+	assert.Contains(t, rr.Body.String(), "link-1")
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFileDetail_Get_Success(t *testing.T) {
+func TestFileHandler_HandleFileLinks_Revoke(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockMetadata.On("GetMetadata", mock.Anything, mock.Anything).Return(&api.GetMetadataResponse{
-		Metadata: &api.FileMetadata{
-			Id:           "file-123",
-			Filename:     "test.txt",
-			UserId:       "user-123",
-			OriginalName: "test.txt",
-			Size:         1024,
-			MimeType:     "text/plain",
-			IsPublic:     false,
-			IsTrashed:    false,
-			CreatedAt:    timestamppb.New(time.Now()),
-			UpdatedAt:    timestamppb.New(time.Now()),
-		},
-	}, nil)
+	mockFile.On("RevokeShareLink", mock.Anything, &api.RevokeShareLinkRequest{
+		FileId: "file-123",
+		LinkId: "link-1",
+		UserId: "user-123",
+	}).Return(&api.RevokeShareLinkResponse{Success: true}, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/file-123", nil)
+	req := NewTestRequest(http.MethodDelete, "/api/v2/files/links/file-123/link-1", nil)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFileDetail(rr, req)
+	handler.HandleFileLinks(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "file-123")
-	assert.Contains(t, rr.Body.String(), "test.txt")
-	mockMetadata.AssertExpectations(t)
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFileDetail_Get_NotFound(t *testing.T) {
+func TestFileHandler_HandleShareLink_Redirect(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockMetadata.On("GetMetadata", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+	mockFile.On("GetDownloadLink", mock.Anything, &api.GetDownloadLinkRequest{
+		ShareToken: "token-abc",
+		Password:   "secret",
+	}).Return(&api.GetDownloadLinkResponse{DownloadUrl: "https://storage.example.com/report.pdf"}, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/file-123", nil)
-	req = ContextWithUser(req, "user-123")
+	req := NewTestRequest(http.MethodGet, "/s/token-abc", nil)
+	req.Header.Set("X-Share-Password", "secret")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFileDetail(rr, req)
+	handler.HandleShareLink(rr, req)
 
-	assert.Equal(t, http.StatusNotFound, rr.Code)
-	mockMetadata.AssertExpectations(t)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://storage.example.com/report.pdf", rr.Header().Get("Location"))
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFileDetail_Update_Success(t *testing.T) {
+func TestFileHandler_HandleShareLink_Errors(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockMetadata.On("UpdateMetadata", mock.Anything, mock.Anything).Return(&api.UpdateMetadataResponse{
-		Metadata: &api.FileMetadata{
-			Id:       "file-123",
-			Filename: "updated.txt",
-			UserId:   "user-123",
-			IsPublic: true,
-		},
-	}, nil)
-
-	req := NewTestRequest(http.MethodPut, "/api/v1/files/file-123", map[string]interface{}{
-		"filename":  "updated.txt",
-		"is_public": true,
-		"tags":      map[string]string{"version": "2"},
-	})
-	req = ContextWithUser(req, "user-123")
-	rr := httptest.NewRecorder()
-
-	handler.HandleFileDetail(rr, req)
+	mockFile.On("GetDownloadLink", mock.Anything, &api.GetDownloadLinkRequest{ShareToken: "expired"}).
+		Return(nil, status.Error(codes.NotFound, "share link has expired"))
+	mockFile.On("GetDownloadLink", mock.Anything, &api.GetDownloadLinkRequest{ShareToken: "locked"}).
+		Return(nil, status.Error(codes.Unauthenticated, "password required"))
 
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "updated.txt")
-	mockMetadata.AssertExpectations(t)
+	tests := map[string]int{
+		"/s/expired": http.StatusNotFound,
+		"/s/locked":  http.StatusUnauthorized,
+		"/s/":        http.StatusNotFound,
+	}
+	for path, code := range tests {
+		rr := httptest.NewRecorder()
+		handler.HandleShareLink(rr, NewTestRequest(http.MethodGet, path, nil))
+		assert.Equal(t, code, rr.Code, path)
+	}
 }
 
-func TestFileHandler_HandleFileDetail_Update_InvalidBody(t *testing.T) {
+func TestFileHandler_HandleShareLink_PasswordSource(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := httptest.NewRequest(http.MethodPut, "/api/v1/files/file-123", strings.NewReader("invalid json"))
-	req.Header.Set("Content-Type", "application/json")
-	req = ContextWithUser(req, "user-123")
-	rr := httptest.NewRecorder()
-
-	handler.HandleFileDetail(rr, req)
+	mockFile.On("GetDownloadLink", mock.Anything, &api.GetDownloadLinkRequest{ShareToken: "token-abc"}).
+		Return(nil, status.Error(codes.Unauthenticated, "password required"))
+	mockFile.On("GetDownloadLink", mock.Anything, &api.GetDownloadLinkRequest{
+		ShareToken: "token-abc",
+		Password:   "secret",
+	}).Return(&api.GetDownloadLinkResponse{DownloadUrl: "https://storage.example.com/report.pdf"}, nil)
 
-	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Contains(t, rr.Body.String(), "invalid request body")
+	rr := httptest.NewRecorder()
+	handler.HandleShareLink(rr, NewTestRequest(http.MethodGet, "/s/token-abc?password=secret", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/s/token-abc", strings.NewReader("password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	handler.HandleShareLink(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFileDetail_Delete_Success(t *testing.T) {
+func TestFileHandler_HandleFiles_SharedFolder(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockFile.On("DeleteFile", mock.Anything, mock.Anything).Return(&api.DeleteFileResponse{
-		Success: true,
+	mockMetadata.On("ListMetadata", mock.Anything, mock.MatchedBy(func(req *api.ListMetadataRequest) bool {
+		return req.UserId == "user-123" && req.OwnerId == "owner-1" && req.Path == "/projects"
+	})).Return(&api.ListMetadataResponse{
+		Items: []*api.FileMetadata{{Id: "file-9", UserId: "owner-1", Path: "/projects"}},
+		Total: proto.Int32(1),
 	}, nil)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files/file-123", nil)
+	req := NewTestRequest(http.MethodGet, "/api/v2/files?owner_id=owner-1&path=/projects", nil)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFileDetail(rr, req)
+	handler.HandleFiles(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "true")
-	mockFile.AssertExpectations(t)
+	assert.Contains(t, rr.Body.String(), "file-9")
+	mockMetadata.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFileDetail_Delete_Error(t *testing.T) {
+func TestFileHandler_HandleFolderShares_Share(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockFile.On("DeleteFile", mock.Anything, mock.Anything).Return(nil, errors.New("delete failed"))
+	mockMetadata.On("ShareFolder", mock.Anything, mock.MatchedBy(func(req *api.ShareFolderRequest) bool {
+		return req.UserId == "user-123" && req.Path == "/projects" && req.Email == "bob@example.com" && req.Role == "editor"
+	})).Return(&api.ShareFolderResponse{
+		Share: &api.FolderShare{Id: "share-1", Path: "/projects", Email: "bob@example.com", Role: "editor"},
+	}, nil)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files/file-123", nil)
+	req := NewTestRequest(http.MethodPost, "/api/v2/folders/shares", map[string]string{
+		"path":  "/projects",
+		"email": "bob@example.com",
+		"role":  "editor",
+	})
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFileDetail(rr, req)
+	handler.HandleFolderShares(rr, req)
 
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
-	mockFile.AssertExpectations(t)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), "share-1")
+	mockMetadata.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleFileDetail_MethodNotAllowed(t *testing.T) {
+func TestFileHandler_HandleFolderShares_Unshare(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files/file-123", nil)
+	mockMetadata.On("UnshareFolder", mock.Anything, &api.UnshareFolderRequest{
+		UserId:  "user-123",
+		OwnerId: "owner-1",
+		Path:    "/projects",
+		Email:   "me@example.com",
+	}).Return(&api.UnshareFolderResponse{Success: true}, nil)
+
+	req := NewTestRequest(http.MethodDelete, "/api/v2/folders/shares?owner_id=owner-1&path=/projects&email=me@example.com", nil)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleFileDetail(rr, req)
+	handler.HandleFolderShares(rr, req)
 
-	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockMetadata.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleInitiateUpload_Success(t *testing.T) {
+func TestFileHandler_HandleFoldersSharedWithMe_Success(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockFile.On("InitiateUpload", mock.Anything, mock.Anything).Return(&api.InitiateUploadResponse{
-		FileId:       "file-123",
-		UploadUrl:    "https://storage.example.com/upload/file-123",
-		UploadMethod: "PUT",
-		Headers:      map[string]string{},
-		ExpiresIn:    900,
-		Success:      true,
+	mockMetadata.On("ListFoldersSharedWithMe", mock.Anything, &api.ListFoldersSharedWithMeRequest{
+		UserId: "user-123",
+	}).Return(&api.ListFoldersSharedWithMeResponse{
+		Folders: []*api.SharedFolder{{OwnerId: "owner-1", OwnerEmail: "alice@example.com", Path: "/projects", Role: "viewer"}},
 	}, nil)
 
-	req := NewTestRequest(http.MethodPost, "/api/v1/files/upload", map[string]interface{}{
-		"filename":  "test.txt",
-		"path":      "/files",
-		"mime_type": "text/plain",
-		"size":      1024,
-		"is_public": false,
-		"tags":      map[string]string{"type": "document"},
-	})
+	req := NewTestRequest(http.MethodGet, "/api/v2/folders/shared", nil)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleInitiateUpload(rr, req)
+	handler.HandleFoldersSharedWithMe(rr, req)
 
-	assert.Equal(t, http.StatusCreated, rr.Code)
-	assert.Equal(t, "https://storage.example.com/upload/file-123", rr.Header().Get("Location"))
-	assert.Contains(t, rr.Body.String(), "file-123")
-	mockFile.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "alice@example.com")
+	mockMetadata.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleInitiateUpload_InvalidMethod(t *testing.T) {
+func TestFileHandler_HandleFileContent_Full(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := NewTestRequest(http.MethodGet, "/api/v1/files/upload", nil)
+	mockFile.On("DownloadContent", mock.Anything, &api.DownloadContentRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+	}).Return(&fakeContentStream{messages: []*api.DownloadContentResponse{
+		{Info: &api.ContentInfo{Filename: "report.pdf", MimeType: "application/pdf", Size: 11, Etag: `"etag-1"`, Length: 11}},
+		{Data: []byte("hello ")},
+		{Data: []byte("world")},
+	}}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/file-123/content", nil)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleInitiateUpload(rr, req)
+	handler.HandleFileDetail(rr, req)
 
-	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "hello world", rr.Body.String())
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=report.pdf", rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, `"etag-1"`, rr.Header().Get("ETag"))
+	assert.Equal(t, "11", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleInitiateUpload_InvalidBody(t *testing.T) {
+func TestFileHandler_HandleFileContent_Range(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload", strings.NewReader("invalid json"))
-	req.Header.Set("Content-Type", "application/json")
+	mockFile.On("DownloadContent", mock.Anything, &api.DownloadContentRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+		Range:  "bytes=6-",
+	}).Return(&fakeContentStream{messages: []*api.DownloadContentResponse{
+		{Info: &api.ContentInfo{Filename: "hello.txt", MimeType: "text/plain", Size: 11, Etag: `"etag-1"`, Offset: 6, Length: 5, Partial: true}},
+		{Data: []byte("world")},
+	}}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/file-123/content", nil)
+	req.Header.Set("Range", "bytes=6-")
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleInitiateUpload(rr, req)
+	handler.HandleFileContent(rr, req)
 
-	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Contains(t, rr.Body.String(), "invalid request body")
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "world", rr.Body.String())
+	assert.Equal(t, "bytes 6-10/11", rr.Header().Get("Content-Range"))
 }
 
-func TestFileHandler_HandleCompleteUpload_Success(t *testing.T) {
+func TestFileHandler_HandleFileContent_NotModified(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockFile.On("CompleteUpload", mock.Anything, mock.Anything).Return(&api.CompleteUploadResponse{
-		Success:     true,
-		StoragePath: "objects/user-123/file-123",
-		CreatedAt:   timestamppb.New(time.Now()),
-	}, nil)
+	mockFile.On("DownloadContent", mock.Anything, &api.DownloadContentRequest{
+		FileId:      "file-123",
+		UserId:      "user-123",
+		IfNoneMatch: `"etag-1"`,
+	}).Return(&fakeContentStream{messages: []*api.DownloadContentResponse{
+		{Info: &api.ContentInfo{Etag: `"etag-1"`, NotModified: true}},
+	}}, nil)
 
-	req := NewTestRequest(http.MethodPost, "/api/v1/files/upload/complete", map[string]interface{}{
-		"file_id": "file-123",
-		"etag":    "abc123",
-	})
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/file-123/content", nil)
+	req.Header.Set("If-None-Match", `"etag-1"`)
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleCompleteUpload(rr, req)
+	handler.HandleFileContent(rr, req)
 
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "true")
-	mockFile.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, `"etag-1"`, rr.Header().Get("ETag"))
 }
 
-func TestFileHandler_HandleCompleteUpload_InvalidMethod(t *testing.T) {
+func TestFileHandler_HandleFileContent_Errors(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := NewTestRequest(http.MethodGet, "/api/v1/files/upload/complete", nil)
+	mockFile.On("DownloadContent", mock.Anything, mock.MatchedBy(func(req *api.DownloadContentRequest) bool {
+		return req.Range == "bytes=500-"
+	})).Return(&fakeContentStream{messages: []*api.DownloadContentResponse{
+		{Info: &api.ContentInfo{Etag: `"etag-1"`, Size: 100, RangeNotSatisfiable: true}},
+	}}, nil)
+	mockFile.On("DownloadContent", mock.Anything, mock.MatchedBy(func(req *api.DownloadContentRequest) bool {
+		return req.FileId == "file-999"
+	})).Return(&fakeContentStream{err: status.Error(codes.PermissionDenied, "access denied")}, nil)
+
+	req := NewTestRequest(http.MethodGet, "/api/v2/files/file-123/content", nil)
+	req.Header.Set("Range", "bytes=500-")
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
-
-	handler.HandleCompleteUpload(rr, req)
-
+	handler.HandleFileContent(rr, req)
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rr.Code)
+	assert.Equal(t, "bytes */100", rr.Header().Get("Content-Range"))
+
+	req = ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/file-999/content", nil), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleFileContent(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	req = ContextWithUser(NewTestRequest(http.MethodPost, "/api/v2/files/file-123/content", nil), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleFileContent(rr, req)
 	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
 }
 
-func TestFileHandler_HandleDownloadLink_Success(t *testing.T) {
+func TestFileHandler_HandleUploadContent(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockFile.On("GetDownloadLink", mock.Anything, mock.Anything).Return(&api.GetDownloadLinkResponse{
-		DownloadUrl: "https://storage.example.com/download/file-123?token=abc",
-		Method:      "GET",
-		Headers:     map[string]string{},
-		ExpiresIn:   3600,
-	}, nil)
+	stream := &fakeUploadStream{resp: &api.UploadContentResponse{FileId: "file-123", VersionId: "version-2", Size: 11, Version: 2}}
+	mockFile.On("UploadContent", mock.Anything).Return(stream, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download/file-123", nil)
+	req := httptest.NewRequest(http.MethodPut, "/api/v2/files/file-123/content", strings.NewReader("hello world"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Checksum-Algorithm", "SHA256")
+	req.Header.Set("X-Checksum", "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=")
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleDownloadLink(rr, req)
+	handler.HandleFileDetail(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "download")
+	assert.Contains(t, rr.Body.String(), "version-2")
+	assert.Equal(t, &api.InitiateUploadRequest{
+		UserId:            "user-123",
+		FileId:            "file-123",
+		Size:              11,
+		MimeType:          "text/plain",
+		ChecksumAlgorithm: "SHA256",
+		Checksum:          "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=",
+	}, stream.header)
+	assert.Equal(t, "hello world", string(stream.data))
 	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleDownloadLink_WithExpires(t *testing.T) {
+func TestFileHandler_HandleUploadContent_Rejected(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockFile.On("GetDownloadLink", mock.Anything, mock.Anything).Return(&api.GetDownloadLinkResponse{
-		DownloadUrl: "https://storage.example.com/download/file-123",
-		Method:      "GET",
-		ExpiresIn:   7200,
-	}, nil)
+	mockFile.On("UploadContent", mock.Anything).Return(&fakeUploadStream{err: status.Error(codes.InvalidArgument, "upload rejected: SHA256 checksum mismatch")}, nil).Once()
+	mockFile.On("UploadContent", mock.Anything).Return(&fakeUploadStream{err: status.Error(codes.ResourceExhausted, "storage quota exceeded")}, nil).Once()
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download/file-123?expires_in=7200", nil)
-	req = ContextWithUser(req, "user-123")
+	req := ContextWithUser(httptest.NewRequest(http.MethodPut, "/api/v2/files/file-123/content", strings.NewReader("hello")), "user-123")
 	rr := httptest.NewRecorder()
+	handler.HandleFileContent(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
 
-	handler.HandleDownloadLink(rr, req)
-
-	assert.Equal(t, http.StatusOK, rr.Code)
+	req = ContextWithUser(httptest.NewRequest(http.MethodPut, "/api/v2/files/file-123/content", strings.NewReader("hello")), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleFileContent(rr, req)
+	assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
 	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleDownloadLink_InvalidMethod(t *testing.T) {
+func TestFileHandler_HandleFormUpload(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/download/file-123", nil)
+	stream := &fakeUploadStream{resp: &api.UploadContentResponse{FileId: "file-123", Size: 11, Version: 1}}
+	mockFile.On("UploadContent", mock.Anything).Return(stream, nil)
+
+	var body strings.Builder
+	form := multipart.NewWriter(&body)
+	form.WriteField("path", "/docs")
+	form.WriteField("is_public", "true")
+	part, _ := form.CreateFormFile("file", "hello.txt")
+	part.Write([]byte("hello world"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/files", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", form.FormDataContentType())
 	req = ContextWithUser(req, "user-123")
 	rr := httptest.NewRecorder()
 
-	handler.HandleDownloadLink(rr, req)
+	handler.HandleFiles(rr, req)
 
-	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), "file-123")
+	assert.Equal(t, &api.InitiateUploadRequest{
+		UserId:   "user-123",
+		Filename: "hello.txt",
+		Path:     "/docs",
+		IsPublic: true,
+		Size:     -1,
+		MimeType: "application/octet-stream",
+	}, stream.header)
+	assert.Equal(t, "hello world", string(stream.data))
+	mockFile.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleTrashFile_Success(t *testing.T) {
+func TestFileHandler_HandleFormUpload_InvalidBody(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	mockMetadata.On("TrashFile", mock.Anything, mock.Anything).Return(&api.TrashFileResponse{
-		Success: true,
-	}, nil)
-
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/trash/file-123", nil)
-	req = ContextWithUser(req, "user-123")
+	req := ContextWithUser(NewTestRequest(http.MethodPost, "/api/v2/files", map[string]string{"path": "/"}), "user-123")
 	rr := httptest.NewRecorder()
+	handler.HandleFiles(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
 
-	handler.HandleTrashFile(rr, req)
+	var body strings.Builder
+	form := multipart.NewWriter(&body)
+	form.WriteField("path", "/docs")
+	form.Close()
 
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "true")
-	mockMetadata.AssertExpectations(t)
+	req = httptest.NewRequest(http.MethodPost, "/api/v2/files", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req = ContextWithUser(req, "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleFiles(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "file part is required")
+	mockFile.AssertNotCalled(t, "UploadContent", mock.Anything)
 }
 
-func TestFileHandler_HandleTrashFile_InvalidMethod(t *testing.T) {
+func TestFileHandler_HandlePreviewLink(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
 	mockFile := new(MockFileClient)
 	handler := NewFileHandler(mockMetadata, mockFile)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/trash/file-123", nil)
-	req = ContextWithUser(req, "user-123")
+	mockFile.On("GetPreviewLink", mock.Anything, &api.GetPreviewLinkRequest{
+		FileId:    "file-123",
+		UserId:    "user-123",
+		Size:      "medium",
+		ExpiresIn: 600,
+	}).Return(&api.GetPreviewLinkResponse{
+		State:     "ready",
+		Url:       "https://storage.example.com/previews/file-123/medium.jpg",
+		MimeType:  "image/jpeg",
+		ExpiresIn: 600,
+	}, nil)
+	mockFile.On("GetPreviewLink", mock.Anything, &api.GetPreviewLinkRequest{
+		FileId: "file-456",
+		UserId: "user-123",
+	}).Return(&api.GetPreviewLinkResponse{State: "pending"}, nil)
+	mockFile.On("GetPreviewLink", mock.Anything, &api.GetPreviewLinkRequest{
+		FileId: "file-789",
+		UserId: "user-123",
+	}).Return(nil, status.Error(codes.NotFound, "file has no preview"))
+
+	req := ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/file-123/preview?size=medium&expires_in=600", nil), "user-123")
 	rr := httptest.NewRecorder()
+	handler.HandleFileDetail(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "previews/file-123/medium.jpg")
 
-	handler.HandleTrashFile(rr, req)
+	req = ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/file-456/preview", nil), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleFileDetail(rr, req)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Contains(t, rr.Body.String(), "pending")
+
+	req = ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/file-789/preview", nil), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleFileDetail(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileHandler_HandleStar(t *testing.T) {
+	t.Parallel()
+
+	mockMetadata := new(MockMetadataClient)
+	handler := NewFileHandler(mockMetadata, new(MockFileClient))
+
+	mockMetadata.On("StarFile", mock.Anything, &api.StarFileRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+	}).Return(&api.StarFileResponse{Success: true}, nil)
+	mockMetadata.On("UnstarFile", mock.Anything, &api.UnstarFileRequest{
+		FileId: "file-123",
+		UserId: "user-123",
+	}).Return(&api.UnstarFileResponse{Success: true}, nil)
+
+	req := ContextWithUser(NewTestRequest(http.MethodPost, "/api/v2/files/star/file-123", nil), "user-123")
+	rr := httptest.NewRecorder()
+	handler.HandleStar(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req = ContextWithUser(NewTestRequest(http.MethodDelete, "/api/v2/files/star/file-123", nil), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleStar(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
 
+	req = ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/star/file-123", nil), "user-123")
+	rr = httptest.NewRecorder()
+	handler.HandleStar(rr, req)
 	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	mockMetadata.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleRestoreFile_Success(t *testing.T) {
+func TestFileHandler_HandleRecent(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
-	mockFile := new(MockFileClient)
-	handler := NewFileHandler(mockMetadata, mockFile)
+	handler := NewFileHandler(mockMetadata, new(MockFileClient))
 
-	mockMetadata.On("RestoreFile", mock.Anything, mock.Anything).Return(&api.RestoreFileResponse{
-		Success: true,
-	}, nil)
+	mockMetadata.On("ListRecent", mock.Anything, &api.ListRecentRequest{
+		UserId: "user-123",
+		Limit:  10,
+	}).Return(&api.ListRecentResponse{Items: []*api.FileActivity{
+		{Metadata: &api.FileMetadata{Id: "file-123", OriginalName: "plan.txt"}, LastAction: "download"},
+	}}, nil)
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/restore/file-123", nil)
-	req = ContextWithUser(req, "user-123")
+	req := ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/recent?limit=10", nil), "user-123")
 	rr := httptest.NewRecorder()
-
-	handler.HandleRestoreFile(rr, req)
+	handler.HandleRecent(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "true")
+	assert.Contains(t, rr.Body.String(), "plan.txt")
+	assert.Contains(t, rr.Body.String(), "download")
 	mockMetadata.AssertExpectations(t)
 }
 
-func TestFileHandler_HandleRestoreFile_InvalidMethod(t *testing.T) {
+func TestFileHandler_HandleStarred(t *testing.T) {
 	t.Parallel()
 
 	mockMetadata := new(MockMetadataClient)
-	mockFile := new(MockFileClient)
-	handler := NewFileHandler(mockMetadata, mockFile)
+	handler := NewFileHandler(mockMetadata, new(MockFileClient))
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/restore/file-123", nil)
-	req = ContextWithUser(req, "user-123")
-	rr := httptest.NewRecorder()
+	mockMetadata.On("ListStarred", mock.Anything, &api.ListStarredRequest{
+		UserId:   "user-123",
+		Page:     2,
+		PageSize: 20,
+	}).Return(&api.ListStarredResponse{
+		Items:    []*api.FileActivity{{Metadata: &api.FileMetadata{Id: "file-123", OriginalName: "plan.txt"}}},
+		Total:    21,
+		Page:     2,
+		PageSize: 20,
+	}, nil)
 
-	handler.HandleRestoreFile(rr, req)
+	req := ContextWithUser(NewTestRequest(http.MethodGet, "/api/v2/files/starred?page=2&page_size=500", nil), "user-123")
+	rr := httptest.NewRecorder()
+	handler.HandleStarred(rr, req)
 
-	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "plan.txt")
+	mockMetadata.AssertExpectations(t)
 }
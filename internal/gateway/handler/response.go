@@ -3,6 +3,9 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func JSONResponse(w http.ResponseWriter, status int, data interface{}) {
@@ -10,3 +13,24 @@ func JSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
+
+func grpcHTTPStatus(err error) int {
+	switch status.Code(err) {
+	case codes.ResourceExhausted:
+		return http.StatusInsufficientStorage
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.OutOfRange:
+		return http.StatusRequestedRangeNotSatisfiable
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -79,9 +79,38 @@ func NewServer(config *configs.Config) (*Server, error) {
 	mux.HandleFunc("/api/v2/files/", middleware.WithAuth(server.fileHandler.HandleFileDetail, authClient))
 	mux.HandleFunc("/api/v2/files/upload", middleware.WithAuth(server.fileHandler.HandleInitiateUpload, authClient))
 	mux.HandleFunc("/api/v2/files/upload/complete", middleware.WithAuth(server.fileHandler.HandleCompleteUpload, authClient))
+	mux.HandleFunc("/api/v2/files/upload/multipart", middleware.WithAuth(server.fileHandler.HandleInitiateMultipartUpload, authClient))
+	mux.HandleFunc("/api/v2/files/upload/multipart/parts", middleware.WithAuth(server.fileHandler.HandleGetPartUploadURLs, authClient))
+	mux.HandleFunc("/api/v2/files/upload/multipart/complete", middleware.WithAuth(server.fileHandler.HandleCompleteMultipartUpload, authClient))
+	mux.HandleFunc("/api/v2/files/upload/multipart/abort", middleware.WithAuth(server.fileHandler.HandleAbortMultipartUpload, authClient))
 	mux.HandleFunc("/api/v2/files/download/", middleware.WithAuth(server.fileHandler.HandleDownloadLink, authClient))
 	mux.HandleFunc("/api/v2/files/trash/", middleware.WithAuth(server.fileHandler.HandleTrashFile, authClient))
 	mux.HandleFunc("/api/v2/files/restore/", middleware.WithAuth(server.fileHandler.HandleRestoreFile, authClient))
+	mux.HandleFunc("/api/v2/files/purge/", middleware.WithAuth(server.fileHandler.HandlePurgeFile, authClient))
+	mux.HandleFunc("/api/v2/files/move", middleware.WithAuth(server.fileHandler.HandleMoveFile, authClient))
+	mux.HandleFunc("/api/v2/files/copy", middleware.WithAuth(server.fileHandler.HandleCopyFile, authClient))
+	mux.HandleFunc("/api/v2/files/batch", middleware.WithAuth(server.fileHandler.HandleBatch, authClient))
+	mux.HandleFunc("/api/v2/files/versions/", middleware.WithAuth(server.fileHandler.HandleFileVersions, authClient))
+	mux.HandleFunc("/api/v2/files/shares/", middleware.WithAuth(server.fileHandler.HandleFileShares, authClient))
+	mux.HandleFunc("/api/v2/files/shared", middleware.WithAuth(server.fileHandler.HandleSharedWithMe, authClient))
+	mux.HandleFunc("/api/v2/files/links/", middleware.WithAuth(server.fileHandler.HandleFileLinks, authClient))
+	mux.HandleFunc("/api/v2/files/search", middleware.WithAuth(server.fileHandler.HandleSearch, authClient))
+	mux.HandleFunc("/api/v2/files/star/", middleware.WithAuth(server.fileHandler.HandleStar, authClient))
+	mux.HandleFunc("/api/v2/files/recent", middleware.WithAuth(server.fileHandler.HandleRecent, authClient))
+	mux.HandleFunc("/api/v2/files/starred", middleware.WithAuth(server.fileHandler.HandleStarred, authClient))
+	mux.HandleFunc("/api/v2/trash/empty", middleware.WithAuth(server.fileHandler.HandleEmptyTrash, authClient))
+	mux.HandleFunc("/api/v2/tags", middleware.WithAuth(server.fileHandler.HandleTags, authClient))
+
+	mux.HandleFunc("/api/v2/folders", middleware.WithAuth(server.fileHandler.HandleFolders, authClient))
+	mux.HandleFunc("/api/v2/folders/rename", middleware.WithAuth(server.fileHandler.HandleRenameFolder, authClient))
+	mux.HandleFunc("/api/v2/folders/move", middleware.WithAuth(server.fileHandler.HandleMoveFolder, authClient))
+	mux.HandleFunc("/api/v2/folders/shares", middleware.WithAuth(server.fileHandler.HandleFolderShares, authClient))
+	mux.HandleFunc("/api/v2/folders/shared", middleware.WithAuth(server.fileHandler.HandleFoldersSharedWithMe, authClient))
+
+	mux.HandleFunc("/api/v2/users/me/usage", middleware.WithAuth(server.fileHandler.HandleUsage, authClient))
+	mux.HandleFunc("/api/v2/users/me/trash-retention", middleware.WithAuth(server.fileHandler.HandleTrashRetention, authClient))
+
+	mux.HandleFunc("/s/", server.fileHandler.HandleShareLink)
 
 	server.httpServer = &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", config.Server.Host, config.Server.Port),
@@ -0,0 +1,97 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+)
+
+// StarFile stars a file the caller can open, their own or one shared with
+// them.
+func (s *metadataService) StarFile(ctx context.Context, input *StarFileInput) (output *StarFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("star_file", status)
+	}()
+
+	hasAccess, _, _, err := s.fileRepo.CheckAccess(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
+	}
+	if !hasAccess {
+		return nil, fmt.Errorf("access denied")
+	}
+	if err := s.fileRepo.SetStarred(ctx, input.UserID, input.FileID, true); err != nil {
+		return nil, err
+	}
+	return &StarFileOutput{Success: true}, nil
+}
+
+func (s *metadataService) UnstarFile(ctx context.Context, input *UnstarFileInput) (output *UnstarFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("unstar_file", status)
+	}()
+
+	if err := s.fileRepo.SetStarred(ctx, input.UserID, input.FileID, false); err != nil {
+		return nil, err
+	}
+	return &UnstarFileOutput{Success: true}, nil
+}
+
+// ListRecent returns the files the caller uploaded, downloaded or edited
+// last. Files they can no longer open are left out.
+func (s *metadataService) ListRecent(ctx context.Context, input *ListRecentInput) (output *ListRecentOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_recent", status)
+	}()
+
+	_, limit := pageBounds(1, input.Limit)
+	items, err := s.fileRepo.ListRecent(ctx, input.UserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent files: %w", err)
+	}
+	return &ListRecentOutput{Items: items}, nil
+}
+
+func (s *metadataService) ListStarred(ctx context.Context, input *ListStarredInput) (output *ListStarredOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_starred", status)
+	}()
+
+	page, pageSize := pageBounds(input.Page, input.PageSize)
+	items, total, err := s.fileRepo.ListStarred(ctx, input.UserID, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list starred files: %w", err)
+	}
+	return &ListStarredOutput{
+		Items:    items,
+		Total:    int64(total),
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// recordActivity puts a file whose metadata the user changed on their recent
+// list. The change is saved by then, so a failure is only logged.
+func (s *metadataService) recordActivity(ctx context.Context, userID, fileID, action string) {
+	if err := s.fileRepo.RecordActivity(ctx, userID, fileID, action); err != nil {
+		log.Printf("Failed to record activity on file %s: %v", fileID, err)
+	}
+}
@@ -0,0 +1,148 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMetadataService_StarFile_Shared(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-2").Return(true, "objects/file-123", "cloud-storage", nil)
+	mockRepo.On("SetStarred", mock.Anything, "user-2", "file-123", true).Return(nil)
+
+	output, err := svc.StarFile(context.Background(), &StarFileInput{FileID: "file-123", UserID: "user-2"})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_StarFile_AccessDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-3").Return(false, "", "", nil)
+
+	output, err := svc.StarFile(context.Background(), &StarFileInput{FileID: "file-123", UserID: "user-3"})
+
+	assert.EqualError(t, err, "access denied")
+	assert.Nil(t, output)
+	mockRepo.AssertNotCalled(t, "SetStarred", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_UnstarFile(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	mockRepo.On("SetStarred", mock.Anything, "user-2", "file-123", false).Return(nil)
+
+	output, err := svc.UnstarFile(context.Background(), &UnstarFileInput{FileID: "file-123", UserID: "user-2"})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_ListRecent_CapsLimit(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	activeAt := time.Now()
+	items := []*models.FileActivity{
+		{File: &models.File{
+			ID:           "file-123",
+			UserID:       "owner-1",
+			Filename:     "plan.txt",
+			OriginalName: "plan.txt",
+			Path:         "/docs",
+			Tags:         map[string]string{},
+			UploadState:  models.UploadStateActive,
+		}, LastAction: models.ActivityDownload, LastActiveAt: &activeAt},
+	}
+	mockRepo.On("ListRecent", mock.Anything, "user-2", maxPageSize).Return(items, nil)
+	mockRepo.On("ListRecent", mock.Anything, "user-2", defaultPageSize).Return(items, nil)
+
+	output, err := svc.ListRecent(context.Background(), &ListRecentInput{UserID: "user-2", Limit: 500})
+	assert.NoError(t, err)
+	assert.Equal(t, items, output.Items)
+
+	_, err = svc.ListRecent(context.Background(), &ListRecentInput{UserID: "user-2"})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_ListStarred(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	starredAt := time.Now()
+	items := []*models.FileActivity{{File: &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}, StarredAt: &starredAt}}
+	mockRepo.On("ListStarred", mock.Anything, "user-2", 1, defaultPageSize).Return(items, 1, nil)
+
+	output, err := svc.ListStarred(context.Background(), &ListStarredInput{UserID: "user-2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, items, output.Items)
+	assert.Equal(t, int64(1), output.Total)
+	assert.Equal(t, 1, output.Page)
+	assert.Equal(t, defaultPageSize, output.PageSize)
+}
+
+func TestMetadataService_UpdateMetadata_ActivityFailureIgnored(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "owner-1", "file-123", models.ActivityEdit).Return(errors.New("db down"))
+
+	output, err := svc.UpdateMetadata(context.Background(), &UpdateMetadataInput{
+		FileID:       "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan-v2.txt",
+		OriginalName: "plan-v2.txt",
+		Path:         "/docs",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "plan-v2.txt", output.File.Filename)
+	mockRepo.AssertExpectations(t)
+}
@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"context"
+	"time"
 
 	"github.com/Sene4ka/cloud_storage/internal/api"
 	"github.com/Sene4ka/cloud_storage/internal/models"
@@ -15,6 +16,25 @@ type MetadataService interface {
 	CheckAccess(ctx context.Context, input *CheckAccessInput) (*CheckAccessOutput, error)
 	TrashFile(ctx context.Context, input *TrashFileInput) (*TrashFileOutput, error)
 	RestoreFile(ctx context.Context, input *RestoreFileInput) (*RestoreFileOutput, error)
+	CreateFolder(ctx context.Context, input *CreateFolderInput) (*CreateFolderOutput, error)
+	ListFolder(ctx context.Context, input *ListFolderInput) (*ListFolderOutput, error)
+	RenameFolder(ctx context.Context, input *RenameFolderInput) (*RenameFolderOutput, error)
+	MoveFolder(ctx context.Context, input *MoveFolderInput) (*MoveFolderOutput, error)
+	DeleteFolder(ctx context.Context, input *DeleteFolderInput) (*DeleteFolderOutput, error)
+	ShareFile(ctx context.Context, input *ShareFileInput) (*ShareFileOutput, error)
+	UnshareFile(ctx context.Context, input *UnshareFileInput) (*UnshareFileOutput, error)
+	ListShares(ctx context.Context, input *ListSharesInput) (*ListSharesOutput, error)
+	ListSharedWithMe(ctx context.Context, input *ListSharedWithMeInput) (*ListSharedWithMeOutput, error)
+	ShareFolder(ctx context.Context, input *ShareFolderInput) (*ShareFolderOutput, error)
+	UnshareFolder(ctx context.Context, input *UnshareFolderInput) (*UnshareFolderOutput, error)
+	ListFolderShares(ctx context.Context, input *ListFolderSharesInput) (*ListFolderSharesOutput, error)
+	ListFoldersSharedWithMe(ctx context.Context, input *ListFoldersSharedWithMeInput) (*ListFoldersSharedWithMeOutput, error)
+	ListTags(ctx context.Context, input *ListTagsInput) (*ListTagsOutput, error)
+	SearchFiles(ctx context.Context, input *SearchFilesInput) (*SearchFilesOutput, error)
+	StarFile(ctx context.Context, input *StarFileInput) (*StarFileOutput, error)
+	UnstarFile(ctx context.Context, input *UnstarFileInput) (*UnstarFileOutput, error)
+	ListRecent(ctx context.Context, input *ListRecentInput) (*ListRecentOutput, error)
+	ListStarred(ctx context.Context, input *ListStarredInput) (*ListStarredOutput, error)
 }
 
 type Server struct {
@@ -28,8 +48,9 @@ func NewServer(service MetadataService) *Server {
 
 func (s *Server) GetMetadata(ctx context.Context, req *api.GetMetadataRequest) (*api.GetMetadataResponse, error) {
 	out, err := s.service.GetMetadata(ctx, &GetMetadataInput{
-		FileID: req.Id,
-		UserID: req.UserId,
+		FileID:         req.Id,
+		UserID:         req.UserId,
+		IncludePending: req.IncludePending,
 	})
 	if err != nil {
 		return nil, err
@@ -45,15 +66,26 @@ func (s *Server) ListMetadata(ctx context.Context, req *api.ListMetadataRequest)
 		val := req.IsTrashed.Value
 		isThrashed = &val
 	}
+	var includeTotal *bool
+	if req.IncludeTotal != nil {
+		val := req.IncludeTotal.Value
+		includeTotal = &val
+	}
 
 	out, err := s.service.ListMetadata(ctx, &ListMetadataInput{
-		UserID:    req.UserId,
-		Page:      int(req.Page),
-		PageSize:  int(req.PageSize),
-		SortBy:    req.SortBy,
-		SortOrder: req.SortOrder,
-		Search:    req.Search,
-		IsTrashed: isThrashed,
+		UserID:         req.UserId,
+		OwnerID:        req.OwnerId,
+		Path:           req.Path,
+		Page:           int(req.Page),
+		PageSize:       int(req.PageSize),
+		SortBy:         req.SortBy,
+		SortOrder:      req.SortOrder,
+		Search:         req.Search,
+		Tags:           convertTagFilters(req.Tags),
+		IsTrashed:      isThrashed,
+		IncludePending: req.IncludePending,
+		PageToken:      req.PageToken,
+		IncludeTotal:   includeTotal,
 	})
 	if err != nil {
 		return nil, err
@@ -64,11 +96,18 @@ func (s *Server) ListMetadata(ctx context.Context, req *api.ListMetadataRequest)
 		protoItems[i] = convertToProto(file)
 	}
 
+	var total *int32
+	if out.Total != nil {
+		count := int32(*out.Total)
+		total = &count
+	}
+
 	return &api.ListMetadataResponse{
-		Items:    protoItems,
-		Total:    int32(out.Total),
-		Page:     req.Page,
-		PageSize: req.PageSize,
+		Items:         protoItems,
+		Total:         total,
+		Page:          req.Page,
+		PageSize:      req.PageSize,
+		NextPageToken: out.NextPageToken,
 	}, nil
 }
 
@@ -78,6 +117,7 @@ func (s *Server) UpdateMetadata(ctx context.Context, req *api.UpdateMetadataRequ
 		UserID:       req.UserId,
 		Filename:     req.Filename,
 		OriginalName: req.OriginalName,
+		Path:         req.Path,
 		IsPublic:     req.IsPublic,
 		Tags:         req.Tags,
 	})
@@ -93,6 +133,7 @@ func (s *Server) CheckAccess(ctx context.Context, req *api.CheckAccessRequest) (
 	out, err := s.service.CheckAccess(ctx, &CheckAccessInput{
 		FileID: req.FileId,
 		UserID: req.UserId,
+		Role:   req.Role,
 	})
 	if err != nil {
 		return nil, err
@@ -130,6 +171,402 @@ func (s *Server) RestoreFile(ctx context.Context, req *api.RestoreFileRequest) (
 	return &api.RestoreFileResponse{Success: out.Success}, nil
 }
 
+func (s *Server) CreateFolder(ctx context.Context, req *api.CreateFolderRequest) (*api.CreateFolderResponse, error) {
+	out, err := s.service.CreateFolder(ctx, &CreateFolderInput{
+		UserID:     req.UserId,
+		ParentPath: req.ParentPath,
+		Name:       req.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CreateFolderResponse{Folder: convertFolderToProto(out.Folder)}, nil
+}
+
+func (s *Server) ListFolder(ctx context.Context, req *api.ListFolderRequest) (*api.ListFolderResponse, error) {
+	out, err := s.service.ListFolder(ctx, &ListFolderInput{
+		UserID:   req.UserId,
+		Path:     req.Path,
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]*api.Folder, len(out.Folders))
+	for i, folder := range out.Folders {
+		folders[i] = convertFolderToProto(folder)
+	}
+	files := make([]*api.FileMetadata, len(out.Files))
+	for i, file := range out.Files {
+		files[i] = convertToProto(file)
+	}
+
+	return &api.ListFolderResponse{
+		Folders:  folders,
+		Files:    files,
+		Total:    int32(out.Total),
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+func (s *Server) RenameFolder(ctx context.Context, req *api.RenameFolderRequest) (*api.RenameFolderResponse, error) {
+	out, err := s.service.RenameFolder(ctx, &RenameFolderInput{
+		UserID:  req.UserId,
+		Path:    req.Path,
+		NewName: req.NewName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.RenameFolderResponse{Folder: convertFolderToProto(out.Folder)}, nil
+}
+
+func (s *Server) MoveFolder(ctx context.Context, req *api.MoveFolderRequest) (*api.MoveFolderResponse, error) {
+	out, err := s.service.MoveFolder(ctx, &MoveFolderInput{
+		UserID:        req.UserId,
+		Path:          req.Path,
+		NewParentPath: req.NewParentPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.MoveFolderResponse{Folder: convertFolderToProto(out.Folder)}, nil
+}
+
+func (s *Server) DeleteFolder(ctx context.Context, req *api.DeleteFolderRequest) (*api.DeleteFolderResponse, error) {
+	out, err := s.service.DeleteFolder(ctx, &DeleteFolderInput{
+		UserID:    req.UserId,
+		Path:      req.Path,
+		Recursive: req.Recursive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.DeleteFolderResponse{
+		Success:      out.Success,
+		TrashedFiles: int32(out.TrashedFiles),
+	}, nil
+}
+
+func (s *Server) ShareFile(ctx context.Context, req *api.ShareFileRequest) (*api.ShareFileResponse, error) {
+	out, err := s.service.ShareFile(ctx, &ShareFileInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+		Email:  req.Email,
+		Role:   req.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.ShareFileResponse{Share: convertShareToProto(out.Share)}, nil
+}
+
+func (s *Server) UnshareFile(ctx context.Context, req *api.UnshareFileRequest) (*api.UnshareFileResponse, error) {
+	out, err := s.service.UnshareFile(ctx, &UnshareFileInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+		Email:  req.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.UnshareFileResponse{Success: out.Success}, nil
+}
+
+func (s *Server) ListShares(ctx context.Context, req *api.ListSharesRequest) (*api.ListSharesResponse, error) {
+	out, err := s.service.ListShares(ctx, &ListSharesInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*api.FileShare, len(out.Shares))
+	for i, share := range out.Shares {
+		shares[i] = convertShareToProto(share)
+	}
+	return &api.ListSharesResponse{Shares: shares}, nil
+}
+
+func (s *Server) ListSharedWithMe(ctx context.Context, req *api.ListSharedWithMeRequest) (*api.ListSharedWithMeResponse, error) {
+	out, err := s.service.ListSharedWithMe(ctx, &ListSharedWithMeInput{
+		UserID:   req.UserId,
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*api.SharedFile, len(out.Items))
+	for i, item := range out.Items {
+		items[i] = &api.SharedFile{
+			Metadata:   convertToProto(item.File),
+			Role:       item.Role,
+			OwnerEmail: item.OwnerEmail,
+			SharedAt:   timestamppb.New(item.SharedAt),
+		}
+	}
+
+	return &api.ListSharedWithMeResponse{
+		Items:    items,
+		Total:    int32(out.Total),
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+func (s *Server) ShareFolder(ctx context.Context, req *api.ShareFolderRequest) (*api.ShareFolderResponse, error) {
+	out, err := s.service.ShareFolder(ctx, &ShareFolderInput{
+		UserID: req.UserId,
+		Path:   req.Path,
+		Email:  req.Email,
+		Role:   req.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.ShareFolderResponse{Share: convertFolderShareToProto(out.Share)}, nil
+}
+
+func (s *Server) UnshareFolder(ctx context.Context, req *api.UnshareFolderRequest) (*api.UnshareFolderResponse, error) {
+	out, err := s.service.UnshareFolder(ctx, &UnshareFolderInput{
+		UserID:  req.UserId,
+		OwnerID: req.OwnerId,
+		Path:    req.Path,
+		Email:   req.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.UnshareFolderResponse{Success: out.Success}, nil
+}
+
+func (s *Server) ListFolderShares(ctx context.Context, req *api.ListFolderSharesRequest) (*api.ListFolderSharesResponse, error) {
+	out, err := s.service.ListFolderShares(ctx, &ListFolderSharesInput{
+		UserID: req.UserId,
+		Path:   req.Path,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*api.FolderShare, len(out.Shares))
+	for i, share := range out.Shares {
+		shares[i] = convertFolderShareToProto(share)
+	}
+	return &api.ListFolderSharesResponse{Shares: shares}, nil
+}
+
+func (s *Server) ListFoldersSharedWithMe(ctx context.Context, req *api.ListFoldersSharedWithMeRequest) (*api.ListFoldersSharedWithMeResponse, error) {
+	out, err := s.service.ListFoldersSharedWithMe(ctx, &ListFoldersSharedWithMeInput{
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]*api.SharedFolder, len(out.Folders))
+	for i, folder := range out.Folders {
+		folders[i] = &api.SharedFolder{
+			OwnerId:    folder.OwnerID,
+			OwnerEmail: folder.OwnerEmail,
+			Path:       folder.Path,
+			Role:       folder.Role,
+			SharedAt:   timestamppb.New(folder.SharedAt),
+		}
+	}
+	return &api.ListFoldersSharedWithMeResponse{Folders: folders}, nil
+}
+
+func (s *Server) ListTags(ctx context.Context, req *api.ListTagsRequest) (*api.ListTagsResponse, error) {
+	out, err := s.service.ListTags(ctx, &ListTagsInput{
+		UserID: req.UserId,
+		Key:    req.Key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]*api.TagKeyCount, len(out.Tags))
+	for i, tag := range out.Tags {
+		values := make([]*api.TagValueCount, len(tag.Values))
+		for j, value := range tag.Values {
+			values[j] = &api.TagValueCount{Value: value.Value, Count: int32(value.Count)}
+		}
+		tags[i] = &api.TagKeyCount{Key: tag.Key, Count: int32(tag.Count), Values: values}
+	}
+	return &api.ListTagsResponse{Tags: tags}, nil
+}
+
+func (s *Server) SearchFiles(ctx context.Context, req *api.SearchFilesRequest) (*api.SearchFilesResponse, error) {
+	out, err := s.service.SearchFiles(ctx, &SearchFilesInput{
+		UserID:        req.UserId,
+		Query:         req.Query,
+		Path:          req.Path,
+		MimeTypes:     req.MimeTypes,
+		MinSize:       req.MinSize,
+		MaxSize:       req.MaxSize,
+		CreatedAfter:  convertTimestamp(req.CreatedAfter),
+		CreatedBefore: convertTimestamp(req.CreatedBefore),
+		UpdatedAfter:  convertTimestamp(req.UpdatedAfter),
+		UpdatedBefore: convertTimestamp(req.UpdatedBefore),
+		Tags:          convertTagFilters(req.Tags),
+		Page:          int(req.Page),
+		PageSize:      int(req.PageSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*api.FileMetadata, len(out.Items))
+	for i, file := range out.Items {
+		items[i] = convertToProto(file)
+	}
+	return &api.SearchFilesResponse{
+		Items:    items,
+		Total:    int32(out.Total),
+		Page:     int32(out.Page),
+		PageSize: int32(out.PageSize),
+		Types:    convertFacetsToProto(out.Types),
+		Years:    convertFacetsToProto(out.Years),
+	}, nil
+}
+
+func (s *Server) StarFile(ctx context.Context, req *api.StarFileRequest) (*api.StarFileResponse, error) {
+	out, err := s.service.StarFile(ctx, &StarFileInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.StarFileResponse{Success: out.Success}, nil
+}
+
+func (s *Server) UnstarFile(ctx context.Context, req *api.UnstarFileRequest) (*api.UnstarFileResponse, error) {
+	out, err := s.service.UnstarFile(ctx, &UnstarFileInput{
+		FileID: req.FileId,
+		UserID: req.UserId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.UnstarFileResponse{Success: out.Success}, nil
+}
+
+func (s *Server) ListRecent(ctx context.Context, req *api.ListRecentRequest) (*api.ListRecentResponse, error) {
+	out, err := s.service.ListRecent(ctx, &ListRecentInput{
+		UserID: req.UserId,
+		Limit:  int(req.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.ListRecentResponse{Items: convertActivitiesToProto(out.Items)}, nil
+}
+
+func (s *Server) ListStarred(ctx context.Context, req *api.ListStarredRequest) (*api.ListStarredResponse, error) {
+	out, err := s.service.ListStarred(ctx, &ListStarredInput{
+		UserID:   req.UserId,
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.ListStarredResponse{
+		Items:    convertActivitiesToProto(out.Items),
+		Total:    int32(out.Total),
+		Page:     int32(out.Page),
+		PageSize: int32(out.PageSize),
+	}, nil
+}
+
+func convertActivitiesToProto(items []*models.FileActivity) []*api.FileActivity {
+	result := make([]*api.FileActivity, len(items))
+	for i, item := range items {
+		activity := &api.FileActivity{
+			Metadata:   convertToProto(item.File),
+			LastAction: item.LastAction,
+		}
+		if item.LastActiveAt != nil {
+			activity.LastActiveAt = timestamppb.New(*item.LastActiveAt)
+		}
+		if item.StarredAt != nil {
+			activity.StarredAt = timestamppb.New(*item.StarredAt)
+		}
+		result[i] = activity
+	}
+	return result
+}
+
+func convertTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func convertFacetsToProto(facets []models.FacetCount) []*api.FacetCount {
+	result := make([]*api.FacetCount, len(facets))
+	for i, facet := range facets {
+		result[i] = &api.FacetCount{Value: facet.Value, Count: int32(facet.Count)}
+	}
+	return result
+}
+
+func convertTagFilters(filters []*api.TagFilter) []models.TagFilter {
+	if len(filters) == 0 {
+		return nil
+	}
+	result := make([]models.TagFilter, len(filters))
+	for i, filter := range filters {
+		result[i] = models.TagFilter{Key: filter.Key, Values: filter.Values}
+	}
+	return result
+}
+
+func convertShareToProto(share *models.FileShare) *api.FileShare {
+	return &api.FileShare{
+		Id:        share.ID,
+		FileId:    share.FileID,
+		UserId:    share.UserID,
+		Email:     share.Email,
+		Role:      share.Role,
+		CreatedAt: timestamppb.New(share.CreatedAt),
+	}
+}
+
+func convertFolderShareToProto(share *models.FolderShare) *api.FolderShare {
+	return &api.FolderShare{
+		Id:        share.ID,
+		OwnerId:   share.OwnerID,
+		Path:      share.Path,
+		UserId:    share.UserID,
+		Email:     share.Email,
+		Role:      share.Role,
+		CreatedAt: timestamppb.New(share.CreatedAt),
+	}
+}
+
+func convertFolderToProto(folder *models.Folder) *api.Folder {
+	return &api.Folder{
+		Id:         folder.ID,
+		UserId:     folder.UserID,
+		Name:       folder.Name,
+		Path:       folder.Path,
+		ParentPath: folder.ParentPath,
+		CreatedAt:  timestamppb.New(folder.CreatedAt),
+		UpdatedAt:  timestamppb.New(folder.UpdatedAt),
+	}
+}
+
 func convertToProto(file *models.File) *api.FileMetadata {
 	var thrashedAt *timestamppb.Timestamp
 	if file.TrashedAt != nil {
@@ -137,20 +574,25 @@ func convertToProto(file *models.File) *api.FileMetadata {
 	}
 
 	return &api.FileMetadata{
-		Id:           file.ID,
-		UserId:       file.UserID,
-		Filename:     file.Filename,
-		OriginalName: file.OriginalName,
-		Path:         file.Path,
-		Size:         file.Size,
-		MimeType:     file.MimeType,
-		StoragePath:  file.StoragePath,
-		Bucket:       file.Bucket,
-		CreatedAt:    timestamppb.New(file.CreatedAt),
-		UpdatedAt:    timestamppb.New(file.UpdatedAt),
-		IsPublic:     file.IsPublic,
-		Tags:         file.Tags,
-		IsTrashed:    file.IsTrashed,
-		TrashedAt:    thrashedAt,
+		Id:                file.ID,
+		UserId:            file.UserID,
+		Filename:          file.Filename,
+		OriginalName:      file.OriginalName,
+		Path:              file.Path,
+		Size:              file.Size,
+		MimeType:          file.MimeType,
+		StoragePath:       file.StoragePath,
+		Bucket:            file.Bucket,
+		CreatedAt:         timestamppb.New(file.CreatedAt),
+		UpdatedAt:         timestamppb.New(file.UpdatedAt),
+		IsPublic:          file.IsPublic,
+		Tags:              file.Tags,
+		IsTrashed:         file.IsTrashed,
+		TrashedAt:         thrashedAt,
+		ChecksumAlgorithm: file.ChecksumAlgorithm,
+		Checksum:          file.Checksum,
+		UploadState:       file.UploadState,
+		PreviewState:      file.PreviewState,
+		Snippet:           file.Snippet,
 	}
 }
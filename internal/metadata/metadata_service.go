@@ -3,6 +3,8 @@ package metadata
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/Sene4ka/cloud_storage/internal/metrics"
@@ -13,19 +15,56 @@ import (
 type FileRepository interface {
 	Create(ctx context.Context, file *models.File) error
 	GetByID(ctx context.Context, id string) (*models.File, error)
-	ListByUserID(ctx context.Context, userID string, page, pageSize int, sortBy, sortOrder, search string, isTrashed *bool) ([]*models.File, int, error)
+	ListByUserID(ctx context.Context, query *models.FileListQuery) ([]*models.File, int, error)
+	ListTags(ctx context.Context, userID, key string) ([]*models.TagCount, error)
+	Search(ctx context.Context, search *models.FileSearch) (*models.SearchResult, error)
 	Update(ctx context.Context, file *models.File) error
 	CheckAccess(ctx context.Context, fileID, userID string) (bool, string, string, error)
 	Delete(ctx context.Context, id, userID string) error
 	SetTrashed(ctx context.Context, fileID, userID string, isTrashed bool) error
+	RecordActivity(ctx context.Context, userID, fileID, action string) error
+	SetStarred(ctx context.Context, userID, fileID string, starred bool) error
+	ListRecent(ctx context.Context, userID string, limit int) ([]*models.FileActivity, error)
+	ListStarred(ctx context.Context, userID string, page, pageSize int) ([]*models.FileActivity, int, error)
 }
 
+type FolderRepository interface {
+	Create(ctx context.Context, folder *models.Folder) error
+	GetByPath(ctx context.Context, userID, folderPath string) (*models.Folder, error)
+	ListChildren(ctx context.Context, userID, folderPath string, page, pageSize int) ([]*models.Folder, []*models.File, int, error)
+	Move(ctx context.Context, userID, oldPath, newPath string) error
+	Delete(ctx context.Context, userID, folderPath string, recursive bool) (int, error)
+}
+
+type ShareRepository interface {
+	Upsert(ctx context.Context, share *models.FileShare) error
+	Delete(ctx context.Context, fileID, userID string) error
+	ListByFileID(ctx context.Context, fileID string) ([]*models.FileShare, error)
+	GetRole(ctx context.Context, fileID, userID string) (string, error)
+	ListSharedWithUser(ctx context.Context, userID string, page, pageSize int) ([]*models.SharedFile, int, error)
+	UpsertFolderShare(ctx context.Context, share *models.FolderShare) error
+	DeleteFolderShare(ctx context.Context, ownerID, folderPath, userID string) error
+	ListFolderShares(ctx context.Context, ownerID, folderPath string) ([]*models.FolderShare, error)
+	GetFolderRole(ctx context.Context, ownerID, folderPath, userID string) (string, error)
+	ListFoldersSharedWithUser(ctx context.Context, userID string) ([]*models.SharedFolder, error)
+}
+
+type UserRepository interface {
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+}
+
+// roleOwner is reported for the owner of a file alongside the share roles.
+const roleOwner = "owner"
+
 type metadataService struct {
-	fileRepo FileRepository
+	fileRepo   FileRepository
+	folderRepo FolderRepository
+	shareRepo  ShareRepository
+	userRepo   UserRepository
 }
 
-func NewMetadataService(fileRepo FileRepository) *metadataService {
-	return &metadataService{fileRepo: fileRepo}
+func NewMetadataService(fileRepo FileRepository, folderRepo FolderRepository, shareRepo ShareRepository, userRepo UserRepository) *metadataService {
+	return &metadataService{fileRepo: fileRepo, folderRepo: folderRepo, shareRepo: shareRepo, userRepo: userRepo}
 }
 
 func (s *metadataService) GetMetadata(ctx context.Context, input *GetMetadataInput) (output *GetMetadataOutput, err error) {
@@ -43,7 +82,19 @@ func (s *metadataService) GetMetadata(ctx context.Context, input *GetMetadataInp
 	}
 
 	if file.UserID != input.UserID && !file.IsPublic {
-		return nil, fmt.Errorf("access denied")
+		role, err := s.fileRole(ctx, file, input.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, fmt.Errorf("access denied")
+		}
+	}
+
+	// Files whose upload has not completed are only visible to their owner,
+	// and only on request.
+	if file.UploadState != models.UploadStateActive && (!input.IncludePending || file.UserID != input.UserID) {
+		return nil, fmt.Errorf("failed to get metadata: file not found")
 	}
 
 	return &GetMetadataOutput{File: file}, nil
@@ -58,25 +109,185 @@ func (s *metadataService) ListMetadata(ctx context.Context, input *ListMetadataI
 		metrics.RecordMetadataOperation("list_metadata", status)
 	}()
 
-	files, total, err := s.fileRepo.ListByUserID(
-		ctx,
-		input.UserID,
-		input.Page,
-		input.PageSize,
-		input.SortBy,
-		input.SortOrder,
-		input.Search,
-		input.IsTrashed,
-	)
+	if input.Path != "" {
+		if err := utils.ValidatePath(input.Path); err != nil {
+			return nil, fmt.Errorf("invalid path: %w", err)
+		}
+	}
+	for _, tag := range input.Tags {
+		if tag.Key == "" {
+			return nil, fmt.Errorf("tag filter key is required")
+		}
+	}
+
+	ownerID := input.UserID
+	isTrashed, includePending := input.IsTrashed, input.IncludePending
+	if input.OwnerID != "" && input.OwnerID != input.UserID {
+		// Grantees of a folder share see the folder's live contents only.
+		if input.Path == "" {
+			return nil, fmt.Errorf("path is required to list a shared folder")
+		}
+		role, err := s.shareRepo.GetFolderRole(ctx, input.OwnerID, input.Path, input.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check access: %w", err)
+		}
+		if role == "" {
+			return nil, fmt.Errorf("access denied")
+		}
+		notTrashed := false
+		ownerID, isTrashed, includePending = input.OwnerID, &notTrashed, false
+	}
+
+	page, pageSize := pageBounds(input.Page, input.PageSize)
+	sortBy, sortOrder := normalizeSort(input.SortBy, input.SortOrder)
+	query := &models.FileListQuery{
+		UserID:         ownerID,
+		FolderPath:     input.Path,
+		Search:         input.Search,
+		Tags:           input.Tags,
+		IsTrashed:      isTrashed,
+		IncludePending: includePending,
+		SortBy:         sortBy,
+		SortOrder:      sortOrder,
+		Offset:         (page - 1) * pageSize,
+		// One more than a page tells whether another page follows.
+		Limit:      pageSize + 1,
+		CountTotal: input.PageToken == "",
+	}
+	if input.PageToken != "" {
+		query.After, err = decodePageToken(input.PageToken, sortBy, sortOrder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.IncludeTotal != nil {
+		query.CountTotal = *input.IncludeTotal
+	}
+
+	files, total, err := s.fileRepo.ListByUserID(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list metadata: %w", err)
 	}
 
-	return &ListMetadataOutput{
+	output = &ListMetadataOutput{
 		Items:    files,
-		Total:    int64(total),
-		Page:     input.Page,
-		PageSize: input.PageSize,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if len(files) > pageSize {
+		output.Items = files[:pageSize]
+		output.NextPageToken = encodePageToken(files[pageSize-1], sortBy, sortOrder)
+	}
+	if query.CountTotal {
+		count := int64(total)
+		output.Total = &count
+	}
+	return output, nil
+}
+
+// ListTags returns the tags on a user's files, ordered by key and value,
+// with the number of files carrying each.
+func (s *metadataService) ListTags(ctx context.Context, input *ListTagsInput) (output *ListTagsOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_tags", status)
+	}()
+
+	counts, err := s.fileRepo.ListTags(ctx, input.UserID, input.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	output = &ListTagsOutput{Tags: []*TagSummary{}}
+	var current *TagSummary
+	for _, count := range counts {
+		if current == nil || current.Key != count.Key {
+			current = &TagSummary{Key: count.Key}
+			output.Tags = append(output.Tags, current)
+		}
+		current.Count += count.Count
+		current.Values = append(current.Values, count)
+	}
+	return output, nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageBounds applies the defaults and limits of paged listings.
+func pageBounds(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// SearchFiles finds a user's live files by name, path and tags and counts
+// the matches by MIME type family and by year.
+func (s *metadataService) SearchFiles(ctx context.Context, input *SearchFilesInput) (output *SearchFilesOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("search_files", status)
+	}()
+
+	if input.Path != "" {
+		if err := utils.ValidatePath(input.Path); err != nil {
+			return nil, fmt.Errorf("invalid path: %w", err)
+		}
+	}
+	for _, tag := range input.Tags {
+		if tag.Key == "" {
+			return nil, fmt.Errorf("tag filter key is required")
+		}
+	}
+	if input.MinSize < 0 || input.MaxSize < 0 {
+		return nil, fmt.Errorf("size bounds must not be negative")
+	}
+	if input.MaxSize > 0 && input.MinSize > input.MaxSize {
+		return nil, fmt.Errorf("min_size must not exceed max_size")
+	}
+
+	page, pageSize := pageBounds(input.Page, input.PageSize)
+	result, err := s.fileRepo.Search(ctx, &models.FileSearch{
+		UserID:        input.UserID,
+		Query:         strings.TrimSpace(input.Query),
+		Path:          input.Path,
+		MimeTypes:     input.MimeTypes,
+		MinSize:       input.MinSize,
+		MaxSize:       input.MaxSize,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		UpdatedAfter:  input.UpdatedAfter,
+		UpdatedBefore: input.UpdatedBefore,
+		Tags:          input.Tags,
+		Page:          page,
+		PageSize:      pageSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search files: %w", err)
+	}
+
+	return &SearchFilesOutput{
+		Items:    result.Files,
+		Total:    int64(result.Total),
+		Page:     page,
+		PageSize: pageSize,
+		Types:    result.Types,
+		Years:    result.Years,
 	}, nil
 }
 
@@ -98,7 +309,19 @@ func (s *metadataService) UpdateMetadata(ctx context.Context, input *UpdateMetad
 		return nil, fmt.Errorf("failed to get metadata: %w", err)
 	}
 
-	if existing.UserID != input.UserID {
+	role, err := s.fileRole(ctx, existing, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	switch role {
+	case roleOwner:
+	case models.ShareRoleEditor:
+		// Editors may rename and retag a file, but where it lives and who
+		// can see it stay under the owner's control.
+		if input.Path != existing.Path || input.IsPublic != existing.IsPublic {
+			return nil, fmt.Errorf("access denied: only the owner can move a file or change its visibility")
+		}
+	default:
 		return nil, fmt.Errorf("access denied")
 	}
 
@@ -112,6 +335,7 @@ func (s *metadataService) UpdateMetadata(ctx context.Context, input *UpdateMetad
 	if err := s.fileRepo.Update(ctx, existing); err != nil {
 		return nil, fmt.Errorf("failed to update metadata: %w", err)
 	}
+	s.recordActivity(ctx, input.UserID, existing.ID, models.ActivityEdit)
 
 	return &UpdateMetadataOutput{File: existing}, nil
 }
@@ -129,6 +353,20 @@ func (s *metadataService) CheckAccess(ctx context.Context, input *CheckAccessInp
 	if err != nil {
 		return nil, fmt.Errorf("failed to check access: %w", err)
 	}
+	if hasAccess && input.Role == models.ShareRoleEditor {
+		file, err := s.fileRepo.GetByID(ctx, input.FileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check access: %w", err)
+		}
+		role, err := s.fileRole(ctx, file, input.UserID)
+		if err != nil {
+			return nil, err
+		}
+		hasAccess = role == roleOwner || role == models.ShareRoleEditor
+	}
+	if !hasAccess {
+		storagePath, bucket = "", ""
+	}
 
 	return &CheckAccessOutput{
 		HasAccess:   hasAccess,
@@ -166,3 +404,161 @@ func (s *metadataService) RestoreFile(ctx context.Context, input *RestoreFileInp
 	}
 	return &RestoreFileOutput{Success: true}, nil
 }
+
+func (s *metadataService) CreateFolder(ctx context.Context, input *CreateFolderInput) (output *CreateFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("create_folder", status)
+	}()
+
+	if err := utils.ValidatePath(input.ParentPath); err != nil {
+		return nil, fmt.Errorf("invalid parent path: %w", err)
+	}
+	if err := validateFolderName(input.ParentPath, input.Name); err != nil {
+		return nil, err
+	}
+
+	folder := models.NewFolder(input.UserID, input.ParentPath, input.Name)
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+	return &CreateFolderOutput{Folder: folder}, nil
+}
+
+func (s *metadataService) ListFolder(ctx context.Context, input *ListFolderInput) (output *ListFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_folder", status)
+	}()
+
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	if input.Path != "/" {
+		if _, err := s.folderRepo.GetByPath(ctx, input.UserID, input.Path); err != nil {
+			return nil, fmt.Errorf("failed to get folder: %w", err)
+		}
+	}
+
+	folders, files, total, err := s.folderRepo.ListChildren(ctx, input.UserID, input.Path, input.Page, input.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder: %w", err)
+	}
+
+	return &ListFolderOutput{
+		Folders:  folders,
+		Files:    files,
+		Total:    int64(total),
+		Page:     input.Page,
+		PageSize: input.PageSize,
+	}, nil
+}
+
+func (s *metadataService) RenameFolder(ctx context.Context, input *RenameFolderInput) (output *RenameFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("rename_folder", status)
+	}()
+
+	if err := validateFolderPath(input.Path); err != nil {
+		return nil, err
+	}
+	parent := path.Dir(input.Path)
+	if err := validateFolderName(parent, input.NewName); err != nil {
+		return nil, err
+	}
+
+	folder, err := s.moveFolder(ctx, input.UserID, input.Path, path.Join(parent, input.NewName))
+	if err != nil {
+		return nil, err
+	}
+	return &RenameFolderOutput{Folder: folder}, nil
+}
+
+func (s *metadataService) MoveFolder(ctx context.Context, input *MoveFolderInput) (output *MoveFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("move_folder", status)
+	}()
+
+	if err := validateFolderPath(input.Path); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidatePath(input.NewParentPath); err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+	if input.NewParentPath == input.Path || strings.HasPrefix(input.NewParentPath, input.Path+"/") {
+		return nil, fmt.Errorf("cannot move a folder into itself")
+	}
+
+	folder, err := s.moveFolder(ctx, input.UserID, input.Path, path.Join(input.NewParentPath, path.Base(input.Path)))
+	if err != nil {
+		return nil, err
+	}
+	return &MoveFolderOutput{Folder: folder}, nil
+}
+
+func (s *metadataService) DeleteFolder(ctx context.Context, input *DeleteFolderInput) (output *DeleteFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("delete_folder", status)
+	}()
+
+	if err := validateFolderPath(input.Path); err != nil {
+		return nil, err
+	}
+
+	trashed, err := s.folderRepo.Delete(ctx, input.UserID, input.Path, input.Recursive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete folder: %w", err)
+	}
+	return &DeleteFolderOutput{Success: true, TrashedFiles: trashed}, nil
+}
+
+func (s *metadataService) moveFolder(ctx context.Context, userID, oldPath, newPath string) (*models.Folder, error) {
+	if oldPath != newPath {
+		if err := s.folderRepo.Move(ctx, userID, oldPath, newPath); err != nil {
+			return nil, fmt.Errorf("failed to move folder: %w", err)
+		}
+	}
+	folder, err := s.folderRepo.GetByPath(ctx, userID, newPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+	return folder, nil
+}
+
+func validateFolderPath(folderPath string) error {
+	if err := utils.ValidatePath(folderPath); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	if folderPath == "/" {
+		return fmt.Errorf("root folder cannot be changed")
+	}
+	return nil
+}
+
+func validateFolderName(parentPath, name string) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid folder name")
+	}
+	if err := utils.ValidatePath(path.Join(parentPath, name)); err != nil {
+		return fmt.Errorf("invalid folder name: %w", err)
+	}
+	return nil
+}
@@ -28,20 +28,30 @@ func (m *MockFileRepository) GetByID(ctx context.Context, id string) (*models.Fi
 	return args.Get(0).(*models.File), args.Error(1)
 }
 
-func (m *MockFileRepository) ListByUserID(
-	ctx context.Context,
-	userID string,
-	page, pageSize int,
-	sortBy, sortOrder, search string,
-	isTrashed *bool,
-) ([]*models.File, int, error) {
-	args := m.Called(ctx, userID, page, pageSize, sortBy, sortOrder, search, isTrashed)
+func (m *MockFileRepository) ListByUserID(ctx context.Context, query *models.FileListQuery) ([]*models.File, int, error) {
+	args := m.Called(ctx, query)
 	if args.Get(0) == nil {
 		return nil, args.Int(1), args.Error(2)
 	}
 	return args.Get(0).([]*models.File), args.Int(1), args.Error(2)
 }
 
+func (m *MockFileRepository) ListTags(ctx context.Context, userID, key string) ([]*models.TagCount, error) {
+	args := m.Called(ctx, userID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.TagCount), args.Error(1)
+}
+
+func (m *MockFileRepository) Search(ctx context.Context, search *models.FileSearch) (*models.SearchResult, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SearchResult), args.Error(1)
+}
+
 func (m *MockFileRepository) Update(ctx context.Context, file *models.File) error {
 	args := m.Called(ctx, file)
 	return args.Error(0)
@@ -62,11 +72,155 @@ func (m *MockFileRepository) SetTrashed(ctx context.Context, fileID, userID stri
 	return args.Error(0)
 }
 
+func (m *MockFileRepository) RecordActivity(ctx context.Context, userID, fileID, action string) error {
+	args := m.Called(ctx, userID, fileID, action)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) SetStarred(ctx context.Context, userID, fileID string, starred bool) error {
+	args := m.Called(ctx, userID, fileID, starred)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ListRecent(ctx context.Context, userID string, limit int) ([]*models.FileActivity, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileActivity), args.Error(1)
+}
+
+func (m *MockFileRepository) ListStarred(ctx context.Context, userID string, page, pageSize int) ([]*models.FileActivity, int, error) {
+	args := m.Called(ctx, userID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*models.FileActivity), args.Int(1), args.Error(2)
+}
+
+type MockFolderRepository struct {
+	mock.Mock
+}
+
+func (m *MockFolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	args := m.Called(ctx, folder)
+	return args.Error(0)
+}
+
+func (m *MockFolderRepository) GetByPath(ctx context.Context, userID, folderPath string) (*models.Folder, error) {
+	args := m.Called(ctx, userID, folderPath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Folder), args.Error(1)
+}
+
+func (m *MockFolderRepository) ListChildren(ctx context.Context, userID, folderPath string, page, pageSize int) ([]*models.Folder, []*models.File, int, error) {
+	args := m.Called(ctx, userID, folderPath, page, pageSize)
+	var folders []*models.Folder
+	if args.Get(0) != nil {
+		folders = args.Get(0).([]*models.Folder)
+	}
+	var files []*models.File
+	if args.Get(1) != nil {
+		files = args.Get(1).([]*models.File)
+	}
+	return folders, files, args.Int(2), args.Error(3)
+}
+
+func (m *MockFolderRepository) Move(ctx context.Context, userID, oldPath, newPath string) error {
+	args := m.Called(ctx, userID, oldPath, newPath)
+	return args.Error(0)
+}
+
+func (m *MockFolderRepository) Delete(ctx context.Context, userID, folderPath string, recursive bool) (int, error) {
+	args := m.Called(ctx, userID, folderPath, recursive)
+	return args.Int(0), args.Error(1)
+}
+
+type MockShareRepository struct {
+	mock.Mock
+}
+
+func (m *MockShareRepository) Upsert(ctx context.Context, share *models.FileShare) error {
+	args := m.Called(ctx, share)
+	return args.Error(0)
+}
+
+func (m *MockShareRepository) Delete(ctx context.Context, fileID, userID string) error {
+	args := m.Called(ctx, fileID, userID)
+	return args.Error(0)
+}
+
+func (m *MockShareRepository) ListByFileID(ctx context.Context, fileID string) ([]*models.FileShare, error) {
+	args := m.Called(ctx, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileShare), args.Error(1)
+}
+
+func (m *MockShareRepository) GetRole(ctx context.Context, fileID, userID string) (string, error) {
+	args := m.Called(ctx, fileID, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockShareRepository) ListSharedWithUser(ctx context.Context, userID string, page, pageSize int) ([]*models.SharedFile, int, error) {
+	args := m.Called(ctx, userID, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*models.SharedFile), args.Int(1), args.Error(2)
+}
+
+func (m *MockShareRepository) UpsertFolderShare(ctx context.Context, share *models.FolderShare) error {
+	args := m.Called(ctx, share)
+	return args.Error(0)
+}
+
+func (m *MockShareRepository) DeleteFolderShare(ctx context.Context, ownerID, folderPath, userID string) error {
+	args := m.Called(ctx, ownerID, folderPath, userID)
+	return args.Error(0)
+}
+
+func (m *MockShareRepository) ListFolderShares(ctx context.Context, ownerID, folderPath string) ([]*models.FolderShare, error) {
+	args := m.Called(ctx, ownerID, folderPath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FolderShare), args.Error(1)
+}
+
+func (m *MockShareRepository) GetFolderRole(ctx context.Context, ownerID, folderPath, userID string) (string, error) {
+	args := m.Called(ctx, ownerID, folderPath, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockShareRepository) ListFoldersSharedWithUser(ctx context.Context, userID string) ([]*models.SharedFolder, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SharedFolder), args.Error(1)
+}
+
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func TestMetadataService_GetMetadata_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	expectedFile := &models.File{
 		ID:           "file-123",
@@ -84,6 +238,7 @@ func TestMetadataService_GetMetadata_Success(t *testing.T) {
 		UpdatedAt:    time.Now(),
 		IsTrashed:    false,
 		TrashedAt:    nil,
+		UploadState:  models.UploadStateActive,
 	}
 
 	mockRepo.On("GetByID", mock.Anything, "file-123").Return(expectedFile, nil)
@@ -105,7 +260,7 @@ func TestMetadataService_GetMetadata_AccessDenied(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	otherUserFile := &models.File{
 		ID:       "file-123",
@@ -130,11 +285,47 @@ func TestMetadataService_GetMetadata_AccessDenied(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestMetadataService_GetMetadata_HidesPendingUpload(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	pendingFile := &models.File{
+		ID:          "file-123",
+		UserID:      "user-456",
+		Filename:    "test.txt",
+		Tags:        map[string]string{},
+		UploadState: models.UploadStatePending,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(pendingFile, nil)
+
+	output, err := svc.GetMetadata(context.Background(), &GetMetadataInput{
+		FileID: "file-123",
+		UserID: "user-456",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+	assert.Nil(t, output)
+
+	output, err = svc.GetMetadata(context.Background(), &GetMetadataInput{
+		FileID:         "file-123",
+		UserID:         "user-456",
+		IncludePending: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, pendingFile, output.File)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestMetadataService_UpdateMetadata_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	existingFile := &models.File{
 		ID:           "file-123",
@@ -158,6 +349,7 @@ func TestMetadataService_UpdateMetadata_Success(t *testing.T) {
 	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
 		return f.Filename == "new.txt" && f.Tags["version"] == "2"
 	})).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-456", "file-123", models.ActivityEdit).Return(nil)
 
 	input := &UpdateMetadataInput{
 		FileID:       "file-123",
@@ -181,7 +373,7 @@ func TestMetadataService_TrashFile_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	mockRepo.On("SetTrashed", mock.Anything, "file-123", "user-456", true).Return(nil)
 
@@ -202,7 +394,7 @@ func TestMetadataService_RestoreFile_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	mockRepo.On("SetTrashed", mock.Anything, "file-123", "user-456", false).Return(nil)
 
@@ -223,7 +415,7 @@ func TestMetadataService_GetMetadata_RepoError(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	mockRepo.On("GetByID", mock.Anything, "file-123").Return(nil, errors.New("db error"))
 
@@ -244,7 +436,7 @@ func TestMetadataService_ListMetadata_Success(t *testing.T) {
 	t.Parallel()
 
 	mockRepo := new(MockFileRepository)
-	svc := NewMetadataService(mockRepo)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
 
 	files := []*models.File{
 		{
@@ -261,8 +453,13 @@ func TestMetadataService_ListMetadata_Success(t *testing.T) {
 		},
 	}
 
-	mockRepo.On("ListByUserID", mock.Anything, "user-456", 1, 10, "created_at", "desc", "", (*bool)(nil)).
-		Return(files, 2, nil)
+	mockRepo.On("ListByUserID", mock.Anything, &models.FileListQuery{
+		UserID:     "user-456",
+		SortBy:     "created_at",
+		SortOrder:  "desc",
+		Limit:      11,
+		CountTotal: true,
+	}).Return(files, 2, nil)
 
 	input := &ListMetadataInput{
 		UserID:    "user-456",
@@ -277,6 +474,301 @@ func TestMetadataService_ListMetadata_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, output)
 	assert.Len(t, output.Items, 2)
-	assert.Equal(t, int64(2), output.Total)
+	assert.Equal(t, int64(2), *output.Total)
+	assert.Empty(t, output.NextPageToken)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestMetadataService_ListMetadata_TagFilters(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	tags := []models.TagFilter{
+		{Key: "project"},
+		{Key: "status", Values: []string{"draft", "review, pending"}},
+	}
+	mockRepo.On("ListByUserID", mock.Anything, &models.FileListQuery{
+		UserID:     "user-456",
+		Tags:       tags,
+		SortBy:     "created_at",
+		SortOrder:  "desc",
+		Limit:      11,
+		CountTotal: true,
+	}).Return([]*models.File{}, 0, nil)
+
+	_, err := svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID:   "user-456",
+		Page:     1,
+		PageSize: 10,
+		Tags:     tags,
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	_, err = svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID: "user-456",
+		Tags:   []models.TagFilter{{Values: []string{"draft"}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestMetadataService_ListMetadata_PageToken(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	files := []*models.File{
+		{ID: "7d9f6a8e-3b1c-4f2a-9e4d-1a2b3c4d5e01", Size: 300},
+		{ID: "7d9f6a8e-3b1c-4f2a-9e4d-1a2b3c4d5e02", Size: 200},
+		{ID: "7d9f6a8e-3b1c-4f2a-9e4d-1a2b3c4d5e03", Size: 100},
+	}
+	mockRepo.On("ListByUserID", mock.Anything, mock.MatchedBy(func(query *models.FileListQuery) bool {
+		return query.After == nil
+	})).Return(files, 0, nil)
+	mockRepo.On("ListByUserID", mock.Anything, &models.FileListQuery{
+		UserID:    "user-456",
+		SortBy:    "size",
+		SortOrder: "desc",
+		After:     &models.FileCursor{Value: int64(200), ID: files[1].ID},
+		Limit:     3,
+	}).Return(files[2:], 0, nil)
+
+	first, err := svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID:    "user-456",
+		PageSize:  2,
+		SortBy:    "size",
+		SortOrder: "DESC",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, first.Items, 2)
+	assert.NotNil(t, first.Total)
+	assert.NotEmpty(t, first.NextPageToken)
+
+	second, err := svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID:    "user-456",
+		PageSize:  2,
+		SortBy:    "size",
+		SortOrder: "desc",
+		PageToken: first.NextPageToken,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, second.Items, 1)
+	assert.Nil(t, second.Total)
+	assert.Empty(t, second.NextPageToken)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_ListMetadata_PageTokenForOtherSort(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	token := encodePageToken(&models.File{ID: "7d9f6a8e-3b1c-4f2a-9e4d-1a2b3c4d5e01", Filename: "a.txt"}, "filename", "asc")
+
+	for _, input := range []*ListMetadataInput{
+		{UserID: "user-456", SortBy: "size", PageToken: token},
+		{UserID: "user-456", SortBy: "filename", PageToken: "not-a-token"},
+	} {
+		_, err := svc.ListMetadata(context.Background(), input)
+		assert.Error(t, err)
+	}
+	mockRepo.AssertNotCalled(t, "ListByUserID", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ListTags_GroupsByKey(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	mockRepo.On("ListTags", mock.Anything, "user-456", "").Return([]*models.TagCount{
+		{Key: "project", Value: "apollo", Count: 3},
+		{Key: "project", Value: "gemini", Count: 1},
+		{Key: "status", Value: "draft", Count: 2},
+	}, nil)
+
+	output, err := svc.ListTags(context.Background(), &ListTagsInput{UserID: "user-456"})
+
+	assert.NoError(t, err)
+	assert.Len(t, output.Tags, 2)
+	assert.Equal(t, "project", output.Tags[0].Key)
+	assert.Equal(t, 4, output.Tags[0].Count)
+	assert.Len(t, output.Tags[0].Values, 2)
+	assert.Equal(t, "status", output.Tags[1].Key)
+	assert.Equal(t, 2, output.Tags[1].Count)
+}
+
+func TestMetadataService_SearchFiles_AppliesPagingDefaults(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	mockRepo.On("Search", mock.Anything, mock.MatchedBy(func(search *models.FileSearch) bool {
+		return search.UserID == "user-456" && search.Query == "report" && search.Page == 1 && search.PageSize == 20
+	})).Return(&models.SearchResult{
+		Files: []*models.File{{ID: "file-1"}},
+		Total: 3,
+		Types: []models.FacetCount{{Value: "application", Count: 2}, {Value: "text", Count: 1}},
+		Years: []models.FacetCount{{Value: "2026", Count: 3}},
+	}, nil)
+
+	output, err := svc.SearchFiles(context.Background(), &SearchFilesInput{UserID: "user-456", Query: " report "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), output.Total)
+	assert.Equal(t, 1, output.Page)
+	assert.Equal(t, 20, output.PageSize)
+	assert.Len(t, output.Items, 1)
+	assert.Len(t, output.Types, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_SearchFiles_InvalidCriteria(t *testing.T) {
+	t.Parallel()
+
+	svc := NewMetadataService(new(MockFileRepository), new(MockFolderRepository), new(MockShareRepository), new(MockUserRepository))
+
+	for name, input := range map[string]*SearchFilesInput{
+		"size range":     {UserID: "user-456", MinSize: 10, MaxSize: 5},
+		"negative size":  {UserID: "user-456", MinSize: -1},
+		"empty tag key":  {UserID: "user-456", Tags: []models.TagFilter{{Values: []string{"x"}}}},
+		"invalid folder": {UserID: "user-456", Path: "../etc"},
+	} {
+		_, err := svc.SearchFiles(context.Background(), input)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMetadataService_CreateFolder_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockFolders := new(MockFolderRepository)
+	svc := NewMetadataService(mockRepo, mockFolders, new(MockShareRepository), new(MockUserRepository))
+
+	mockFolders.On("Create", mock.Anything, mock.MatchedBy(func(folder *models.Folder) bool {
+		return folder.UserID == "user-123" && folder.Path == "/docs/reports" && folder.ParentPath == "/docs"
+	})).Return(nil)
+
+	output, err := svc.CreateFolder(context.Background(), &CreateFolderInput{
+		UserID:     "user-123",
+		ParentPath: "/docs",
+		Name:       "reports",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "reports", output.Folder.Name)
+	mockFolders.AssertExpectations(t)
+}
+
+func TestMetadataService_CreateFolder_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockFolders := new(MockFolderRepository)
+	svc := NewMetadataService(mockRepo, mockFolders, new(MockShareRepository), new(MockUserRepository))
+
+	output, err := svc.CreateFolder(context.Background(), &CreateFolderInput{
+		UserID:     "user-123",
+		ParentPath: "/",
+		Name:       "a/b",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockFolders.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ListFolder_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockFolders := new(MockFolderRepository)
+	svc := NewMetadataService(mockRepo, mockFolders, new(MockShareRepository), new(MockUserRepository))
+
+	folders := []*models.Folder{{ID: "folder-1", Name: "reports", Path: "/docs/reports"}}
+	files := []*models.File{{ID: "file-1", OriginalName: "notes.txt", Path: "/docs"}}
+
+	mockFolders.On("GetByPath", mock.Anything, "user-123", "/docs").Return(&models.Folder{ID: "folder-0", Path: "/docs"}, nil)
+	mockFolders.On("ListChildren", mock.Anything, "user-123", "/docs", 1, 20).Return(folders, files, 2, nil)
+
+	output, err := svc.ListFolder(context.Background(), &ListFolderInput{
+		UserID:   "user-123",
+		Path:     "/docs",
+		Page:     1,
+		PageSize: 20,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, output.Folders, 1)
+	assert.Len(t, output.Files, 1)
+	assert.Equal(t, int64(2), output.Total)
+	mockFolders.AssertExpectations(t)
+}
+
+func TestMetadataService_RenameFolder_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockFolders := new(MockFolderRepository)
+	svc := NewMetadataService(mockRepo, mockFolders, new(MockShareRepository), new(MockUserRepository))
+
+	mockFolders.On("Move", mock.Anything, "user-123", "/docs/old", "/docs/new").Return(nil)
+	mockFolders.On("GetByPath", mock.Anything, "user-123", "/docs/new").Return(&models.Folder{ID: "folder-1", Name: "new", Path: "/docs/new"}, nil)
+
+	output, err := svc.RenameFolder(context.Background(), &RenameFolderInput{
+		UserID:  "user-123",
+		Path:    "/docs/old",
+		NewName: "new",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/docs/new", output.Folder.Path)
+	mockFolders.AssertExpectations(t)
+}
+
+func TestMetadataService_MoveFolder_IntoItself(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockFolders := new(MockFolderRepository)
+	svc := NewMetadataService(mockRepo, mockFolders, new(MockShareRepository), new(MockUserRepository))
+
+	output, err := svc.MoveFolder(context.Background(), &MoveFolderInput{
+		UserID:        "user-123",
+		Path:          "/docs",
+		NewParentPath: "/docs/reports",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	assert.Contains(t, err.Error(), "cannot move a folder into itself")
+	mockFolders.AssertNotCalled(t, "Move", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_DeleteFolder_Recursive(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockFolders := new(MockFolderRepository)
+	svc := NewMetadataService(mockRepo, mockFolders, new(MockShareRepository), new(MockUserRepository))
+
+	mockFolders.On("Delete", mock.Anything, "user-123", "/docs", true).Return(3, nil)
+
+	output, err := svc.DeleteFolder(context.Background(), &DeleteFolderInput{
+		UserID:    "user-123",
+		Path:      "/docs",
+		Recursive: true,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	assert.Equal(t, 3, output.TrashedFiles)
+	mockFolders.AssertExpectations(t)
+}
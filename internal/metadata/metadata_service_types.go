@@ -1,6 +1,8 @@
 package metadata
 
 import (
+	"time"
+
 	"github.com/Sene4ka/cloud_storage/internal/models"
 )
 
@@ -22,8 +24,9 @@ type CreateMetadataOutput struct {
 }
 
 type GetMetadataInput struct {
-	FileID string
-	UserID string
+	FileID         string
+	UserID         string
+	IncludePending bool
 }
 
 type GetMetadataOutput struct {
@@ -31,20 +34,32 @@ type GetMetadataOutput struct {
 }
 
 type ListMetadataInput struct {
-	UserID    string
-	Page      int
-	PageSize  int
-	SortBy    string
-	SortOrder string
-	Search    string
-	IsTrashed *bool
+	UserID         string
+	OwnerID        string
+	Path           string
+	Page           int
+	PageSize       int
+	SortBy         string
+	SortOrder      string
+	Search         string
+	Tags           []models.TagFilter
+	IsTrashed      *bool
+	IncludePending bool
+	// PageToken continues a listing where the previous page ended; Page is
+	// ignored with it.
+	PageToken string
+	// IncludeTotal defaults to counting the total for page based listings
+	// only.
+	IncludeTotal *bool
 }
 
 type ListMetadataOutput struct {
-	Items    []*models.File
-	Total    int64
-	Page     int
-	PageSize int
+	Items []*models.File
+	// Total is nil unless it was counted.
+	Total         *int64
+	Page          int
+	PageSize      int
+	NextPageToken string
 }
 
 type UpdateMetadataInput struct {
@@ -64,6 +79,9 @@ type UpdateMetadataOutput struct {
 type CheckAccessInput struct {
 	FileID string
 	UserID string
+	// Role is the least role the caller needs: viewer when empty, or editor
+	// for changes.
+	Role string
 }
 
 type CheckAccessOutput struct {
@@ -98,3 +116,223 @@ type DeleteFileMetadataInput struct {
 type DeleteFileMetadataOutput struct {
 	Success bool
 }
+
+type CreateFolderInput struct {
+	UserID     string
+	ParentPath string
+	Name       string
+}
+
+type CreateFolderOutput struct {
+	Folder *models.Folder
+}
+
+type ListFolderInput struct {
+	UserID   string
+	Path     string
+	Page     int
+	PageSize int
+}
+
+type ListFolderOutput struct {
+	Folders  []*models.Folder
+	Files    []*models.File
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+type RenameFolderInput struct {
+	UserID  string
+	Path    string
+	NewName string
+}
+
+type RenameFolderOutput struct {
+	Folder *models.Folder
+}
+
+type MoveFolderInput struct {
+	UserID        string
+	Path          string
+	NewParentPath string
+}
+
+type MoveFolderOutput struct {
+	Folder *models.Folder
+}
+
+type DeleteFolderInput struct {
+	UserID    string
+	Path      string
+	Recursive bool
+}
+
+type DeleteFolderOutput struct {
+	Success      bool
+	TrashedFiles int
+}
+
+type ShareFileInput struct {
+	FileID string
+	UserID string
+	Email  string
+	Role   string
+}
+
+type ShareFileOutput struct {
+	Share *models.FileShare
+}
+
+type UnshareFileInput struct {
+	FileID string
+	UserID string
+	Email  string
+}
+
+type UnshareFileOutput struct {
+	Success bool
+}
+
+type ListSharesInput struct {
+	FileID string
+	UserID string
+}
+
+type ListSharesOutput struct {
+	Shares []*models.FileShare
+}
+
+type ListSharedWithMeInput struct {
+	UserID   string
+	Page     int
+	PageSize int
+}
+
+type ListSharedWithMeOutput struct {
+	Items    []*models.SharedFile
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+type ShareFolderInput struct {
+	UserID string
+	Path   string
+	Email  string
+	Role   string
+}
+
+type ShareFolderOutput struct {
+	Share *models.FolderShare
+}
+
+type UnshareFolderInput struct {
+	UserID  string
+	OwnerID string
+	Path    string
+	Email   string
+}
+
+type UnshareFolderOutput struct {
+	Success bool
+}
+
+type ListFolderSharesInput struct {
+	UserID string
+	Path   string
+}
+
+type ListFolderSharesOutput struct {
+	Shares []*models.FolderShare
+}
+
+type ListFoldersSharedWithMeInput struct {
+	UserID string
+}
+
+type ListFoldersSharedWithMeOutput struct {
+	Folders []*models.SharedFolder
+}
+
+type ListTagsInput struct {
+	UserID string
+	Key    string
+}
+
+// TagSummary is a tag with the number of files carrying it, broken down by
+// value.
+type TagSummary struct {
+	Key    string
+	Count  int
+	Values []*models.TagCount
+}
+
+type ListTagsOutput struct {
+	Tags []*TagSummary
+}
+
+type SearchFilesInput struct {
+	UserID        string
+	Query         string
+	Path          string
+	MimeTypes     []string
+	MinSize       int64
+	MaxSize       int64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Tags          []models.TagFilter
+	Page          int
+	PageSize      int
+}
+
+type SearchFilesOutput struct {
+	Items    []*models.File
+	Total    int64
+	Page     int
+	PageSize int
+	Types    []models.FacetCount
+	Years    []models.FacetCount
+}
+
+type StarFileInput struct {
+	FileID string
+	UserID string
+}
+
+type StarFileOutput struct {
+	Success bool
+}
+
+type UnstarFileInput struct {
+	FileID string
+	UserID string
+}
+
+type UnstarFileOutput struct {
+	Success bool
+}
+
+type ListRecentInput struct {
+	UserID string
+	Limit  int
+}
+
+type ListRecentOutput struct {
+	Items []*models.FileActivity
+}
+
+type ListStarredInput struct {
+	UserID   string
+	Page     int
+	PageSize int
+}
+
+type ListStarredOutput struct {
+	Items    []*models.FileActivity
+	Total    int64
+	Page     int
+	PageSize int
+}
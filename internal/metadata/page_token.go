@@ -0,0 +1,87 @@
+package metadata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/google/uuid"
+)
+
+// pageToken is the content of the opaque token continuing a listing after
+// the last file of a page. It records the sort it was issued for, so that
+// a token is not applied to a listing ordered differently.
+type pageToken struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Value     string `json:"v"`
+	ID        string `json:"i"`
+}
+
+// normalizeSort returns the sort field and direction a listing is ordered
+// by, matching the repository: newest first unless a known field is given,
+// and ascending unless asked otherwise.
+func normalizeSort(sortBy, sortOrder string) (string, string) {
+	switch sortBy {
+	case "created_at", "updated_at", "filename", "size", "path":
+	default:
+		return "created_at", "desc"
+	}
+	if strings.ToLower(sortOrder) == "desc" {
+		return sortBy, "desc"
+	}
+	return sortBy, "asc"
+}
+
+func encodePageToken(file *models.File, sortBy, sortOrder string) string {
+	token := pageToken{SortBy: sortBy, SortOrder: sortOrder, ID: file.ID}
+	switch sortBy {
+	case "created_at":
+		token.Value = file.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		token.Value = file.UpdatedAt.Format(time.RFC3339Nano)
+	case "filename":
+		token.Value = file.Filename
+	case "size":
+		token.Value = strconv.FormatInt(file.Size, 10)
+	case "path":
+		token.Value = file.Path
+	}
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePageToken(encoded, sortBy, sortOrder string) (*models.FileCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	var token pageToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	if token.SortBy != sortBy || token.SortOrder != sortOrder {
+		return nil, fmt.Errorf("page token was issued for a different sort order")
+	}
+	if _, err := uuid.Parse(token.ID); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+
+	cursor := &models.FileCursor{ID: token.ID}
+	switch sortBy {
+	case "created_at", "updated_at":
+		cursor.Value, err = time.Parse(time.RFC3339Nano, token.Value)
+	case "size":
+		cursor.Value, err = strconv.ParseInt(token.Value, 10, 64)
+	default:
+		cursor.Value = token.Value
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,258 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Sene4ka/cloud_storage/internal/metrics"
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/Sene4ka/cloud_storage/internal/utils"
+)
+
+func (s *metadataService) ShareFile(ctx context.Context, input *ShareFileInput) (output *ShareFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("share_file", status)
+	}()
+
+	if !models.IsValidShareRole(input.Role) {
+		return nil, fmt.Errorf("invalid role: %q", input.Role)
+	}
+
+	file, err := s.getSharableFile(ctx, input.FileID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(input.Email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.ID == file.UserID {
+		return nil, fmt.Errorf("cannot share a file with its owner")
+	}
+
+	share := models.NewFileShare(file.ID, file.UserID, user.ID, input.Role)
+	share.Email = user.Email
+	if err := s.shareRepo.Upsert(ctx, share); err != nil {
+		return nil, fmt.Errorf("failed to share file: %w", err)
+	}
+	return &ShareFileOutput{Share: share}, nil
+}
+
+// UnshareFile revokes a share. Besides the owner, a grantee may remove their
+// own access.
+func (s *metadataService) UnshareFile(ctx context.Context, input *UnshareFileInput) (output *UnshareFileOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("unshare_file", status)
+	}()
+
+	file, err := s.fileRepo.GetByID(ctx, input.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(input.Email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if file.UserID != input.UserID && user.ID != input.UserID {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if err := s.shareRepo.Delete(ctx, file.ID, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to unshare file: %w", err)
+	}
+	return &UnshareFileOutput{Success: true}, nil
+}
+
+func (s *metadataService) ListShares(ctx context.Context, input *ListSharesInput) (output *ListSharesOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_shares", status)
+	}()
+
+	file, err := s.fileRepo.GetByID(ctx, input.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if file.UserID != input.UserID {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	shares, err := s.shareRepo.ListByFileID(ctx, file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	return &ListSharesOutput{Shares: shares}, nil
+}
+
+func (s *metadataService) ListSharedWithMe(ctx context.Context, input *ListSharedWithMeInput) (output *ListSharedWithMeOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_shared_with_me", status)
+	}()
+
+	items, total, err := s.shareRepo.ListSharedWithUser(ctx, input.UserID, input.Page, input.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared files: %w", err)
+	}
+	return &ListSharedWithMeOutput{
+		Items:    items,
+		Total:    int64(total),
+		Page:     input.Page,
+		PageSize: input.PageSize,
+	}, nil
+}
+
+// ShareFolder grants a user a role on every file under one of the caller's
+// folders, including files added to it later.
+func (s *metadataService) ShareFolder(ctx context.Context, input *ShareFolderInput) (output *ShareFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("share_folder", status)
+	}()
+
+	if !models.IsValidShareRole(input.Role) {
+		return nil, fmt.Errorf("invalid role: %q", input.Role)
+	}
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	if input.Path == "/" {
+		return nil, fmt.Errorf("root folder cannot be shared")
+	}
+
+	if _, err := s.folderRepo.GetByPath(ctx, input.UserID, input.Path); err != nil {
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(input.Email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.ID == input.UserID {
+		return nil, fmt.Errorf("cannot share a folder with its owner")
+	}
+
+	share := models.NewFolderShare(input.UserID, input.Path, user.ID, input.Role)
+	share.Email = user.Email
+	if err := s.shareRepo.UpsertFolderShare(ctx, share); err != nil {
+		return nil, fmt.Errorf("failed to share folder: %w", err)
+	}
+	return &ShareFolderOutput{Share: share}, nil
+}
+
+// UnshareFolder revokes a folder share. The owner identifies the folder by
+// path alone; a grantee removing their own access also passes the owner.
+func (s *metadataService) UnshareFolder(ctx context.Context, input *UnshareFolderInput) (output *UnshareFolderOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("unshare_folder", status)
+	}()
+
+	ownerID := input.OwnerID
+	if ownerID == "" {
+		ownerID = input.UserID
+	}
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(input.Email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if ownerID != input.UserID && user.ID != input.UserID {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if err := s.shareRepo.DeleteFolderShare(ctx, ownerID, input.Path, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to unshare folder: %w", err)
+	}
+	return &UnshareFolderOutput{Success: true}, nil
+}
+
+func (s *metadataService) ListFolderShares(ctx context.Context, input *ListFolderSharesInput) (output *ListFolderSharesOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_folder_shares", status)
+	}()
+
+	if err := utils.ValidatePath(input.Path); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	shares, err := s.shareRepo.ListFolderShares(ctx, input.UserID, input.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder shares: %w", err)
+	}
+	return &ListFolderSharesOutput{Shares: shares}, nil
+}
+
+func (s *metadataService) ListFoldersSharedWithMe(ctx context.Context, input *ListFoldersSharedWithMeInput) (output *ListFoldersSharedWithMeOutput, err error) {
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		metrics.RecordMetadataOperation("list_folders_shared_with_me", status)
+	}()
+
+	folders, err := s.shareRepo.ListFoldersSharedWithUser(ctx, input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared folders: %w", err)
+	}
+	return &ListFoldersSharedWithMeOutput{Folders: folders}, nil
+}
+
+// fileRole returns the role userID holds on file: roleOwner, a share role,
+// or an empty string when the file is not shared with them, directly or
+// through one of its folders. Grantees lose access while the file is pending
+// or in the owner's trash.
+func (s *metadataService) fileRole(ctx context.Context, file *models.File, userID string) (string, error) {
+	if file.UserID == userID {
+		return roleOwner, nil
+	}
+	if file.IsTrashed || file.UploadState != models.UploadStateActive {
+		return "", nil
+	}
+	role, err := s.shareRepo.GetRole(ctx, file.ID, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check access: %w", err)
+	}
+	return role, nil
+}
+
+func (s *metadataService) getSharableFile(ctx context.Context, fileID, userID string) (*models.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if file.UserID != userID {
+		return nil, fmt.Errorf("access denied")
+	}
+	if file.UploadState != models.UploadStateActive {
+		return nil, fmt.Errorf("file upload is not complete")
+	}
+	if file.IsTrashed {
+		return nil, fmt.Errorf("file is in trash")
+	}
+	return file, nil
+}
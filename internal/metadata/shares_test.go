@@ -0,0 +1,663 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMetadataService_ShareFile_Success(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, mockUsers)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockUsers.On("GetByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: "user-2", Email: "bob@example.com"}, nil)
+	mockShares.On("Upsert", mock.Anything, mock.MatchedBy(func(s *models.FileShare) bool {
+		return s.FileID == "file-123" && s.OwnerID == "owner-1" && s.UserID == "user-2" && s.Role == models.ShareRoleEditor
+	})).Return(nil)
+
+	output, err := svc.ShareFile(context.Background(), &ShareFileInput{
+		FileID: "file-123",
+		UserID: "owner-1",
+		Email:  " bob@example.com ",
+		Role:   models.ShareRoleEditor,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com", output.Share.Email)
+	mockShares.AssertExpectations(t)
+}
+
+func TestMetadataService_ShareFile_InvalidRole(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	output, err := svc.ShareFile(context.Background(), &ShareFileInput{
+		FileID: "file-123",
+		UserID: "owner-1",
+		Email:  "bob@example.com",
+		Role:   "admin",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ShareFile_NotOwner(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	output, err := svc.ShareFile(context.Background(), &ShareFileInput{
+		FileID: "file-123",
+		UserID: "user-2",
+		Email:  "carol@example.com",
+		Role:   models.ShareRoleViewer,
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ShareFile_WithOwner(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, mockUsers)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockUsers.On("GetByEmail", mock.Anything, "alice@example.com").Return(&models.User{ID: "owner-1", Email: "alice@example.com"}, nil)
+
+	output, err := svc.ShareFile(context.Background(), &ShareFileInput{
+		FileID: "file-123",
+		UserID: "owner-1",
+		Email:  "alice@example.com",
+		Role:   models.ShareRoleViewer,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_UnshareFile_ByGrantee(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, mockUsers)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockUsers.On("GetByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: "user-2", Email: "bob@example.com"}, nil)
+	mockShares.On("Delete", mock.Anything, "file-123", "user-2").Return(nil)
+
+	output, err := svc.UnshareFile(context.Background(), &UnshareFileInput{
+		FileID: "file-123",
+		UserID: "user-2",
+		Email:  "bob@example.com",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockShares.AssertExpectations(t)
+}
+
+func TestMetadataService_UnshareFile_OtherGrantee(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, mockUsers)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockUsers.On("GetByEmail", mock.Anything, "carol@example.com").Return(&models.User{ID: "user-3", Email: "carol@example.com"}, nil)
+
+	output, err := svc.UnshareFile(context.Background(), &UnshareFileInput{
+		FileID: "file-123",
+		UserID: "user-2",
+		Email:  "carol@example.com",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ListShares_NotOwner(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	output, err := svc.ListShares(context.Background(), &ListSharesInput{FileID: "file-123", UserID: "user-2"})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "ListByFileID", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ListSharedWithMe_Success(t *testing.T) {
+	t.Parallel()
+
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(new(MockFileRepository), new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	shared := []*models.SharedFile{
+		{File: &models.File{
+			ID:           "file-123",
+			UserID:       "owner-1",
+			Filename:     "plan.txt",
+			OriginalName: "plan.txt",
+			Path:         "/docs",
+			Tags:         map[string]string{},
+			UploadState:  models.UploadStateActive,
+		}, Role: models.ShareRoleViewer, OwnerEmail: "alice@example.com"},
+	}
+	mockShares.On("ListSharedWithUser", mock.Anything, "user-2", 1, 20).Return(shared, 1, nil)
+
+	output, err := svc.ListSharedWithMe(context.Background(), &ListSharedWithMeInput{UserID: "user-2", Page: 1, PageSize: 20})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), output.Total)
+	assert.Equal(t, shared, output.Items)
+}
+
+func TestMetadataService_GetMetadata_SharedViewer(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockShares.On("GetRole", mock.Anything, "file-123", "user-2").Return(models.ShareRoleViewer, nil)
+
+	output, err := svc.GetMetadata(context.Background(), &GetMetadataInput{FileID: "file-123", UserID: "user-2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, file, output.File)
+}
+
+func TestMetadataService_GetMetadata_SharedFileInTrash(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+	file.IsTrashed = true
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+
+	output, err := svc.GetMetadata(context.Background(), &GetMetadataInput{FileID: "file-123", UserID: "user-2"})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "GetRole", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_UpdateMetadata_Editor(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockShares.On("GetRole", mock.Anything, "file-123", "user-2").Return(models.ShareRoleEditor, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(f *models.File) bool {
+		return f.UserID == "owner-1" && f.Filename == "plan-v2.txt"
+	})).Return(nil)
+	mockRepo.On("RecordActivity", mock.Anything, "user-2", "file-123", models.ActivityEdit).Return(nil)
+
+	output, err := svc.UpdateMetadata(context.Background(), &UpdateMetadataInput{
+		FileID:       "file-123",
+		UserID:       "user-2",
+		Filename:     "plan-v2.txt",
+		OriginalName: "plan-v2.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{"status": "draft"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "plan-v2.txt", output.File.Filename)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_UpdateMetadata_EditorCannotMove(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockShares.On("GetRole", mock.Anything, "file-123", "user-2").Return(models.ShareRoleEditor, nil)
+
+	output, err := svc.UpdateMetadata(context.Background(), &UpdateMetadataInput{
+		FileID:       "file-123",
+		UserID:       "user-2",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/elsewhere",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_UpdateMetadata_Viewer(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockShares.On("GetRole", mock.Anything, "file-123", "user-2").Return(models.ShareRoleViewer, nil)
+
+	output, err := svc.UpdateMetadata(context.Background(), &UpdateMetadataInput{
+		FileID:       "file-123",
+		UserID:       "user-2",
+		Filename:     "renamed.txt",
+		OriginalName: "renamed.txt",
+		Path:         "/docs",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Nil(t, output)
+}
+
+func TestMetadataService_CheckAccess_EditorRole(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	mockRepo.On("CheckAccess", mock.Anything, "file-123", "user-2").Return(true, "objects/file-123", "cloud-storage", nil)
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockShares.On("GetRole", mock.Anything, "file-123", "user-2").Return(models.ShareRoleViewer, nil)
+
+	output, err := svc.CheckAccess(context.Background(), &CheckAccessInput{
+		FileID: "file-123",
+		UserID: "user-2",
+		Role:   models.ShareRoleEditor,
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, output.HasAccess)
+	assert.Empty(t, output.StoragePath)
+
+	output, err = svc.CheckAccess(context.Background(), &CheckAccessInput{FileID: "file-123", UserID: "user-2"})
+
+	assert.NoError(t, err)
+	assert.True(t, output.HasAccess)
+	assert.Equal(t, "objects/file-123", output.StoragePath)
+}
+
+func TestMetadataService_ShareFile_UserNotFound(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, mockUsers)
+
+	file := &models.File{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, "file-123").Return(file, nil)
+	mockUsers.On("GetByEmail", mock.Anything, "nobody@example.com").Return(nil, errors.New("user not found"))
+
+	output, err := svc.ShareFile(context.Background(), &ShareFileInput{
+		FileID: "file-123",
+		UserID: "owner-1",
+		Email:  "nobody@example.com",
+		Role:   models.ShareRoleViewer,
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not found")
+	assert.Nil(t, output)
+}
+
+func TestMetadataService_ShareFolder_Success(t *testing.T) {
+	t.Parallel()
+
+	mockFolders := new(MockFolderRepository)
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(new(MockFileRepository), mockFolders, mockShares, mockUsers)
+
+	mockFolders.On("GetByPath", mock.Anything, "owner-1", "/projects/apollo").Return(models.NewFolder("owner-1", "/projects", "apollo"), nil)
+	mockUsers.On("GetByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: "user-2", Email: "bob@example.com"}, nil)
+	mockShares.On("UpsertFolderShare", mock.Anything, mock.MatchedBy(func(s *models.FolderShare) bool {
+		return s.OwnerID == "owner-1" && s.Path == "/projects/apollo" && s.UserID == "user-2" && s.Role == models.ShareRoleViewer
+	})).Return(nil)
+
+	output, err := svc.ShareFolder(context.Background(), &ShareFolderInput{
+		UserID: "owner-1",
+		Path:   "/projects/apollo",
+		Email:  "bob@example.com",
+		Role:   models.ShareRoleViewer,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com", output.Share.Email)
+	mockShares.AssertExpectations(t)
+}
+
+func TestMetadataService_ShareFolder_Root(t *testing.T) {
+	t.Parallel()
+
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(new(MockFileRepository), new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	output, err := svc.ShareFolder(context.Background(), &ShareFolderInput{
+		UserID: "owner-1",
+		Path:   "/",
+		Email:  "bob@example.com",
+		Role:   models.ShareRoleViewer,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "UpsertFolderShare", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ShareFolder_FolderNotFound(t *testing.T) {
+	t.Parallel()
+
+	mockFolders := new(MockFolderRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(new(MockFileRepository), mockFolders, mockShares, new(MockUserRepository))
+
+	mockFolders.On("GetByPath", mock.Anything, "owner-1", "/missing").Return(nil, errors.New("folder not found"))
+
+	output, err := svc.ShareFolder(context.Background(), &ShareFolderInput{
+		UserID: "owner-1",
+		Path:   "/missing",
+		Email:  "bob@example.com",
+		Role:   models.ShareRoleEditor,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "UpsertFolderShare", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_UnshareFolder_ByGrantee(t *testing.T) {
+	t.Parallel()
+
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(new(MockFileRepository), new(MockFolderRepository), mockShares, mockUsers)
+
+	mockUsers.On("GetByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: "user-2", Email: "bob@example.com"}, nil)
+	mockShares.On("DeleteFolderShare", mock.Anything, "owner-1", "/projects", "user-2").Return(nil)
+
+	output, err := svc.UnshareFolder(context.Background(), &UnshareFolderInput{
+		UserID:  "user-2",
+		OwnerID: "owner-1",
+		Path:    "/projects",
+		Email:   "bob@example.com",
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.Success)
+	mockShares.AssertExpectations(t)
+}
+
+func TestMetadataService_UnshareFolder_OtherGrantee(t *testing.T) {
+	t.Parallel()
+
+	mockShares := new(MockShareRepository)
+	mockUsers := new(MockUserRepository)
+	svc := NewMetadataService(new(MockFileRepository), new(MockFolderRepository), mockShares, mockUsers)
+
+	mockUsers.On("GetByEmail", mock.Anything, "carol@example.com").Return(&models.User{ID: "user-3", Email: "carol@example.com"}, nil)
+
+	output, err := svc.UnshareFolder(context.Background(), &UnshareFolderInput{
+		UserID:  "user-2",
+		OwnerID: "owner-1",
+		Path:    "/projects",
+		Email:   "carol@example.com",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "DeleteFolderShare", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ListMetadata_SharedFolder(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	notTrashed := false
+	mockShares.On("GetFolderRole", mock.Anything, "owner-1", "/projects/apollo", "user-2").Return(models.ShareRoleViewer, nil)
+	mockRepo.On("ListByUserID", mock.Anything, &models.FileListQuery{
+		UserID:     "owner-1",
+		FolderPath: "/projects/apollo",
+		IsTrashed:  &notTrashed,
+		SortBy:     "created_at",
+		SortOrder:  "desc",
+		Limit:      21,
+		CountTotal: true,
+	}).Return([]*models.File{{
+		ID:           "file-123",
+		UserID:       "owner-1",
+		Filename:     "plan.txt",
+		OriginalName: "plan.txt",
+		Path:         "/docs",
+		Tags:         map[string]string{},
+		UploadState:  models.UploadStateActive,
+	}}, 1, nil)
+
+	output, err := svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID:         "user-2",
+		OwnerID:        "owner-1",
+		Path:           "/projects/apollo",
+		Page:           1,
+		PageSize:       20,
+		IncludePending: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, output.Items, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMetadataService_ListMetadata_SharedFolderDenied(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := new(MockFileRepository)
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(mockRepo, new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	mockShares.On("GetFolderRole", mock.Anything, "owner-1", "/private", "user-2").Return("", nil)
+
+	output, err := svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID:   "user-2",
+		OwnerID:  "owner-1",
+		Path:     "/private",
+		Page:     1,
+		PageSize: 20,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockRepo.AssertNotCalled(t, "ListByUserID", mock.Anything, mock.Anything)
+}
+
+func TestMetadataService_ListMetadata_SharedFolderRequiresPath(t *testing.T) {
+	t.Parallel()
+
+	mockShares := new(MockShareRepository)
+	svc := NewMetadataService(new(MockFileRepository), new(MockFolderRepository), mockShares, new(MockUserRepository))
+
+	output, err := svc.ListMetadata(context.Background(), &ListMetadataInput{
+		UserID:   "user-2",
+		OwnerID:  "owner-1",
+		Page:     1,
+		PageSize: 20,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, output)
+	mockShares.AssertNotCalled(t, "GetFolderRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -60,6 +60,28 @@ var (
 		[]string{"operation", "status"},
 	)
 
+	uploadGCCleanedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "upload_gc_cleaned_total",
+			Help: "Total number of abandoned upload resources removed by the garbage collector",
+		},
+		[]string{"resource"},
+	)
+
+	uploadGCErrorsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "upload_gc_errors_total",
+			Help: "Total number of abandoned uploads the garbage collector failed to remove",
+		},
+	)
+
+	dedupSavedBytesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "dedup_saved_bytes_total",
+			Help: "Total number of uploaded bytes not stored again because the same content already existed",
+		},
+	)
+
 	mailOperationsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "mail_operations_total",
@@ -119,3 +141,15 @@ func RecordMetadataOperation(operation, status string) {
 func RecordMailOperation(operation, status string) {
 	mailOperationsTotal.WithLabelValues(operation, status).Inc()
 }
+
+func RecordUploadGCCleaned(resource string) {
+	uploadGCCleanedTotal.WithLabelValues(resource).Inc()
+}
+
+func RecordUploadGCError() {
+	uploadGCErrorsTotal.Inc()
+}
+
+func RecordDedupSaved(bytes int64) {
+	dedupSavedBytesTotal.Add(float64(bytes))
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	ActivityUpload   = "upload"
+	ActivityDownload = "download"
+	ActivityEdit     = "edit"
+)
+
+// FileActivity is a file as a user last dealt with it: what they did with
+// it and when, and since when they have it starred. A starred file the user
+// has not touched yet has no last action.
+type FileActivity struct {
+	File         *File
+	LastAction   string
+	LastActiveAt *time.Time
+	StarredAt    *time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Blob is a stored object shared by every file and version with the same
+// content. It is keyed by the hex SHA-256 of that content and removed from
+// the storage once its reference count drops to zero. KeyOwnerID names the
+// user whose key the object is encrypted with; it is empty for plaintext.
+type Blob struct {
+	Hash        string    `db:"hash" json:"hash"`
+	Bucket      string    `db:"bucket" json:"bucket"`
+	StoragePath string    `db:"storage_path" json:"storage_path"`
+	Size        int64     `db:"size" json:"size"`
+	RefCount    int       `db:"ref_count" json:"ref_count"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	KeyOwnerID  string    `db:"key_owner_id" json:"key_owner_id"`
+}
+
+func NewBlob(hash, bucket, storagePath, keyOwnerID string, size int64) *Blob {
+	return &Blob{
+		Hash:        hash,
+		Bucket:      bucket,
+		StoragePath: storagePath,
+		Size:        size,
+		RefCount:    1,
+		CreatedAt:   time.Now(),
+		KeyOwnerID:  keyOwnerID,
+	}
+}
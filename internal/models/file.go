@@ -1,29 +1,63 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type File struct {
-	ID           string            `db:"id" json:"id"`
-	UserID       string            `db:"user_id" json:"user_id"`
-	Filename     string            `db:"filename" json:"filename"`
-	OriginalName string            `db:"original_name" json:"original_name"`
-	Path         string            `db:"path" json:"path"`
-	Size         int64             `db:"size" json:"size"`
-	MimeType     string            `db:"mime_type" json:"mime_type"`
-	StoragePath  string            `db:"storage_path" json:"storage_path"`
-	Bucket       string            `db:"bucket" json:"bucket"`
-	IsPublic     bool              `db:"is_public" json:"is_public"`
-	Tags         map[string]string `db:"tags" json:"tags"`
-	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time         `db:"updated_at" json:"updated_at"`
-	IsTrashed    bool              `db:"is_trashed" json:"is_trashed"`
-	TrashedAt    *time.Time        `db:"trashed_at" json:"trashed_at"`
+	ID                string            `db:"id" json:"id"`
+	UserID            string            `db:"user_id" json:"user_id"`
+	Filename          string            `db:"filename" json:"filename"`
+	OriginalName      string            `db:"original_name" json:"original_name"`
+	Path              string            `db:"path" json:"path"`
+	Size              int64             `db:"size" json:"size"`
+	MimeType          string            `db:"mime_type" json:"mime_type"`
+	StoragePath       string            `db:"storage_path" json:"storage_path"`
+	Bucket            string            `db:"bucket" json:"bucket"`
+	IsPublic          bool              `db:"is_public" json:"is_public"`
+	Tags              map[string]string `db:"tags" json:"tags"`
+	CreatedAt         time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time         `db:"updated_at" json:"updated_at"`
+	IsTrashed         bool              `db:"is_trashed" json:"is_trashed"`
+	TrashedAt         *time.Time        `db:"trashed_at" json:"trashed_at"`
+	ChecksumAlgorithm string            `db:"checksum_algorithm" json:"checksum_algorithm"`
+	Checksum          string            `db:"checksum" json:"checksum"`
+	UploadState       string            `db:"upload_state" json:"upload_state"`
+	PreviewState      string            `db:"preview_state" json:"preview_state"`
+	BlobHash          string            `db:"blob_hash" json:"blob_hash"`
+	KeyOwnerID        string            `db:"key_owner_id" json:"key_owner_id"`
+	// Snippet is the passage of the file's text matching a search, set in
+	// search results only.
+	Snippet string `db:"-" json:"snippet,omitempty"`
 }
 
+const (
+	UploadStatePending = "pending"
+	UploadStateActive  = "active"
+	UploadStateFailed  = "failed"
+)
+
+const (
+	PreviewStateNone       = "none"
+	PreviewStatePending    = "pending"
+	PreviewStateProcessing = "processing"
+	PreviewStateReady      = "ready"
+	PreviewStateFailed     = "failed"
+)
+
+const (
+	ContentStateNone       = "none"
+	ContentStatePending    = "pending"
+	ContentStateProcessing = "processing"
+	ContentStateReady      = "ready"
+	ContentStateFailed     = "failed"
+)
+
+var ErrNameConflict = errors.New("a file with the same name already exists")
+
 func NewFile(userID, filename, originalName, path, mimeType, storagePath, bucket string, size int64, isPublic bool, tags map[string]string) *File {
 	return &File{
 		ID:           uuid.New().String(),
@@ -41,5 +75,7 @@ func NewFile(userID, filename, originalName, path, mimeType, storagePath, bucket
 		UpdatedAt:    time.Now(),
 		IsTrashed:    false,
 		TrashedAt:    nil,
+		UploadState:  UploadStatePending,
+		PreviewState: PreviewStateNone,
 	}
 }
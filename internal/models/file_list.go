@@ -0,0 +1,27 @@
+package models
+
+// FileListQuery selects a page of a user's files.
+type FileListQuery struct {
+	UserID string
+	// FolderPath limits the listing to a folder and the folders below it.
+	FolderPath     string
+	Search         string
+	Tags           []TagFilter
+	IsTrashed      *bool
+	IncludePending bool
+	SortBy         string
+	SortOrder      string
+	// After continues the listing behind a file; Offset is ignored then.
+	After  *FileCursor
+	Offset int
+	Limit  int
+	// CountTotal asks for the number of matching files across all pages.
+	CountTotal bool
+}
+
+// FileCursor is the position of a file in a sorted listing: the value of
+// the sort field, typed like the column, and the file ID breaking ties.
+type FileCursor struct {
+	Value interface{}
+	ID    string
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Folder struct {
+	ID         string    `db:"id" json:"id"`
+	UserID     string    `db:"user_id" json:"user_id"`
+	Name       string    `db:"name" json:"name"`
+	Path       string    `db:"path" json:"path"`
+	ParentPath string    `db:"parent_path" json:"parent_path"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func NewFolder(userID, parentPath, name string) *Folder {
+	return &Folder{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Name:       name,
+		Path:       path.Join(parentPath, name),
+		ParentPath: parentPath,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
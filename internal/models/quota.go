@@ -0,0 +1,18 @@
+package models
+
+import (
+	"errors"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+type StorageUsage struct {
+	UserID        string `db:"user_id" json:"user_id"`
+	Plan          string `db:"plan" json:"plan"`
+	UsedBytes     int64  `db:"used_bytes" json:"used_bytes"`
+	ReservedBytes int64  `db:"reserved_bytes" json:"reserved_bytes"`
+	LimitBytes    int64  `db:"max_bytes" json:"limit_bytes"`
+	UsedFiles     int64  `db:"used_files" json:"used_files"`
+	ReservedFiles int64  `db:"reserved_files" json:"reserved_files"`
+	LimitFiles    int64  `db:"max_files" json:"limit_files"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// FileSearch describes a search over a user's live files. Zero values leave
+// a criterion out.
+type FileSearch struct {
+	UserID string
+	Query  string
+	// Path limits the search to a folder and the folders below it.
+	Path string
+	// MimeTypes holds families such as "image" or full types such as
+	// "application/pdf"; a file has to match one of them.
+	MimeTypes     []string
+	MinSize       int64
+	MaxSize       int64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Tags          []TagFilter
+	Page          int
+	PageSize      int
+}
+
+// FacetCount is the number of matching files sharing a facet value.
+type FacetCount struct {
+	Value string
+	Count int
+}
+
+// SearchResult is a page of matching files along with the total number of
+// matches and their breakdown by MIME type family and by year of creation.
+type SearchResult struct {
+	Files []*File
+	Total int
+	Types []FacetCount
+	Years []FacetCount
+}
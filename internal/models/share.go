@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ShareRoleViewer = "viewer"
+	ShareRoleEditor = "editor"
+)
+
+// FileShare grants a user other than the owner access to a single file.
+// Email is filled from the grantee's account when shares are listed.
+type FileShare struct {
+	ID        string    `db:"id" json:"id"`
+	FileID    string    `db:"file_id" json:"file_id"`
+	OwnerID   string    `db:"owner_id" json:"owner_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Email     string    `db:"-" json:"email"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// SharedFile is a file as seen by a user it has been shared with.
+type SharedFile struct {
+	File       *File
+	Role       string
+	OwnerEmail string
+	SharedAt   time.Time
+}
+
+// FolderShare grants a user access to every file under one of the owner's
+// folders, including files added after the share was created.
+type FolderShare struct {
+	ID        string    `db:"id" json:"id"`
+	OwnerID   string    `db:"owner_id" json:"owner_id"`
+	Path      string    `db:"path" json:"path"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Email     string    `db:"-" json:"email"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// SharedFolder is a folder as seen by a user it has been shared with.
+type SharedFolder struct {
+	OwnerID    string
+	OwnerEmail string
+	Path       string
+	Role       string
+	SharedAt   time.Time
+}
+
+func NewFileShare(fileID, ownerID, userID, role string) *FileShare {
+	return &FileShare{
+		ID:        uuid.New().String(),
+		FileID:    fileID,
+		OwnerID:   ownerID,
+		UserID:    userID,
+		Role:      role,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func NewFolderShare(ownerID, folderPath, userID, role string) *FolderShare {
+	return &FolderShare{
+		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
+		Path:      folderPath,
+		UserID:    userID,
+		Role:      role,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func IsValidShareRole(role string) bool {
+	return role == ShareRoleViewer || role == ShareRoleEditor
+}
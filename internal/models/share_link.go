@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShareLink lets anyone holding its token download a file without an
+// account. Only a hash of the token is stored; the token itself is handed
+// out once, when the link is created.
+type ShareLink struct {
+	ID               string     `db:"id" json:"id"`
+	FileID           string     `db:"file_id" json:"file_id"`
+	UserID           string     `db:"user_id" json:"user_id"`
+	TokenHash        string     `db:"token_hash" json:"-"`
+	PasswordHash     string     `db:"password_hash" json:"-"`
+	ExpiresAt        *time.Time `db:"expires_at" json:"expires_at"`
+	MaxDownloads     int        `db:"max_downloads" json:"max_downloads"`
+	DownloadCount    int        `db:"download_count" json:"download_count"`
+	LastDownloadedAt *time.Time `db:"last_downloaded_at" json:"last_downloaded_at"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt        *time.Time `db:"revoked_at" json:"revoked_at"`
+}
+
+func NewShareLink(fileID, userID, tokenHash string) *ShareLink {
+	return &ShareLink{
+		ID:        uuid.New().String(),
+		FileID:    fileID,
+		UserID:    userID,
+		TokenHash: tokenHash,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (l *ShareLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// Usable reports whether the link can still be used to download at now.
+func (l *ShareLink) Usable(now time.Time) bool {
+	if l.RevokedAt != nil {
+		return false
+	}
+	if l.ExpiresAt != nil && !now.Before(*l.ExpiresAt) {
+		return false
+	}
+	return l.MaxDownloads == 0 || l.DownloadCount < l.MaxDownloads
+}
@@ -0,0 +1,15 @@
+package models
+
+// TagFilter matches files carrying the tag Key. When Values are given, the
+// tag has to have one of them.
+type TagFilter struct {
+	Key    string
+	Values []string
+}
+
+// TagCount is the number of files carrying a tag with a given value.
+type TagCount struct {
+	Key   string
+	Value string
+	Count int
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type MultipartUpload struct {
+	FileID    string    `db:"file_id" json:"file_id"`
+	UploadID  string    `db:"upload_id" json:"upload_id"`
+	PartSize  int64     `db:"part_size" json:"part_size"`
+	PartCount int       `db:"part_count" json:"part_count"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+func NewMultipartUpload(fileID, uploadID string, partSize int64, partCount int) *MultipartUpload {
+	return &MultipartUpload{
+		FileID:    fileID,
+		UploadID:  uploadID,
+		PartSize:  partSize,
+		PartCount: partCount,
+		CreatedAt: time.Now(),
+	}
+}
+
+// StaleUpload is an upload that was never completed. UploadID is set when
+// the file still has an open multipart session.
+type StaleUpload struct {
+	File     *File
+	UploadID string
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserKey is the data key a user's objects are encrypted with, wrapped by
+// the master key MasterKeyID names. Only the wrapped form is ever stored.
+type UserKey struct {
+	UserID      string     `db:"user_id" json:"user_id"`
+	WrappedKey  []byte     `db:"wrapped_key" json:"-"`
+	MasterKeyID string     `db:"master_key_id" json:"master_key_id"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	RotatedAt   *time.Time `db:"rotated_at" json:"rotated_at"`
+}
+
+func NewUserKey(userID string, wrappedKey []byte, masterKeyID string) *UserKey {
+	return &UserKey{
+		UserID:      userID,
+		WrappedKey:  wrappedKey,
+		MasterKeyID: masterKeyID,
+		CreatedAt:   time.Now(),
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrVersionConflict = errors.New("version was changed by another request")
+
+// FileVersion is a version of a file's content other than the current one,
+// which lives on the file row itself. Pending versions are uploads that have
+// not been completed yet; active ones are superseded content kept for
+// history.
+type FileVersion struct {
+	ID                string     `db:"id" json:"id"`
+	FileID            string     `db:"file_id" json:"file_id"`
+	UserID            string     `db:"user_id" json:"user_id"`
+	Version           int        `db:"version" json:"version"`
+	Size              int64      `db:"size" json:"size"`
+	MimeType          string     `db:"mime_type" json:"mime_type"`
+	StoragePath       string     `db:"storage_path" json:"storage_path"`
+	Bucket            string     `db:"bucket" json:"bucket"`
+	ChecksumAlgorithm string     `db:"checksum_algorithm" json:"checksum_algorithm"`
+	Checksum          string     `db:"checksum" json:"checksum"`
+	UploadState       string     `db:"upload_state" json:"upload_state"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	SupersededAt      *time.Time `db:"superseded_at" json:"superseded_at"`
+	BlobHash          string     `db:"blob_hash" json:"blob_hash"`
+	KeyOwnerID        string     `db:"key_owner_id" json:"key_owner_id"`
+}
+
+func NewFileVersion(file *File, storagePath, bucket, mimeType string, size int64) *FileVersion {
+	return &FileVersion{
+		ID:          uuid.New().String(),
+		FileID:      file.ID,
+		UserID:      file.UserID,
+		Size:        size,
+		MimeType:    mimeType,
+		StoragePath: storagePath,
+		Bucket:      bucket,
+		UploadState: UploadStatePending,
+		CreatedAt:   time.Now(),
+	}
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const blobColumns = `hash, bucket, storage_path, size, ref_count, created_at, key_owner_id`
+
+type blobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBlobRepository(db *pgxpool.Pool) *blobRepository {
+	return &blobRepository{db: db}
+}
+
+// AttachFile records blob as the content of a file and takes a reference on
+// it. When a blob with the same hash exists already, the file is pointed at
+// that blob's object, and takes over its encryption, instead. The blob the
+// file ends up with is returned.
+func (r *blobRepository) AttachFile(ctx context.Context, fileID string, blob *models.Blob) (*models.Blob, error) {
+	return r.attach(ctx, `
+		UPDATE files
+		SET blob_hash = $1, bucket = $2, storage_path = $3, key_owner_id = $4, updated_at = NOW()
+		WHERE id = $5
+	`, fileID, blob)
+}
+
+// AttachVersion does the same as AttachFile for a version upload.
+func (r *blobRepository) AttachVersion(ctx context.Context, versionID string, blob *models.Blob) (*models.Blob, error) {
+	return r.attach(ctx, `
+		UPDATE file_versions
+		SET blob_hash = $1, bucket = $2, storage_path = $3, key_owner_id = $4
+		WHERE id = $5
+	`, versionID, blob)
+}
+
+func (r *blobRepository) attach(ctx context.Context, update, id string, blob *models.Blob) (*models.Blob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	stored, err := scanBlob(tx.QueryRow(ctx, `
+		INSERT INTO blobs (`+blobColumns+`)
+		VALUES ($1, $2, $3, $4, 1, $5, $6)
+		ON CONFLICT (hash) DO UPDATE SET ref_count = blobs.ref_count + 1
+		RETURNING `+blobColumns,
+		blob.Hash, blob.Bucket, blob.StoragePath, blob.Size, blob.CreatedAt, blob.KeyOwnerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	if err := r.point(ctx, tx, update, id, stored); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit blob: %w", err)
+	}
+	return stored, nil
+}
+
+// AttachExisting points a file at a blob that is already stored, for copies
+// that share their source's content. It fails when the blob has been
+// released in the meantime.
+func (r *blobRepository) AttachExisting(ctx context.Context, fileID, hash string) (*models.Blob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	stored, err := scanBlob(tx.QueryRow(ctx, `
+		UPDATE blobs
+		SET ref_count = ref_count + 1
+		WHERE hash = $1 AND ref_count > 0
+		RETURNING `+blobColumns, hash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("blob not found")
+		}
+		return nil, fmt.Errorf("failed to reference blob: %w", err)
+	}
+
+	err = r.point(ctx, tx, `
+		UPDATE files
+		SET blob_hash = $1, bucket = $2, storage_path = $3, key_owner_id = $4, updated_at = NOW()
+		WHERE id = $5
+	`, fileID, stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit blob: %w", err)
+	}
+	return stored, nil
+}
+
+func (r *blobRepository) point(ctx context.Context, tx pgx.Tx, update, id string, blob *models.Blob) error {
+	result, err := tx.Exec(ctx, update, blob.Hash, blob.Bucket, blob.StoragePath, blob.KeyOwnerID, id)
+	if err != nil {
+		return fmt.Errorf("failed to attach blob: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("content owner not found")
+	}
+	return nil
+}
+
+// Release drops one reference to a blob. When that was the last one, the
+// blob row is deleted and returned so the caller can remove its object;
+// otherwise nil is returned. A blob still named by a file or version is
+// never deleted, even if its count says otherwise.
+func (r *blobRepository) Release(ctx context.Context, hash string) (*models.Blob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var refs int
+	err = tx.QueryRow(ctx, `
+		UPDATE blobs
+		SET ref_count = GREATEST(ref_count - 1, 0)
+		WHERE hash = $1
+		RETURNING ref_count
+	`, hash).Scan(&refs)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("blob not found")
+		}
+		return nil, fmt.Errorf("failed to release blob: %w", err)
+	}
+
+	var released *models.Blob
+	if refs == 0 {
+		released, err = scanBlob(tx.QueryRow(ctx, `
+			DELETE FROM blobs
+			WHERE hash = $1
+				AND NOT EXISTS (SELECT 1 FROM files WHERE blob_hash = $1)
+				AND NOT EXISTS (SELECT 1 FROM file_versions WHERE blob_hash = $1)
+			RETURNING `+blobColumns, hash))
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, fmt.Errorf("failed to delete blob: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit blob release: %w", err)
+	}
+	return released, nil
+}
+
+func scanBlob(row pgx.Row) (*models.Blob, error) {
+	var blob models.Blob
+	err := row.Scan(
+		&blob.Hash,
+		&blob.Bucket,
+		&blob.StoragePath,
+		&blob.Size,
+		&blob.RefCount,
+		&blob.CreatedAt,
+		&blob.KeyOwnerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
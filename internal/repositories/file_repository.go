@@ -2,8 +2,11 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"html"
 	"strings"
+	"time"
 
 	"github.com/Sene4ka/cloud_storage/internal/models"
 	"github.com/jackc/pgx/v5"
@@ -23,13 +26,24 @@ func (r *fileRepository) Create(ctx context.Context, file *models.File) error {
 		INSERT INTO files (
 			id, user_id, filename, original_name, path, size, mime_type,
 			storage_path, bucket, is_public, tags, created_at, updated_at,
-			is_trashed, trashed_at
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			key_owner_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ensureFolders(ctx, tx, file.UserID, file.Path); err != nil {
+		return err
+	}
+
 	tags := formatTags(file.Tags)
-	_, err := r.db.Exec(ctx, query,
+	_, err = tx.Exec(ctx, query,
 		file.ID,
 		file.UserID,
 		file.Filename,
@@ -45,11 +59,22 @@ func (r *fileRepository) Create(ctx context.Context, file *models.File) error {
 		file.UpdatedAt,
 		file.IsTrashed,
 		file.TrashedAt,
+		file.ChecksumAlgorithm,
+		file.Checksum,
+		file.UploadState,
+		file.KeyOwnerID,
 	)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return models.ErrNameConflict
+		}
 		return fmt.Errorf("failed to create file: %w", err)
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit file: %w", err)
+	}
 	return nil
 }
 
@@ -58,7 +83,8 @@ func (r *fileRepository) GetByID(ctx context.Context, id string) (*models.File,
 		SELECT
 			id, user_id, filename, original_name, path, size, mime_type,
 			storage_path, bucket, is_public, tags, created_at, updated_at,
-			is_trashed, trashed_at
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			preview_state, blob_hash, key_owner_id
 		FROM files
 		WHERE id = $1
 	`
@@ -82,6 +108,12 @@ func (r *fileRepository) GetByID(ctx context.Context, id string) (*models.File,
 		&file.UpdatedAt,
 		&file.IsTrashed,
 		&file.TrashedAt,
+		&file.ChecksumAlgorithm,
+		&file.Checksum,
+		&file.UploadState,
+		&file.PreviewState,
+		&file.BlobHash,
+		&file.KeyOwnerID,
 	)
 
 	if err != nil {
@@ -95,59 +127,93 @@ func (r *fileRepository) GetByID(ctx context.Context, id string) (*models.File,
 	return &file, nil
 }
 
-func (r *fileRepository) ListByUserID(ctx context.Context, userID string, page, pageSize int, sortBy, sortOrder, search string, isTrashed *bool) ([]*models.File, int, error) {
-	offset := (page - 1) * pageSize
+// listSortFields are the columns files can be listed by.
+var listSortFields = map[string]bool{"created_at": true, "updated_at": true, "filename": true, "size": true, "path": true}
 
+// ListByUserID lists a user's files. A non-empty folder path limits the
+// result to files in that folder and the folders below it, and every tag
+// filter has to match. Files sharing a sort value are ordered by ID so that
+// a listing continued after a cursor neither skips nor repeats files. The
+// total is only counted when asked for.
+func (r *fileRepository) ListByUserID(ctx context.Context, query *models.FileListQuery) ([]*models.File, int, error) {
 	whereClause := "WHERE user_id = $1"
-	args := []interface{}{userID}
+	args := []interface{}{query.UserID}
 	argCount := 1
 
-	if isTrashed != nil {
+	if !query.IncludePending {
+		whereClause += " AND upload_state = 'active'"
+	}
+
+	if query.FolderPath != "" && query.FolderPath != "/" {
 		argCount++
-		whereClause += fmt.Sprintf(" AND is_trashed = $%d", argCount)
-		args = append(args, *isTrashed)
+		whereClause += fmt.Sprintf(" AND (path = $%d OR left(path, length($%d) + 1) = $%d || '/')", argCount, argCount, argCount)
+		args = append(args, query.FolderPath)
 	}
 
-	if search != "" {
+	if query.IsTrashed != nil {
 		argCount++
-		whereClause += fmt.Sprintf(" AND (filename ILIKE $%d OR original_name ILIKE $%d)", argCount, argCount)
-		args = append(args, "%"+search+"%")
+		whereClause += fmt.Sprintf(" AND is_trashed = $%d", argCount)
+		args = append(args, *query.IsTrashed)
 	}
 
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM files %s", whereClause)
-	var total int
-	err := r.db.QueryRow(ctx, countQuery, args[:argCount]...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count files: %w", err)
+	if query.Search != "" {
+		argCount += 2
+		whereClause += fmt.Sprintf(" AND (filename ILIKE $%d OR original_name ILIKE $%d OR %s)",
+			argCount-1, argCount-1, contentMatches(fmt.Sprintf("$%d", argCount)))
+		args = append(args, "%"+query.Search+"%", query.Search)
 	}
 
-	query := fmt.Sprintf(`
+	whereClause, args = appendTagFilters(whereClause, args, query.Tags)
+	argCount = len(args)
+
+	total := 0
+	if query.CountTotal {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM files %s", whereClause)
+		err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count files: %w", err)
+		}
+	}
+
+	sortBy, order := "created_at", "DESC"
+	if listSortFields[query.SortBy] {
+		sortBy, order = query.SortBy, "ASC"
+		if strings.ToUpper(query.SortOrder) == "DESC" {
+			order = "DESC"
+		}
+	}
+
+	if query.After != nil {
+		comparison := ">"
+		if order == "DESC" {
+			comparison = "<"
+		}
+		whereClause += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", sortBy, comparison, argCount+1, argCount+2)
+		args = append(args, query.After.Value, query.After.ID)
+		argCount += 2
+	}
+
+	listQuery := fmt.Sprintf(`
         SELECT
             id, user_id, filename, original_name, path, size, mime_type,
             storage_path, bucket, is_public, tags, created_at, updated_at,
-            is_trashed, trashed_at
+            is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+            preview_state
         FROM files
         %s
-    `, whereClause)
-
-	if sortBy != "" {
-		validSortFields := map[string]bool{"created_at": true, "updated_at": true, "filename": true, "size": true, "path": true}
-		if validSortFields[sortBy] {
-			order := "ASC"
-			if strings.ToUpper(sortOrder) == "DESC" {
-				order = "DESC"
-			}
-			query += fmt.Sprintf(" ORDER BY %s %s", sortBy, order)
-		}
-	} else {
-		query += " ORDER BY created_at DESC"
-	}
+        ORDER BY %s %s, id %s
+    `, whereClause, sortBy, order, order)
 
 	argCount++
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, pageSize, offset)
+	listQuery += fmt.Sprintf(" LIMIT $%d", argCount)
+	args = append(args, query.Limit)
+	if query.After == nil {
+		argCount++
+		listQuery += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, query.Offset)
+	}
 
-	rows, err := r.db.Query(ctx, query, args...)
+	rows, err := r.db.Query(ctx, listQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list files: %w", err)
 	}
@@ -173,6 +239,10 @@ func (r *fileRepository) ListByUserID(ctx context.Context, userID string, page,
 			&file.UpdatedAt,
 			&file.IsTrashed,
 			&file.TrashedAt,
+			&file.ChecksumAlgorithm,
+			&file.Checksum,
+			&file.UploadState,
+			&file.PreviewState,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan file: %w", err)
@@ -180,7 +250,13 @@ func (r *fileRepository) ListByUserID(ctx context.Context, userID string, page,
 		file.Tags = parseTags(tags)
 		files = append(files, &file)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list files: %w", err)
+	}
 
+	if err := r.attachSnippets(ctx, files, query.Search); err != nil {
+		return nil, 0, err
+	}
 	return files, total, nil
 }
 
@@ -197,6 +273,10 @@ func (r *fileRepository) Update(ctx context.Context, file *models.File) error {
 		WHERE id = $7 AND user_id = $8
 	`
 
+	if err := ensureFolders(ctx, r.db, file.UserID, file.Path); err != nil {
+		return err
+	}
+
 	tags := formatTags(file.Tags)
 	result, err := r.db.Exec(ctx, query,
 		file.Filename,
@@ -231,18 +311,26 @@ func (r *fileRepository) Delete(ctx context.Context, id, userID string) error {
 	return nil
 }
 
+// CheckAccess reports whether userID may read a file: as its owner, because
+// it is public, or through a share of any role on the file or one of its
+// folders. Shared files stop being readable by grantees while they are in
+// the owner's trash.
 func (r *fileRepository) CheckAccess(ctx context.Context, fileID, userID string) (bool, string, string, error) {
 	query := `
-		SELECT storage_path, bucket, is_public, user_id
-		FROM files
-		WHERE id = $1
+		SELECT f.storage_path, f.bucket, f.is_public, f.user_id,
+			NOT f.is_trashed AND (
+				EXISTS (SELECT 1 FROM file_shares s WHERE s.file_id = f.id AND s.user_id::text = $2)
+				OR EXISTS (SELECT 1 FROM folder_shares fs WHERE ` + folderShareCovers + ` AND fs.user_id::text = $2)
+			)
+		FROM files f
+		WHERE f.id = $1 AND f.upload_state = 'active'
 	`
 
-	row := r.db.QueryRow(ctx, query, fileID)
+	row := r.db.QueryRow(ctx, query, fileID, userID)
 	var storagePath, bucket string
-	var isPublic bool
+	var isPublic, isShared bool
 	var fileUserID string
-	err := row.Scan(&storagePath, &bucket, &isPublic, &fileUserID)
+	err := row.Scan(&storagePath, &bucket, &isPublic, &fileUserID, &isShared)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return false, "", "", fmt.Errorf("file not found")
@@ -253,7 +341,7 @@ func (r *fileRepository) CheckAccess(ctx context.Context, fileID, userID string)
 	if isPublic {
 		return true, storagePath, bucket, nil
 	}
-	if fileUserID == userID {
+	if fileUserID == userID || isShared {
 		return true, storagePath, bucket, nil
 	}
 	return false, "", "", nil
@@ -274,18 +362,243 @@ func (r *fileRepository) SetTrashed(ctx context.Context, fileID, userID string,
 	if result.RowsAffected() == 0 {
 		return fmt.Errorf("file not found, or access denied")
 	}
+
+	// The folder may have been deleted while the file was in the trash.
+	if !isTrashed {
+		var path string
+		if err := r.db.QueryRow(ctx, `SELECT path FROM files WHERE id = $1`, fileID).Scan(&path); err != nil {
+			return fmt.Errorf("failed to get file path: %w", err)
+		}
+		if err := ensureFolders(ctx, r.db, userID, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByName returns the file occupying a name in a folder, trashed or not,
+// or nil if the name is free.
+func (r *fileRepository) FindByName(ctx context.Context, userID, path, originalName string) (*models.File, error) {
+	files, err := queryFiles(ctx, r.db, `
+		SELECT
+			id, user_id, filename, original_name, path, size, mime_type,
+			storage_path, bucket, is_public, tags, created_at, updated_at,
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			blob_hash, key_owner_id
+		FROM files
+		WHERE user_id = $1 AND path = $2 AND original_name = $3
+	`, userID, path, originalName)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return files[0], nil
+}
+
+func (r *fileRepository) ListNamesWithPrefix(ctx context.Context, userID, path, prefix string) ([]string, error) {
+	query := `
+		SELECT original_name
+		FROM files
+		WHERE user_id = $1 AND path = $2 AND left(original_name, length($3)) = $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, path, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list names: %w", err)
+	}
+	return names, nil
+}
+
+const moveFileQuery = `
+	UPDATE files
+	SET path = $1, original_name = $2, updated_at = NOW()
+	WHERE id = $3 AND user_id = $4
+`
+
+func (r *fileRepository) Move(ctx context.Context, fileID, userID, path, originalName string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ensureFolders(ctx, tx, userID, path); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, moveFileQuery, path, originalName, fileID, userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.ErrNameConflict
+		}
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found or access denied")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit move: %w", err)
+	}
+	return nil
+}
+
+// Replace moves a file to path under originalName, which the file
+// displacedID holds now. In the same transaction the displaced file is
+// renamed out of the way and put in the trash, so a failure leaves both
+// files as they were. Purging the displaced file is up to the caller.
+func (r *fileRepository) Replace(ctx context.Context, fileID, userID, path, originalName, displacedID string) error {
+	displace := `
+		UPDATE files
+		SET original_name = left(original_name, 200) || ' (' || id || ')',
+			is_trashed = TRUE, trashed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ensureFolders(ctx, tx, userID, path); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, displace, displacedID, userID); err != nil {
+		return fmt.Errorf("failed to displace file: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, moveFileQuery, path, originalName, fileID, userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.ErrNameConflict
+		}
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found or access denied")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit replace: %w", err)
+	}
+	return nil
+}
+
+// SetTrashedBatch trashes or restores several files of one user in a single
+// transaction and returns the ids that were actually changed. Only active
+// files that are not in the trash yet can be trashed.
+func (r *fileRepository) SetTrashedBatch(ctx context.Context, fileIDs []string, userID string, isTrashed bool) ([]string, error) {
+	whereClause := "WHERE id = ANY($2::uuid[]) AND user_id = $3"
+	if isTrashed {
+		whereClause += " AND is_trashed = FALSE AND upload_state = 'active'"
+	}
+	query := fmt.Sprintf(`
+		UPDATE files
+		SET is_trashed = $1,
+			trashed_at = CASE WHEN $1 THEN NOW() ELSE NULL END,
+			updated_at = NOW()
+		%s
+		RETURNING id, path
+	`, whereClause)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, isTrashed, fileIDs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set trashed status: %w", err)
+	}
+
+	var updated []string
+	paths := make(map[string]bool)
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		updated = append(updated, id)
+		paths[path] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to set trashed status: %w", err)
+	}
+
+	if !isTrashed {
+		for path := range paths {
+			if err := ensureFolders(ctx, tx, userID, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit trashed status: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *fileRepository) SetChecksum(ctx context.Context, fileID, algorithm, checksum string) error {
+	query := `
+		UPDATE files
+		SET checksum_algorithm = $1, checksum = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	result, err := r.db.Exec(ctx, query, algorithm, checksum, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to set checksum: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found")
+	}
+	return nil
+}
+
+func (r *fileRepository) SetObjectInfo(ctx context.Context, fileID string, size int64, mimeType string) error {
+	query := `
+		UPDATE files
+		SET size = $1, mime_type = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	result, err := r.db.Exec(ctx, query, size, mimeType, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to set object info: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found")
+	}
 	return nil
 }
 
 func formatTags(tags map[string]string) string {
 	if tags == nil {
-		return ""
+		return "{}"
 	}
-	var parts []string
-	for k, v := range tags {
-		parts = append(parts, k+"="+v)
+	raw, err := json.Marshal(tags)
+	if err != nil {
+		return "{}"
 	}
-	return strings.Join(parts, ",")
+	return string(raw)
 }
 
 func parseTags(tags string) map[string]string {
@@ -293,12 +606,840 @@ func parseTags(tags string) map[string]string {
 	if tags == "" {
 		return result
 	}
-	parts := strings.Split(tags, ",")
-	for _, part := range parts {
-		kv := strings.SplitN(part, "=", 2)
-		if len(kv) == 2 {
-			result[kv[0]] = kv[1]
-		}
+	if err := json.Unmarshal([]byte(tags), &result); err != nil {
+		return make(map[string]string)
 	}
 	return result
 }
+
+// appendTagFilters adds a condition for every tag filter to a WHERE clause
+// whose arguments are args.
+func appendTagFilters(whereClause string, args []interface{}, tags []models.TagFilter) (string, []interface{}) {
+	for _, tag := range tags {
+		args = append(args, tag.Key)
+		whereClause += fmt.Sprintf(" AND tags ? $%d", len(args))
+		if len(tag.Values) > 0 {
+			args = append(args, tag.Values)
+			whereClause += fmt.Sprintf(" AND tags->>$%d = ANY($%d)", len(args)-1, len(args))
+		}
+	}
+	return whereClause, args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Search returns a page of a user's live files matching search, best
+// matches first when there is a query and newest first otherwise. The query
+// is matched against the words of the name, the path and the tags, and as a
+// substring of each of them.
+func (r *fileRepository) Search(ctx context.Context, search *models.FileSearch) (*models.SearchResult, error) {
+	whereClause := "WHERE user_id = $1 AND upload_state = 'active' AND is_trashed = FALSE"
+	args := []interface{}{search.UserID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	orderBy := "created_at DESC, id"
+	if search.Query != "" {
+		query := arg(search.Query)
+		pattern := arg("%" + likeEscaper.Replace(search.Query) + "%")
+		whereClause += fmt.Sprintf(" AND (search_vector @@ websearch_to_tsquery('simple', %s) OR original_name ILIKE %s OR path ILIKE %s OR tags::text ILIKE %s OR %s)",
+			query, pattern, pattern, pattern, contentMatches(query))
+		orderBy = fmt.Sprintf(`ts_rank(search_vector, websearch_to_tsquery('simple', %s)) + similarity(original_name, %s) +
+			coalesce((SELECT ts_rank(c.content_vector, websearch_to_tsquery('simple', %s)) FROM file_contents c WHERE c.file_id = files.id), 0) DESC,
+			created_at DESC, id`, query, query, query)
+	}
+	if search.Path != "" && search.Path != "/" {
+		folder := arg(search.Path)
+		whereClause += fmt.Sprintf(" AND (path = %s OR left(path, length(%s) + 1) = %s || '/')", folder, folder, folder)
+	}
+	if len(search.MimeTypes) > 0 {
+		var families, types []string
+		for _, mimeType := range search.MimeTypes {
+			if strings.Contains(mimeType, "/") {
+				types = append(types, mimeType)
+			} else {
+				families = append(families, mimeType)
+			}
+		}
+		whereClause += fmt.Sprintf(" AND (split_part(mime_type, '/', 1) = ANY(%s) OR mime_type = ANY(%s))", arg(families), arg(types))
+	}
+	if search.MinSize > 0 {
+		whereClause += " AND size >= " + arg(search.MinSize)
+	}
+	if search.MaxSize > 0 {
+		whereClause += " AND size <= " + arg(search.MaxSize)
+	}
+	if search.CreatedAfter != nil {
+		whereClause += " AND created_at >= " + arg(*search.CreatedAfter)
+	}
+	if search.CreatedBefore != nil {
+		whereClause += " AND created_at < " + arg(*search.CreatedBefore)
+	}
+	if search.UpdatedAfter != nil {
+		whereClause += " AND updated_at >= " + arg(*search.UpdatedAfter)
+	}
+	if search.UpdatedBefore != nil {
+		whereClause += " AND updated_at < " + arg(*search.UpdatedBefore)
+	}
+	whereClause, args = appendTagFilters(whereClause, args, search.Tags)
+
+	result := &models.SearchResult{}
+	var err error
+	result.Types, err = r.facet(ctx, "split_part(mime_type, '/', 1)", "2 DESC, 1", whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+	result.Years, err = r.facet(ctx, "to_char(created_at, 'YYYY')", "1 DESC", whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, facet := range result.Types {
+		result.Total += facet.Count
+	}
+
+	filterArgs := len(args)
+	query := fmt.Sprintf(`
+		SELECT
+			id, user_id, filename, original_name, path, size, mime_type,
+			storage_path, bucket, is_public, tags, created_at, updated_at,
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			blob_hash, key_owner_id
+		FROM files
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, whereClause, orderBy, filterArgs+1, filterArgs+2)
+	args = append(args, search.PageSize, (search.Page-1)*search.PageSize)
+
+	result.Files, err = queryFiles(ctx, r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.attachSnippets(ctx, result.Files, search.Query); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// contentMatches is a condition on files matching the extracted text of a
+// file against the web search query in the parameter param.
+func contentMatches(param string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM file_contents c WHERE c.file_id = files.id AND c.content_vector @@ websearch_to_tsquery('simple', %s))", param)
+}
+
+// headlineOptions has ts_headline mark matches with control characters,
+// which extracted text never contains, so that the rest of a snippet can be
+// escaped before the marks are turned into HTML.
+const headlineOptions = "StartSel=\"\x02\", StopSel=\"\x03\", MaxWords=20, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// attachSnippets sets the snippet of every file whose text matches the web
+// search query. Snippets are HTML with the matches in <mark> elements.
+func (r *fileRepository) attachSnippets(ctx context.Context, files []*models.File, search string) error {
+	if search == "" || len(files) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.File, len(files))
+	ids := make([]string, len(files))
+	for i, file := range files {
+		byID[file.ID] = file
+		ids[i] = file.ID
+	}
+
+	query := `
+		SELECT c.file_id, ts_headline('simple', c.content, q, $3)
+		FROM file_contents c, websearch_to_tsquery('simple', $2) q
+		WHERE c.file_id = ANY($1::uuid[]) AND c.content_vector @@ q
+	`
+	rows, err := r.db.Query(ctx, query, ids, search, headlineOptions)
+	if err != nil {
+		return fmt.Errorf("failed to get snippets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fileID, headline string
+		if err := rows.Scan(&fileID, &headline); err != nil {
+			return fmt.Errorf("failed to scan snippet: %w", err)
+		}
+		if file, ok := byID[fileID]; ok {
+			file.Snippet = formatSnippet(headline)
+		}
+	}
+	return rows.Err()
+}
+
+var snippetMarks = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+func formatSnippet(headline string) string {
+	return snippetMarks.Replace(html.EscapeString(strings.Join(strings.Fields(headline), " ")))
+}
+
+// facet counts the files matching whereClause by the value of expr.
+func (r *fileRepository) facet(ctx context.Context, expr, orderBy, whereClause string, args []interface{}) ([]models.FacetCount, error) {
+	query := fmt.Sprintf("SELECT %s, COUNT(*) FROM files %s GROUP BY 1 ORDER BY %s", expr, whereClause, orderBy)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search facets: %w", err)
+	}
+	defer rows.Close()
+
+	var facets []models.FacetCount
+	for rows.Next() {
+		var facet models.FacetCount
+		if err := rows.Scan(&facet.Value, &facet.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan search facet: %w", err)
+		}
+		facets = append(facets, facet)
+	}
+	return facets, rows.Err()
+}
+
+// ListTags counts a user's live files by tag and value. A non-empty key
+// limits the result to that tag.
+func (r *fileRepository) ListTags(ctx context.Context, userID, key string) ([]*models.TagCount, error) {
+	query := `
+		SELECT t.key, t.value, COUNT(*)
+		FROM files f, jsonb_each_text(f.tags) t
+		WHERE f.user_id = $1 AND f.upload_state = 'active' AND f.is_trashed = FALSE
+			AND ($2 = '' OR t.key = $2)
+		GROUP BY t.key, t.value
+		ORDER BY t.key, t.value
+	`
+	rows, err := r.db.Query(ctx, query, userID, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*models.TagCount
+	for rows.Next() {
+		var count models.TagCount
+		if err := rows.Scan(&count.Key, &count.Value, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		counts = append(counts, &count)
+	}
+	return counts, rows.Err()
+}
+
+func (r *fileRepository) CreateMultipartUpload(ctx context.Context, upload *models.MultipartUpload) error {
+	query := `
+		INSERT INTO multipart_uploads (file_id, upload_id, part_size, part_count, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		upload.FileID,
+		upload.UploadID,
+		upload.PartSize,
+		upload.PartCount,
+		upload.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (r *fileRepository) GetMultipartUpload(ctx context.Context, fileID string) (*models.MultipartUpload, error) {
+	query := `
+		SELECT file_id, upload_id, part_size, part_count, created_at
+		FROM multipart_uploads
+		WHERE file_id = $1
+	`
+
+	var upload models.MultipartUpload
+	err := r.db.QueryRow(ctx, query, fileID).Scan(
+		&upload.FileID,
+		&upload.UploadID,
+		&upload.PartSize,
+		&upload.PartCount,
+		&upload.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("multipart upload not found")
+		}
+		return nil, fmt.Errorf("failed to get multipart upload: %w", err)
+	}
+	return &upload, nil
+}
+
+func (r *fileRepository) DeleteMultipartUpload(ctx context.Context, fileID string) error {
+	query := `DELETE FROM multipart_uploads WHERE file_id = $1`
+
+	if _, err := r.db.Exec(ctx, query, fileID); err != nil {
+		return fmt.Errorf("failed to delete multipart upload: %w", err)
+	}
+	return nil
+}
+
+func (r *fileRepository) SetUploadState(ctx context.Context, fileID, state string) error {
+	query := `
+		UPDATE files
+		SET upload_state = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	result, err := r.db.Exec(ctx, query, state, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to set upload state: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found")
+	}
+	return nil
+}
+
+func (r *fileRepository) ListStaleUploads(ctx context.Context, before, multipartBefore time.Time, limit int) ([]*models.StaleUpload, error) {
+	query := `
+		SELECT
+			f.id, f.user_id, f.filename, f.original_name, f.path, f.size, f.mime_type,
+			f.storage_path, f.bucket, f.is_public, f.tags, f.created_at, f.updated_at,
+			f.is_trashed, f.trashed_at, f.checksum_algorithm, f.checksum, f.upload_state,
+			f.blob_hash, f.key_owner_id, COALESCE(mu.upload_id, '')
+		FROM files f
+		LEFT JOIN multipart_uploads mu ON mu.file_id = f.id
+		WHERE f.upload_state <> 'active'
+			AND (
+				(mu.file_id IS NULL AND f.created_at < $1)
+				OR (mu.file_id IS NOT NULL AND mu.created_at < $2)
+			)
+		ORDER BY f.created_at
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, before, multipartBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var uploads []*models.StaleUpload
+	for rows.Next() {
+		var file models.File
+		var tags, uploadID string
+		err := rows.Scan(
+			&file.ID,
+			&file.UserID,
+			&file.Filename,
+			&file.OriginalName,
+			&file.Path,
+			&file.Size,
+			&file.MimeType,
+			&file.StoragePath,
+			&file.Bucket,
+			&file.IsPublic,
+			&tags,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+			&file.IsTrashed,
+			&file.TrashedAt,
+			&file.ChecksumAlgorithm,
+			&file.Checksum,
+			&file.UploadState,
+			&file.BlobHash,
+			&file.KeyOwnerID,
+			&uploadID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stale upload: %w", err)
+		}
+		file.Tags = parseTags(tags)
+		uploads = append(uploads, &models.StaleUpload{File: &file, UploadID: uploadID})
+	}
+	return uploads, rows.Err()
+}
+
+// ListExpiredTrash returns trashed files older than their owner's retention
+// period, or defaultRetentionDays for owners without one. A default of 0
+// keeps the trash of those owners.
+func (r *fileRepository) ListExpiredTrash(ctx context.Context, defaultRetentionDays, limit int) ([]*models.File, error) {
+	query := `
+		SELECT
+			f.id, f.user_id, f.filename, f.original_name, f.path, f.size, f.mime_type,
+			f.storage_path, f.bucket, f.is_public, f.tags, f.created_at, f.updated_at,
+			f.is_trashed, f.trashed_at, f.checksum_algorithm, f.checksum, f.upload_state,
+			f.blob_hash, f.key_owner_id
+		FROM files f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.is_trashed = TRUE
+			AND f.trashed_at < NOW() - make_interval(days => COALESCE(u.trash_retention_days, NULLIF($1::int, 0)))
+		ORDER BY f.trashed_at
+		LIMIT $2
+	`
+	return queryFiles(ctx, r.db, query, defaultRetentionDays, limit)
+}
+
+// SetTrashRetention sets how many days a user's trash is kept; 0 falls back
+// to the configured default.
+func (r *fileRepository) SetTrashRetention(ctx context.Context, userID string, days int) error {
+	query := `UPDATE users SET trash_retention_days = NULLIF($2::int, 0) WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, userID, days)
+	if err != nil {
+		return fmt.Errorf("failed to set trash retention: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (r *fileRepository) ListTrashed(ctx context.Context, userID string, limit int) ([]*models.File, error) {
+	query := `
+		SELECT
+			id, user_id, filename, original_name, path, size, mime_type,
+			storage_path, bucket, is_public, tags, created_at, updated_at,
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			blob_hash, key_owner_id
+		FROM files
+		WHERE user_id = $1 AND is_trashed = TRUE
+		ORDER BY trashed_at
+		LIMIT $2
+	`
+	return queryFiles(ctx, r.db, query, userID, limit)
+}
+
+func (r *fileRepository) SetPreviewState(ctx context.Context, fileID, state string) error {
+	query := `
+		UPDATE files
+		SET preview_state = $1, preview_updated_at = NOW()
+		WHERE id = $2
+	`
+	result, err := r.db.Exec(ctx, query, state, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to set preview state: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found")
+	}
+	return nil
+}
+
+// ClaimPendingPreviews marks up to limit files waiting for previews as
+// processing and returns them. Files stuck in processing since before
+// staleBefore, e.g. after a crash, are claimed again.
+func (r *fileRepository) ClaimPendingPreviews(ctx context.Context, staleBefore time.Time, limit int) ([]*models.File, error) {
+	query := `
+		UPDATE files
+		SET preview_state = 'processing', preview_updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM files
+			WHERE preview_state = 'pending'
+				OR (preview_state = 'processing' AND preview_updated_at < $1)
+			ORDER BY preview_updated_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			id, user_id, filename, original_name, path, size, mime_type,
+			storage_path, bucket, is_public, tags, created_at, updated_at,
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			blob_hash, key_owner_id
+	`
+	return queryFiles(ctx, r.db, query, staleBefore, limit)
+}
+
+// FinishPreview records the outcome of a claimed preview job. It reports
+// false when the file was rescheduled or removed in the meantime, in which
+// case the state is left alone.
+func (r *fileRepository) FinishPreview(ctx context.Context, fileID, state string) (bool, error) {
+	query := `
+		UPDATE files
+		SET preview_state = $1, preview_updated_at = NOW()
+		WHERE id = $2 AND preview_state = 'processing'
+	`
+	result, err := r.db.Exec(ctx, query, state, fileID)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish preview: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// SetContentState records how far text extraction of a file has got.
+func (r *fileRepository) SetContentState(ctx context.Context, fileID, state string) error {
+	query := `
+		UPDATE files
+		SET content_state = $1, content_updated_at = NOW()
+		WHERE id = $2
+	`
+	result, err := r.db.Exec(ctx, query, state, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to set content state: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("file not found")
+	}
+	return nil
+}
+
+// ClaimPendingContents marks up to limit files waiting for text extraction
+// as processing and returns them. Files stuck in processing since before
+// staleBefore are claimed again.
+func (r *fileRepository) ClaimPendingContents(ctx context.Context, staleBefore time.Time, limit int) ([]*models.File, error) {
+	query := `
+		UPDATE files
+		SET content_state = 'processing', content_updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM files
+			WHERE content_state = 'pending'
+				OR (content_state = 'processing' AND content_updated_at < $1)
+			ORDER BY content_updated_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			id, user_id, filename, original_name, path, size, mime_type,
+			storage_path, bucket, is_public, tags, created_at, updated_at,
+			is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+			blob_hash, key_owner_id
+	`
+	return queryFiles(ctx, r.db, query, staleBefore, limit)
+}
+
+// FinishContent stores the text extracted from a file, or drops the stored
+// text unless state is ready. It reports false without changing anything
+// when the file is no longer being processed, which happens when it got new
+// content in the meantime.
+func (r *fileRepository) FinishContent(ctx context.Context, fileID, state, content string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE files
+		SET content_state = $1, content_updated_at = NOW()
+		WHERE id = $2 AND content_state = 'processing'
+	`, state, fileID)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish content: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if state == models.ContentStateReady {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO file_contents (file_id, content, extracted_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (file_id) DO UPDATE
+			SET content = EXCLUDED.content, extracted_at = EXCLUDED.extracted_at
+		`, fileID, content)
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM file_contents WHERE file_id = $1", fileID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store content: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// TouchAccessed records a download of a file. The time is only written
+// when the stored one is more than an hour old, to keep downloads from
+// rewriting the row every time.
+func (r *fileRepository) TouchAccessed(ctx context.Context, fileID string) error {
+	query := `
+		UPDATE files
+		SET last_accessed_at = NOW()
+		WHERE id = $1
+			AND (last_accessed_at IS NULL OR last_accessed_at < NOW() - INTERVAL '1 hour')
+	`
+	if _, err := r.db.Exec(ctx, query, fileID); err != nil {
+		return fmt.Errorf("failed to record access: %w", err)
+	}
+	return nil
+}
+
+// RecordActivity notes that userID just did action with a file, replacing
+// what was noted before.
+func (r *fileRepository) RecordActivity(ctx context.Context, userID, fileID, action string) error {
+	query := `
+		INSERT INTO file_activity (user_id, file_id, last_action, last_active_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, file_id) DO UPDATE
+		SET last_action = EXCLUDED.last_action, last_active_at = EXCLUDED.last_active_at
+	`
+	if _, err := r.db.Exec(ctx, query, userID, fileID, action); err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// SetStarred stars or unstars a file for userID. Starring a starred file
+// keeps the time it was first starred.
+func (r *fileRepository) SetStarred(ctx context.Context, userID, fileID string, starred bool) error {
+	query := `UPDATE file_activity SET starred_at = NULL WHERE user_id = $1 AND file_id = $2`
+	if starred {
+		query = `
+			INSERT INTO file_activity (user_id, file_id, starred_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (user_id, file_id) DO UPDATE
+			SET starred_at = COALESCE(file_activity.starred_at, EXCLUDED.starred_at)
+		`
+	}
+	if _, err := r.db.Exec(ctx, query, userID, fileID); err != nil {
+		return fmt.Errorf("failed to update star: %w", err)
+	}
+	return nil
+}
+
+// activityVisible limits the files of a user's activity a to those the
+// user can still open: active, out of the trash, and owned, public or
+// shared with them.
+const activityVisible = `f.upload_state = 'active' AND f.is_trashed = FALSE AND (
+		f.user_id = a.user_id OR f.is_public
+		OR EXISTS (SELECT 1 FROM file_shares s WHERE s.file_id = f.id AND s.user_id = a.user_id)
+		OR EXISTS (SELECT 1 FROM folder_shares fs WHERE ` + folderShareCovers + ` AND fs.user_id = a.user_id)
+	)`
+
+// ListRecent returns the files userID uploaded, downloaded or edited last,
+// most recent first.
+func (r *fileRepository) ListRecent(ctx context.Context, userID string, limit int) ([]*models.FileActivity, error) {
+	query := `
+		SELECT ` + activityColumns + `
+		FROM file_activity a
+		JOIN files f ON f.id = a.file_id
+		WHERE a.user_id = $1 AND a.last_active_at IS NOT NULL AND ` + activityVisible + `
+		ORDER BY a.last_active_at DESC, f.id
+		LIMIT $2
+	`
+	return queryActivities(ctx, r.db, query, userID, limit)
+}
+
+// ListStarred returns a page of the files userID starred, last starred
+// first, and how many there are.
+func (r *fileRepository) ListStarred(ctx context.Context, userID string, page, pageSize int) ([]*models.FileActivity, int, error) {
+	const where = `
+		FROM file_activity a
+		JOIN files f ON f.id = a.file_id
+		WHERE a.user_id = $1 AND a.starred_at IS NOT NULL AND ` + activityVisible
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+where, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count starred files: %w", err)
+	}
+
+	query := `SELECT ` + activityColumns + where + `
+		ORDER BY a.starred_at DESC, f.id
+		LIMIT $2 OFFSET $3
+	`
+	items, err := queryActivities(ctx, r.db, query, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+const activityColumns = `
+			f.id, f.user_id, f.filename, f.original_name, f.path, f.size, f.mime_type,
+			f.storage_path, f.bucket, f.is_public, f.tags, f.created_at, f.updated_at,
+			f.is_trashed, f.trashed_at, f.checksum_algorithm, f.checksum, f.upload_state,
+			COALESCE(a.last_action, ''), a.last_active_at, a.starred_at
+		`
+
+func queryActivities(ctx context.Context, db *pgxpool.Pool, query string, args ...interface{}) ([]*models.FileActivity, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file activity: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.FileActivity
+	for rows.Next() {
+		var file models.File
+		var item models.FileActivity
+		var tags string
+		err := rows.Scan(
+			&file.ID,
+			&file.UserID,
+			&file.Filename,
+			&file.OriginalName,
+			&file.Path,
+			&file.Size,
+			&file.MimeType,
+			&file.StoragePath,
+			&file.Bucket,
+			&file.IsPublic,
+			&tags,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+			&file.IsTrashed,
+			&file.TrashedAt,
+			&file.ChecksumAlgorithm,
+			&file.Checksum,
+			&file.UploadState,
+			&item.LastAction,
+			&item.LastActiveAt,
+			&item.StarredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file activity: %w", err)
+		}
+		file.Tags = parseTags(tags)
+		item.File = &file
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list file activity: %w", err)
+	}
+	return items, nil
+}
+
+// ListMigrationCandidates returns the objects in bucket created before
+// createdBefore that nobody has downloaded since idleSince, both current
+// content and content only kept as a version. Each object is returned once,
+// as a file carrying the object's storage fields; for a version these are
+// the version's own.
+func (r *fileRepository) ListMigrationCandidates(ctx context.Context, bucket string, createdBefore, idleSince time.Time, limit int) ([]*models.File, error) {
+	query := `
+		SELECT DISTINCT ON (o.storage_path)
+			o.id, o.user_id, o.filename, o.original_name, o.path, o.size, o.mime_type,
+			o.storage_path, o.bucket, o.is_public, o.tags, o.created_at, o.updated_at,
+			o.is_trashed, o.trashed_at, o.checksum_algorithm, o.checksum, o.upload_state,
+			o.blob_hash, o.key_owner_id
+		FROM (
+			SELECT f.id, f.user_id, f.filename, f.original_name, f.path, f.size, f.mime_type,
+				f.storage_path, f.bucket, f.is_public, f.tags, f.created_at, f.updated_at,
+				f.is_trashed, f.trashed_at, f.checksum_algorithm, f.checksum, f.upload_state,
+				f.blob_hash, f.key_owner_id
+			FROM files f
+			WHERE f.upload_state = 'active'
+			UNION ALL
+			SELECT f.id, f.user_id, f.filename, f.original_name, f.path, v.size, v.mime_type,
+				v.storage_path, v.bucket, f.is_public, f.tags, v.created_at, f.updated_at,
+				f.is_trashed, f.trashed_at, v.checksum_algorithm, v.checksum, v.upload_state,
+				v.blob_hash, v.key_owner_id
+			FROM file_versions v
+			JOIN files f ON f.id = v.file_id
+			WHERE v.upload_state = 'active'
+		) o
+		WHERE o.bucket = $1
+			AND o.created_at < $2
+			AND NOT EXISTS (
+				SELECT 1 FROM files a
+				WHERE a.bucket = o.bucket AND a.storage_path = o.storage_path
+					AND COALESCE(a.last_accessed_at, a.created_at) >= $3
+			)
+		ORDER BY o.storage_path
+		LIMIT $4
+	`
+	return queryFiles(ctx, r.db, query, bucket, createdBefore, idleSince, limit)
+}
+
+// RelocateObject points everything stored at storagePath in fromBucket,
+// the blob as well as the files and versions sharing it, at toBucket in
+// one transaction. The referring rows are locked and checked first, so a
+// file purged since it was listed is not moved. It reports false when
+// nothing referred to the object any more.
+func (r *fileRepository) RelocateObject(ctx context.Context, storagePath, fromBucket, toBucket string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var referrers int
+	err = tx.QueryRow(ctx, `
+		SELECT
+			(SELECT count(*) FROM (
+				SELECT 1 FROM files
+				WHERE bucket = $1 AND storage_path = $2 AND upload_state = 'active'
+				FOR UPDATE
+			) f)
+			+ (SELECT count(*) FROM (
+				SELECT 1 FROM file_versions
+				WHERE bucket = $1 AND storage_path = $2 AND upload_state = 'active'
+				FOR UPDATE
+			) v)
+	`, fromBucket, storagePath).Scan(&referrers)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock object: %w", err)
+	}
+	if referrers == 0 {
+		return false, nil
+	}
+
+	var moved int64
+	for _, update := range []string{
+		`UPDATE blobs SET bucket = $1 WHERE bucket = $2 AND storage_path = $3`,
+		`UPDATE files SET bucket = $1 WHERE bucket = $2 AND storage_path = $3`,
+		`UPDATE file_versions SET bucket = $1 WHERE bucket = $2 AND storage_path = $3`,
+	} {
+		result, err := tx.Exec(ctx, update, toBucket, fromBucket, storagePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to relocate object: %w", err)
+		}
+		moved += result.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit relocation: %w", err)
+	}
+	return moved > 0, nil
+}
+
+// ObjectInUse reports whether a file, version or blob refers to the object
+// stored at storagePath in bucket.
+func (r *fileRepository) ObjectInUse(ctx context.Context, bucket, storagePath string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM files WHERE bucket = $1 AND storage_path = $2)
+			OR EXISTS (SELECT 1 FROM file_versions WHERE bucket = $1 AND storage_path = $2)
+			OR EXISTS (SELECT 1 FROM blobs WHERE bucket = $1 AND storage_path = $2)
+	`
+	var inUse bool
+	if err := r.db.QueryRow(ctx, query, bucket, storagePath).Scan(&inUse); err != nil {
+		return false, fmt.Errorf("failed to check object: %w", err)
+	}
+	return inUse, nil
+}
+
+func queryFiles(ctx context.Context, db *pgxpool.Pool, query string, args ...interface{}) ([]*models.File, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		var file models.File
+		var tags string
+		err := rows.Scan(
+			&file.ID,
+			&file.UserID,
+			&file.Filename,
+			&file.OriginalName,
+			&file.Path,
+			&file.Size,
+			&file.MimeType,
+			&file.StoragePath,
+			&file.Bucket,
+			&file.IsPublic,
+			&tags,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+			&file.IsTrashed,
+			&file.TrashedAt,
+			&file.ChecksumAlgorithm,
+			&file.Checksum,
+			&file.UploadState,
+			&file.BlobHash,
+			&file.KeyOwnerID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		file.Tags = parseTags(tags)
+		files = append(files, &file)
+	}
+	return files, rows.Err()
+}
@@ -0,0 +1,313 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ensureFoldersQuery creates every missing folder along a path, so a file can
+// never live in a folder that does not exist.
+const ensureFoldersQuery = `
+	INSERT INTO folders (id, user_id, name, path, parent_path, created_at, updated_at)
+	SELECT
+		gen_random_uuid(), $1, s.seg[n],
+		'/' || array_to_string(s.seg[1:n], '/'),
+		'/' || array_to_string(s.seg[1:n-1], '/'),
+		NOW(), NOW()
+	FROM (SELECT string_to_array(trim(leading '/' from $2::text), '/') AS seg) s
+	CROSS JOIN LATERAL generate_series(1, COALESCE(array_length(s.seg, 1), 0)) AS n
+	ON CONFLICT (user_id, path) DO NOTHING
+`
+
+// subtreeCondition matches a path and everything below it. Plain prefix
+// comparison is used instead of LIKE because '_' is a valid path character.
+const subtreeCondition = `(path = $2 OR left(path, length($2) + 1) = $2 || '/')`
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func ensureFolders(ctx context.Context, db execer, userID, folderPath string) error {
+	if _, err := db.Exec(ctx, ensureFoldersQuery, userID, folderPath); err != nil {
+		return fmt.Errorf("failed to create parent folders: %w", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+type folderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFolderRepository(db *pgxpool.Pool) *folderRepository {
+	return &folderRepository{db: db}
+}
+
+func (r *folderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ensureFolders(ctx, tx, folder.UserID, folder.ParentPath); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO folders (id, user_id, name, path, parent_path, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, folder.ID, folder.UserID, folder.Name, folder.Path, folder.ParentPath, folder.CreatedAt, folder.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("folder already exists")
+		}
+		return fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit folder: %w", err)
+	}
+	return nil
+}
+
+func (r *folderRepository) GetByPath(ctx context.Context, userID, folderPath string) (*models.Folder, error) {
+	query := `
+		SELECT id, user_id, name, path, parent_path, created_at, updated_at
+		FROM folders
+		WHERE user_id = $1 AND path = $2
+	`
+
+	var folder models.Folder
+	err := r.db.QueryRow(ctx, query, userID, folderPath).Scan(
+		&folder.ID,
+		&folder.UserID,
+		&folder.Name,
+		&folder.Path,
+		&folder.ParentPath,
+		&folder.CreatedAt,
+		&folder.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("folder not found")
+		}
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+	return &folder, nil
+}
+
+// ListChildren returns one page of a folder's direct children, subfolders
+// first, followed by files. The total covers both.
+func (r *folderRepository) ListChildren(ctx context.Context, userID, folderPath string, page, pageSize int) ([]*models.Folder, []*models.File, int, error) {
+	offset := (page - 1) * pageSize
+
+	var folderCount, fileCount int
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM folders WHERE user_id = $1 AND parent_path = $2),
+			(SELECT COUNT(*) FROM files WHERE user_id = $1 AND path = $2 AND is_trashed = FALSE AND upload_state = 'active')
+	`, userID, folderPath).Scan(&folderCount, &fileCount)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to count folder children: %w", err)
+	}
+
+	var folders []*models.Folder
+	if offset < folderCount {
+		rows, err := r.db.Query(ctx, `
+			SELECT id, user_id, name, path, parent_path, created_at, updated_at
+			FROM folders
+			WHERE user_id = $1 AND parent_path = $2
+			ORDER BY name
+			LIMIT $3 OFFSET $4
+		`, userID, folderPath, pageSize, offset)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to list folders: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var folder models.Folder
+			err := rows.Scan(
+				&folder.ID,
+				&folder.UserID,
+				&folder.Name,
+				&folder.Path,
+				&folder.ParentPath,
+				&folder.CreatedAt,
+				&folder.UpdatedAt,
+			)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("failed to scan folder: %w", err)
+			}
+			folders = append(folders, &folder)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to list folders: %w", err)
+		}
+	}
+
+	var files []*models.File
+	if remaining := pageSize - len(folders); remaining > 0 {
+		fileOffset := offset - folderCount
+		if fileOffset < 0 {
+			fileOffset = 0
+		}
+		files, err = queryFiles(ctx, r.db, `
+			SELECT
+				id, user_id, filename, original_name, path, size, mime_type,
+				storage_path, bucket, is_public, tags, created_at, updated_at,
+				is_trashed, trashed_at, checksum_algorithm, checksum, upload_state,
+				blob_hash, key_owner_id
+			FROM files
+			WHERE user_id = $1 AND path = $2 AND is_trashed = FALSE AND upload_state = 'active'
+			ORDER BY original_name
+			LIMIT $3 OFFSET $4
+		`, userID, folderPath, remaining, fileOffset)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	return folders, files, folderCount + fileCount, nil
+}
+
+// Move renames a folder to newPath and rewrites the paths of all its
+// subfolders, files and folder shares in a single transaction.
+func (r *folderRepository) Move(ctx context.Context, userID, oldPath, newPath string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `SELECT id FROM folders WHERE user_id = $1 AND path = $2 FOR UPDATE`, userID, oldPath).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("folder not found")
+		}
+		return fmt.Errorf("failed to lock folder: %w", err)
+	}
+
+	newParent := path.Dir(newPath)
+	if err := ensureFolders(ctx, tx, userID, newParent); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE folders
+		SET
+			path = $3 || substr(path, length($2) + 1),
+			parent_path = CASE WHEN path = $2 THEN $4 ELSE $3 || substr(parent_path, length($2) + 1) END,
+			name = CASE WHEN path = $2 THEN $5 ELSE name END,
+			updated_at = NOW()
+		WHERE user_id = $1 AND `+subtreeCondition,
+		userID, oldPath, newPath, newParent, path.Base(newPath))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("folder already exists")
+		}
+		return fmt.Errorf("failed to move folders: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE files
+		SET path = $3 || substr(path, length($2) + 1), updated_at = NOW()
+		WHERE user_id = $1 AND `+subtreeCondition,
+		userID, oldPath, newPath)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("a file with the same name already exists at the destination")
+		}
+		return fmt.Errorf("failed to move files: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE folder_shares
+		SET path = $3 || substr(path, length($2) + 1), updated_at = NOW()
+		WHERE owner_id = $1 AND `+subtreeCondition,
+		userID, oldPath, newPath)
+	if err != nil {
+		return fmt.Errorf("failed to move folder shares: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit move: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a folder. Unless recursive is set the folder must be empty;
+// a recursive delete moves the files inside it to the trash and returns how
+// many were trashed.
+func (r *folderRepository) Delete(ctx context.Context, userID, folderPath string, recursive bool) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `SELECT id FROM folders WHERE user_id = $1 AND path = $2 FOR UPDATE`, userID, folderPath).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, fmt.Errorf("folder not found")
+		}
+		return 0, fmt.Errorf("failed to lock folder: %w", err)
+	}
+
+	trashed := 0
+	if recursive {
+		result, err := tx.Exec(ctx, `
+			UPDATE files
+			SET is_trashed = TRUE, trashed_at = NOW(), updated_at = NOW()
+			WHERE user_id = $1 AND is_trashed = FALSE AND `+subtreeCondition,
+			userID, folderPath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to trash folder contents: %w", err)
+		}
+		trashed = int(result.RowsAffected())
+	} else {
+		var notEmpty bool
+		err := tx.QueryRow(ctx, `
+			SELECT
+				EXISTS (SELECT 1 FROM folders WHERE user_id = $1 AND parent_path = $2)
+				OR EXISTS (SELECT 1 FROM files WHERE user_id = $1 AND path = $2 AND is_trashed = FALSE)
+		`, userID, folderPath).Scan(&notEmpty)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check folder contents: %w", err)
+		}
+		if notEmpty {
+			return 0, fmt.Errorf("folder is not empty")
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM folders WHERE user_id = $1 AND `+subtreeCondition, userID, folderPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete folder: %w", err)
+	}
+
+	// Shares are keyed by path, so they would otherwise carry over to a
+	// folder created later under the same name.
+	_, err = tx.Exec(ctx, `DELETE FROM folder_shares WHERE owner_id = $1 AND `+subtreeCondition, userID, folderPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete folder shares: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit delete: %w", err)
+	}
+	return trashed, nil
+}
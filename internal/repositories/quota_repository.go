@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type quotaRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewQuotaRepository(db *pgxpool.Pool) *quotaRepository {
+	return &quotaRepository{db: db}
+}
+
+const ensureUserQuotaQuery = `
+	INSERT INTO user_quotas (user_id)
+	VALUES ($1)
+	ON CONFLICT (user_id) DO NOTHING
+`
+
+const selectUsageQuery = `
+	SELECT
+		q.user_id, q.plan,
+		q.used_bytes, q.reserved_bytes, COALESCE(q.max_bytes, p.max_bytes),
+		q.used_files, q.reserved_files, COALESCE(q.max_files, p.max_files)
+	FROM user_quotas q
+	JOIN quota_plans p ON p.name = q.plan
+	WHERE q.user_id = $1
+`
+
+func scanUsage(row pgx.Row) (*models.StorageUsage, error) {
+	var usage models.StorageUsage
+	err := row.Scan(
+		&usage.UserID,
+		&usage.Plan,
+		&usage.UsedBytes,
+		&usage.ReservedBytes,
+		&usage.LimitBytes,
+		&usage.UsedFiles,
+		&usage.ReservedFiles,
+		&usage.LimitFiles,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (r *quotaRepository) GetUsage(ctx context.Context, userID string) (*models.StorageUsage, error) {
+	if _, err := r.db.Exec(ctx, ensureUserQuotaQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to init quota: %w", err)
+	}
+
+	usage, err := scanUsage(r.db.QueryRow(ctx, selectUsageQuery, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return usage, nil
+}
+
+// Reserve books space for a file that is about to be uploaded. The check and
+// the booking happen under a row lock, so concurrent uploads cannot overshoot
+// the limit together.
+func (r *quotaRepository) Reserve(ctx context.Context, userID, fileID string, bytes int64) error {
+	return r.reserve(ctx, userID, fileID, bytes, 1)
+}
+
+// ReserveBytes books space for content that does not add a file, such as a
+// new version of an existing one.
+func (r *quotaRepository) ReserveBytes(ctx context.Context, userID, id string, bytes int64) error {
+	return r.reserve(ctx, userID, id, bytes, 0)
+}
+
+func (r *quotaRepository) reserve(ctx context.Context, userID, id string, bytes int64, files int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, ensureUserQuotaQuery, userID); err != nil {
+		return fmt.Errorf("failed to init quota: %w", err)
+	}
+
+	usage, err := scanUsage(tx.QueryRow(ctx, selectUsageQuery+" FOR UPDATE OF q", userID))
+	if err != nil {
+		return fmt.Errorf("failed to get usage: %w", err)
+	}
+	if usage.UsedBytes+usage.ReservedBytes+bytes > usage.LimitBytes ||
+		usage.UsedFiles+usage.ReservedFiles+int64(files) > usage.LimitFiles {
+		return models.ErrQuotaExceeded
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE user_quotas
+		SET reserved_bytes = reserved_bytes + $1,
+			reserved_files = reserved_files + $2,
+			updated_at = NOW()
+		WHERE user_id = $3
+	`, bytes, files, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reserve quota: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO quota_reservations (file_id, user_id, bytes, files, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, id, userID, bytes, files)
+	if err != nil {
+		return fmt.Errorf("failed to save reservation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	return nil
+}
+
+// Commit turns the reservation of a completed upload into used space. The
+// reservation is dropped, so committing twice is a no-op. When more bytes
+// were stored than reserved, as for an upload of unknown size, the excess is
+// checked against the limit under a row lock and ErrQuotaExceeded is
+// returned if it does not fit; the reservation is then kept.
+func (r *quotaRepository) Commit(ctx context.Context, fileID string, bytes int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	var reserved int64
+	var files int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM quota_reservations
+		WHERE file_id = $1
+		RETURNING user_id, bytes, files
+	`, fileID).Scan(&userID, &reserved, &files)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("failed to delete reservation: %w", err)
+	}
+
+	if bytes > reserved {
+		usage, err := scanUsage(tx.QueryRow(ctx, selectUsageQuery+" FOR UPDATE OF q", userID))
+		if err != nil {
+			return fmt.Errorf("failed to get usage: %w", err)
+		}
+		if usage.UsedBytes+usage.ReservedBytes-reserved+bytes > usage.LimitBytes {
+			return models.ErrQuotaExceeded
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE user_quotas
+		SET reserved_bytes = GREATEST(reserved_bytes - $1, 0),
+			reserved_files = GREATEST(reserved_files - $2, 0),
+			used_bytes = used_bytes + $3,
+			used_files = used_files + $2,
+			updated_at = NOW()
+		WHERE user_id = $4
+	`, reserved, files, bytes, userID)
+	if err != nil {
+		return fmt.Errorf("failed to commit quota: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit quota: %w", err)
+	}
+	return nil
+}
+
+// Release frees whatever a file holds: its reservation if the upload never
+// completed, otherwise the committed usage.
+func (r *quotaRepository) Release(ctx context.Context, userID, fileID string, bytes int64) error {
+	return r.release(ctx, userID, fileID, bytes, 1)
+}
+
+// ReleaseBytes is the counterpart of ReserveBytes.
+func (r *quotaRepository) ReleaseBytes(ctx context.Context, userID, id string, bytes int64) error {
+	return r.release(ctx, userID, id, bytes, 0)
+}
+
+func (r *quotaRepository) release(ctx context.Context, userID, id string, bytes int64, files int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var reserved int64
+	var reservedFiles int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM quota_reservations
+		WHERE file_id = $1
+		RETURNING bytes, files
+	`, id).Scan(&reserved, &reservedFiles)
+
+	switch {
+	case err == nil:
+		_, err = tx.Exec(ctx, `
+			UPDATE user_quotas
+			SET reserved_bytes = GREATEST(reserved_bytes - $1, 0),
+				reserved_files = GREATEST(reserved_files - $2, 0),
+				updated_at = NOW()
+			WHERE user_id = $3
+		`, reserved, reservedFiles, userID)
+	case err == pgx.ErrNoRows:
+		_, err = tx.Exec(ctx, `
+			UPDATE user_quotas
+			SET used_bytes = GREATEST(used_bytes - $1, 0),
+				used_files = GREATEST(used_files - $2, 0),
+				updated_at = NOW()
+			WHERE user_id = $3
+		`, bytes, files, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release quota: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to release quota: %w", err)
+	}
+	return nil
+}
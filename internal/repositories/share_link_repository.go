@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const shareLinkColumns = `
+	id, file_id, user_id, token_hash, password_hash, expires_at, max_downloads,
+	download_count, last_downloaded_at, created_at, revoked_at
+`
+
+type shareLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShareLinkRepository(db *pgxpool.Pool) *shareLinkRepository {
+	return &shareLinkRepository{db: db}
+}
+
+func (r *shareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	query := `
+		INSERT INTO share_links (
+			id, file_id, user_id, token_hash, password_hash, expires_at, max_downloads, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		link.ID,
+		link.FileID,
+		link.UserID,
+		link.TokenHash,
+		link.PasswordHash,
+		link.ExpiresAt,
+		link.MaxDownloads,
+		link.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+	return nil
+}
+
+func (r *shareLinkRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE token_hash = $1`
+	return scanShareLink(r.db.QueryRow(ctx, query, tokenHash))
+}
+
+func (r *shareLinkRepository) GetByID(ctx context.Context, id string) (*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE id = $1`
+	return scanShareLink(r.db.QueryRow(ctx, query, id))
+}
+
+func (r *shareLinkRepository) ListByFileID(ctx context.Context, fileID string) ([]*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE file_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*models.ShareLink
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	return links, nil
+}
+
+func (r *shareLinkRepository) Revoke(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, `UPDATE share_links SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("share link not found")
+	}
+	return nil
+}
+
+// RecordDownload counts a download against the link. It reports false,
+// without counting, when the link was revoked, expired or used up in the
+// meantime, so concurrent requests cannot exceed max_downloads.
+func (r *shareLinkRepository) RecordDownload(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE share_links
+		SET download_count = download_count + 1, last_downloaded_at = NOW()
+		WHERE id = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND (max_downloads = 0 OR download_count < max_downloads)
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to record download: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func scanShareLink(row pgx.Row) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := row.Scan(
+		&link.ID,
+		&link.FileID,
+		&link.UserID,
+		&link.TokenHash,
+		&link.PasswordHash,
+		&link.ExpiresAt,
+		&link.MaxDownloads,
+		&link.DownloadCount,
+		&link.LastDownloadedAt,
+		&link.CreatedAt,
+		&link.RevokedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("share link not found")
+		}
+		return nil, fmt.Errorf("failed to scan share link: %w", err)
+	}
+	return &link, nil
+}
@@ -0,0 +1,336 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// folderShareCovers matches the folder shares fs granting access to file f:
+// those on f's folder or on any folder above it.
+const folderShareCovers = `fs.owner_id = f.user_id AND (f.path = fs.path OR left(f.path, length(fs.path) + 1) = fs.path || '/')`
+
+type shareRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShareRepository(db *pgxpool.Pool) *shareRepository {
+	return &shareRepository{db: db}
+}
+
+// Upsert creates a share or changes the role of an existing one. The stored
+// id and creation time are written back to share.
+func (r *shareRepository) Upsert(ctx context.Context, share *models.FileShare) error {
+	query := `
+		INSERT INTO file_shares (id, file_id, owner_id, user_id, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (file_id, user_id) DO UPDATE
+		SET role = EXCLUDED.role, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		share.ID,
+		share.FileID,
+		share.OwnerID,
+		share.UserID,
+		share.Role,
+		share.CreatedAt,
+		share.UpdatedAt,
+	).Scan(&share.ID, &share.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save share: %w", err)
+	}
+	return nil
+}
+
+func (r *shareRepository) Delete(ctx context.Context, fileID, userID string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM file_shares WHERE file_id = $1 AND user_id = $2`, fileID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("share not found")
+	}
+	return nil
+}
+
+func (r *shareRepository) ListByFileID(ctx context.Context, fileID string) ([]*models.FileShare, error) {
+	query := `
+		SELECT s.id, s.file_id, s.owner_id, s.user_id, u.email, s.role, s.created_at, s.updated_at
+		FROM file_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.file_id = $1
+		ORDER BY s.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []*models.FileShare
+	for rows.Next() {
+		var share models.FileShare
+		err := rows.Scan(
+			&share.ID,
+			&share.FileID,
+			&share.OwnerID,
+			&share.UserID,
+			&share.Email,
+			&share.Role,
+			&share.CreatedAt,
+			&share.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share: %w", err)
+		}
+		shares = append(shares, &share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	return shares, nil
+}
+
+// GetRole returns the strongest role userID has been granted on a file,
+// either directly or through a share on one of its folders, or an empty
+// string if the file is not shared with them.
+func (r *shareRepository) GetRole(ctx context.Context, fileID, userID string) (string, error) {
+	query := `
+		SELECT role FROM (
+			SELECT s.role FROM file_shares s WHERE s.file_id = $1 AND s.user_id = $2
+			UNION ALL
+			SELECT fs.role
+			FROM files f
+			JOIN folder_shares fs ON ` + folderShareCovers + `
+			WHERE f.id = $1 AND fs.user_id = $2
+		) roles
+		ORDER BY role = 'editor' DESC
+		LIMIT 1
+	`
+
+	var role string
+	err := r.db.QueryRow(ctx, query, fileID, userID).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get share: %w", err)
+	}
+	return role, nil
+}
+
+// ListSharedWithUser returns completed, untrashed files other users have
+// shared with userID, most recently shared first.
+func (r *shareRepository) ListSharedWithUser(ctx context.Context, userID string, page, pageSize int) ([]*models.SharedFile, int, error) {
+	const where = `
+		FROM file_shares s
+		JOIN files f ON f.id = s.file_id
+		JOIN users u ON u.id = s.owner_id
+		WHERE s.user_id = $1 AND f.upload_state = 'active' AND f.is_trashed = FALSE
+	`
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+where, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count shared files: %w", err)
+	}
+
+	query := `
+		SELECT
+			f.id, f.user_id, f.filename, f.original_name, f.path, f.size, f.mime_type,
+			f.storage_path, f.bucket, f.is_public, f.tags, f.created_at, f.updated_at,
+			f.is_trashed, f.trashed_at, f.checksum_algorithm, f.checksum, f.upload_state,
+			s.role, u.email, s.created_at
+	` + where + `
+		ORDER BY s.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list shared files: %w", err)
+	}
+	defer rows.Close()
+
+	var shared []*models.SharedFile
+	for rows.Next() {
+		var file models.File
+		var item models.SharedFile
+		var tags string
+		err := rows.Scan(
+			&file.ID,
+			&file.UserID,
+			&file.Filename,
+			&file.OriginalName,
+			&file.Path,
+			&file.Size,
+			&file.MimeType,
+			&file.StoragePath,
+			&file.Bucket,
+			&file.IsPublic,
+			&tags,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+			&file.IsTrashed,
+			&file.TrashedAt,
+			&file.ChecksumAlgorithm,
+			&file.Checksum,
+			&file.UploadState,
+			&item.Role,
+			&item.OwnerEmail,
+			&item.SharedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan shared file: %w", err)
+		}
+		file.Tags = parseTags(tags)
+		item.File = &file
+		shared = append(shared, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list shared files: %w", err)
+	}
+	return shared, total, nil
+}
+
+// UpsertFolderShare creates a folder share or changes the role of an existing
+// one. The stored id and creation time are written back to share.
+func (r *shareRepository) UpsertFolderShare(ctx context.Context, share *models.FolderShare) error {
+	query := `
+		INSERT INTO folder_shares (id, owner_id, path, user_id, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (owner_id, path, user_id) DO UPDATE
+		SET role = EXCLUDED.role, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		share.ID,
+		share.OwnerID,
+		share.Path,
+		share.UserID,
+		share.Role,
+		share.CreatedAt,
+		share.UpdatedAt,
+	).Scan(&share.ID, &share.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save folder share: %w", err)
+	}
+	return nil
+}
+
+func (r *shareRepository) DeleteFolderShare(ctx context.Context, ownerID, folderPath, userID string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM folder_shares WHERE owner_id = $1 AND path = $2 AND user_id = $3`, ownerID, folderPath, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete folder share: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("share not found")
+	}
+	return nil
+}
+
+func (r *shareRepository) ListFolderShares(ctx context.Context, ownerID, folderPath string) ([]*models.FolderShare, error) {
+	query := `
+		SELECT s.id, s.owner_id, s.path, s.user_id, u.email, s.role, s.created_at, s.updated_at
+		FROM folder_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.owner_id = $1 AND s.path = $2
+		ORDER BY s.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, ownerID, folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder shares: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []*models.FolderShare
+	for rows.Next() {
+		var share models.FolderShare
+		err := rows.Scan(
+			&share.ID,
+			&share.OwnerID,
+			&share.Path,
+			&share.UserID,
+			&share.Email,
+			&share.Role,
+			&share.CreatedAt,
+			&share.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan folder share: %w", err)
+		}
+		shares = append(shares, &share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list folder shares: %w", err)
+	}
+	return shares, nil
+}
+
+// GetFolderRole returns the strongest role userID holds on folderPath of
+// ownerID through a share on that folder or one above it, or an empty string.
+func (r *shareRepository) GetFolderRole(ctx context.Context, ownerID, folderPath, userID string) (string, error) {
+	query := `
+		SELECT role
+		FROM folder_shares
+		WHERE owner_id = $1 AND user_id = $3
+			AND ($2 = path OR left($2, length(path) + 1) = path || '/')
+		ORDER BY role = 'editor' DESC
+		LIMIT 1
+	`
+
+	var role string
+	err := r.db.QueryRow(ctx, query, ownerID, folderPath, userID).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get folder share: %w", err)
+	}
+	return role, nil
+}
+
+// ListFoldersSharedWithUser returns the folders other users have shared with
+// userID, most recently shared first.
+func (r *shareRepository) ListFoldersSharedWithUser(ctx context.Context, userID string) ([]*models.SharedFolder, error) {
+	query := `
+		SELECT s.owner_id, u.email, s.path, s.role, s.created_at
+		FROM folder_shares s
+		JOIN users u ON u.id = s.owner_id
+		WHERE s.user_id = $1
+		ORDER BY s.created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared folders: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []*models.SharedFolder
+	for rows.Next() {
+		var folder models.SharedFolder
+		err := rows.Scan(
+			&folder.OwnerID,
+			&folder.OwnerEmail,
+			&folder.Path,
+			&folder.Role,
+			&folder.SharedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shared folder: %w", err)
+		}
+		folders = append(folders, &folder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list shared folders: %w", err)
+	}
+	return folders, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userKeyColumns = `user_id, wrapped_key, master_key_id, created_at, rotated_at`
+
+type userKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserKeyRepository(db *pgxpool.Pool) *userKeyRepository {
+	return &userKeyRepository{db: db}
+}
+
+// Get returns the key of a user, or nil if none has been created yet.
+func (r *userKeyRepository) Get(ctx context.Context, userID string) (*models.UserKey, error) {
+	query := `SELECT ` + userKeyColumns + ` FROM user_keys WHERE user_id = $1`
+
+	key, err := scanUserKey(r.db.QueryRow(ctx, query, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user key: %w", err)
+	}
+	return key, nil
+}
+
+// Create stores a new user key. If another request created one for the same
+// user first, that key is kept and returned instead.
+func (r *userKeyRepository) Create(ctx context.Context, key *models.UserKey) (*models.UserKey, error) {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO user_keys (`+userKeyColumns+`)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO NOTHING
+	`, key.UserID, key.WrappedKey, key.MasterKeyID, key.CreatedAt, key.RotatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user key: %w", err)
+	}
+
+	stored, err := r.Get(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("user key not found")
+	}
+	return stored, nil
+}
+
+// ListStale returns up to limit keys wrapped by a master key other than
+// masterKeyID.
+func (r *userKeyRepository) ListStale(ctx context.Context, masterKeyID string, limit int) ([]*models.UserKey, error) {
+	query := `
+		SELECT ` + userKeyColumns + `
+		FROM user_keys
+		WHERE master_key_id <> $1
+		ORDER BY user_id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, masterKeyID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.UserKey
+	for rows.Next() {
+		key, err := scanUserKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list user keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Rewrap replaces the wrapped form of a key. It reports false when the key
+// is no longer wrapped by the master key it was read with, e.g. because a
+// concurrent rotation got to it first.
+func (r *userKeyRepository) Rewrap(ctx context.Context, key *models.UserKey, wrappedKey []byte, masterKeyID string) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_keys
+		SET wrapped_key = $1, master_key_id = $2, rotated_at = NOW()
+		WHERE user_id = $3 AND master_key_id = $4
+	`, wrappedKey, masterKeyID, key.UserID, key.MasterKeyID)
+	if err != nil {
+		return false, fmt.Errorf("failed to rewrap user key: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func scanUserKey(row pgx.Row) (*models.UserKey, error) {
+	var key models.UserKey
+	err := row.Scan(
+		&key.UserID,
+		&key.WrappedKey,
+		&key.MasterKeyID,
+		&key.CreatedAt,
+		&key.RotatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,254 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const versionColumns = `
+	id, file_id, user_id, version, size, mime_type, storage_path, bucket,
+	checksum_algorithm, checksum, upload_state, created_at, superseded_at, blob_hash,
+	key_owner_id
+`
+
+type versionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewVersionRepository(db *pgxpool.Pool) *versionRepository {
+	return &versionRepository{db: db}
+}
+
+func (r *versionRepository) Create(ctx context.Context, version *models.FileVersion) error {
+	query := `
+		INSERT INTO file_versions (` + versionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		version.ID,
+		version.FileID,
+		version.UserID,
+		version.Version,
+		version.Size,
+		version.MimeType,
+		version.StoragePath,
+		version.Bucket,
+		version.ChecksumAlgorithm,
+		version.Checksum,
+		version.UploadState,
+		version.CreatedAt,
+		version.SupersededAt,
+		version.BlobHash,
+		version.KeyOwnerID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create version: %w", err)
+	}
+	return nil
+}
+
+func (r *versionRepository) GetByID(ctx context.Context, id string) (*models.FileVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM file_versions WHERE id = $1`
+
+	version, err := scanVersion(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("version not found")
+		}
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+	return version, nil
+}
+
+// ListByFileID returns every version row of a file, pending uploads
+// included, newest first.
+func (r *versionRepository) ListByFileID(ctx context.Context, fileID string) ([]*models.FileVersion, error) {
+	query := `
+		SELECT ` + versionColumns + `
+		FROM file_versions
+		WHERE file_id = $1
+		ORDER BY version DESC, created_at DESC
+	`
+	return r.queryVersions(ctx, query, fileID)
+}
+
+func (r *versionRepository) GetCurrentVersion(ctx context.Context, fileID string) (int, error) {
+	var current int
+	err := r.db.QueryRow(ctx, `SELECT current_version FROM files WHERE id = $1`, fileID).Scan(&current)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, fmt.Errorf("file not found")
+		}
+		return 0, fmt.Errorf("failed to get current version: %w", err)
+	}
+	return current, nil
+}
+
+// Promote makes the content described by version the current content of its
+// file. The content it replaces is written into the same version row, which
+// becomes the newest entry of the file's history. Existing previews are
+// queued for regeneration. The version row must still be in the upload state
+// the caller loaded it in, so a pending upload is promoted only once;
+// otherwise ErrVersionConflict is returned. The new current version number
+// is returned.
+func (r *versionRepository) Promote(ctx context.Context, version *models.FileVersion) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previous models.FileVersion
+	err = tx.QueryRow(ctx, `
+		SELECT current_version, size, mime_type, storage_path, bucket, checksum_algorithm, checksum, blob_hash, key_owner_id
+		FROM files
+		WHERE id = $1
+		FOR UPDATE
+	`, version.FileID).Scan(
+		&previous.Version,
+		&previous.Size,
+		&previous.MimeType,
+		&previous.StoragePath,
+		&previous.Bucket,
+		&previous.ChecksumAlgorithm,
+		&previous.Checksum,
+		&previous.BlobHash,
+		&previous.KeyOwnerID,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, fmt.Errorf("file not found")
+		}
+		return 0, fmt.Errorf("failed to lock file: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE files
+		SET size = $1, mime_type = $2, storage_path = $3, bucket = $4,
+			checksum_algorithm = $5, checksum = $6, blob_hash = $7, key_owner_id = $8,
+			current_version = current_version + 1, updated_at = NOW(),
+			preview_state = CASE WHEN preview_state = 'none' THEN 'none' ELSE 'pending' END,
+			preview_updated_at = NOW(),
+			content_state = CASE WHEN content_state = 'none' THEN 'none' ELSE 'pending' END,
+			content_updated_at = NOW()
+		WHERE id = $9
+	`, version.Size, version.MimeType, version.StoragePath, version.Bucket,
+		version.ChecksumAlgorithm, version.Checksum, version.BlobHash, version.KeyOwnerID, version.FileID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update file: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE file_versions
+		SET version = $1, size = $2, mime_type = $3, storage_path = $4, bucket = $5,
+			checksum_algorithm = $6, checksum = $7, blob_hash = $8, key_owner_id = $9,
+			upload_state = 'active', superseded_at = NOW()
+		WHERE id = $10 AND upload_state = $11
+	`, previous.Version, previous.Size, previous.MimeType, previous.StoragePath, previous.Bucket,
+		previous.ChecksumAlgorithm, previous.Checksum, previous.BlobHash, previous.KeyOwnerID, version.ID,
+		version.UploadState)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive previous version: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, models.ErrVersionConflict
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit version: %w", err)
+	}
+	return previous.Version + 1, nil
+}
+
+func (r *versionRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM file_versions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete version: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("version not found")
+	}
+	return nil
+}
+
+// ListExpired returns superseded versions that fall outside the retention
+// policy: beyond the newest maxVersions-1 of their file, or superseded longer
+// than maxAge ago. A zero limit disables that rule.
+func (r *versionRepository) ListExpired(ctx context.Context, maxVersions int, maxAge time.Duration, limit int) ([]*models.FileVersion, error) {
+	query := `
+		SELECT ` + versionColumns + `
+		FROM (
+			SELECT v.*, row_number() OVER (PARTITION BY file_id ORDER BY version DESC) AS rn
+			FROM file_versions v
+			WHERE upload_state = 'active'
+		) v
+		WHERE ($1::int > 0 AND rn >= $1::int)
+			OR ($2::float8 > 0 AND superseded_at < NOW() - make_interval(secs => $2::float8))
+		LIMIT $3
+	`
+	return r.queryVersions(ctx, query, maxVersions, maxAge.Seconds(), limit)
+}
+
+// ListStale returns pending version uploads started before the given time.
+func (r *versionRepository) ListStale(ctx context.Context, before time.Time, limit int) ([]*models.FileVersion, error) {
+	query := `
+		SELECT ` + versionColumns + `
+		FROM file_versions
+		WHERE upload_state = 'pending' AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	return r.queryVersions(ctx, query, before, limit)
+}
+
+func (r *versionRepository) queryVersions(ctx context.Context, query string, args ...interface{}) ([]*models.FileVersion, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*models.FileVersion
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	return versions, nil
+}
+
+func scanVersion(row pgx.Row) (*models.FileVersion, error) {
+	var version models.FileVersion
+	err := row.Scan(
+		&version.ID,
+		&version.FileID,
+		&version.UserID,
+		&version.Version,
+		&version.Size,
+		&version.MimeType,
+		&version.StoragePath,
+		&version.Bucket,
+		&version.ChecksumAlgorithm,
+		&version.Checksum,
+		&version.UploadState,
+		&version.CreatedAt,
+		&version.SupersededAt,
+		&version.BlobHash,
+		&version.KeyOwnerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
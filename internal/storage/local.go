@@ -0,0 +1,624 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Local keeps objects on the local filesystem, for development and tests
+// that should not need a MinIO. Object data lives under root/objects, its
+// metadata under root/meta and unfinished multipart uploads under
+// root/uploads. Presigned URLs point at publicURL and are served by
+// ServeHTTP; they are signed with an HMAC of signingKey. Encryption is not
+// supported.
+type Local struct {
+	root       string
+	publicURL  *url.URL
+	signingKey []byte
+}
+
+// objectMeta is what Local records next to the data of an object.
+type objectMeta struct {
+	ContentType    string `json:"content_type"`
+	ETag           string `json:"etag"`
+	ChecksumSHA256 string `json:"checksum_sha256"`
+	ChecksumCRC32C string `json:"checksum_crc32c"`
+}
+
+// uploadMeta describes an unfinished multipart upload.
+type uploadMeta struct {
+	Bucket      string `json:"bucket"`
+	Object      string `json:"object"`
+	ContentType string `json:"content_type"`
+}
+
+// checksums are the digests Local computes over everything it writes. The
+// ETag is the hex MD5 of the content, as with S3.
+type checksums struct {
+	md5    hash.Hash
+	sha256 hash.Hash
+	crc32c hash.Hash
+}
+
+func newChecksums() *checksums {
+	return &checksums{md5: md5.New(), sha256: sha256.New(), crc32c: crc32.New(crc32.MakeTable(crc32.Castagnoli))}
+}
+
+func (c *checksums) writer(w io.Writer) io.Writer {
+	return io.MultiWriter(w, c.md5, c.sha256, c.crc32c)
+}
+
+// errBadContent marks writes rejected for what the client sent.
+var errBadContent = errors.New("bad content")
+
+// expectedChecksums are the checksums a client asked a write to be
+// verified against, base64 encoded. Empty values are not checked.
+type expectedChecksums struct {
+	MD5    string
+	SHA256 string
+	CRC32C string
+}
+
+func NewLocal(root, publicURL string, signingKey []byte) (*Local, error) {
+	if len(signingKey) == 0 {
+		return nil, fmt.Errorf("signing key is required")
+	}
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid public url: %s", publicURL)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage root: %w", err)
+	}
+	for _, dir := range []string{"objects", "meta", "uploads", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+	return &Local{root: root, publicURL: u, signingKey: signingKey}, nil
+}
+
+func (l *Local) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	dir, err := l.bucketPath("objects", bucketName)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (l *Local) MakeBucket(ctx context.Context, bucketName string, opts MakeBucketOptions) error {
+	for _, tree := range []string{"objects", "meta"} {
+		dir, err := l.bucketPath(tree, bucketName)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l *Local) StatObject(ctx context.Context, bucketName, objectName string, opts StatObjectOptions) (ObjectInfo, error) {
+	if opts.Encryption != nil {
+		return ObjectInfo{}, ErrEncryptionUnsupported
+	}
+	dataPath, metaPath, err := l.objectPaths(bucketName, objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	meta, err := readMeta(metaPath)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	stat, err := os.Stat(dataPath)
+	if err != nil {
+		return ObjectInfo{}, notFound(err)
+	}
+
+	info := ObjectInfo{
+		Size:         stat.Size(),
+		ETag:         meta.ETag,
+		ContentType:  meta.ContentType,
+		LastModified: stat.ModTime(),
+	}
+	if opts.Checksum {
+		info.ChecksumSHA256, info.ChecksumCRC32C = meta.ChecksumSHA256, meta.ChecksumCRC32C
+	}
+	return info, nil
+}
+
+func (l *Local) GetObject(ctx context.Context, bucketName, objectName string, opts GetObjectOptions) (io.ReadCloser, error) {
+	if opts.Encryption != nil {
+		return nil, ErrEncryptionUnsupported
+	}
+	f, _, err := l.open(bucketName, objectName, opts.MatchETag)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Offset == 0 && opts.Length == 0 {
+		return f, nil
+	}
+	length := opts.Length
+	if length == 0 {
+		length = 1<<63 - 1 - opts.Offset
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, opts.Offset, length), f}, nil
+}
+
+// open opens the data of an object along with its metadata, failing with
+// ErrPreconditionFailed if matchETag is set and differs from its ETag.
+func (l *Local) open(bucketName, objectName, matchETag string) (*os.File, *objectMeta, error) {
+	dataPath, metaPath, err := l.objectPaths(bucketName, objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, err := readMeta(metaPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if matchETag != "" && strings.Trim(matchETag, `"`) != meta.ETag {
+		return nil, nil, ErrPreconditionFailed
+	}
+	f, err := os.Open(dataPath)
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return f, meta, nil
+}
+
+func (l *Local) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts PutObjectOptions) (UploadInfo, error) {
+	if opts.Encryption != nil {
+		return UploadInfo{}, ErrEncryptionUnsupported
+	}
+	return l.put(bucketName, objectName, reader, objectSize, opts.ContentType, expectedChecksums{})
+}
+
+func (l *Local) put(bucketName, objectName string, reader io.Reader, objectSize int64, contentType string, expected expectedChecksums) (UploadInfo, error) {
+	dataPath, metaPath, err := l.objectPaths(bucketName, objectName)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	tmp, sums, size, err := l.writeTemp(reader, objectSize)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	defer os.Remove(tmp)
+
+	meta := &objectMeta{
+		ContentType:    contentType,
+		ETag:           hex.EncodeToString(sums.md5.Sum(nil)),
+		ChecksumSHA256: base64.StdEncoding.EncodeToString(sums.sha256.Sum(nil)),
+		ChecksumCRC32C: base64.StdEncoding.EncodeToString(sums.crc32c.Sum(nil)),
+	}
+	if err := expected.verify(sums); err != nil {
+		return UploadInfo{}, err
+	}
+	if err := l.commit(tmp, dataPath, metaPath, meta); err != nil {
+		return UploadInfo{}, err
+	}
+	return UploadInfo{ETag: meta.ETag, Size: size}, nil
+}
+
+func (l *Local) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	dataPath, metaPath, err := l.objectPaths(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	// Like S3, removing an object that does not exist succeeds.
+	for _, path := range []string{metaPath, dataPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove object: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l *Local) NewMultipartUpload(ctx context.Context, bucketName, objectName string, opts PutObjectOptions) (string, error) {
+	if opts.Encryption != nil {
+		return "", ErrEncryptionUnsupported
+	}
+	if _, _, err := l.objectPaths(bucketName, objectName); err != nil {
+		return "", err
+	}
+	uploadID := uuid.New().String()
+	dir := filepath.Join(l.root, "uploads", uploadID)
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+	meta := uploadMeta{Bucket: bucketName, Object: objectName, ContentType: opts.ContentType}
+	if err := writeJSON(filepath.Join(dir, "upload.json"), meta); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// putPart stores one part of a multipart upload. The part's ETag is kept
+// next to it so listing the parts does not have to read them.
+func (l *Local) putPart(bucketName, objectName, uploadID string, partNumber int, reader io.Reader, size int64, expected expectedChecksums) (UploadInfo, error) {
+	dir, err := l.uploadDir(bucketName, objectName, uploadID)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	if partNumber < 1 || partNumber > 10000 {
+		return UploadInfo{}, fmt.Errorf("%w: invalid part number: %d", errBadContent, partNumber)
+	}
+	tmp, sums, written, err := l.writeTemp(reader, size)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	defer os.Remove(tmp)
+	if err := expected.verify(sums); err != nil {
+		return UploadInfo{}, err
+	}
+
+	etag := hex.EncodeToString(sums.md5.Sum(nil))
+	partPath := filepath.Join(dir, strconv.Itoa(partNumber)+".part")
+	if err := os.Rename(tmp, partPath); err != nil {
+		return UploadInfo{}, fmt.Errorf("failed to store part: %w", err)
+	}
+	if err := writeFileAtomic(l.tempPath(), partPath+".etag", []byte(etag)); err != nil {
+		return UploadInfo{}, err
+	}
+	return UploadInfo{ETag: etag, Size: written}, nil
+}
+
+func (l *Local) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string, partNumberMarker, maxParts int) (ListObjectPartsResult, error) {
+	parts, err := l.listParts(bucketName, objectName, uploadID)
+	if err != nil {
+		return ListObjectPartsResult{}, err
+	}
+	start := sort.Search(len(parts), func(i int) bool { return parts[i].PartNumber > partNumberMarker })
+	parts = parts[start:]
+
+	var result ListObjectPartsResult
+	if maxParts > 0 && len(parts) > maxParts {
+		parts = parts[:maxParts]
+		result.IsTruncated = true
+		result.NextPartNumberMarker = parts[len(parts)-1].PartNumber
+	}
+	result.ObjectParts = parts
+	return result, nil
+}
+
+func (l *Local) listParts(bucketName, objectName, uploadID string) ([]ObjectPart, error) {
+	dir, err := l.uploadDir(bucketName, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+
+	var parts []ObjectPart
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".part.etag")
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		etag, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part: %w", err)
+		}
+		stat, err := os.Stat(filepath.Join(dir, name+".part"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part: %w", err)
+		}
+		parts = append(parts, ObjectPart{PartNumber: number, ETag: string(etag), Size: stat.Size()})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// CompleteMultipartUpload concatenates the listed parts into the object.
+// Its ETag is formed the way S3 forms multipart ETags.
+func (l *Local) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart, opts PutObjectOptions) (UploadInfo, error) {
+	dir, err := l.uploadDir(bucketName, objectName, uploadID)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	meta, err := readUploadMeta(dir)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	uploaded, err := l.listParts(bucketName, objectName, uploadID)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	etags := make(map[int]string, len(uploaded))
+	for _, part := range uploaded {
+		etags[part.PartNumber] = part.ETag
+	}
+
+	readers := make([]io.Reader, 0, len(parts))
+	partMD5s := md5.New()
+	for i, part := range parts {
+		if i > 0 && part.PartNumber <= parts[i-1].PartNumber {
+			return UploadInfo{}, fmt.Errorf("%w: parts must be in ascending order", errBadContent)
+		}
+		if etag, ok := etags[part.PartNumber]; !ok || etag != strings.Trim(part.ETag, `"`) {
+			return UploadInfo{}, fmt.Errorf("%w: part %d not found", errBadContent, part.PartNumber)
+		}
+		raw, _ := hex.DecodeString(etags[part.PartNumber])
+		partMD5s.Write(raw)
+
+		f, err := os.Open(filepath.Join(dir, strconv.Itoa(part.PartNumber)+".part"))
+		if err != nil {
+			return UploadInfo{}, fmt.Errorf("failed to read part: %w", err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	dataPath, metaPath, err := l.objectPaths(bucketName, objectName)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	tmp, sums, size, err := l.writeTemp(io.MultiReader(readers...), -1)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	defer os.Remove(tmp)
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = meta.ContentType
+	}
+	objMeta := &objectMeta{
+		ContentType:    contentType,
+		ETag:           fmt.Sprintf("%s-%d", hex.EncodeToString(partMD5s.Sum(nil)), len(parts)),
+		ChecksumSHA256: base64.StdEncoding.EncodeToString(sums.sha256.Sum(nil)),
+		ChecksumCRC32C: base64.StdEncoding.EncodeToString(sums.crc32c.Sum(nil)),
+	}
+	if err := l.commit(tmp, dataPath, metaPath, objMeta); err != nil {
+		return UploadInfo{}, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return UploadInfo{}, fmt.Errorf("failed to clean up upload: %w", err)
+	}
+	return UploadInfo{ETag: objMeta.ETag, Size: size}, nil
+}
+
+func (l *Local) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	dir, err := l.uploadDir(bucketName, objectName, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+	return nil
+}
+
+func (l *Local) CopyObject(ctx context.Context, dst CopyDestOptions, src CopySrcOptions) (UploadInfo, error) {
+	if dst.Encryption != nil || src.Encryption != nil {
+		return UploadInfo{}, ErrEncryptionUnsupported
+	}
+	f, meta, err := l.open(src.Bucket, src.Object, "")
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	defer f.Close()
+	return l.put(dst.Bucket, dst.Object, f, -1, meta.ContentType, expectedChecksums{})
+}
+
+// writeTemp writes reader to a temporary file and returns its path along
+// with the checksums and size of what was written. A non-negative size has
+// to match.
+func (l *Local) writeTemp(reader io.Reader, size int64) (string, *checksums, int64, error) {
+	f, err := os.CreateTemp(filepath.Join(l.root, "tmp"), "put-")
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	sums := newChecksums()
+	written, err := io.Copy(sums.writer(f), reader)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("%w: size mismatch: expected %d, got %d", errBadContent, size, written)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", nil, 0, fmt.Errorf("failed to write object: %w", err)
+	}
+	return f.Name(), sums, written, nil
+}
+
+// commit moves a written temporary file in place as the data of an object
+// and records its metadata. The two are replaced one after the other, so a
+// read racing an overwrite may briefly see them out of step; that is fine
+// for the setups this driver is meant for.
+func (l *Local) commit(tmp, dataPath, metaPath string, meta *objectMeta) error {
+	for _, path := range []string{dataPath, metaPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return fmt.Errorf("failed to create object directory: %w", err)
+		}
+	}
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(l.tempPath(), metaPath, raw); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dataPath); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	return nil
+}
+
+func (l *Local) tempPath() string {
+	return filepath.Join(l.root, "tmp")
+}
+
+// bucketPath returns the directory of a bucket within one of the trees
+// under root.
+func (l *Local) bucketPath(tree, bucketName string) (string, error) {
+	if bucketName == "" || bucketName == "." || bucketName == ".." || strings.ContainsAny(bucketName, `/\`) {
+		return "", fmt.Errorf("invalid bucket name: %q", bucketName)
+	}
+	return filepath.Join(l.root, tree, bucketName), nil
+}
+
+// objectPaths returns where the data and the metadata of an object are
+// kept. Names that could escape the bucket directory are rejected.
+func (l *Local) objectPaths(bucketName, objectName string) (string, string, error) {
+	if !validObjectName(objectName) {
+		return "", "", fmt.Errorf("invalid object name: %q", objectName)
+	}
+	dataDir, err := l.bucketPath("objects", bucketName)
+	if err != nil {
+		return "", "", err
+	}
+	metaDir, _ := l.bucketPath("meta", bucketName)
+	name := filepath.FromSlash(objectName)
+	return filepath.Join(dataDir, name), filepath.Join(metaDir, name+".json"), nil
+}
+
+func validObjectName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, `\`) {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// uploadDir returns the directory of a multipart upload, checking that it
+// was started for the given object.
+func (l *Local) uploadDir(bucketName, objectName, uploadID string) (string, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", ErrUploadNotFound
+	}
+	dir := filepath.Join(l.root, "uploads", uploadID)
+	meta, err := readUploadMeta(dir)
+	if err != nil {
+		return "", err
+	}
+	if meta.Bucket != bucketName || meta.Object != objectName {
+		return "", ErrUploadNotFound
+	}
+	return dir, nil
+}
+
+func (e expectedChecksums) verify(sums *checksums) error {
+	for _, check := range []struct {
+		name     string
+		expected string
+		sum      hash.Hash
+	}{
+		{"MD5", e.MD5, sums.md5},
+		{"SHA256", e.SHA256, sums.sha256},
+		{"CRC32C", e.CRC32C, sums.crc32c},
+	} {
+		if check.expected != "" && check.expected != base64.StdEncoding.EncodeToString(check.sum.Sum(nil)) {
+			return fmt.Errorf("%w: %s checksum mismatch", errBadContent, check.name)
+		}
+	}
+	return nil
+}
+
+func readMeta(path string) (*objectMeta, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var meta objectMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("failed to read object metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+func readUploadMeta(dir string) (*uploadMeta, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	var meta uploadMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return &meta, nil
+}
+
+func writeJSON(path string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o640)
+}
+
+// writeFileAtomic replaces path with data through a temporary file in
+// tmpDir, so readers never see a partial file.
+func writeFileAtomic(tmpDir, path string, data []byte) error {
+	f, err := os.CreateTemp(tmpDir, "meta-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrObjectNotFound
+	}
+	return err
+}
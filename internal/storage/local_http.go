@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters of the URLs Local presigns. Every other parameter, and
+// the headers listed in localSignedHeaders, are covered by the signature.
+const (
+	localExpires       = "X-Local-Expires"
+	localSignedHeaders = "X-Local-SignedHeaders"
+	localSignature     = "X-Local-Signature"
+)
+
+func (l *Local) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
+	return l.PresignHeader(ctx, http.MethodGet, bucketName, objectName, expires, reqParams, nil)
+}
+
+// PresignHeader signs a request for an object. The client has to send the
+// extra headers along with exactly the signed values.
+func (l *Local) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	if _, _, err := l.objectPaths(bucketName, objectName); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for k, v := range reqParams {
+		query[k] = v
+	}
+	query.Set(localExpires, strconv.FormatInt(time.Now().Add(expires).Unix(), 10))
+	names := make([]string, 0, len(extraHeaders))
+	for name := range extraHeaders {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		query.Set(localSignedHeaders, strings.Join(names, ";"))
+	}
+	query.Set(localSignature, l.sign(method, bucketName, objectName, query, extraHeaders))
+
+	u := l.publicURL.JoinPath(bucketName, objectName)
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// sign returns the signature of a request: an HMAC over the method, the
+// object, the query without the signature and the signed headers.
+func (l *Local) sign(method, bucketName, objectName string, query url.Values, headers http.Header) string {
+	unsigned := url.Values{}
+	for k, v := range query {
+		if k != localSignature {
+			unsigned[k] = v
+		}
+	}
+
+	mac := hmac.New(sha256.New, l.signingKey)
+	fmt.Fprintf(mac, "%s\n%s/%s\n%s\n", method, bucketName, objectName, unsigned.Encode())
+	if signed := query.Get(localSignedHeaders); signed != "" {
+		for _, name := range strings.Split(signed, ";") {
+			fmt.Fprintf(mac, "%s:%s\n", name, headers.Get(name))
+		}
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves the URLs presigned by Local: GET and HEAD read an
+// object, PUT writes it, or one part of a multipart upload when the URL
+// carries partNumber and uploadId. Checksum headers sent with a PUT are
+// verified the way S3 verifies them.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "ETag")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, PUT")
+		w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	bucketName, objectName, ok := l.splitPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	signedMethod := r.Method
+	if signedMethod == http.MethodHead {
+		signedMethod = http.MethodGet
+	}
+	if err := l.verify(r, signedMethod, bucketName, objectName); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		l.serveObject(w, r, bucketName, objectName)
+	case http.MethodPut:
+		l.receiveObject(w, r, bucketName, objectName)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// splitPath strips the path of the public URL, if any, and splits the rest
+// into a bucket and an object name.
+func (l *Local) splitPath(path string) (string, string, bool) {
+	path = strings.TrimPrefix(path, strings.TrimSuffix(l.publicURL.Path, "/"))
+	bucketName, objectName, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok || bucketName == "" || !validObjectName(objectName) {
+		return "", "", false
+	}
+	return bucketName, objectName, true
+}
+
+func (l *Local) verify(r *http.Request, method, bucketName, objectName string) error {
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get(localExpires), 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing expiry")
+	}
+	if time.Now().Unix() > expires {
+		return fmt.Errorf("url expired")
+	}
+
+	headers := r.Header.Clone()
+	if r.ContentLength >= 0 && r.Method == http.MethodPut {
+		headers.Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	expected := l.sign(method, bucketName, objectName, query, headers)
+	if !hmac.Equal([]byte(expected), []byte(query.Get(localSignature))) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+func (l *Local) serveObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	f, meta, err := l.open(bucketName, objectName, "")
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	contentType := meta.ContentType
+	if override := query.Get("response-content-type"); override != "" {
+		contentType = override
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if disposition := query.Get("response-content-disposition"); disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	w.Header().Set("ETag", strconv.Quote(meta.ETag))
+	http.ServeContent(w, r, "", stat.ModTime(), f)
+}
+
+func (l *Local) receiveObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	expected := expectedChecksums{
+		MD5:    r.Header.Get("Content-MD5"),
+		SHA256: r.Header.Get("X-Amz-Checksum-Sha256"),
+		CRC32C: r.Header.Get("X-Amz-Checksum-Crc32c"),
+	}
+
+	var info UploadInfo
+	var err error
+	query := r.URL.Query()
+	if uploadID := query.Get("uploadId"); uploadID != "" {
+		partNumber, convErr := strconv.Atoi(query.Get("partNumber"))
+		if convErr != nil {
+			http.Error(w, "invalid part number", http.StatusBadRequest)
+			return
+		}
+		info, err = l.putPart(bucketName, objectName, uploadID, partNumber, r.Body, r.ContentLength, expected)
+	} else {
+		info, err = l.put(bucketName, objectName, r.Body, r.ContentLength, r.Header.Get("Content-Type"), expected)
+	}
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	w.Header().Set("ETag", strconv.Quote(info.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrObjectNotFound), errors.Is(err, ErrUploadNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPreconditionFailed):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, errBadContent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
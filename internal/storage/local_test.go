@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLocal(t *testing.T) (*Local, *httptest.Server) {
+	t.Helper()
+	var local *Local
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	local, err := NewLocal(t.TempDir(), server.URL, []byte("test-signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := local.MakeBucket(context.Background(), "cloud-storage", MakeBucketOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return local, server
+}
+
+func doRequest(t *testing.T, method string, u *url.URL, body string, headers map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, u.String(), strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+func TestLocal_PutGetStat(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	info, err := local.PutObject(ctx, "cloud-storage", "user-1/a.txt", strings.NewReader("hello world"), 11, PutObjectOptions{ContentType: "text/plain"})
+	assert.NoError(t, err)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", info.ETag)
+
+	stat, err := local.StatObject(ctx, "cloud-storage", "user-1/a.txt", StatObjectOptions{Checksum: true})
+	assert.NoError(t, err)
+	sum := sha256.Sum256([]byte("hello world"))
+	assert.Equal(t, int64(11), stat.Size)
+	assert.Equal(t, "text/plain", stat.ContentType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), stat.ChecksumSHA256)
+
+	body, err := local.GetObject(ctx, "cloud-storage", "user-1/a.txt", GetObjectOptions{Offset: 6, Length: 5, MatchETag: info.ETag})
+	assert.NoError(t, err)
+	assert.Equal(t, "world", readAll(t, body))
+	body.Close()
+
+	_, err = local.GetObject(ctx, "cloud-storage", "user-1/a.txt", GetObjectOptions{MatchETag: "other"})
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	assert.NoError(t, local.RemoveObject(ctx, "cloud-storage", "user-1/a.txt"))
+	_, err = local.StatObject(ctx, "cloud-storage", "user-1/a.txt", StatObjectOptions{})
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	assert.NoError(t, local.RemoveObject(ctx, "cloud-storage", "user-1/a.txt"))
+}
+
+func TestLocal_RejectsUnsafeNames(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"../escape", "a/../../b", "/abs", "a//b", ""} {
+		_, err := local.PutObject(ctx, "cloud-storage", name, strings.NewReader("x"), 1, PutObjectOptions{})
+		assert.Error(t, err, name)
+	}
+	_, err := local.PutObject(ctx, "..", "a", strings.NewReader("x"), 1, PutObjectOptions{})
+	assert.Error(t, err)
+}
+
+func TestLocal_RejectsEncryption(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+
+	_, err := local.PutObject(context.Background(), "cloud-storage", "a", strings.NewReader("x"), 1, PutObjectOptions{Encryption: make(CustomerKey, 32)})
+	assert.ErrorIs(t, err, ErrEncryptionUnsupported)
+}
+
+func TestLocal_PresignedPutAndGet(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte("hello world"))
+	checksum := base64.StdEncoding.EncodeToString(sum[:])
+	headers := http.Header{}
+	headers.Set("Content-Type", "text/plain")
+	headers.Set("Content-Length", "11")
+	headers.Set("x-amz-checksum-sha256", checksum)
+
+	putURL, err := local.PresignHeader(ctx, http.MethodPut, "cloud-storage", "user-1/a.txt", time.Minute, nil, headers)
+	assert.NoError(t, err)
+
+	resp := doRequest(t, http.MethodPut, putURL, "hello world", map[string]string{"Content-Type": "application/json", "x-amz-checksum-sha256": checksum})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "content type differs from the signed one")
+
+	resp = doRequest(t, http.MethodPut, putURL, "hello world", map[string]string{"Content-Type": "text/plain", "x-amz-checksum-sha256": checksum})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"5eb63bbbe01eeed093cb22bb8f5acdc3"`, resp.Header.Get("ETag"))
+
+	params := url.Values{}
+	params.Set("response-content-disposition", `attachment; filename="a.txt"`)
+	getURL, err := local.PresignedGetObject(ctx, "cloud-storage", "user-1/a.txt", time.Minute, params)
+	assert.NoError(t, err)
+
+	resp = doRequest(t, http.MethodGet, getURL, "", map[string]string{"Range": "bytes=0-4"})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "hello", readAll(t, resp.Body))
+	assert.Equal(t, `attachment; filename="a.txt"`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+
+	tampered := *getURL
+	query := tampered.Query()
+	query.Set("response-content-disposition", "inline")
+	tampered.RawQuery = query.Encode()
+	resp = doRequest(t, http.MethodGet, &tampered, "", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	expired, err := local.PresignedGetObject(ctx, "cloud-storage", "user-1/a.txt", -time.Minute, nil)
+	assert.NoError(t, err)
+	resp = doRequest(t, http.MethodGet, expired, "", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestLocal_PresignedPutChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte("something else"))
+	headers := http.Header{}
+	headers.Set("x-amz-checksum-sha256", base64.StdEncoding.EncodeToString(sum[:]))
+
+	putURL, err := local.PresignHeader(ctx, http.MethodPut, "cloud-storage", "a.txt", time.Minute, nil, headers)
+	assert.NoError(t, err)
+
+	resp := doRequest(t, http.MethodPut, putURL, "hello world", map[string]string{"x-amz-checksum-sha256": headers.Get("x-amz-checksum-sha256")})
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, err = local.StatObject(ctx, "cloud-storage", "a.txt", StatObjectOptions{})
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestLocal_MultipartUpload(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	uploadID, err := local.NewMultipartUpload(ctx, "cloud-storage", "big.bin", PutObjectOptions{ContentType: "application/octet-stream"})
+	assert.NoError(t, err)
+
+	for n, body := range []string{"hello ", "world"} {
+		params := url.Values{}
+		params.Set("partNumber", []string{"1", "2"}[n])
+		params.Set("uploadId", uploadID)
+		partURL, err := local.PresignHeader(ctx, http.MethodPut, "cloud-storage", "big.bin", time.Minute, params, nil)
+		assert.NoError(t, err)
+		resp := doRequest(t, http.MethodPut, partURL, body, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	listed, err := local.ListObjectParts(ctx, "cloud-storage", "big.bin", uploadID, 0, 1)
+	assert.NoError(t, err)
+	assert.True(t, listed.IsTruncated)
+	assert.Equal(t, 1, listed.NextPartNumberMarker)
+	rest, err := local.ListObjectParts(ctx, "cloud-storage", "big.bin", uploadID, 1, 1000)
+	assert.NoError(t, err)
+	assert.False(t, rest.IsTruncated)
+	parts := append(listed.ObjectParts, rest.ObjectParts...)
+	assert.Len(t, parts, 2)
+
+	info, err := local.CompleteMultipartUpload(ctx, "cloud-storage", "big.bin", uploadID, []CompletePart{
+		{PartNumber: 1, ETag: parts[0].ETag},
+		{PartNumber: 2, ETag: parts[1].ETag},
+	}, PutObjectOptions{})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(info.ETag, "-2"))
+	assert.Equal(t, int64(11), info.Size)
+
+	body, err := local.GetObject(ctx, "cloud-storage", "big.bin", GetObjectOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", readAll(t, body))
+	body.Close()
+
+	assert.ErrorIs(t, local.AbortMultipartUpload(ctx, "cloud-storage", "big.bin", uploadID), ErrUploadNotFound)
+}
+
+func TestLocal_CopyObject(t *testing.T) {
+	t.Parallel()
+
+	local, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	_, err := local.PutObject(ctx, "cloud-storage", "a.txt", strings.NewReader("hello"), 5, PutObjectOptions{ContentType: "text/plain"})
+	assert.NoError(t, err)
+
+	_, err = local.CopyObject(ctx, CopyDestOptions{Bucket: "cloud-storage", Object: "b.txt"}, CopySrcOptions{Bucket: "cloud-storage", Object: "a.txt"})
+	assert.NoError(t, err)
+
+	stat, err := local.StatObject(ctx, "cloud-storage", "b.txt", StatObjectOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), stat.Size)
+	assert.Equal(t, "text/plain", stat.ContentType)
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Sene4ka/cloud_storage/configs"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// MinIO stores objects in MinIO or any other S3 compatible storage.
+// Presigned URLs are signed for the public endpoint, as clients may not be
+// able to reach the one the service talks to.
+type MinIO struct {
+	client          *minio.Client
+	presignedClient *minio.Client
+}
+
+func NewMinIO(config configs.MinIOConfig) (*MinIO, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+		Secure: config.UseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+
+	presignedEndpoint := config.PublicEndpoint
+	if presignedEndpoint == "" {
+		presignedEndpoint = config.Endpoint
+	}
+	presignedClient, err := minio.New(presignedEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+		Secure: config.UseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create presigned minio client: %w", err)
+	}
+
+	return &MinIO{client: client, presignedClient: presignedClient}, nil
+}
+
+func (m *MinIO) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	return m.client.BucketExists(ctx, bucketName)
+}
+
+func (m *MinIO) MakeBucket(ctx context.Context, bucketName string, opts MakeBucketOptions) error {
+	return m.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: opts.Region})
+}
+
+func (m *MinIO) StatObject(ctx context.Context, bucketName, objectName string, opts StatObjectOptions) (ObjectInfo, error) {
+	sse, err := serverSide(opts.Encryption)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := m.client.StatObject(ctx, bucketName, objectName, minio.StatObjectOptions{Checksum: opts.Checksum, ServerSideEncryption: sse})
+	if err != nil {
+		return ObjectInfo{}, minioError(err)
+	}
+	return ObjectInfo{
+		Size:           info.Size,
+		ETag:           info.ETag,
+		ContentType:    info.ContentType,
+		LastModified:   info.LastModified,
+		ChecksumSHA256: info.ChecksumSHA256,
+		ChecksumCRC32C: info.ChecksumCRC32C,
+	}, nil
+}
+
+func (m *MinIO) GetObject(ctx context.Context, bucketName, objectName string, opts GetObjectOptions) (io.ReadCloser, error) {
+	sse, err := serverSide(opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	getOpts := minio.GetObjectOptions{ServerSideEncryption: sse}
+	if opts.MatchETag != "" {
+		if err := getOpts.SetMatchETag(opts.MatchETag); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Length > 0 {
+		err = getOpts.SetRange(opts.Offset, opts.Offset+opts.Length-1)
+	} else if opts.Offset > 0 {
+		err = getOpts.SetRange(opts.Offset, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := m.client.GetObject(ctx, bucketName, objectName, getOpts)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return obj, nil
+}
+
+func (m *MinIO) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts PutObjectOptions) (UploadInfo, error) {
+	putOpts, err := putOptions(opts)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	info, err := m.client.PutObject(ctx, bucketName, objectName, reader, objectSize, putOpts)
+	if err != nil {
+		return UploadInfo{}, minioError(err)
+	}
+	return UploadInfo{ETag: info.ETag, Size: info.Size}, nil
+}
+
+func (m *MinIO) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	return m.client.RemoveObject(ctx, bucketName, objectName, minio.RemoveObjectOptions{})
+}
+
+func (m *MinIO) NewMultipartUpload(ctx context.Context, bucketName, objectName string, opts PutObjectOptions) (string, error) {
+	putOpts, err := putOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	return minio.Core{Client: m.client}.NewMultipartUpload(ctx, bucketName, objectName, putOpts)
+}
+
+func (m *MinIO) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string, partNumberMarker, maxParts int) (ListObjectPartsResult, error) {
+	result, err := minio.Core{Client: m.client}.ListObjectParts(ctx, bucketName, objectName, uploadID, partNumberMarker, maxParts)
+	if err != nil {
+		return ListObjectPartsResult{}, minioError(err)
+	}
+	parts := make([]ObjectPart, len(result.ObjectParts))
+	for i, part := range result.ObjectParts {
+		parts[i] = ObjectPart{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size}
+	}
+	return ListObjectPartsResult{
+		ObjectParts:          parts,
+		IsTruncated:          result.IsTruncated,
+		NextPartNumberMarker: result.NextPartNumberMarker,
+	}, nil
+}
+
+func (m *MinIO) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart, opts PutObjectOptions) (UploadInfo, error) {
+	putOpts, err := putOptions(opts)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	completeParts := make([]minio.CompletePart, len(parts))
+	for i, part := range parts {
+		completeParts[i] = minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+	}
+	info, err := minio.Core{Client: m.client}.CompleteMultipartUpload(ctx, bucketName, objectName, uploadID, completeParts, putOpts)
+	if err != nil {
+		return UploadInfo{}, minioError(err)
+	}
+	return UploadInfo{ETag: info.ETag, Size: info.Size}, nil
+}
+
+func (m *MinIO) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	return minioError(minio.Core{Client: m.client}.AbortMultipartUpload(ctx, bucketName, objectName, uploadID))
+}
+
+func (m *MinIO) CopyObject(ctx context.Context, dst CopyDestOptions, src CopySrcOptions) (UploadInfo, error) {
+	dstSSE, err := serverSide(dst.Encryption)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	srcSSE, err := serverSide(src.Encryption)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	info, err := m.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: dst.Bucket, Object: dst.Object, Encryption: dstSSE},
+		minio.CopySrcOptions{Bucket: src.Bucket, Object: src.Object, Encryption: srcSSE},
+	)
+	if err != nil {
+		return UploadInfo{}, minioError(err)
+	}
+	return UploadInfo{ETag: info.ETag, Size: info.Size}, nil
+}
+
+func (m *MinIO) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
+	return m.presignedClient.PresignedGetObject(ctx, bucketName, objectName, expires, reqParams)
+}
+
+func (m *MinIO) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	return m.presignedClient.PresignHeader(ctx, method, bucketName, objectName, expires, reqParams, extraHeaders)
+}
+
+func putOptions(opts PutObjectOptions) (minio.PutObjectOptions, error) {
+	sse, err := serverSide(opts.Encryption)
+	if err != nil {
+		return minio.PutObjectOptions{}, err
+	}
+	return minio.PutObjectOptions{ContentType: opts.ContentType, ServerSideEncryption: sse, PartSize: opts.PartSize}, nil
+}
+
+func serverSide(key CustomerKey) (encrypt.ServerSide, error) {
+	if key == nil {
+		return nil, nil
+	}
+	sse, err := encrypt.NewSSEC(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption key: %w", err)
+	}
+	return sse, nil
+}
+
+// minioError maps the S3 errors callers act upon to the errors of this
+// package, keeping the original error in the message.
+func minioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	case "NoSuchUpload":
+		return fmt.Errorf("%w: %v", ErrUploadNotFound, err)
+	case "PreconditionFailed":
+		return fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+	}
+	return err
+}
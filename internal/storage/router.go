@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Backend is the set of operations every storage driver implements.
+type Backend interface {
+	BucketExists(ctx context.Context, bucketName string) (bool, error)
+	MakeBucket(ctx context.Context, bucketName string, opts MakeBucketOptions) error
+	StatObject(ctx context.Context, bucketName, objectName string, opts StatObjectOptions) (ObjectInfo, error)
+	GetObject(ctx context.Context, bucketName, objectName string, opts GetObjectOptions) (io.ReadCloser, error)
+	PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts PutObjectOptions) (UploadInfo, error)
+	RemoveObject(ctx context.Context, bucketName, objectName string) error
+	NewMultipartUpload(ctx context.Context, bucketName, objectName string, opts PutObjectOptions) (string, error)
+	ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string, partNumberMarker, maxParts int) (ListObjectPartsResult, error)
+	CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart, opts PutObjectOptions) (UploadInfo, error)
+	AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error
+	CopyObject(ctx context.Context, dst CopyDestOptions, src CopySrcOptions) (UploadInfo, error)
+	PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error)
+	PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error)
+}
+
+var (
+	_ Backend = (*MinIO)(nil)
+	_ Backend = (*Local)(nil)
+	_ Backend = (*Router)(nil)
+)
+
+// Router sends every call to the backend of the bucket it names, so that
+// buckets can live on different endpoints. Buckets without a backend of
+// their own are served by the fallback.
+type Router struct {
+	fallback Backend
+	buckets  map[string]Backend
+}
+
+func NewRouter(fallback Backend, buckets map[string]Backend) *Router {
+	return &Router{fallback: fallback, buckets: buckets}
+}
+
+func (r *Router) backend(bucketName string) Backend {
+	if backend, ok := r.buckets[bucketName]; ok {
+		return backend
+	}
+	return r.fallback
+}
+
+func (r *Router) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	return r.backend(bucketName).BucketExists(ctx, bucketName)
+}
+
+func (r *Router) MakeBucket(ctx context.Context, bucketName string, opts MakeBucketOptions) error {
+	return r.backend(bucketName).MakeBucket(ctx, bucketName, opts)
+}
+
+func (r *Router) StatObject(ctx context.Context, bucketName, objectName string, opts StatObjectOptions) (ObjectInfo, error) {
+	return r.backend(bucketName).StatObject(ctx, bucketName, objectName, opts)
+}
+
+func (r *Router) GetObject(ctx context.Context, bucketName, objectName string, opts GetObjectOptions) (io.ReadCloser, error) {
+	return r.backend(bucketName).GetObject(ctx, bucketName, objectName, opts)
+}
+
+func (r *Router) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts PutObjectOptions) (UploadInfo, error) {
+	return r.backend(bucketName).PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func (r *Router) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	return r.backend(bucketName).RemoveObject(ctx, bucketName, objectName)
+}
+
+func (r *Router) NewMultipartUpload(ctx context.Context, bucketName, objectName string, opts PutObjectOptions) (string, error) {
+	return r.backend(bucketName).NewMultipartUpload(ctx, bucketName, objectName, opts)
+}
+
+func (r *Router) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string, partNumberMarker, maxParts int) (ListObjectPartsResult, error) {
+	return r.backend(bucketName).ListObjectParts(ctx, bucketName, objectName, uploadID, partNumberMarker, maxParts)
+}
+
+func (r *Router) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart, opts PutObjectOptions) (UploadInfo, error) {
+	return r.backend(bucketName).CompleteMultipartUpload(ctx, bucketName, objectName, uploadID, parts, opts)
+}
+
+func (r *Router) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	return r.backend(bucketName).AbortMultipartUpload(ctx, bucketName, objectName, uploadID)
+}
+
+// CopyObject copies within a backend server side. Between backends the
+// object is streamed through the service.
+func (r *Router) CopyObject(ctx context.Context, dst CopyDestOptions, src CopySrcOptions) (UploadInfo, error) {
+	srcBackend, dstBackend := r.backend(src.Bucket), r.backend(dst.Bucket)
+	if srcBackend == dstBackend {
+		return dstBackend.CopyObject(ctx, dst, src)
+	}
+
+	info, err := srcBackend.StatObject(ctx, src.Bucket, src.Object, StatObjectOptions{Encryption: src.Encryption})
+	if err != nil {
+		return UploadInfo{}, fmt.Errorf("failed to stat source object: %w", err)
+	}
+	body, err := srcBackend.GetObject(ctx, src.Bucket, src.Object, GetObjectOptions{Encryption: src.Encryption, MatchETag: info.ETag})
+	if err != nil {
+		return UploadInfo{}, fmt.Errorf("failed to read source object: %w", err)
+	}
+	defer body.Close()
+
+	return dstBackend.PutObject(ctx, dst.Bucket, dst.Object, body, info.Size, PutObjectOptions{ContentType: info.ContentType, Encryption: dst.Encryption})
+}
+
+func (r *Router) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
+	return r.backend(bucketName).PresignedGetObject(ctx, bucketName, objectName, expires, reqParams)
+}
+
+func (r *Router) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	return r.backend(bucketName).PresignHeader(ctx, method, bucketName, objectName, expires, reqParams, extraHeaders)
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_RoutesByBucket(t *testing.T) {
+	t.Parallel()
+
+	hot, _ := newTestLocal(t)
+	cold, _ := newTestLocal(t)
+	ctx := context.Background()
+	assert.NoError(t, cold.MakeBucket(ctx, "cold", MakeBucketOptions{}))
+	router := NewRouter(hot, map[string]Backend{"cold": cold})
+
+	_, err := router.PutObject(ctx, "cold", "a.txt", strings.NewReader("hello"), 5, PutObjectOptions{})
+	assert.NoError(t, err)
+
+	_, err = cold.StatObject(ctx, "cold", "a.txt", StatObjectOptions{})
+	assert.NoError(t, err)
+	_, err = hot.StatObject(ctx, "cold", "a.txt", StatObjectOptions{})
+	assert.Error(t, err)
+}
+
+func TestRouter_CopyBetweenBackends(t *testing.T) {
+	t.Parallel()
+
+	hot, _ := newTestLocal(t)
+	cold, _ := newTestLocal(t)
+	ctx := context.Background()
+	assert.NoError(t, cold.MakeBucket(ctx, "cold", MakeBucketOptions{}))
+	router := NewRouter(hot, map[string]Backend{"cold": cold})
+
+	_, err := router.PutObject(ctx, "cloud-storage", "a.txt", strings.NewReader("hello"), 5, PutObjectOptions{ContentType: "text/plain"})
+	assert.NoError(t, err)
+
+	info, err := router.CopyObject(ctx, CopyDestOptions{Bucket: "cold", Object: "a.txt"}, CopySrcOptions{Bucket: "cloud-storage", Object: "a.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+
+	stat, err := cold.StatObject(ctx, "cold", "a.txt", StatObjectOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", stat.ContentType)
+	body, err := router.GetObject(ctx, "cold", "a.txt", GetObjectOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", readAll(t, body))
+	body.Close()
+}
@@ -0,0 +1,106 @@
+// Package storage holds the object storage drivers of the file service and
+// the backend-neutral types they exchange with it.
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrObjectNotFound        = errors.New("object not found")
+	ErrUploadNotFound        = errors.New("multipart upload not found")
+	ErrPreconditionFailed    = errors.New("object does not match precondition")
+	ErrEncryptionUnsupported = errors.New("encryption is not supported by this storage driver")
+)
+
+// CustomerKey is a client supplied key an object is encrypted with by the
+// storage (SSE-C). A nil key means the object is stored in plaintext.
+type CustomerKey []byte
+
+// SetHeaders adds the headers that carry the key on a request to h.
+func (k CustomerKey) SetHeaders(h http.Header) {
+	if k == nil {
+		return
+	}
+	sum := md5.Sum(k)
+	h.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+	h.Set("X-Amz-Server-Side-Encryption-Customer-Key", base64.StdEncoding.EncodeToString(k))
+	h.Set("X-Amz-Server-Side-Encryption-Customer-Key-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+type MakeBucketOptions struct {
+	Region string
+}
+
+// StatObjectOptions controls StatObject. Checksum asks for the checksums
+// the storage verified on upload.
+type StatObjectOptions struct {
+	Checksum   bool
+	Encryption CustomerKey
+}
+
+// GetObjectOptions controls GetObject. A non-zero Length reads that many
+// bytes from Offset; otherwise the object is read from Offset to its end. A
+// MatchETag makes the read fail with ErrPreconditionFailed when the object
+// has changed.
+type GetObjectOptions struct {
+	Encryption CustomerKey
+	MatchETag  string
+	Offset     int64
+	Length     int64
+}
+
+// PutObjectOptions controls writes. PartSize is the size of the parts an
+// object of unknown size is uploaded in, for drivers that need one.
+type PutObjectOptions struct {
+	ContentType string
+	Encryption  CustomerKey
+	PartSize    uint64
+}
+
+type ObjectInfo struct {
+	Size           int64
+	ETag           string
+	ContentType    string
+	LastModified   time.Time
+	ChecksumSHA256 string
+	ChecksumCRC32C string
+}
+
+type UploadInfo struct {
+	ETag string
+	Size int64
+}
+
+type ObjectPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+type ListObjectPartsResult struct {
+	ObjectParts          []ObjectPart
+	IsTruncated          bool
+	NextPartNumberMarker int
+}
+
+type CompletePart struct {
+	PartNumber int
+	ETag       string
+}
+
+type CopyDestOptions struct {
+	Bucket     string
+	Object     string
+	Encryption CustomerKey
+}
+
+type CopySrcOptions struct {
+	Bucket     string
+	Object     string
+	Encryption CustomerKey
+}
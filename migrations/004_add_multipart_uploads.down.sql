@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_multipart_uploads_created_at;
+
+DROP TABLE IF EXISTS multipart_uploads;
\ No newline at end of file
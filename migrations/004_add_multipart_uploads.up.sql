@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS multipart_uploads (
+    file_id UUID PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+    upload_id TEXT NOT NULL,
+    part_size BIGINT NOT NULL,
+    part_count INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_multipart_uploads_created_at ON multipart_uploads(created_at);
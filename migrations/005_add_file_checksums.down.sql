@@ -0,0 +1,3 @@
+ALTER TABLE files
+DROP COLUMN IF EXISTS checksum_algorithm,
+DROP COLUMN IF EXISTS checksum;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS checksum_algorithm VARCHAR(16) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS checksum TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_quota_reservations_user_id;
+
+DROP TABLE IF EXISTS quota_reservations;
+DROP TABLE IF EXISTS user_quotas;
+DROP TABLE IF EXISTS quota_plans;
\ No newline at end of file
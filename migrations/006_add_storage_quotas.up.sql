@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS quota_plans (
+    name VARCHAR(50) PRIMARY KEY,
+    max_bytes BIGINT NOT NULL,
+    max_files BIGINT NOT NULL
+);
+
+INSERT INTO quota_plans (name, max_bytes, max_files)
+VALUES ('default', 10737418240, 100000)
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS user_quotas (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    plan VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES quota_plans(name),
+    max_bytes BIGINT DEFAULT NULL,
+    max_files BIGINT DEFAULT NULL,
+    used_bytes BIGINT NOT NULL DEFAULT 0,
+    used_files BIGINT NOT NULL DEFAULT 0,
+    reserved_bytes BIGINT NOT NULL DEFAULT 0,
+    reserved_files BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS quota_reservations (
+    file_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bytes BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_reservations_user_id ON quota_reservations(user_id);
+
+INSERT INTO user_quotas (user_id, used_bytes, used_files)
+SELECT user_id, SUM(size), COUNT(*) FROM files GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
\ No newline at end of file
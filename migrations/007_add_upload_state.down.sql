@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_files_upload_state_created_at;
+
+ALTER TABLE files
+DROP COLUMN IF EXISTS upload_state;
\ No newline at end of file
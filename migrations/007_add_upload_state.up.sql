@@ -0,0 +1,5 @@
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS upload_state VARCHAR(16) NOT NULL DEFAULT 'active'
+CHECK (upload_state IN ('pending', 'active', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_files_upload_state_created_at ON files(created_at) WHERE upload_state <> 'active';
\ No newline at end of file
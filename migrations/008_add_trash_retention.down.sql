@@ -0,0 +1,2 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS trash_retention_days;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS trash_retention_days INTEGER DEFAULT NULL CHECK (trash_retention_days > 0);
\ No newline at end of file
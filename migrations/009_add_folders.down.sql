@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_folders_user_parent;
+
+DROP TABLE IF EXISTS folders;
\ No newline at end of file
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS folders (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    parent_path TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT folders_user_path_unique UNIQUE (user_id, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folders_user_parent ON folders(user_id, parent_path);
+
+INSERT INTO folders (id, user_id, name, path, parent_path)
+SELECT gen_random_uuid(), s.user_id, s.seg[n], '/' || array_to_string(s.seg[1:n], '/'), '/' || array_to_string(s.seg[1:n-1], '/')
+FROM (
+    SELECT DISTINCT user_id, string_to_array(trim(leading '/' from path), '/') AS seg
+    FROM files
+    WHERE path <> '/'
+) s
+CROSS JOIN LATERAL generate_series(1, array_length(s.seg, 1)) AS n
+ON CONFLICT (user_id, path) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE quota_reservations
+DROP COLUMN IF EXISTS files;
+
+DROP TABLE IF EXISTS file_versions;
+
+ALTER TABLE files
+DROP COLUMN IF EXISTS current_version;
\ No newline at end of file
@@ -0,0 +1,24 @@
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS current_version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS file_versions (
+    id UUID PRIMARY KEY,
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL DEFAULT 0,
+    size BIGINT NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    storage_path TEXT NOT NULL,
+    bucket VARCHAR(100) NOT NULL,
+    checksum_algorithm VARCHAR(16) NOT NULL DEFAULT '',
+    checksum TEXT NOT NULL DEFAULT '',
+    upload_state VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (upload_state IN ('pending', 'active')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    superseded_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_versions_file_id ON file_versions(file_id, version DESC);
+CREATE INDEX IF NOT EXISTS idx_file_versions_pending ON file_versions(created_at) WHERE upload_state = 'pending';
+
+ALTER TABLE quota_reservations
+ADD COLUMN IF NOT EXISTS files SMALLINT NOT NULL DEFAULT 1;
\ No newline at end of file
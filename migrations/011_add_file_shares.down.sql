@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_file_shares_user_id;
+
+DROP TABLE IF EXISTS file_shares;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS file_shares (
+    id UUID PRIMARY KEY,
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'editor')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT file_shares_file_user_unique UNIQUE (file_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_shares_user_id ON file_shares(user_id, created_at DESC);
\ No newline at end of file
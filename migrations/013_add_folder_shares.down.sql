@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_folder_shares_user_id;
+
+DROP TABLE IF EXISTS folder_shares;
\ No newline at end of file
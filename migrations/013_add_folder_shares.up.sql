@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS folder_shares (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'editor')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT folder_shares_owner_path_user_unique UNIQUE (owner_id, path, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_shares_user_id ON folder_shares(user_id, owner_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_files_preview_queue;
+
+ALTER TABLE files
+DROP COLUMN IF EXISTS preview_state,
+DROP COLUMN IF EXISTS preview_updated_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS preview_state VARCHAR(16) NOT NULL DEFAULT 'none'
+CHECK (preview_state IN ('none', 'pending', 'processing', 'ready', 'failed')),
+ADD COLUMN IF NOT EXISTS preview_updated_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_files_preview_queue ON files(preview_updated_at) WHERE preview_state IN ('pending', 'processing');
\ No newline at end of file
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_file_versions_blob_hash;
+DROP INDEX IF EXISTS idx_files_blob_hash;
+
+ALTER TABLE file_versions
+DROP COLUMN IF EXISTS blob_hash;
+
+ALTER TABLE files
+DROP COLUMN IF EXISTS blob_hash;
+
+DROP TABLE IF EXISTS blobs;
\ No newline at end of file
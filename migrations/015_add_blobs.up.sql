@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS blobs (
+    hash VARCHAR(64) PRIMARY KEY,
+    bucket VARCHAR(100) NOT NULL,
+    storage_path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 1 CHECK (ref_count >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS blob_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE file_versions
+ADD COLUMN IF NOT EXISTS blob_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_files_blob_hash ON files(blob_hash) WHERE blob_hash <> '';
+CREATE INDEX IF NOT EXISTS idx_file_versions_blob_hash ON file_versions(blob_hash) WHERE blob_hash <> '';
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE file_versions DROP COLUMN IF EXISTS key_owner_id;
+ALTER TABLE files DROP COLUMN IF EXISTS key_owner_id;
+ALTER TABLE blobs DROP COLUMN IF EXISTS key_owner_id;
+
+DROP INDEX IF EXISTS idx_user_keys_master_key_id;
+DROP TABLE IF EXISTS user_keys;
\ No newline at end of file
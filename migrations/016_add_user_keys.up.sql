@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS user_keys (
+    user_id UUID PRIMARY KEY,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_keys_master_key_id ON user_keys(master_key_id);
+
+ALTER TABLE blobs
+ADD COLUMN IF NOT EXISTS key_owner_id VARCHAR(36) NOT NULL DEFAULT '';
+
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS key_owner_id VARCHAR(36) NOT NULL DEFAULT '';
+
+ALTER TABLE file_versions
+ADD COLUMN IF NOT EXISTS key_owner_id VARCHAR(36) NOT NULL DEFAULT '';
\ No newline at end of file
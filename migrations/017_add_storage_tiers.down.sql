@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_file_versions_bucket_storage_path;
+DROP INDEX IF EXISTS idx_files_bucket_storage_path;
+ALTER TABLE files DROP COLUMN IF EXISTS last_accessed_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_files_bucket_storage_path ON files(bucket, storage_path);
+
+CREATE INDEX IF NOT EXISTS idx_file_versions_bucket_storage_path ON file_versions(bucket, storage_path);
\ No newline at end of file
@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_files_tags;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'files' AND column_name = 'tags' AND data_type = 'jsonb'
+    ) THEN
+        ALTER TABLE files ADD COLUMN tags_text TEXT;
+
+        UPDATE files f
+        SET tags_text = (SELECT string_agg(key || '=' || value, ',') FROM jsonb_each_text(f.tags));
+
+        ALTER TABLE files DROP COLUMN tags;
+        ALTER TABLE files RENAME COLUMN tags_text TO tags;
+    END IF;
+END $$;
\ No newline at end of file
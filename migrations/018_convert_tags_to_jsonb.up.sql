@@ -0,0 +1,24 @@
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'files' AND column_name = 'tags' AND data_type = 'text'
+    ) THEN
+        ALTER TABLE files ADD COLUMN tags_json JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+        UPDATE files f
+        SET tags_json = t.tags
+        FROM (
+            SELECT id, jsonb_object_agg(split_part(kv, '=', 1), substr(kv, strpos(kv, '=') + 1)) AS tags
+            FROM files, unnest(string_to_array(files.tags, ',')) AS kv
+            WHERE strpos(kv, '=') > 0
+            GROUP BY id
+        ) t
+        WHERE f.id = t.id;
+
+        ALTER TABLE files DROP COLUMN tags;
+        ALTER TABLE files RENAME COLUMN tags_json TO tags;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_files_tags ON files USING GIN (tags);
\ No newline at end of file
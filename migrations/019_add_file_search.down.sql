@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_files_original_name_trgm;
+DROP INDEX IF EXISTS idx_files_search_vector;
+
+ALTER TABLE files
+DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', translate(coalesce(original_name, ''), '._-', '   ')), 'A') ||
+    setweight(to_tsvector('simple', translate(coalesce(path, ''), '/._-', '    ')), 'B') ||
+    setweight(jsonb_to_tsvector('simple', coalesce(tags, '{}'::jsonb), '["key", "string"]'), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_files_search_vector ON files USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_files_original_name_trgm ON files USING GIN (original_name gin_trgm_ops);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_files_user_size;
+DROP INDEX IF EXISTS idx_files_user_filename;
+DROP INDEX IF EXISTS idx_files_user_updated_at;
+DROP INDEX IF EXISTS idx_files_user_created_at;
\ No newline at end of file
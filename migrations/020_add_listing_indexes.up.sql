@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_files_user_created_at ON files(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_files_user_updated_at ON files(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_files_user_filename ON files(user_id, filename, id);
+CREATE INDEX IF NOT EXISTS idx_files_user_size ON files(user_id, size, id);
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS file_contents;
+
+DROP INDEX IF EXISTS idx_files_content_queue;
+
+ALTER TABLE files
+DROP COLUMN IF EXISTS content_updated_at,
+DROP COLUMN IF EXISTS content_state;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE files
+ADD COLUMN IF NOT EXISTS content_state VARCHAR(16) NOT NULL DEFAULT 'none'
+CHECK (content_state IN ('none', 'pending', 'processing', 'ready', 'failed')),
+ADD COLUMN IF NOT EXISTS content_updated_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_files_content_queue ON files(content_updated_at) WHERE content_state IN ('pending', 'processing');
+
+CREATE TABLE IF NOT EXISTS file_contents (
+    file_id UUID PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    content_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED,
+    extracted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_contents_vector ON file_contents USING GIN (content_vector);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS file_activity;
\ No newline at end of file
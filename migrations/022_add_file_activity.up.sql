@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS file_activity (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    last_action VARCHAR(16) CHECK (last_action IN ('upload', 'download', 'edit')),
+    last_active_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    starred_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (user_id, file_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_activity_recent ON file_activity(user_id, last_active_at DESC) WHERE last_active_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_file_activity_starred ON file_activity(user_id, starred_at DESC) WHERE starred_at IS NOT NULL;
\ No newline at end of file
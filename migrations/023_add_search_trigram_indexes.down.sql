@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_files_tags_trgm;
+DROP INDEX IF EXISTS idx_files_path_trgm;
\ No newline at end of file
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_files_path_trgm ON files USING GIN (path gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_files_tags_trgm ON files USING GIN ((tags::text) gin_trgm_ops);
\ No newline at end of file